@@ -10,6 +10,7 @@ import (
 	"math/rand"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/jinzhu/gorm"
 	"github.com/joho/godotenv"
 )
@@ -22,6 +23,7 @@ type Demo struct {
 	transactionService *services.TransactionService
 	fairnessService    *services.FairnessService
 	metricsService     *services.MetricsService
+	ledgerService      *services.LedgerService
 	users              []models.User
 }
 
@@ -60,6 +62,7 @@ func (d *Demo) initialize() error {
 	d.transactionService = services.NewTransactionService(d.db)
 	d.fairnessService = services.NewFairnessService(d.db)
 	d.metricsService = services.NewMetricsService(d.db)
+	d.ledgerService = services.NewLedgerService(d.db)
 
 	return nil
 }
@@ -604,6 +607,29 @@ func (d *Demo) getTFICategory(tfi int) string {
 	return "Needs Improvement"
 }
 
+// fundWallet mints amount into a user's wallet from the issuance account
+func (d *Demo) fundWallet(userID uuid.UUID, amount models.Money) error {
+	tx := d.db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	account, err := d.ledgerService.UserAccount(tx, userID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	issuance, err := d.ledgerService.SystemAccount(tx, models.AccountTypeIssuance)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := d.ledgerService.Move(tx, nil, "Demo starting balance", issuance, account, amount); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
 func (d *Demo) generateTransactionHistory() {
 	fmt.Println("\n💰 Step 6: Generating realistic transaction history...")
 
@@ -615,39 +641,34 @@ func (d *Demo) generateTransactionHistory() {
 
 	// Generate initial wallet balances
 	for _, user := range d.users {
-		initialBalance := float64(rand.Intn(500) + 100) // 100-600 FC
-
-		// Create wallet directly
-		wallet := &models.Wallet{
-			UserID:  user.ID,
-			Balance: initialBalance,
-		}
+		initialBalance := models.FC(int64(rand.Intn(500) + 100)) // 100-600 FC
 
-		if err := d.db.Create(wallet).Error; err != nil {
-			log.Printf("Error creating wallet for %s: %v", user.Username, err)
+		// Top up the wallet created at registration through the ledger
+		if err := d.fundWallet(user.ID, initialBalance); err != nil {
+			log.Printf("Error funding wallet for %s: %v", user.Username, err)
 		} else {
-			fmt.Printf("   💳 %s: %.2f FC initial balance\n", user.FirstName, wallet.Balance)
+			fmt.Printf("   💳 %s: %s FC initial balance\n", user.FirstName, initialBalance.StringFixed(2))
 		}
 	}
 
 	// Generate diverse transactions over the past 3 months
 	transactionCount := 0
-	totalVolume := 0.0
+	var totalVolume models.Money
 
 	// Monthly issuance rewards (based on PFI)
 	for _, user := range d.users {
 		for month := 2; month >= 0; month-- {
 			// Higher PFI users get more monthly rewards
 			rewardMultiplier := float64(user.PFI)/100.0*0.5 + 0.5 // 0.5x to 1.0x
-			baseReward := 50.0
-			reward := baseReward * rewardMultiplier
+			baseReward := models.FC(50)
+			reward := baseReward.MulRate(rewardMultiplier)
 
 			transaction := &models.Transaction{
 				UserID:      user.ID,
 				Type:        models.TransactionTypeMonthlyIssuance,
 				Amount:      reward,
 				Description: "Monthly FairCoin issuance reward",
-				Status:      "completed",
+				Status:      models.TransactionStatusCompleted,
 				CreatedAt:   time.Now().AddDate(0, -month, -rand.Intn(28)),
 			}
 
@@ -666,14 +687,14 @@ func (d *Demo) generateTransactionHistory() {
 		} // Max 5 rewards
 
 		for i := 0; i < rewardCount; i++ {
-			reward := float64(rand.Intn(30) + 20) // 20-50 FC
+			reward := models.FC(int64(rand.Intn(30) + 20)) // 20-50 FC
 
 			transaction := &models.Transaction{
 				UserID:      user.ID,
 				Type:        models.TransactionTypeFairnessReward,
 				Amount:      reward,
 				Description: "Community service fairness reward",
-				Status:      "completed",
+				Status:      models.TransactionStatusCompleted,
 				CreatedAt:   time.Now().AddDate(0, 0, -rand.Intn(90)),
 			}
 
@@ -692,15 +713,15 @@ func (d *Demo) generateTransactionHistory() {
 			for i := 0; i < incentiveCount; i++ {
 				// Higher TFI merchants get bigger incentives
 				incentiveMultiplier := float64(user.TFI)/100.0*0.5 + 0.5
-				baseIncentive := 75.0
-				incentive := baseIncentive * incentiveMultiplier
+				baseIncentive := models.FC(75)
+				incentive := baseIncentive.MulRate(incentiveMultiplier)
 
 				transaction := &models.Transaction{
 					UserID:      user.ID,
 					Type:        models.TransactionTypeMerchantIncentive,
 					Amount:      incentive,
 					Description: "Fair trade merchant incentive",
-					Status:      "completed",
+					Status:      models.TransactionStatusCompleted,
 					CreatedAt:   time.Now().AddDate(0, 0, -rand.Intn(90)),
 				}
 
@@ -723,8 +744,8 @@ func (d *Demo) generateTransactionHistory() {
 			toUser = d.users[rand.Intn(len(d.users))]
 		}
 
-		amount := float64(rand.Intn(200) + 10) // 10-210 FC
-		fee := amount * 0.001                  // 0.1% fee
+		amount := models.FC(int64(rand.Intn(200) + 10)) // 10-210 FC
+		fee := amount.MulFrac(1, 1000)                  // 0.1% fee
 
 		transaction := &models.Transaction{
 			UserID:      fromUser.ID,
@@ -733,7 +754,7 @@ func (d *Demo) generateTransactionHistory() {
 			Amount:      amount,
 			Fee:         fee,
 			Description: "P2P transfer",
-			Status:      "completed",
+			Status:      models.TransactionStatusCompleted,
 			CreatedAt:   time.Now().AddDate(0, 0, -rand.Intn(90)),
 		}
 
@@ -743,7 +764,7 @@ func (d *Demo) generateTransactionHistory() {
 		}
 	}
 
-	fmt.Printf("   📈 Generated %d transactions with %.2f FC total volume\n", transactionCount, totalVolume)
+	fmt.Printf("   📈 Generated %d transactions with %s FC total volume\n", transactionCount, totalVolume.StringFixed(2))
 	fmt.Println("   ✅ Transaction history generated")
 }
 
@@ -835,8 +856,8 @@ func (d *Demo) generateVotes(proposal *models.Proposal) {
 
 		// Calculate voting power (60% wallet balance + 40% PFI)
 		// For demo purposes, assume equal wallet balances
-		totalSupply := 10000.0 // Estimated total FairCoin supply
-		walletBalance := 200.0 // Average wallet balance
+		totalSupply := models.FC(10000) // Estimated total FairCoin supply
+		walletBalance := models.FC(200) // Average wallet balance
 		votingPower := voter.CalculateVotingPower(walletBalance, totalSupply)
 
 		// Vote based on proposal type and voter profile
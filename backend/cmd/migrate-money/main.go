@@ -0,0 +1,41 @@
+package main
+
+import (
+	"faircoin/internal/config"
+	"faircoin/internal/database"
+	"faircoin/internal/models"
+	"log"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using environment variables")
+	}
+
+	// Load configuration
+	cfg := config.Load()
+
+	// Initialize database
+	db, err := database.Initialize(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	// Convert float64 balances, amounts and fees to fixed-point minor units
+	if err := database.MigrateMoneyColumns(db); err != nil {
+		log.Fatalf("Failed to migrate money columns: %v", err)
+	}
+
+	// Report the resulting circulating supply so it can be reconciled
+	var totalSupply struct {
+		Total models.Money
+	}
+	db.Model(&models.Wallet{}).Select("SUM(balance) as total").Scan(&totalSupply)
+
+	log.Printf("Circulating supply after migration: %s FC", totalSupply.Total)
+	log.Println("Money migration completed successfully")
+}
@@ -50,8 +50,8 @@ func main() {
 				toIdx = rand.Intn(len(users))
 			}
 
-			amount := float64(rand.Intn(100) + 20) // Random amount between 20-120
-			fee := amount * 0.001
+			amount := models.FC(int64(rand.Intn(100) + 20)) // Random amount between 20-120
+			fee := amount.MulFrac(1, 1000)
 
 			// Create date within the month
 			baseDate := time.Now().AddDate(0, -month, 0)
@@ -67,14 +67,14 @@ func main() {
 				Amount:      amount,
 				Fee:         fee,
 				Description: "Transfer transaction",
-				Status:      "completed",
+				Status:      models.TransactionStatusCompleted,
 				CreatedAt:   transactionDate,
 			}
 
 			if err := db.Create(transaction).Error; err != nil {
 				log.Printf("Error creating transfer transaction: %v", err)
 			} else {
-				fmt.Printf("Created transfer: %.2f FC from %s to %s on %s\n",
+				fmt.Printf("Created transfer: %s FC from %s to %s on %s\n",
 					amount, users[fromIdx].Username, users[toIdx].Username,
 					transactionDate.Format("2006-01-02"))
 			}
@@ -89,7 +89,7 @@ func main() {
 		monthEnd := monthStart.AddDate(0, 1, 0).Add(-time.Second)
 
 		var monthVolume struct {
-			Total float64
+			Total models.Money
 			Count int64
 		}
 
@@ -97,7 +97,7 @@ func main() {
 			Where("created_at BETWEEN ? AND ? AND type = ?", monthStart, monthEnd, models.TransactionTypeTransfer).
 			Select("COALESCE(SUM(amount), 0) as total, COUNT(*) as count").Scan(&monthVolume)
 
-		fmt.Printf("%s: %.2f FC (%d transactions)\n", date.Format("Jan 2006"), monthVolume.Total, monthVolume.Count)
+		fmt.Printf("%s: %s FC (%d transactions)\n", date.Format("Jan 2006"), monthVolume.Total.StringFixed(2), monthVolume.Count)
 	}
 
 	fmt.Println("\nTransaction volume data creation completed!")
@@ -42,7 +42,7 @@ func main() {
 	db.Find(&allTransactions)
 	fmt.Printf("All transactions:\n")
 	for _, tx := range allTransactions {
-		fmt.Printf("  ID: %s, Type: %s, Amount: %.2f, Date: %s\n", tx.ID, tx.Type, tx.Amount, tx.CreatedAt.Format("2006-01-02 15:04:05"))
+		fmt.Printf("  ID: %s, Type: %s, Amount: %s, Date: %s\n", tx.ID, tx.Type, tx.Amount.StringFixed(2), tx.CreatedAt.Format("2006-01-02 15:04:05"))
 	}
 
 	// Check transactions by month for the last 6 months
@@ -70,6 +70,6 @@ func main() {
 	db.Where("type = ?", models.TransactionTypeTransfer).Limit(5).Find(&transactions)
 
 	for _, tx := range transactions {
-		fmt.Printf("ID: %s, Amount: %.2f, Date: %s\n", tx.ID, tx.Amount, tx.CreatedAt.Format("2006-01-02 15:04:05"))
+		fmt.Printf("ID: %s, Amount: %s, Date: %s\n", tx.ID, tx.Amount.StringFixed(2), tx.CreatedAt.Format("2006-01-02 15:04:05"))
 	}
 }
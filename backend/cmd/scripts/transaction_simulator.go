@@ -94,19 +94,19 @@ func main() {
 		}
 
 		// Generate random amount within type's range
-		amount := txType.MinAmount + rand.Float64()*(txType.MaxAmount-txType.MinAmount)
-		amount = float64(int(amount*100)) / 100 // Round to 2 decimal places
+		rawAmount := txType.MinAmount + rand.Float64()*(txType.MaxAmount-txType.MinAmount)
+		amount := models.MoneyFromFloat(float64(int(rawAmount*100)) / 100) // Round to 2 decimal places
 
 		// Calculate fee (0.1% for most transactions)
-		fee := 0.0
+		var fee models.Money
 		if txType.Type == models.TransactionTypeTransfer || txType.Type == models.TransactionTypeMerchantIncentive {
-			fee = amount * 0.001
+			fee = amount.MulFrac(1, 1000)
 		}
 
 		// Generate description based on transaction type
 		description := generateDescription(txType.Type, fromUser.Username, toUser)
 
-		// Create transaction
+		// Create transaction; only payments between users can be declined
 		transaction := &models.Transaction{
 			ID:          uuid.New(),
 			UserID:      fromUser.ID,
@@ -114,9 +114,16 @@ func main() {
 			Amount:      amount,
 			Fee:         fee,
 			Description: description,
-			Status:      selectStatus(),
+			Status:      models.TransactionStatusCompleted,
 			CreatedAt:   transactionDate,
 		}
+		if txType.RequiresToUser {
+			if reason := selectFailure(); reason != "" {
+				transaction.Status = models.TransactionStatusFailed
+				transaction.FailureReason = reason
+				transaction.Fee = 0 // Declined payments are not charged
+			}
+		}
 
 		if toUser != nil {
 			transaction.ToUserID = &toUser.ID
@@ -199,9 +206,11 @@ func generateDescription(txType models.TransactionType, fromUser string, toUser
 	}
 }
 
-func selectStatus() string {
-	statuses := []string{"completed", "pending", "failed"}
-	weights := []float64{0.85, 0.10, 0.05} // 85% completed, 10% pending, 5% failed
+// selectFailure returns why a simulated payment was declined, or "" for
+// one that went through
+func selectFailure() string {
+	reasons := []string{"insufficient balance", "spending limit exceeded", "wallet is frozen"}
+	weights := []float64{0.04, 0.02, 0.01} // 93% of payments go through
 
 	r := rand.Float64()
 	cumulative := 0.0
@@ -209,11 +218,11 @@ func selectStatus() string {
 	for i, weight := range weights {
 		cumulative += weight
 		if r <= cumulative {
-			return statuses[i]
+			return reasons[i]
 		}
 	}
 
-	return "completed"
+	return ""
 }
 
 func showSummaryStats(db *gorm.DB) {
@@ -237,17 +246,17 @@ func showSummaryStats(db *gorm.DB) {
 
 	for _, txType := range types {
 		var count int64
-		var totalAmount float64
+		var totalAmount models.Money
 
 		db.Model(&models.Transaction{}).Where("type = ?", txType).Count(&count)
 		db.Model(&models.Transaction{}).Where("type = ?", txType).Select("COALESCE(SUM(amount), 0)").Row().Scan(&totalAmount)
 
-		fmt.Printf("  %s: %d transactions, %.2f FC total\n", txType, count, totalAmount)
+		fmt.Printf("  %s: %d transactions, %s FC total\n", txType, count, totalAmount.StringFixed(2))
 	}
 
 	// Transactions by status
 	fmt.Println("\nTransactions by status:")
-	statuses := []string{"completed", "pending", "failed"}
+	statuses := []models.TransactionStatus{models.TransactionStatusCompleted, models.TransactionStatusFailed}
 	for _, status := range statuses {
 		var count int64
 		db.Model(&models.Transaction{}).Where("status = ?", status).Count(&count)
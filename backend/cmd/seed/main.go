@@ -98,7 +98,7 @@ func main() {
 				continue
 			}
 
-			amount := float64(rand.Intn(1000) + 10)
+			amount := models.FC(int64(rand.Intn(1000) + 10))
 			_, err := walletService.Transfer(
 				userIDs[fromIdx],
 				userIDs[toIdx],
@@ -109,7 +109,7 @@ func main() {
 				log.Printf("Error creating transaction: %v", err)
 				continue
 			}
-			log.Printf("Created transaction: %.2f FC", amount)
+			log.Printf("Created transaction: %s FC", amount)
 		}
 	}
 
@@ -124,7 +124,7 @@ func main() {
 					continue
 				}
 
-				amount := float64(rand.Intn(50) + 20) // Smaller amounts to avoid balance issues
+				amount := models.FC(int64(rand.Intn(50) + 20)) // Smaller amounts to avoid balance issues
 
 				// Create transaction record directly in database (bypassing balance checks)
 				transaction := &models.Transaction{
@@ -132,16 +132,16 @@ func main() {
 					ToUserID:    &userIDs[toIdx],
 					Type:        models.TransactionTypeTransfer,
 					Amount:      amount,
-					Fee:         amount * 0.001,
+					Fee:         amount.MulFrac(1, 1000),
 					Description: "Historical test transaction",
-					Status:      "completed",
+					Status:      models.TransactionStatusCompleted,
 					CreatedAt:   time.Now().AddDate(0, -month, -rand.Intn(28)),
 				}
 
 				if err := transactionService.GetDB().Create(transaction).Error; err != nil {
 					log.Printf("Error creating transfer transaction: %v", err)
 				} else {
-					log.Printf("Created transfer transaction: %.2f FC for month -%d", amount, month)
+					log.Printf("Created transfer transaction: %s FC for month -%d", amount, month)
 				}
 			}
 		}
@@ -158,17 +158,17 @@ func main() {
 		transaction := &models.Transaction{
 			UserID:      userID,
 			Type:        models.TransactionTypeFairnessReward,
-			Amount:      float64(rand.Intn(50) + 10),
+			Amount:      models.FC(int64(rand.Intn(50) + 10)),
 			Fee:         0,
 			Description: "Monthly fairness reward",
-			Status:      "completed",
+			Status:      models.TransactionStatusCompleted,
 			CreatedAt:   time.Now().AddDate(0, 0, -rand.Intn(7)),
 		}
 
 		if err := transactionService.GetDB().Create(transaction).Error; err != nil {
 			log.Printf("Error creating fairness reward: %v", err)
 		} else {
-			log.Printf("Created fairness reward: %.2f FC", transaction.Amount)
+			log.Printf("Created fairness reward: %s FC", transaction.Amount)
 		}
 	}
 
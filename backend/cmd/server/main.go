@@ -44,6 +44,94 @@ func main() {
 	governanceService := services.NewGovernanceService(db)
 	monetaryService := services.NewMonetaryService(db)
 	metricsService := services.NewMetricsService(db)
+	ledgerService := services.NewLedgerService(db)
+	idempotencyService := services.NewIdempotencyService(db)
+	treasuryService := services.NewTreasuryService(db)
+	vestingService := services.NewVestingService(db)
+	standingOrderService := services.NewStandingOrderService(db, walletService)
+	feeService := services.NewFeeService(db)
+	demurrageService := services.NewDemurrageService(db)
+	chainService := services.NewTransactionChainService(db)
+	reservesService := services.NewReservesService(db)
+
+	// Enforce the per-wallet holding cap on transfers, issuance and treasury payouts
+	holdingCap, err := services.NewHoldingCap(cfg.HoldingCapPercentage, cfg.HoldingCapMinimum,
+		cfg.HoldingCapOverflow, cfg.HoldingCapExemptIDs)
+	if err != nil {
+		log.Fatalf("Invalid holding cap configuration: %v", err)
+	}
+	walletService.SetHoldingCap(holdingCap)
+	monetaryService.SetHoldingCap(holdingCap)
+	treasuryService.SetHoldingCap(holdingCap)
+	monetaryService.SetRewardVesting(cfg.IssuanceVestingCliff, cfg.IssuanceVestingPeriod)
+
+	// Default send limits, lower for unverified users
+	spendingLimits, err := services.NewSpendingLimits(cfg.SendLimitDaily, cfg.SendLimitMonthly,
+		cfg.SendLimitDailyUnverified, cfg.SendLimitMonthlyUnverified)
+	if err != nil {
+		log.Fatalf("Invalid send limit configuration: %v", err)
+	}
+	walletService.SetSpendingLimits(spendingLimits)
+
+	// Credit lines for trusted members
+	creditPolicy, err := services.NewCreditPolicy(cfg.CreditMinPFI, cfg.CreditBaseLimit, cfg.CreditPerPFIPoint,
+		cfg.CreditMaxLimit, cfg.CreditHistoryMonths, cfg.CreditCouncilMaxLimit)
+	if err != nil {
+		log.Fatalf("Invalid credit configuration: %v", err)
+	}
+	walletService.SetCreditPolicy(creditPolicy)
+
+	// Sign voucher codes and payment URIs with keys derived from the JWT secret
+	walletService.SetSigningSecret(cfg.JWTSecret)
+	chainService.SetSigningSecret(cfg.JWTSecret)
+	if err := chainService.SetTrustedKeys(cfg.ChainTrustedKeys); err != nil {
+		log.Fatalf("Invalid chain configuration: %v", err)
+	}
+
+	// Chain transactions that predate the tamper-evident log, before
+	// anything else records new ones
+	if sealed, err := chainService.SealLegacyTransactions(); err != nil {
+		log.Printf("Warning: Failed to chain existing transactions: %v", err)
+	} else if sealed > 0 {
+		log.Printf("Added %d existing transactions to the transaction chain", sealed)
+	}
+	if sealed, err := chainService.SealStatusHistory(); err != nil {
+		log.Printf("Warning: Failed to chain existing status history: %v", err)
+	} else if sealed > 0 {
+		log.Printf("Added %d existing status changes to the status chain", sealed)
+	}
+
+	// Record the configured fee policy as a schedule version if it changed
+	feePolicy, err := services.NewFeePolicy(cfg.FeeRateBPS, cfg.FeeMinimum, cfg.FeeMaximum,
+		cfg.FeeTiers, cfg.FeeMerchantRates, cfg.FeeFreeBelowPFI, cfg.FeeFreeForVerified)
+	if err != nil {
+		log.Fatalf("Invalid fee configuration: %v", err)
+	}
+	if schedule, err := feeService.EnsureConfigSchedule(feePolicy); err != nil {
+		log.Printf("Warning: Failed to record fee schedule: %v", err)
+	} else if schedule.Source != services.PolicySourceConfig {
+		log.Printf("Fee schedule version %d was adopted by governance; fee configuration is not applied", schedule.Version)
+	}
+	if err := demurrageService.Configure(cfg.DemurrageRate, cfg.DemurrageThreshold, cfg.DemurrageDestination); err != nil {
+		log.Fatalf("Invalid demurrage configuration: %v", err)
+	}
+	if err := reservesService.SetSnapshotInterval(cfg.ReservesSnapshotInterval); err != nil {
+		log.Fatalf("Invalid reserves configuration: %v", err)
+	}
+	if err := fairnessService.SetAttestationReview(cfg.AttestationRequiredCount, cfg.AttestationReviewerMinPFI); err != nil {
+		log.Fatalf("Invalid attestation configuration: %v", err)
+	}
+	if err := fairnessService.SetAttestationAging(cfg.AttestationAging); err != nil {
+		log.Fatalf("Invalid attestation aging configuration: %v", err)
+	}
+
+	// Bring wallets that predate the ledger into it
+	if err := ledgerService.EnsureOpeningBalances(); err != nil {
+		log.Printf("Warning: Failed to record opening ledger balances: %v", err)
+	}
+	if err := treasuryService.SweepFeeIncome(); err != nil {
+		log.Printf("Warning: Failed to sweep fee income into treasury: %v", err)
+	}
 
 	// Start background services
 	go func() {
@@ -60,6 +148,9 @@ func main() {
 			if err := monetaryService.ProcessMonthlyIssuance(); err != nil {
 				log.Printf("Error processing monthly issuance: %v", err)
 			}
+			if _, err := demurrageService.ProcessMonthlyDemurrage(); err != nil {
+				log.Printf("Error charging demurrage: %v", err)
+			}
 
 			// Update fairness metrics
 			if err := metricsService.UpdateDailyMetrics(); err != nil {
@@ -75,6 +166,68 @@ func main() {
 			if err := metricsService.CheckForAlerts(); err != nil {
 				log.Printf("Error checking for alerts: %v", err)
 			}
+
+			// Unlock vested and time-locked funds
+			if err := vestingService.ReleaseMatured(); err != nil {
+				log.Printf("Error releasing locked funds: %v", err)
+			}
+
+			// Expire unpaid payment requests
+			if err := walletService.ExpireInvoices(); err != nil {
+				log.Printf("Error expiring invoices: %v", err)
+			}
+
+			// Refund vouchers that were never redeemed
+			if err := walletService.ExpireVouchers(); err != nil {
+				log.Printf("Error expiring vouchers: %v", err)
+			}
+
+			// Resolve escrows that reached their timeout
+			if err := walletService.ResolveExpiredEscrows(); err != nil {
+				log.Printf("Error resolving expired escrows: %v", err)
+			}
+
+			// Close finished proposals, pay out passed treasury spends and
+			// adopt passed fee schedules and demurrage rates
+			if err := governanceService.ProcessExpiredProposals(); err != nil {
+				log.Printf("Error processing expired proposals: %v", err)
+			}
+			if err := treasuryService.ExecutePassedProposals(); err != nil {
+				log.Printf("Error executing treasury spends: %v", err)
+			}
+			if err := feeService.ApplyPassedProposals(); err != nil {
+				log.Printf("Error applying fee schedule proposals: %v", err)
+			}
+			if err := demurrageService.ApplyPassedProposals(); err != nil {
+				log.Printf("Error applying demurrage rate proposals: %v", err)
+			}
+
+			// Sign the transaction chain's head
+			if _, err := chainService.CreateCheckpoint(); err != nil {
+				log.Printf("Error creating chain checkpoint: %v", err)
+			}
+
+			// Publish a balance snapshot for proof of reserves
+			if _, err := reservesService.SnapshotIfDue(); err != nil {
+				log.Printf("Error creating balance snapshot: %v", err)
+			}
+
+			// Drop expired idempotency keys
+			if err := idempotencyService.PurgeExpired(); err != nil {
+				log.Printf("Error purging idempotency keys: %v", err)
+			}
+		}
+	}()
+
+	// Pay due standing orders
+	go func() {
+		ticker := time.NewTicker(1 * time.Minute)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := standingOrderService.ProcessDue(); err != nil {
+				log.Printf("Error processing standing orders: %v", err)
+			}
 		}
 	}()
 
@@ -90,7 +243,8 @@ func main() {
 		origin := c.Request.Header.Get("Origin")
 		c.Header("Access-Control-Allow-Origin", origin)
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization, Idempotency-Key")
+		c.Header("Access-Control-Expose-Headers", "Idempotent-Replayed")
 		c.Header("Access-Control-Allow-Credentials", "true")
 
 		if c.Request.Method == "OPTIONS" {
@@ -110,6 +264,15 @@ func main() {
 		governanceService,
 		monetaryService,
 		metricsService,
+		ledgerService,
+		idempotencyService,
+		treasuryService,
+		vestingService,
+		standingOrderService,
+		feeService,
+		demurrageService,
+		chainService,
+		reservesService,
 		cfg,
 	)
 
@@ -131,7 +294,9 @@ func main() {
 			users.GET("/profile", apiHandler.GetProfile)
 			users.PUT("/profile", apiHandler.UpdateProfile)
 			users.GET("/pfi", apiHandler.GetPFI)
-			users.POST("/attest", apiHandler.AttestUser)
+			users.POST("/attest", apiHandler.IdempotencyMiddleware(), apiHandler.AttestUser)
+			users.GET("/attestations", apiHandler.GetUserAttestations)
+			users.POST("/attestations/:id/revoke", apiHandler.RevokeAttestation)
 		}
 
 		// Wallet routes (protected)
@@ -140,7 +305,68 @@ func main() {
 		{
 			wallet.GET("/balance", apiHandler.GetBalance)
 			wallet.GET("/history", apiHandler.GetTransactionHistory)
-			wallet.POST("/send", apiHandler.SendFairCoins)
+			wallet.GET("/statement", apiHandler.GetStatement)
+			wallet.GET("/locks", apiHandler.GetLocks)
+			wallet.GET("/limits", apiHandler.GetSpendingLimits)
+			wallet.GET("/pots", apiHandler.GetPots)
+			wallet.POST("/pots", apiHandler.CreatePot)
+			wallet.POST("/pots/move", apiHandler.MovePotFunds)
+			wallet.PUT("/pots/:id", apiHandler.UpdatePot)
+			wallet.DELETE("/pots/:id", apiHandler.DeletePot)
+			wallet.GET("/credit", apiHandler.GetCreditStatus)
+			wallet.POST("/credit/request", apiHandler.RequestCredit)
+			wallet.POST("/send", apiHandler.IdempotencyMiddleware(), apiHandler.SendFairCoins)
+			wallet.GET("/fee-quote", apiHandler.GetFeeQuote)
+			wallet.GET("/demurrage", apiHandler.GetDemurrageProjection)
+			wallet.GET("/reserves-proof", apiHandler.GetBalanceProof)
+			wallet.POST("/batch", apiHandler.IdempotencyMiddleware(), apiHandler.SendBatch)
+			wallet.GET("/transactions/:id", apiHandler.GetTransaction)
+			wallet.POST("/transactions/:id/refund", apiHandler.IdempotencyMiddleware(), apiHandler.RefundTransaction)
+		}
+
+		// Invoice routes (protected)
+		invoices := v1.Group("/invoices")
+		invoices.Use(apiHandler.AuthMiddleware())
+		{
+			invoices.POST("/", apiHandler.IdempotencyMiddleware(), apiHandler.CreateInvoice)
+			invoices.GET("/issued", apiHandler.GetIssuedInvoices)
+			invoices.GET("/inbox", apiHandler.GetInvoiceInbox)
+			invoices.GET("/:id", apiHandler.GetInvoice)
+			invoices.POST("/:id/pay", apiHandler.IdempotencyMiddleware(), apiHandler.PayInvoice)
+			invoices.POST("/:id/cancel", apiHandler.CancelInvoice)
+		}
+
+		// Standing order routes (protected)
+		standingOrders := v1.Group("/standing-orders")
+		standingOrders.Use(apiHandler.AuthMiddleware())
+		{
+			standingOrders.GET("/", apiHandler.GetStandingOrders)
+			standingOrders.POST("/", apiHandler.IdempotencyMiddleware(), apiHandler.CreateStandingOrder)
+			standingOrders.GET("/:id", apiHandler.GetStandingOrder)
+			standingOrders.POST("/:id/:action", apiHandler.UpdateStandingOrderStatus)
+		}
+
+		// Escrow routes (protected)
+		escrow := v1.Group("/escrow")
+		escrow.Use(apiHandler.AuthMiddleware())
+		{
+			escrow.GET("/", apiHandler.GetEscrows)
+			escrow.POST("/", apiHandler.IdempotencyMiddleware(), apiHandler.OpenEscrow)
+			escrow.GET("/:id", apiHandler.GetEscrow)
+			escrow.POST("/:id/release", apiHandler.IdempotencyMiddleware(), apiHandler.ReleaseEscrow)
+			escrow.POST("/:id/refund", apiHandler.IdempotencyMiddleware(), apiHandler.RefundEscrow)
+		}
+
+		// Voucher routes (protected)
+		vouchers := v1.Group("/vouchers")
+		vouchers.Use(apiHandler.AuthMiddleware())
+		{
+			vouchers.GET("/", apiHandler.GetVouchers)
+			vouchers.POST("/", apiHandler.IdempotencyMiddleware(), apiHandler.IssueVoucher)
+			vouchers.POST("/check", apiHandler.CheckVoucher)
+			vouchers.POST("/redeem", apiHandler.IdempotencyMiddleware(), apiHandler.RedeemVoucher)
+			vouchers.GET("/:id", apiHandler.GetVoucher)
+			vouchers.POST("/:id/cancel", apiHandler.CancelVoucher)
 		}
 
 		// Merchant routes (protected)
@@ -149,8 +375,10 @@ func main() {
 		{
 			merchants.GET("/", apiHandler.GetMerchants)
 			merchants.POST("/register", apiHandler.RegisterMerchant)
+			merchants.POST("/payment-uri", apiHandler.CreatePaymentURI)
+			merchants.GET("/payment-uri/resolve", apiHandler.ResolvePaymentURI)
 			merchants.GET("/:id/tfi", apiHandler.GetMerchantTFI)
-			merchants.POST("/:id/rate", apiHandler.RateMerchant)
+			merchants.POST("/:id/rate", apiHandler.IdempotencyMiddleware(), apiHandler.RateMerchant)
 		}
 
 		// Governance routes (protected)
@@ -159,8 +387,12 @@ func main() {
 		{
 			governance.GET("/proposals", apiHandler.GetProposals)
 			governance.POST("/proposals", apiHandler.CreateProposal)
-			governance.POST("/proposals/:id/vote", apiHandler.VoteOnProposal)
+			governance.POST("/proposals/:id/vote", apiHandler.IdempotencyMiddleware(), apiHandler.VoteOnProposal)
 			governance.GET("/council", apiHandler.GetCouncilMembers)
+			governance.GET("/credit-requests", apiHandler.GetCreditRequests)
+			governance.POST("/credit-requests/:id/vote", apiHandler.IdempotencyMiddleware(), apiHandler.VoteOnCreditRequest)
+			governance.GET("/attestations", apiHandler.GetAttestationQueue)
+			governance.POST("/attestations/:id/review", apiHandler.IdempotencyMiddleware(), apiHandler.ReviewAttestation)
 		}
 
 		// Public routes
@@ -169,6 +401,12 @@ func main() {
 			public.GET("/stats", apiHandler.GetCommunityStats)
 			public.GET("/cbi", apiHandler.GetCommunityBasketIndex)
 			public.GET("/merchants", apiHandler.GetPublicMerchants)
+			public.GET("/supply", apiHandler.GetSupplyProof)
+			public.GET("/chain/checkpoints", apiHandler.GetChainCheckpoints)
+			public.GET("/reserves", apiHandler.GetReserves)
+			public.POST("/reserves/verify", apiHandler.VerifyBalanceProof)
+			public.GET("/treasury", apiHandler.GetTreasury)
+			public.GET("/fee-schedule", apiHandler.GetFeeSchedule)
 		}
 
 		// Public Fairness Metrics routes
@@ -203,6 +441,26 @@ func main() {
 			admin.GET("/transaction-volume", apiHandler.GetTransactionVolume)
 			admin.PUT("/users/:id", apiHandler.UpdateUserStatus)
 			admin.GET("/monetary-policy", apiHandler.GetMonetaryPolicyInfo)
+			admin.GET("/ledger/accounts", apiHandler.GetLedgerAccounts)
+			admin.GET("/ledger/accounts/:id/entries", apiHandler.GetLedgerAccountEntries)
+			admin.POST("/treasury/proposals/:id/execute", apiHandler.ExecuteTreasuryProposal)
+			admin.POST("/fee-schedule/proposals/:id/apply", apiHandler.ApplyFeeScheduleProposal)
+			admin.GET("/demurrage/runs", apiHandler.GetDemurrageRuns)
+			admin.POST("/demurrage/proposals/:id/apply", apiHandler.ApplyDemurrageProposal)
+			admin.GET("/holding-cap", apiHandler.GetHoldingCapReport)
+			admin.POST("/escrow/:id/:action", apiHandler.ResolveEscrow)
+			admin.POST("/transactions/:id/reverse", apiHandler.ReverseTransaction)
+			admin.POST("/attestations/:id/revoke", apiHandler.AdminRevokeAttestation)
+			admin.GET("/users/:id/locks", apiHandler.GetUserLocks)
+			admin.POST("/users/:id/locks", apiHandler.CreateLock)
+			admin.GET("/users/:id/wallet", apiHandler.GetWalletControls)
+			admin.PUT("/users/:id/wallet/limits", apiHandler.SetWalletLimits)
+			admin.POST("/users/:id/wallet/:action", apiHandler.FreezeWallet)
+			admin.POST("/users/:id/credit/:action", apiHandler.SuspendCredit)
+			admin.GET("/credit/exposure", apiHandler.GetCreditExposure)
+			admin.GET("/chain/verify", apiHandler.VerifyTransactionChain)
+			admin.POST("/chain/checkpoints", apiHandler.CreateChainCheckpoint)
+			admin.POST("/reserves/snapshot", apiHandler.CreateBalanceSnapshot)
 			admin.POST("/make-admin", apiHandler.MakeUserAdmin) // Temporary endpoint
 
 			// Admin fairness metrics endpoints
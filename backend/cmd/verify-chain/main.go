@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"faircoin/internal/config"
+	"faircoin/internal/database"
+	"faircoin/internal/services"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	checkpoint := flag.Bool("checkpoint", false, "sign the chains' heads after verifying them")
+	exportPath := flag.String("export", "", "write the signed checkpoints as JSON to this file")
+	flag.Parse()
+
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using environment variables")
+	}
+
+	// Load configuration
+	cfg := config.Load()
+
+	// Initialize database
+	db, err := database.Initialize(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	chainService := services.NewTransactionChainService(db)
+	chainService.SetSigningSecret(cfg.JWTSecret)
+	if err := chainService.SetTrustedKeys(cfg.ChainTrustedKeys); err != nil {
+		log.Fatalf("Invalid chain configuration: %v", err)
+	}
+
+	log.Println("=== Transaction Chain Verification ===")
+	log.Printf("Checkpoint signing key: %s", chainService.PublicKey())
+
+	report, err := chainService.VerifyChain()
+	if err != nil {
+		log.Fatalf("Failed to verify transaction chain: %v", err)
+	}
+
+	log.Printf("Transactions in chain: %d", report.Transactions)
+	log.Printf("Head: #%d %s", report.HeadSeq, report.HeadHash)
+	log.Printf("Status changes in chain: %d", report.StatusChanges)
+	log.Printf("Status head: #%d %s", report.StatusHeadSeq, report.StatusHeadHash)
+	log.Printf("Checkpoints verified: %d", report.CheckpointsVerified)
+	for _, b := range report.Breaks {
+		switch {
+		case b.ChainSeq == 0 && b.StatusChange != "":
+			log.Printf("❌ %s %s (status change %s): %s", b.Chain, b.Kind, b.StatusChange, b.Detail)
+		case b.ChainSeq == 0:
+			log.Printf("❌ %s %s (transaction %s): %s", b.Chain, b.Kind, b.TransactionID, b.Detail)
+		case b.TransactionID != "":
+			log.Printf("❌ %s %s at #%d (transaction %s): %s", b.Chain, b.Kind, b.ChainSeq, b.TransactionID, b.Detail)
+		default:
+			log.Printf("❌ %s %s at #%d: %s", b.Chain, b.Kind, b.ChainSeq, b.Detail)
+		}
+	}
+	if report.BreakCount > len(report.Breaks) {
+		log.Printf("... and %d more", report.BreakCount-len(report.Breaks))
+	}
+
+	if report.Valid && *checkpoint {
+		created, err := chainService.CreateCheckpoint()
+		if err != nil {
+			log.Fatalf("Failed to create checkpoint: %v", err)
+		}
+		if created != nil {
+			log.Printf("Signed checkpoint at #%d", created.ChainSeq)
+		}
+	}
+
+	if *exportPath != "" {
+		export, err := chainService.ExportCheckpoints()
+		if err != nil {
+			log.Fatalf("Failed to export checkpoints: %v", err)
+		}
+		encoded, _ := json.MarshalIndent(export, "", "  ")
+		if err := os.WriteFile(*exportPath, encoded, 0644); err != nil {
+			log.Fatalf("Failed to write %s: %v", *exportPath, err)
+		}
+		log.Printf("Exported %d checkpoints to %s", len(export.Checkpoints), *exportPath)
+	}
+
+	if !report.Valid {
+		log.Printf("Chain is broken in %d places", report.BreakCount)
+		os.Exit(1)
+	}
+	log.Println("✅ Chain is intact")
+}
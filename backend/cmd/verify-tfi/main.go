@@ -41,7 +41,7 @@ func main() {
 	log.Println("User Information:")
 	log.Println("================")
 	for _, user := range users {
-		balance := 0.0
+		var balance models.Money
 		if user.Wallet != nil {
 			balance = user.Wallet.Balance
 		}
@@ -49,7 +49,7 @@ func main() {
 		log.Printf("👤 %s (%s %s)", user.Username, user.FirstName, user.LastName)
 		log.Printf("   📧 Email: %s", user.Email)
 		log.Printf("   ⭐ PFI: %d | TFI: %d", user.PFI, user.TFI)
-		log.Printf("   💰 Balance: %.2f FC", balance)
+		log.Printf("   💰 Balance: %s FC", balance.StringFixed(2))
 
 		roles := []string{}
 		if user.IsAdmin {
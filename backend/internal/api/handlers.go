@@ -1,10 +1,15 @@
 package api
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"faircoin/internal/config"
 	"faircoin/internal/models"
 	"faircoin/internal/services"
 	"fmt"
+	"io"
 	"math"
 	"net/http"
 	"strconv"
@@ -25,6 +30,15 @@ type Handler struct {
 	governanceService  *services.GovernanceService
 	monetaryService    *services.MonetaryService
 	metricsService     *services.MetricsService
+	ledgerService      *services.LedgerService
+	idempotencyService *services.IdempotencyService
+	treasuryService    *services.TreasuryService
+	vestingService     *services.VestingService
+	standingOrders     *services.StandingOrderService
+	feeService         *services.FeeService
+	demurrageService   *services.DemurrageService
+	chainService       *services.TransactionChainService
+	reservesService    *services.ReservesService
 	config             *config.Config
 }
 
@@ -37,6 +51,15 @@ func NewHandler(
 	governanceService *services.GovernanceService,
 	monetaryService *services.MonetaryService,
 	metricsService *services.MetricsService,
+	ledgerService *services.LedgerService,
+	idempotencyService *services.IdempotencyService,
+	treasuryService *services.TreasuryService,
+	vestingService *services.VestingService,
+	standingOrders *services.StandingOrderService,
+	feeService *services.FeeService,
+	demurrageService *services.DemurrageService,
+	chainService *services.TransactionChainService,
+	reservesService *services.ReservesService,
 	cfg *config.Config,
 ) *Handler {
 	return &Handler{
@@ -47,6 +70,15 @@ func NewHandler(
 		governanceService:  governanceService,
 		monetaryService:    monetaryService,
 		metricsService:     metricsService,
+		ledgerService:      ledgerService,
+		idempotencyService: idempotencyService,
+		treasuryService:    treasuryService,
+		vestingService:     vestingService,
+		standingOrders:     standingOrders,
+		feeService:         feeService,
+		demurrageService:   demurrageService,
+		chainService:       chainService,
+		reservesService:    reservesService,
 		config:             cfg,
 	}
 }
@@ -129,6 +161,135 @@ func (h *Handler) AdminMiddleware() gin.HandlerFunc {
 	})
 }
 
+// idempotencyResponseWriter captures the response body so it can be stored
+type idempotencyResponseWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *idempotencyResponseWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// idempotencyClaimKey is the gin context key of the request's idempotency claim
+const idempotencyClaimKey = "idempotency_claim"
+
+// IdempotencyMiddleware makes money-moving endpoints safe to retry. When a
+// request carries an Idempotency-Key header, the first response is stored and
+// any retry with the same key and body gets that response replayed without
+// running the handler again. Handlers write through h.wallet(c),
+// h.standingOrderService(c), h.fairness(c) and h.governance(c) so a crash
+// before the response is stored can be reconciled (see
+// services.IdempotencyService.Begin). Must run after AuthMiddleware.
+func (h *Handler) IdempotencyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := strings.TrimSpace(c.GetHeader("Idempotency-Key"))
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > 255 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key must be at most 255 characters"})
+			c.Abort()
+			return
+		}
+
+		userIDStr, _ := c.Get("user_id")
+		userID, err := uuid.Parse(fmt.Sprint(userIDStr))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		hash.Write([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n"))
+		hash.Write(body)
+		fingerprint := hex.EncodeToString(hash.Sum(nil))
+
+		record, replay, err := h.idempotencyService.Begin(userID, key, c.Request.Method, c.Request.URL.Path, fingerprint)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, services.ErrIdempotencyKeyReused) {
+				status = http.StatusUnprocessableEntity
+			} else if errors.Is(err, services.ErrIdempotencyInProgress) {
+				status = http.StatusConflict
+			}
+			c.JSON(status, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+
+		if replay {
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(record.StatusCode, "application/json; charset=utf-8", []byte(record.ResponseBody))
+			c.Abort()
+			return
+		}
+
+		c.Set(idempotencyClaimKey, record)
+		writer := &idempotencyResponseWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = writer
+		c.Next()
+
+		// Server errors are not stored so the client can safely retry
+		if writer.Status() >= http.StatusInternalServerError {
+			if err := h.idempotencyService.Abandon(record); err != nil {
+				fmt.Printf("Warning: Failed to release idempotency key %s: %v\n", key, err)
+			}
+			return
+		}
+		if err := h.idempotencyService.Complete(record, writer.Status(), writer.body.String()); err != nil {
+			fmt.Printf("Warning: Failed to store idempotent response for key %s: %v\n", key, err)
+		}
+	}
+}
+
+// wallet returns the wallet service for the request, scoped to its
+// idempotency claim if it has one
+func (h *Handler) wallet(c *gin.Context) *services.WalletService {
+	if claim, ok := c.Get(idempotencyClaimKey); ok {
+		return h.walletService.WithIdempotency(claim.(*models.IdempotencyRecord))
+	}
+	return h.walletService
+}
+
+// standingOrderService returns the standing order service for the request,
+// scoped to its idempotency claim if it has one
+func (h *Handler) standingOrderService(c *gin.Context) *services.StandingOrderService {
+	if claim, ok := c.Get(idempotencyClaimKey); ok {
+		return h.standingOrders.WithIdempotency(claim.(*models.IdempotencyRecord))
+	}
+	return h.standingOrders
+}
+
+// fairness returns the fairness service for the request, scoped to its
+// idempotency claim if it has one
+func (h *Handler) fairness(c *gin.Context) *services.FairnessService {
+	if claim, ok := c.Get(idempotencyClaimKey); ok {
+		return h.fairnessService.WithIdempotency(claim.(*models.IdempotencyRecord))
+	}
+	return h.fairnessService
+}
+
+// governance returns the governance service for the request, scoped to its
+// idempotency claim if it has one
+func (h *Handler) governance(c *gin.Context) *services.GovernanceService {
+	if claim, ok := c.Get(idempotencyClaimKey); ok {
+		return h.governanceService.WithIdempotency(claim.(*models.IdempotencyRecord))
+	}
+	return h.governanceService
+}
+
 // generateToken generates a JWT token for a user
 func (h *Handler) generateToken(user *models.User) (string, error) {
 	claims := &Claims{
@@ -351,7 +512,7 @@ func (h *Handler) AttestUser(c *gin.Context) {
 		return
 	}
 
-	attestation, err := h.fairnessService.CreateAttestation(userID, attesterID, req.Type, req.Value, req.Description)
+	attestation, err := h.fairness(c).CreateAttestation(userID, attesterID, req.Type, req.Value, req.Description)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -363,8 +524,8 @@ func (h *Handler) AttestUser(c *gin.Context) {
 	})
 }
 
-// GetBalance returns the user's wallet balance
-func (h *Handler) GetBalance(c *gin.Context) {
+// GetAttestationQueue lists the pending attestations the user can review
+func (h *Handler) GetAttestationQueue(c *gin.Context) {
 	userIDStr, _ := c.Get("user_id")
 	userID, err := uuid.Parse(userIDStr.(string))
 	if err != nil {
@@ -372,17 +533,24 @@ func (h *Handler) GetBalance(c *gin.Context) {
 		return
 	}
 
-	wallet, err := h.walletService.GetBalance(userID)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	attestations, err := h.fairnessService.GetAttestationQueue(userID, limit)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Wallet not found"})
+		c.JSON(attestationErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, wallet)
+	c.JSON(http.StatusOK, gin.H{
+		"attestations": attestations,
+	})
 }
 
-// GetTransactionHistory returns the user's transaction history
-func (h *Handler) GetTransactionHistory(c *gin.Context) {
+// ReviewAttestation records the user's review of a pending attestation
+func (h *Handler) ReviewAttestation(c *gin.Context) {
 	userIDStr, _ := c.Get("user_id")
 	userID, err := uuid.Parse(userIDStr.(string))
 	if err != nil {
@@ -390,109 +558,134 @@ func (h *Handler) GetTransactionHistory(c *gin.Context) {
 		return
 	}
 
-	// Parse pagination parameters
-	limitStr := c.DefaultQuery("limit", "20")
-	offsetStr := c.DefaultQuery("offset", "0")
+	attestationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attestation ID"})
+		return
+	}
 
-	limit, _ := strconv.Atoi(limitStr)
-	offset, _ := strconv.Atoi(offsetStr)
+	var req struct {
+		Approve bool   `json:"approve"`
+		Comment string `json:"comment"`
+	}
 
-	if limit > 100 {
-		limit = 100 // Cap at 100 transactions per request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	transactions, err := h.transactionService.GetUserTransactions(userID, limit, offset)
+	attestation, err := h.fairness(c).ReviewAttestation(attestationID, userID, req.Approve, req.Comment)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get transaction history"})
+		c.JSON(attestationErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"transactions": transactions,
-		"limit":        limit,
-		"offset":       offset,
+		"message":     "Review recorded successfully",
+		"attestation": attestation,
 	})
 }
 
-// SendFairCoins handles FairCoin transfers
-func (h *Handler) SendFairCoins(c *gin.Context) {
-	fromUserIDStr, _ := c.Get("user_id")
-	fromUserID, err := uuid.Parse(fromUserIDStr.(string))
+// GetUserAttestations lists the attestations the user gave and received
+func (h *Handler) GetUserAttestations(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sender ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	var req struct {
-		ToUsername  string  `json:"to_username" binding:"required"`
-		Amount      float64 `json:"amount" binding:"required,gt=0"`
-		Description string  `json:"description"`
+	given, received, err := h.fairnessService.GetUserAttestations(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	c.JSON(http.StatusOK, gin.H{
+		"given":    given,
+		"received": received,
+	})
+}
+
+// RevokeAttestation revokes an attestation the user gave
+func (h *Handler) RevokeAttestation(c *gin.Context) {
+	h.revokeAttestation(c, false)
+}
+
+// AdminRevokeAttestation revokes any attestation, e.g. a fraudulent one (admin only)
+func (h *Handler) AdminRevokeAttestation(c *gin.Context) {
+	h.revokeAttestation(c, true)
+}
+
+func (h *Handler) revokeAttestation(c *gin.Context, asAdmin bool) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	// Get recipient user
-	toUser, err := h.userService.GetUserByUsername(req.ToUsername)
+	attestationID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Recipient not found"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attestation ID"})
 		return
 	}
 
-	transaction, err := h.walletService.Transfer(fromUserID, toUser.ID, req.Amount, req.Description)
-	if err != nil {
+	var req struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":     "Transfer successful",
-		"transaction": transaction,
-	})
-}
-
-// GetMerchants returns all verified merchants
-func (h *Handler) GetMerchants(c *gin.Context) {
-	merchants, err := h.userService.GetMerchants()
+	attestation, err := h.fairnessService.RevokeAttestation(attestationID, userID, asAdmin, req.Reason)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get merchants"})
+		c.JSON(attestationErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"merchants": merchants,
+		"message":     "Attestation revoked successfully",
+		"attestation": attestation,
 	})
 }
 
-// GetPublicMerchants returns public merchant list (no auth required)
-func (h *Handler) GetPublicMerchants(c *gin.Context) {
-	merchants, err := h.userService.GetMerchants()
+// attestationErrorStatus maps attestation review and revocation errors to
+// HTTP status codes
+func attestationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrAttestationNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrAttestationDecided), errors.Is(err, services.ErrAttestationRevoked):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrNotAttestationReviewer), errors.Is(err, services.ErrNotAttestationAttester):
+		return http.StatusForbidden
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// GetBalance returns the user's wallet balance
+func (h *Handler) GetBalance(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get merchants"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	// Remove sensitive information for public API
-	publicMerchants := make([]map[string]interface{}, len(merchants))
-	for i, merchant := range merchants {
-		publicMerchants[i] = map[string]interface{}{
-			"id":         merchant.ID,
-			"username":   merchant.Username,
-			"first_name": merchant.FirstName,
-			"last_name":  merchant.LastName,
-			"tfi":        merchant.TFI,
-		}
+	wallet, err := h.walletService.GetBalance(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Wallet not found"})
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"merchants": publicMerchants,
-	})
+	c.JSON(http.StatusOK, wallet)
 }
 
-// RegisterMerchant registers the current user as a merchant
-func (h *Handler) RegisterMerchant(c *gin.Context) {
+// GetLocks returns the user's locked and spendable balance with upcoming unlocks
+func (h *Handler) GetLocks(c *gin.Context) {
 	userIDStr, _ := c.Get("user_id")
 	userID, err := uuid.Parse(userIDStr.(string))
 	if err != nil {
@@ -500,36 +693,35 @@ func (h *Handler) RegisterMerchant(c *gin.Context) {
 		return
 	}
 
-	if err := h.userService.RegisterMerchant(userID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register as merchant"})
+	summary, err := h.vestingService.GetLockSummary(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Wallet not found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Successfully registered as merchant",
-	})
+	c.JSON(http.StatusOK, summary)
 }
 
-// GetMerchantTFI returns a merchant's TFI breakdown
-func (h *Handler) GetMerchantTFI(c *gin.Context) {
-	merchantIDStr := c.Param("id")
-	merchantID, err := uuid.Parse(merchantIDStr)
+// GetPots returns the user's savings pots and how the balance is split
+func (h *Handler) GetPots(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid merchant ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	breakdown, err := h.fairnessService.GetMerchantTFIBreakdown(merchantID)
+	summary, err := h.walletService.GetPots(userID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Merchant not found"})
+		c.JSON(http.StatusNotFound, gin.H{"error": "Wallet not found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, breakdown)
+	c.JSON(http.StatusOK, summary)
 }
 
-// RateMerchant creates a rating for a merchant
-func (h *Handler) RateMerchant(c *gin.Context) {
+// CreatePot adds a savings pot to the user's wallet
+func (h *Handler) CreatePot(c *gin.Context) {
 	userIDStr, _ := c.Get("user_id")
 	userID, err := uuid.Parse(userIDStr.(string))
 	if err != nil {
@@ -537,20 +729,10 @@ func (h *Handler) RateMerchant(c *gin.Context) {
 		return
 	}
 
-	merchantIDStr := c.Param("id")
-	merchantID, err := uuid.Parse(merchantIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid merchant ID"})
-		return
-	}
-
 	var req struct {
-		DeliveryRating      int    `json:"delivery_rating" binding:"required,min=1,max=10"`
-		QualityRating       int    `json:"quality_rating" binding:"required,min=1,max=10"`
-		TransparencyRating  int    `json:"transparency_rating" binding:"required,min=1,max=10"`
-		EnvironmentalRating int    `json:"environmental_rating" binding:"min=1,max=10"`
-		Comments            string `json:"comments"`
-		TransactionID       string `json:"transaction_id"`
+		Name        string        `json:"name" binding:"required"`
+		Goal        *models.Money `json:"goal"`
+		LockedUntil *time.Time    `json:"locked_until"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -558,63 +740,37 @@ func (h *Handler) RateMerchant(c *gin.Context) {
 		return
 	}
 
-	// Default environmental rating if not provided
-	if req.EnvironmentalRating == 0 {
-		req.EnvironmentalRating = 5
-	}
-
-	var transactionID *uuid.UUID
-	if req.TransactionID != "" {
-		txID, err := uuid.Parse(req.TransactionID)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID"})
-			return
-		}
-		transactionID = &txID
-	}
-
-	rating, err := h.fairnessService.CreateRating(
-		userID, merchantID, transactionID,
-		req.DeliveryRating, req.QualityRating, req.TransparencyRating, req.EnvironmentalRating,
-		req.Comments,
-	)
+	pot, err := h.walletService.CreatePot(userID, req.Name, req.Goal, req.LockedUntil)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
-		"message": "Rating created successfully",
-		"rating":  rating,
+		"message": "Savings pot created successfully",
+		"pot":     pot,
 	})
 }
 
-// GetProposals returns all active governance proposals
-func (h *Handler) GetProposals(c *gin.Context) {
-	proposals, err := h.governanceService.GetActiveProposals()
+// UpdatePot renames a savings pot or changes its goal or lock date
+func (h *Handler) UpdatePot(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get proposals"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"proposals": proposals,
-	})
-}
-
-// CreateProposal creates a new governance proposal
-func (h *Handler) CreateProposal(c *gin.Context) {
-	proposerIDStr, _ := c.Get("user_id")
-	proposerID, err := uuid.Parse(proposerIDStr.(string))
+	potID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid proposer ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pot ID"})
 		return
 	}
 
 	var req struct {
-		Title       string `json:"title" binding:"required,min=10,max=200"`
-		Description string `json:"description" binding:"required,min=50"`
-		Type        string `json:"type" binding:"required"`
+		Name        *string       `json:"name"`
+		Goal        *models.Money `json:"goal"` // 0 removes the goal
+		LockedUntil *time.Time    `json:"locked_until"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -622,21 +778,24 @@ func (h *Handler) CreateProposal(c *gin.Context) {
 		return
 	}
 
-	proposalType := models.ProposalType(req.Type)
-	proposal, err := h.governanceService.CreateProposal(proposerID, req.Title, req.Description, proposalType)
+	pot, err := h.walletService.UpdatePot(userID, potID, services.PotUpdate{
+		Name:        req.Name,
+		Goal:        req.Goal,
+		LockedUntil: req.LockedUntil,
+	})
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(potErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"message":  "Proposal created successfully",
-		"proposal": proposal,
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Savings pot updated successfully",
+		"pot":     pot,
 	})
 }
 
-// VoteOnProposal handles voting on proposals
-func (h *Handler) VoteOnProposal(c *gin.Context) {
+// DeletePot removes a savings pot and returns its money to the spendable balance
+func (h *Handler) DeletePot(c *gin.Context) {
 	userIDStr, _ := c.Get("user_id")
 	userID, err := uuid.Parse(userIDStr.(string))
 	if err != nil {
@@ -644,15 +803,38 @@ func (h *Handler) VoteOnProposal(c *gin.Context) {
 		return
 	}
 
-	proposalIDStr := c.Param("id")
-	proposalID, err := uuid.Parse(proposalIDStr)
+	potID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid proposal ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pot ID"})
+		return
+	}
+
+	summary, err := h.walletService.DeletePot(userID, potID)
+	if err != nil {
+		c.JSON(potErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Savings pot deleted successfully",
+		"pots":    summary,
+	})
+}
+
+// MovePotFunds moves money between the spendable balance and savings pots.
+// A missing pot ID stands for the spendable balance.
+func (h *Handler) MovePotFunds(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
 	var req struct {
-		Vote bool `json:"vote"`
+		FromPotID *uuid.UUID   `json:"from_pot_id"`
+		ToPotID   *uuid.UUID   `json:"to_pot_id"`
+		Amount    models.Money `json:"amount" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -660,368 +842,2640 @@ func (h *Handler) VoteOnProposal(c *gin.Context) {
 		return
 	}
 
-	if err := h.governanceService.VoteOnProposal(userID, proposalID, req.Vote); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	summary, err := h.walletService.MovePotFunds(userID, req.FromPotID, req.ToPotID, req.Amount)
+	if err != nil {
+		c.JSON(potErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Vote recorded successfully",
+		"message": "Money moved successfully",
+		"pots":    summary,
 	})
 }
 
-// GetCouncilMembers returns the current community council members
-func (h *Handler) GetCouncilMembers(c *gin.Context) {
-	members, err := h.governanceService.GetCouncilMembers()
+// potErrorStatus maps savings pot errors to HTTP status codes
+func potErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrPotNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrPotLocked):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// GetCreditStatus returns the user's credit line and repayment history
+func (h *Handler) GetCreditStatus(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get council members"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"council_members": members,
-	})
+	status, err := h.walletService.GetCreditStatus(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
 }
 
-// GetCommunityStats returns community statistics
-func (h *Handler) GetCommunityStats(c *gin.Context) {
-	stats, err := h.transactionService.GetCommunityStats()
+// RequestCredit asks the council for a credit limit above the formula's
+func (h *Handler) RequestCredit(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get community stats"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	// Add monetary policy stats
-	monthlyStats, err := h.monetaryService.GetCurrentMonthStats()
-	if err == nil {
-		for key, value := range monthlyStats {
-			stats[key] = value
-		}
+	var req struct {
+		Limit  models.Money `json:"limit" binding:"required"`
+		Reason string       `json:"reason" binding:"required"`
 	}
 
-	c.JSON(http.StatusOK, stats)
-}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-// GetCommunityBasketIndex returns the current CBI
-func (h *Handler) GetCommunityBasketIndex(c *gin.Context) {
-	cbi, err := h.monetaryService.GetCommunityBasketIndex()
+	request, err := h.walletService.RequestCredit(userID, req.Limit, req.Reason)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get CBI"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"cbi": cbi,
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Credit request submitted to the council",
+		"request": request,
 	})
 }
 
-// Admin-specific handlers
-
-// GetAdminStats returns comprehensive admin statistics
-func (h *Handler) GetAdminStats(c *gin.Context) {
-	stats := make(map[string]interface{})
+// creditErrorStatus maps credit line errors to HTTP status codes
+func creditErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrCreditRequestNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrCreditRequestDecided):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrNotCouncilMember):
+		return http.StatusForbidden
+	default:
+		return http.StatusBadRequest
+	}
+}
 
-	// Total users
-	var totalUsers int64
-	if err := h.userService.GetDB().Model(&models.User{}).Count(&totalUsers).Error; err == nil {
-		stats["total_users"] = totalUsers
+// transactionFilterFromQuery reads the search filters and pagination shared
+// by the user and admin transaction lists. Dates are inclusive days
+// (YYYY-MM-DD, UTC).
+func transactionFilterFromQuery(c *gin.Context, defaultLimit int) (services.TransactionFilter, error) {
+	filter := services.TransactionFilter{
+		Type:         models.TransactionType(c.Query("type")),
+		Status:       c.Query("status"),
+		Counterparty: c.Query("counterparty"),
+		Query:        c.Query("q"),
+		Cursor:       c.Query("cursor"),
 	}
 
-	// Total supply (sum of all wallet balances)
-	var totalSupply struct {
-		Total float64
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultLimit)))
+	if err != nil || limit <= 0 {
+		return filter, fmt.Errorf("invalid limit")
 	}
-	if err := h.walletService.GetDB().Model(&models.Wallet{}).Select("SUM(balance) as total").Scan(&totalSupply).Error; err == nil {
-		stats["total_supply"] = totalSupply.Total
+	filter.Limit = min(limit, 100) // Cap at 100 transactions per request
+	if filter.Offset, err = strconv.Atoi(c.DefaultQuery("offset", "0")); err != nil || filter.Offset < 0 {
+		return filter, fmt.Errorf("invalid offset")
 	}
 
-	// Daily transactions (last 24 hours)
-	var dailyTx int64
-	yesterday := time.Now().AddDate(0, 0, -1)
-	if err := h.transactionService.GetDB().Model(&models.Transaction{}).
-		Where("created_at > ?", yesterday).Count(&dailyTx).Error; err == nil {
-		stats["daily_transactions"] = dailyTx
+	for _, bound := range []struct {
+		key    string
+		amount **models.Money
+	}{{"min_amount", &filter.MinAmount}, {"max_amount", &filter.MaxAmount}} {
+		if value := c.Query(bound.key); value != "" {
+			amount, err := models.ParseMoney(value)
+			if err != nil {
+				return filter, fmt.Errorf("invalid %s", bound.key)
+			}
+			*bound.amount = &amount
+		}
 	}
 
-	// Average PFI
-	var avgPFI struct {
-		Avg float64
+	from, err := queryDate(c, "from")
+	if err != nil {
+		return filter, err
 	}
-	if err := h.userService.GetDB().Model(&models.User{}).Select("AVG(pfi) as avg").Scan(&avgPFI).Error; err == nil {
-		stats["avg_pfi"] = avgPFI.Avg
+	to, err := queryDate(c, "to")
+	if err != nil {
+		return filter, err
+	}
+	filter.From = from
+	if to != nil {
+		end := to.AddDate(0, 0, 1)
+		filter.To = &end
 	}
+	return filter, nil
+}
 
-	c.JSON(http.StatusOK, stats)
+// queryDate parses an optional YYYY-MM-DD query parameter as midnight UTC
+func queryDate(c *gin.Context, key string) (*time.Time, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s date, use YYYY-MM-DD", key)
+	}
+	return &date, nil
 }
 
-// GetAllUsers returns all users with their details (admin only)
-func (h *Handler) GetAllUsers(c *gin.Context) {
-	var users []models.User
-	if err := h.userService.GetDB().Preload("Wallet").Find(&users).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+// GetTransactionHistory returns the user's transactions, newest first,
+// filtered by the query parameters. Follow next_cursor for further pages.
+func (h *Handler) GetTransactionHistory(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	// Transform data for admin view
-	var adminUsers []map[string]interface{}
-	for _, user := range users {
-		adminUser := map[string]interface{}{
-			"id":                user.ID,
-			"username":          user.Username,
-			"email":             user.Email,
-			"first_name":        user.FirstName,
-			"last_name":         user.LastName,
-			"pfi":               user.PFI,
-			"tfi":               user.TFI,
-			"is_verified":       user.IsVerified,
-			"is_merchant":       user.IsMerchant,
-			"is_admin":          user.IsAdmin,
-			"community_service": user.CommunityService,
-			"created_at":        user.CreatedAt,
-		}
+	filter, err := transactionFilterFromQuery(c, 20)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.UserID = &userID
 
-		// Add wallet balance if available
-		if user.Wallet != nil {
-			adminUser["balance"] = user.Wallet.Balance
-		} else {
-			adminUser["balance"] = 0.0
+	page, err := h.transactionService.SearchTransactions(filter)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
-
-		adminUsers = append(adminUsers, adminUser)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get transaction history"})
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"users": adminUsers})
+	c.JSON(http.StatusOK, gin.H{
+		"transactions": page.Transactions,
+		"next_cursor":  page.NextCursor,
+		"limit":        filter.Limit,
+		"offset":       filter.Offset,
+	})
 }
 
-// GetAllTransactions returns all transactions (admin only)
-func (h *Handler) GetAllTransactions(c *gin.Context) {
-	var transactions []models.Transaction
-	if err := h.transactionService.GetDB().
-		Order("created_at DESC").
-		Limit(100). // Limit to last 100 transactions
-		Find(&transactions).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transactions"})
+// GetStatement exports the user's statement for a date range as CSV, OFX,
+// camt.053 XML or JSON. from and to are inclusive dates (YYYY-MM-DD, UTC)
+// and default to the current month.
+func (h *Handler) GetStatement(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	// Transform data for admin view
-	var adminTransactions []map[string]interface{}
-	for _, tx := range transactions {
-		adminTx := map[string]interface{}{
-			"id":          tx.ID,
-			"type":        tx.Type,
-			"amount":      tx.Amount,
-			"fee":         tx.Fee,
-			"status":      tx.Status,
-			"description": tx.Description,
-			"created_at":  tx.CreatedAt,
-			"user_id":     tx.UserID,
-		}
-
-		// Get usernames if needed
-		var fromUser models.User
-		if err := h.userService.GetDB().First(&fromUser, "id = ?", tx.UserID).Error; err == nil {
-			adminTx["from_user"] = fromUser.Username
+	now := time.Now().UTC()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	for _, day := range []struct {
+		key  string
+		date *time.Time
+	}{{"from", &from}, {"to", &to}} {
+		date, err := queryDate(c, day.key)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
-
-		if tx.ToUserID != nil {
-			var toUser models.User
-			if err := h.userService.GetDB().First(&toUser, "id = ?", *tx.ToUserID).Error; err == nil {
-				adminTx["to_user"] = toUser.Username
-			}
+		if date != nil {
+			*day.date = *date
 		}
-
-		adminTransactions = append(adminTransactions, adminTx)
 	}
 
-	c.JSON(http.StatusOK, gin.H{"transactions": adminTransactions})
-}
-
-// GetPFIDistribution returns PFI score distribution
-func (h *Handler) GetPFIDistribution(c *gin.Context) {
-	var distribution []struct {
-		Range string
-		Count int64
+	format := c.DefaultQuery("format", services.StatementFormatCSV)
+	var contentType, extension string
+	switch format {
+	case services.StatementFormatCSV:
+		contentType, extension = "text/csv; charset=utf-8", "csv"
+	case services.StatementFormatOFX:
+		contentType, extension = "application/x-ofx", "ofx"
+	case services.StatementFormatCamt053:
+		contentType, extension = "application/xml; charset=utf-8", "xml"
+	case services.StatementFormatJSON:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format, use csv, ofx, camt053 or json"})
+		return
 	}
 
-	// Get counts for different PFI ranges
-	var excellent, good, average, poor int64
-
-	h.userService.GetDB().Model(&models.User{}).Where("pfi >= 90").Count(&excellent)
-	h.userService.GetDB().Model(&models.User{}).Where("pfi >= 70 AND pfi < 90").Count(&good)
-	h.userService.GetDB().Model(&models.User{}).Where("pfi >= 50 AND pfi < 70").Count(&average)
-	h.userService.GetDB().Model(&models.User{}).Where("pfi < 50").Count(&poor)
+	statement, err := h.transactionService.GetStatement(userID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if format == services.StatementFormatJSON {
+		c.JSON(http.StatusOK, statement)
+		return
+	}
 
-	// Ensure data is in the correct order for the chart
-	distribution = append(distribution, struct {
-		Range string
-		Count int64
-	}{"excellent", excellent})
-	distribution = append(distribution, struct {
-		Range string
-		Count int64
-	}{"good", good})
-	distribution = append(distribution, struct {
-		Range string
-		Count int64
-	}{"average", average})
-	distribution = append(distribution, struct {
-		Range string
-		Count int64
-	}{"poor", poor})
+	var buf bytes.Buffer
+	switch format {
+	case services.StatementFormatCSV:
+		err = statement.WriteCSV(&buf)
+	case services.StatementFormatOFX:
+		err = statement.WriteOFX(&buf)
+	case services.StatementFormatCamt053:
+		err = statement.WriteCamt053(&buf)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write statement"})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{"distribution": distribution})
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"faircoin-statement-%s-%s.%s\"",
+		from.Format("20060102"), to.Format("20060102"), extension))
+	c.Data(http.StatusOK, contentType, buf.Bytes())
 }
 
-// GetRecentActivity returns recent system activity
-func (h *Handler) GetRecentActivity(c *gin.Context) {
-	activities := []map[string]interface{}{}
-
-	// Recent user registrations
-	var recentUsers []models.User
-	h.userService.GetDB().Order("created_at DESC").Limit(5).Find(&recentUsers)
-	for _, user := range recentUsers {
-		activities = append(activities, map[string]interface{}{
-			"type":    "user_registration",
-			"message": "New user " + user.Username + " registered",
-			"time":    user.CreatedAt,
-			"icon":    "user-plus",
-			"color":   "#3498db",
-		})
-	}
-
-	// Recent transactions
-	var recentTx []models.Transaction
-	h.transactionService.GetDB().Order("created_at DESC").Limit(5).Find(&recentTx)
-	for _, tx := range recentTx {
-		activities = append(activities, map[string]interface{}{
-			"type":    "transaction",
-			"message": "Transaction: " + strconv.FormatFloat(tx.Amount, 'f', 2, 64) + " FC",
-			"time":    tx.CreatedAt,
-			"icon":    "exchange-alt",
-			"color":   "#2ecc71",
-		})
+// SendFairCoins handles FairCoin transfers
+func (h *Handler) SendFairCoins(c *gin.Context) {
+	fromUserIDStr, _ := c.Get("user_id")
+	fromUserID, err := uuid.Parse(fromUserIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sender ID"})
+		return
 	}
 
-	// Recent proposals
-	var recentProposals []models.Proposal
-	h.governanceService.GetDB().Order("created_at DESC").Limit(3).Find(&recentProposals)
-	for _, proposal := range recentProposals {
-		activities = append(activities, map[string]interface{}{
-			"type":    "proposal",
-			"message": "New proposal: " + proposal.Title,
-			"time":    proposal.CreatedAt,
-			"icon":    "vote-yea",
-			"color":   "#f39c12",
-		})
+	var req struct {
+		ToUsername  string       `json:"to_username" binding:"required"`
+		Amount      models.Money `json:"amount" binding:"required,gt=0"`
+		Description string       `json:"description"`
 	}
 
-	c.JSON(http.StatusOK, gin.H{"activities": activities})
-}
-
-// UpdateUserStatus allows admin to update user verification status and roles
-func (h *Handler) UpdateUserStatus(c *gin.Context) {
-	userID := c.Param("id")
-	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "User ID is required"})
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Parse UUID
-	parsedUserID, err := uuid.Parse(userID)
+	// Get recipient user
+	toUser, err := h.userService.GetUserByUsername(req.ToUsername)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		c.JSON(http.StatusNotFound, gin.H{"error": "Recipient not found"})
+		return
+	}
+
+	transaction, err := h.wallet(c).Transfer(fromUserID, toUser.ID, req.Amount, req.Description)
+	if err != nil {
+		if errors.Is(err, services.ErrHoldingCapExceeded) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "code": "holding_cap_exceeded"})
+			return
+		}
+		if errors.Is(err, services.ErrSpendingLimitExceeded) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "code": "spending_limit_exceeded"})
+			return
+		}
+		if errors.Is(err, services.ErrWalletFrozen) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": "wallet_frozen"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Transfer successful",
+		"transaction": transaction,
+	})
+}
+
+// GetSpendingLimits returns the user's send limits and how much is left
+func (h *Handler) GetSpendingLimits(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	status, err := h.walletService.GetSpendingStatus(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// GetFeeQuote previews the fee on a transfer before it is sent
+func (h *Handler) GetFeeQuote(c *gin.Context) {
+	fromUserIDStr, _ := c.Get("user_id")
+	fromUserID, err := uuid.Parse(fromUserIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sender ID"})
+		return
+	}
+
+	toUsername := c.Query("to_username")
+	if toUsername == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to_username is required"})
+		return
+	}
+	amount, err := models.ParseMoney(c.Query("amount"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid amount"})
+		return
+	}
+
+	toUser, err := h.userService.GetUserByUsername(toUsername)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Recipient not found"})
+		return
+	}
+
+	quote, err := h.walletService.QuoteFee(fromUserID, toUser.ID, amount)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	wallet, err := h.walletService.GetBalance(fromUserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Wallet not found"})
+		return
+	}
+
+	available, err := h.walletService.AvailableToSpend(wallet)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check available funds"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"quote":      quote,
+		"spendable":  wallet.Spendable(),
+		"available":  available, // Including any credit line
+		"sufficient": available >= quote.Total,
+	})
+}
+
+// OpenEscrow holds a payment to a merchant until delivery is confirmed
+func (h *Handler) OpenEscrow(c *gin.Context) {
+	buyerIDStr, _ := c.Get("user_id")
+	buyerID, err := uuid.Parse(buyerIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid buyer ID"})
+		return
+	}
+
+	var req struct {
+		MerchantID    string       `json:"merchant_id" binding:"required"`
+		Amount        models.Money `json:"amount" binding:"required,gt=0"`
+		Description   string       `json:"description"`
+		TimeoutHours  int          `json:"timeout_hours"`
+		TimeoutAction string       `json:"timeout_action"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	merchantID, err := uuid.Parse(req.MerchantID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid merchant ID"})
+		return
+	}
+
+	escrow, err := h.wallet(c).OpenEscrow(buyerID, merchantID, req.Amount, req.Description,
+		time.Duration(req.TimeoutHours)*time.Hour, models.EscrowTimeoutAction(req.TimeoutAction))
+	if err != nil {
+		c.JSON(escrowErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Escrow opened successfully",
+		"escrow":  escrow,
+	})
+}
+
+// GetEscrows returns the user's escrows as buyer or merchant
+func (h *Handler) GetEscrows(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	escrows, err := h.walletService.GetUserEscrows(userID, c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get escrows"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"escrows": escrows})
+}
+
+// GetEscrow returns one escrow the user takes part in
+func (h *Handler) GetEscrow(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	escrowID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid escrow ID"})
+		return
+	}
+
+	escrow, err := h.walletService.GetEscrow(escrowID)
+	if err != nil || (escrow.BuyerID != userID && escrow.MerchantID != userID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Escrow not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"escrow": escrow})
+}
+
+// ReleaseEscrow confirms delivery and pays the merchant. The buyer may
+// include a delivery rating, which is tied to the escrow's transaction.
+func (h *Handler) ReleaseEscrow(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	escrowID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid escrow ID"})
+		return
+	}
+
+	var req struct {
+		Rating *struct {
+			DeliveryRating      int    `json:"delivery_rating" binding:"required,min=1,max=10"`
+			QualityRating       int    `json:"quality_rating" binding:"required,min=1,max=10"`
+			TransparencyRating  int    `json:"transparency_rating" binding:"required,min=1,max=10"`
+			EnvironmentalRating int    `json:"environmental_rating" binding:"omitempty,min=1,max=10"`
+			Comments            string `json:"comments"`
+		} `json:"rating"`
+	}
+
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	escrow, err := h.wallet(c).ReleaseEscrow(escrowID, &userID, false)
+	if err != nil {
+		c.JSON(escrowErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"message": "Escrow released successfully",
+		"escrow":  escrow,
+	}
+
+	if req.Rating != nil {
+		if req.Rating.EnvironmentalRating == 0 {
+			req.Rating.EnvironmentalRating = 5
+		}
+		rating, err := h.fairnessService.CreateRating(
+			userID, escrow.MerchantID, &escrow.TransactionID,
+			req.Rating.DeliveryRating, req.Rating.QualityRating, req.Rating.TransparencyRating, req.Rating.EnvironmentalRating,
+			req.Rating.Comments,
+		)
+		if err != nil {
+			// The release already happened; report the rating problem alongside it
+			response["rating_error"] = err.Error()
+		} else {
+			response["rating"] = rating
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// RefundEscrow returns the escrowed payment to the buyer (merchant only)
+func (h *Handler) RefundEscrow(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	escrowID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid escrow ID"})
+		return
+	}
+
+	escrow, err := h.wallet(c).RefundEscrow(escrowID, &userID, false)
+	if err != nil {
+		c.JSON(escrowErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Escrow refunded successfully",
+		"escrow":  escrow,
+	})
+}
+
+// escrowErrorStatus maps escrow errors to HTTP status codes
+func escrowErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrEscrowNotOpen), errors.Is(err, services.ErrTransactionChanged):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrHoldingCapExceeded), errors.Is(err, services.ErrSpendingLimitExceeded):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, services.ErrWalletFrozen):
+		return http.StatusForbidden
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// IssueVoucher reserves funds and returns a signed voucher code that anyone
+// holding it can redeem later
+func (h *Handler) IssueVoucher(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req struct {
+		Amount    models.Money `json:"amount" binding:"required"`
+		Memo      string       `json:"memo"`
+		ExpiresAt *time.Time   `json:"expires_at"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var expiresAt time.Time
+	if req.ExpiresAt != nil {
+		expiresAt = *req.ExpiresAt
+	}
+
+	voucher, err := h.wallet(c).IssueVoucher(userID, req.Amount, req.Memo, expiresAt)
+	if err != nil {
+		c.JSON(voucherErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Voucher issued successfully",
+		"voucher": voucher,
+	})
+}
+
+// GetVouchers returns the vouchers the user issued
+func (h *Handler) GetVouchers(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	vouchers, err := h.walletService.GetIssuedVouchers(userID, c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get vouchers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"vouchers": vouchers})
+}
+
+// GetVoucher returns one voucher the user issued
+func (h *Handler) GetVoucher(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	voucherID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid voucher ID"})
+		return
+	}
+
+	voucher, err := h.walletService.GetVoucher(voucherID, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Voucher not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"voucher": voucher})
+}
+
+// CheckVoucher verifies a voucher code and shows its amount, expiry and
+// status without redeeming it
+func (h *Handler) CheckVoucher(c *gin.Context) {
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	voucher, err := h.walletService.CheckVoucher(req.Code)
+	if err != nil {
+		c.JSON(voucherErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":         voucher.ID,
+		"amount":     voucher.Amount,
+		"memo":       voucher.Memo,
+		"status":     voucher.Status,
+		"expires_at": voucher.ExpiresAt,
+		"redeemable": voucher.Status == models.VoucherStatusOutstanding && voucher.ExpiresAt.After(time.Now()),
+	})
+}
+
+// RedeemVoucher pays a voucher out to the user presenting its code
+func (h *Handler) RedeemVoucher(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	voucher, err := h.wallet(c).RedeemVoucher(req.Code, userID)
+	if err != nil {
+		c.JSON(voucherErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Voucher redeemed successfully",
+		"voucher": voucher,
+	})
+}
+
+// CancelVoucher refunds an outstanding voucher to the user who issued it
+func (h *Handler) CancelVoucher(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	voucherID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid voucher ID"})
+		return
+	}
+
+	voucher, err := h.walletService.CancelVoucher(voucherID, userID)
+	if err != nil {
+		c.JSON(voucherErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Voucher cancelled successfully",
+		"voucher": voucher,
+	})
+}
+
+// voucherErrorStatus maps voucher errors to HTTP status codes
+func voucherErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrVoucherInvalid):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrVoucherNotOutstanding), errors.Is(err, services.ErrTransactionChanged):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrHoldingCapExceeded), errors.Is(err, services.ErrSpendingLimitExceeded):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, services.ErrWalletFrozen):
+		return http.StatusForbidden
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// SendBatch sends many transfers at once from a JSON body or a CSV upload
+// (username,amount[,description]) sent as text/csv or as a multipart "file"
+func (h *Handler) SendBatch(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sender ID"})
+		return
+	}
+
+	mode := services.BatchMode(c.Query("mode"))
+	var lines []services.BatchLine
+
+	switch c.ContentType() {
+	case "text/csv":
+		lines, err = services.ParseBatchCSV(c.Request.Body)
+	case "multipart/form-data":
+		file, openErr := c.FormFile("file")
+		if openErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "CSV file is required"})
+			return
+		}
+		f, openErr := file.Open()
+		if openErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read CSV file"})
+			return
+		}
+		defer f.Close()
+		if formMode := c.PostForm("mode"); formMode != "" {
+			mode = services.BatchMode(formMode)
+		}
+		lines, err = services.ParseBatchCSV(f)
+	default:
+		var req struct {
+			Mode  services.BatchMode   `json:"mode"`
+			Lines []services.BatchLine `json:"lines" binding:"required"`
+		}
+		err = c.ShouldBindJSON(&req)
+		if req.Mode != "" {
+			mode = req.Mode
+		}
+		lines = req.Lines
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.wallet(c).BatchTransfer(userID, lines, mode)
+	if err != nil {
+		if errors.Is(err, services.ErrBatchFailed) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "batch": result})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Batch processed",
+		"batch":   result,
+	})
+}
+
+// GetTransaction returns a transaction the user sent or received, with its
+// status history
+func (h *Handler) GetTransaction(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	transactionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID"})
+		return
+	}
+
+	transaction, err := h.transactionService.GetTransactionByID(transactionID)
+	if err != nil || (transaction.UserID != userID && (transaction.ToUserID == nil || *transaction.ToUserID != userID)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"transaction": transaction})
+}
+
+// transactionErrorStatus maps refund and reversal errors to HTTP status codes
+func transactionErrorStatus(err error) int {
+	if errors.Is(err, services.ErrTransactionChanged) || errors.Is(err, services.ErrInvalidTransition) {
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+// RefundTransaction refunds all or part of a payment the user received
+func (h *Handler) RefundTransaction(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	transactionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID"})
+		return
+	}
+
+	var req struct {
+		Amount models.Money `json:"amount"` // Omit for a full refund
+		Reason string       `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	refund, err := h.wallet(c).Refund(transactionID, userID, req.Amount, req.Reason)
+	if err != nil {
+		c.JSON(transactionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Refund completed successfully",
+		"transaction": refund,
+	})
+}
+
+// CreateInvoice sends a payment request to another user
+func (h *Handler) CreateInvoice(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req struct {
+		PayerUsername string       `json:"payer_username" binding:"required"`
+		Amount        models.Money `json:"amount"` // Optional when line items are given
+		Description   string       `json:"description"`
+		ExpiresAt     *time.Time   `json:"expires_at"`
+		LineItems     []struct {
+			Description string       `json:"description" binding:"required"`
+			Quantity    int64        `json:"quantity"`
+			UnitPrice   models.Money `json:"unit_price" binding:"required"`
+		} `json:"line_items" binding:"dive"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	payer, err := h.userService.GetUserByUsername(req.PayerUsername)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payer not found"})
+		return
+	}
+
+	items := make([]models.InvoiceLineItem, len(req.LineItems))
+	for i, item := range req.LineItems {
+		items[i] = models.InvoiceLineItem{
+			Description: item.Description,
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+		}
+	}
+
+	var expiresAt time.Time
+	if req.ExpiresAt != nil {
+		expiresAt = *req.ExpiresAt
+	}
+
+	invoice, err := h.wallet(c).CreateInvoice(userID, payer.ID, req.Amount, req.Description, expiresAt, items)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Payment request sent successfully",
+		"invoice": invoice,
+	})
+}
+
+// GetIssuedInvoices lists and searches the payment requests the user sent
+func (h *Handler) GetIssuedInvoices(c *gin.Context) {
+	h.searchInvoices(c, true)
+}
+
+// GetInvoiceInbox lists and searches the payment requests the user was asked to pay
+func (h *Handler) GetInvoiceInbox(c *gin.Context) {
+	h.searchInvoices(c, false)
+}
+
+func (h *Handler) searchInvoices(c *gin.Context, issued bool) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 100 {
+		limit = 100 // Cap at 100 invoices per request
+	}
+
+	invoices, total, err := h.walletService.SearchInvoices(userID, services.InvoiceFilter{
+		Issued:       issued,
+		Status:       c.Query("status"),
+		Query:        c.Query("q"),
+		Counterparty: c.Query("counterparty"),
+		Limit:        limit,
+		Offset:       offset,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get invoices"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"invoices": invoices,
+		"total":    total,
+		"limit":    limit,
+		"offset":   offset,
+	})
+}
+
+// GetInvoice returns one invoice the user issued or was asked to pay
+func (h *Handler) GetInvoice(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	invoiceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice ID"})
+		return
+	}
+
+	invoice, err := h.walletService.GetInvoice(invoiceID, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invoice": invoice})
+}
+
+// PayInvoice pays an invoice from the user's inbox
+func (h *Handler) PayInvoice(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	invoiceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice ID"})
+		return
+	}
+
+	invoice, err := h.wallet(c).PayInvoice(invoiceID, userID)
+	if err != nil {
+		c.JSON(invoiceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Invoice paid successfully",
+		"invoice": invoice,
+	})
+}
+
+// CancelInvoice withdraws a payment request the user sent
+func (h *Handler) CancelInvoice(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	invoiceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice ID"})
+		return
+	}
+
+	invoice, err := h.walletService.CancelInvoice(invoiceID, userID)
+	if err != nil {
+		c.JSON(invoiceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Invoice cancelled successfully",
+		"invoice": invoice,
+	})
+}
+
+// invoiceErrorStatus maps invoice errors to HTTP status codes
+func invoiceErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvoiceNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvoiceNotOpen):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrHoldingCapExceeded):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// CreateStandingOrder schedules a future-dated or recurring transfer
+func (h *Handler) CreateStandingOrder(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req struct {
+		ToUsername  string       `json:"to_username" binding:"required"`
+		Amount      models.Money `json:"amount" binding:"required,gt=0"`
+		Description string       `json:"description"`
+		Frequency   string       `json:"frequency" binding:"required"`
+		CronExpr    string       `json:"cron_expr"`
+		StartAt     *time.Time   `json:"start_at"`
+		EndAt       *time.Time   `json:"end_at"`
+		MaxRetries  *int         `json:"max_retries"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	toUser, err := h.userService.GetUserByUsername(req.ToUsername)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Recipient not found"})
+		return
+	}
+
+	orderReq := services.StandingOrderRequest{
+		UserID:      userID,
+		ToUserID:    toUser.ID,
+		Amount:      req.Amount,
+		Description: req.Description,
+		Frequency:   models.StandingOrderFrequency(req.Frequency),
+		CronExpr:    req.CronExpr,
+		EndAt:       req.EndAt,
+		MaxRetries:  req.MaxRetries,
+	}
+	if req.StartAt != nil {
+		orderReq.StartAt = *req.StartAt
+	}
+
+	order, err := h.standingOrderService(c).CreateStandingOrder(orderReq)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":        "Standing order created successfully",
+		"standing_order": order,
+	})
+}
+
+// GetStandingOrders returns the standing orders the user pays
+func (h *Handler) GetStandingOrders(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	orders, err := h.standingOrders.GetUserStandingOrders(userID, c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get standing orders"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"standing_orders": orders})
+}
+
+// GetStandingOrder returns one of the user's standing orders with its run history
+func (h *Handler) GetStandingOrder(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	orderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid standing order ID"})
+		return
+	}
+
+	order, err := h.standingOrders.GetStandingOrder(orderID, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Standing order not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"standing_order": order})
+}
+
+// UpdateStandingOrderStatus pauses, resumes or cancels one of the user's standing orders
+func (h *Handler) UpdateStandingOrderStatus(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	orderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid standing order ID"})
+		return
+	}
+
+	var order *models.StandingOrder
+	switch c.Param("action") {
+	case "pause":
+		order, err = h.standingOrders.PauseStandingOrder(orderID, userID)
+	case "resume":
+		order, err = h.standingOrders.ResumeStandingOrder(orderID, userID)
+	case "cancel":
+		order, err = h.standingOrders.CancelStandingOrder(orderID, userID)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Action must be pause, resume or cancel"})
+		return
+	}
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrStandingOrderState) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Standing order updated successfully",
+		"standing_order": order,
+	})
+}
+
+// GetMerchants returns all verified merchants
+func (h *Handler) GetMerchants(c *gin.Context) {
+	merchants, err := h.userService.GetMerchants()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get merchants"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"merchants": merchants,
+	})
+}
+
+// GetPublicMerchants returns public merchant list (no auth required)
+func (h *Handler) GetPublicMerchants(c *gin.Context) {
+	merchants, err := h.userService.GetMerchants()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get merchants"})
+		return
+	}
+
+	// Remove sensitive information for public API
+	publicMerchants := make([]map[string]interface{}, len(merchants))
+	for i, merchant := range merchants {
+		publicMerchants[i] = map[string]interface{}{
+			"id":         merchant.ID,
+			"username":   merchant.Username,
+			"first_name": merchant.FirstName,
+			"last_name":  merchant.LastName,
+			"tfi":        merchant.TFI,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"merchants": publicMerchants,
+	})
+}
+
+// RegisterMerchant registers the current user as a merchant
+func (h *Handler) RegisterMerchant(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.userService.RegisterMerchant(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register as merchant"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Successfully registered as merchant",
+	})
+}
+
+// GetMerchantTFI returns a merchant's TFI breakdown
+func (h *Handler) GetMerchantTFI(c *gin.Context) {
+	merchantIDStr := c.Param("id")
+	merchantID, err := uuid.Parse(merchantIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid merchant ID"})
+		return
+	}
+
+	breakdown, err := h.fairnessService.GetMerchantTFIBreakdown(merchantID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Merchant not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, breakdown)
+}
+
+// CreatePaymentURI generates a signed faircoin: payment URI for the
+// calling merchant, to be shown as a link or QR code at the point of sale
+func (h *Handler) CreatePaymentURI(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req struct {
+		Amount    *models.Money `json:"amount"`
+		Reference string        `json:"reference"`
+		ExpiresAt *time.Time    `json:"expires_at"`
+		InvoiceID *uuid.UUID    `json:"invoice_id"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	paymentURI, err := h.walletService.CreatePaymentURI(userID, req.Amount, req.Reference, req.ExpiresAt, req.InvoiceID)
+	if err != nil {
+		c.JSON(paymentURIErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, paymentURI)
+}
+
+// ResolvePaymentURI turns a scanned faircoin: URI into a pre-filled
+// transfer or invoice payment for the calling user
+func (h *Handler) ResolvePaymentURI(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	uri := c.Query("uri")
+	if uri == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "uri is required"})
+		return
+	}
+
+	resolved, err := h.walletService.ResolvePaymentURI(uri, userID)
+	if err != nil {
+		c.JSON(paymentURIErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resolved)
+}
+
+// paymentURIErrorStatus maps payment URI errors to HTTP status codes
+func paymentURIErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvoiceNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvoiceNotOpen):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrPaymentURIExpired):
+		return http.StatusGone
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// RateMerchant creates a rating for a merchant
+func (h *Handler) RateMerchant(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	merchantIDStr := c.Param("id")
+	merchantID, err := uuid.Parse(merchantIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid merchant ID"})
+		return
+	}
+
+	var req struct {
+		DeliveryRating      int    `json:"delivery_rating" binding:"required,min=1,max=10"`
+		QualityRating       int    `json:"quality_rating" binding:"required,min=1,max=10"`
+		TransparencyRating  int    `json:"transparency_rating" binding:"required,min=1,max=10"`
+		EnvironmentalRating int    `json:"environmental_rating" binding:"min=1,max=10"`
+		Comments            string `json:"comments"`
+		TransactionID       string `json:"transaction_id"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Default environmental rating if not provided
+	if req.EnvironmentalRating == 0 {
+		req.EnvironmentalRating = 5
+	}
+
+	var transactionID *uuid.UUID
+	if req.TransactionID != "" {
+		txID, err := uuid.Parse(req.TransactionID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID"})
+			return
+		}
+		transactionID = &txID
+	}
+
+	rating, err := h.fairness(c).CreateRating(
+		userID, merchantID, transactionID,
+		req.DeliveryRating, req.QualityRating, req.TransparencyRating, req.EnvironmentalRating,
+		req.Comments,
+	)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Rating created successfully",
+		"rating":  rating,
+	})
+}
+
+// GetProposals returns all active governance proposals
+func (h *Handler) GetProposals(c *gin.Context) {
+	proposals, err := h.governanceService.GetActiveProposals()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get proposals"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"proposals": proposals,
+	})
+}
+
+// CreateProposal creates a new governance proposal
+func (h *Handler) CreateProposal(c *gin.Context) {
+	proposerIDStr, _ := c.Get("user_id")
+	proposerID, err := uuid.Parse(proposerIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid proposer ID"})
+		return
+	}
+
+	var req struct {
+		Title       string `json:"title" binding:"required,min=10,max=200"`
+		Description string `json:"description" binding:"required,min=50"`
+		Type        string `json:"type" binding:"required"`
+
+		// Required for treasury_spend proposals
+		TreasuryAmount      models.Money `json:"treasury_amount"`
+		TreasuryRecipientID string       `json:"treasury_recipient_id"`
+
+		// Required for fee_schedule proposals
+		FeePolicy *services.FeePolicy `json:"fee_policy"`
+
+		// Optional for monetary_policy proposals
+		DemurrageRate *float64 `json:"demurrage_rate"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	proposalType := models.ProposalType(req.Type)
+	var proposal *models.Proposal
+	if proposalType == models.ProposalTypeTreasurySpend {
+		recipientID, parseErr := uuid.Parse(req.TreasuryRecipientID)
+		if parseErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid treasury recipient ID"})
+			return
+		}
+		proposal, err = h.governanceService.CreateTreasuryProposal(proposerID, req.Title, req.Description, recipientID, req.TreasuryAmount)
+	} else if proposalType == models.ProposalTypeFeeSchedule && req.FeePolicy != nil {
+		proposal, err = h.governanceService.CreateFeeScheduleProposal(proposerID, req.Title, req.Description, *req.FeePolicy)
+	} else if proposalType == models.ProposalTypeMonetaryPolicy && req.DemurrageRate != nil {
+		proposal, err = h.governanceService.CreateDemurrageProposal(proposerID, req.Title, req.Description, *req.DemurrageRate)
+	} else {
+		proposal, err = h.governanceService.CreateProposal(proposerID, req.Title, req.Description, proposalType)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Proposal created successfully",
+		"proposal": proposal,
+	})
+}
+
+// VoteOnProposal handles voting on proposals
+func (h *Handler) VoteOnProposal(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	proposalIDStr := c.Param("id")
+	proposalID, err := uuid.Parse(proposalIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid proposal ID"})
+		return
+	}
+
+	var req struct {
+		Vote bool `json:"vote"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.governance(c).VoteOnProposal(userID, proposalID, req.Vote); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Vote recorded successfully",
+	})
+}
+
+// GetCouncilMembers returns the current community council members
+func (h *Handler) GetCouncilMembers(c *gin.Context) {
+	members, err := h.governanceService.GetCouncilMembers()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get council members"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"council_members": members,
+	})
+}
+
+// GetCreditRequests lists credit requests for the council, pending ones by
+// default
+func (h *Handler) GetCreditRequests(c *gin.Context) {
+	status := models.CreditRequestStatus(c.DefaultQuery("status", string(models.CreditRequestPending)))
+	if status == "all" {
+		status = ""
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	requests, err := h.walletService.GetCreditRequests(status, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get credit requests"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"requests": requests,
+	})
+}
+
+// VoteOnCreditRequest records a council member's vote on a credit request
+func (h *Handler) VoteOnCreditRequest(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	requestID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request ID"})
+		return
+	}
+
+	var req struct {
+		Approve bool   `json:"approve"`
+		Comment string `json:"comment"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	request, err := h.wallet(c).VoteOnCreditRequest(requestID, userID, req.Approve, req.Comment)
+	if err != nil {
+		c.JSON(creditErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Vote recorded successfully",
+		"request": request,
+	})
+}
+
+// GetCommunityStats returns community statistics
+func (h *Handler) GetCommunityStats(c *gin.Context) {
+	stats, err := h.transactionService.GetCommunityStats()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get community stats"})
+		return
+	}
+
+	// Add monetary policy stats
+	monthlyStats, err := h.monetaryService.GetCurrentMonthStats()
+	if err == nil {
+		for key, value := range monthlyStats {
+			stats[key] = value
+		}
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetCommunityBasketIndex returns the current CBI
+func (h *Handler) GetCommunityBasketIndex(c *gin.Context) {
+	cbi, err := h.monetaryService.GetCommunityBasketIndex()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get CBI"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"cbi": cbi,
+	})
+}
+
+// GetSupplyProof returns the ledger proof that circulating supply equals
+// total issuance minus burns
+func (h *Handler) GetSupplyProof(c *gin.Context) {
+	proof, err := h.ledgerService.GetSupplyProof()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build supply proof"})
+		return
+	}
+
+	c.JSON(http.StatusOK, proof)
+}
+
+// GetChainCheckpoints exports the signed checkpoints of the transaction
+// chain so they can be kept outside the system
+func (h *Handler) GetChainCheckpoints(c *gin.Context) {
+	export, err := h.chainService.ExportCheckpoints()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export checkpoints"})
+		return
+	}
+
+	c.JSON(http.StatusOK, export)
+}
+
+// VerifyTransactionChain walks the transaction chain and reports any breaks
+// (admin only)
+func (h *Handler) VerifyTransactionChain(c *gin.Context) {
+	report, err := h.chainService.VerifyChain()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify transaction chain"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// CreateChainCheckpoint signs the transaction chain's head now rather than
+// at the next hourly run (admin only)
+func (h *Handler) CreateChainCheckpoint(c *gin.Context) {
+	checkpoint, err := h.chainService.CreateCheckpoint()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if checkpoint == nil {
+		c.JSON(http.StatusOK, gin.H{"message": "The chain has not grown since the last checkpoint"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Checkpoint created successfully",
+		"checkpoint": checkpoint,
+	})
+}
+
+// GetReserves returns the latest balance snapshots: the published roots and
+// totals members check their inclusion proofs against
+func (h *Handler) GetReserves(c *gin.Context) {
+	snapshots, err := h.reservesService.GetSnapshots(10)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get balance snapshots"})
+		return
+	}
+
+	var latest *models.BalanceSnapshot
+	if len(snapshots) > 0 {
+		latest = &snapshots[0]
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"latest":    latest,
+		"snapshots": snapshots,
+	})
+}
+
+// VerifyBalanceProof checks an inclusion proof against the root published
+// for its snapshot
+func (h *Handler) VerifyBalanceProof(c *gin.Context) {
+	var proof services.BalanceProof
+	if err := c.ShouldBindJSON(&proof); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.reservesService.VerifyBalanceProof(&proof); err != nil {
+		if errors.Is(err, services.ErrProofInvalid) || errors.Is(err, services.ErrSnapshotNotFound) {
+			c.JSON(http.StatusOK, gin.H{"valid": false, "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify balance proof"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"valid": true})
+}
+
+// GetBalanceProof returns the proof that the user's balance is in the
+// latest snapshot, or the one given by snapshot_id
+func (h *Handler) GetBalanceProof(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var snapshotID *uuid.UUID
+	if idStr := c.Query("snapshot_id"); idStr != "" {
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid snapshot ID"})
+			return
+		}
+		snapshotID = &id
+	}
+
+	proof, err := h.reservesService.GetBalanceProof(userID, snapshotID)
+	if err != nil {
+		if errors.Is(err, services.ErrSnapshotNotFound) || errors.Is(err, services.ErrNotInSnapshot) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build balance proof"})
+		return
+	}
+
+	c.JSON(http.StatusOK, proof)
+}
+
+// CreateBalanceSnapshot publishes a balance snapshot now rather than at the
+// next scheduled run (admin only)
+func (h *Handler) CreateBalanceSnapshot(c *gin.Context) {
+	snapshot, err := h.reservesService.CreateSnapshot()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if snapshot == nil {
+		c.JSON(http.StatusOK, gin.H{"message": "There are no wallets to snapshot"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Balance snapshot created successfully",
+		"snapshot": snapshot,
+	})
+}
+
+// GetTreasury returns the community treasury balance and its recent activity
+func (h *Handler) GetTreasury(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	info, err := h.treasuryService.GetTreasuryInfo(limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get treasury"})
+		return
+	}
+
+	c.JSON(http.StatusOK, info)
+}
+
+// GetDemurrageProjection shows the user what demurrage they will be charged
+func (h *Handler) GetDemurrageProjection(c *gin.Context) {
+	userIDStr, _ := c.Get("user_id")
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	months, err := strconv.Atoi(c.DefaultQuery("months", "12"))
+	if err != nil || months < 0 {
+		months = 12
+	}
+	if months > 60 {
+		months = 60
+	}
+
+	projection, err := h.demurrageService.Project(userID, months)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, projection)
+}
+
+// GetFeeSchedule returns the fee policy in force and the schedule history
+func (h *Handler) GetFeeSchedule(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	current, policy, err := h.feeService.Current(h.feeService.GetDB())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get fee schedule"})
+		return
+	}
+	history, err := h.feeService.GetSchedules(limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get fee schedule history"})
+		return
+	}
+
+	version := 0
+	if current != nil {
+		version = current.Version
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"version": version,
+		"policy":  policy,
+		"history": history,
+	})
+}
+
+// Admin-specific handlers
+
+// GetAdminStats returns comprehensive admin statistics
+func (h *Handler) GetAdminStats(c *gin.Context) {
+	stats := make(map[string]interface{})
+
+	// Total users
+	var totalUsers int64
+	if err := h.userService.GetDB().Model(&models.User{}).Count(&totalUsers).Error; err == nil {
+		stats["total_users"] = totalUsers
+	}
+
+	// Total supply (sum of all wallet balances)
+	var totalSupply struct {
+		Total models.Money
+	}
+	if err := h.walletService.GetDB().Model(&models.Wallet{}).Select("SUM(balance) as total").Scan(&totalSupply).Error; err == nil {
+		stats["total_supply"] = totalSupply.Total
+	}
+
+	// Daily transactions (last 24 hours)
+	var dailyTx int64
+	yesterday := time.Now().AddDate(0, 0, -1)
+	if err := h.transactionService.GetDB().Model(&models.Transaction{}).
+		Where("created_at > ?", yesterday).Count(&dailyTx).Error; err == nil {
+		stats["daily_transactions"] = dailyTx
+	}
+
+	// Average PFI
+	var avgPFI struct {
+		Avg float64
+	}
+	if err := h.userService.GetDB().Model(&models.User{}).Select("AVG(pfi) as avg").Scan(&avgPFI).Error; err == nil {
+		stats["avg_pfi"] = avgPFI.Avg
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetAllUsers returns all users with their details (admin only)
+func (h *Handler) GetAllUsers(c *gin.Context) {
+	var users []models.User
+	if err := h.userService.GetDB().Preload("Wallet").Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		return
+	}
+
+	// Transform data for admin view
+	var adminUsers []map[string]interface{}
+	for _, user := range users {
+		adminUser := map[string]interface{}{
+			"id":                user.ID,
+			"username":          user.Username,
+			"email":             user.Email,
+			"first_name":        user.FirstName,
+			"last_name":         user.LastName,
+			"pfi":               user.PFI,
+			"tfi":               user.TFI,
+			"is_verified":       user.IsVerified,
+			"is_merchant":       user.IsMerchant,
+			"is_admin":          user.IsAdmin,
+			"community_service": user.CommunityService,
+			"created_at":        user.CreatedAt,
+		}
+
+		// Add wallet balance if available
+		if user.Wallet != nil {
+			adminUser["balance"] = user.Wallet.Balance
+		} else {
+			adminUser["balance"] = models.Money(0)
+		}
+
+		adminUsers = append(adminUsers, adminUser)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"users": adminUsers})
+}
+
+// GetAllTransactions returns transactions matching the query parameters,
+// newest first (admin only)
+func (h *Handler) GetAllTransactions(c *gin.Context) {
+	filter, err := transactionFilterFromQuery(c, 100)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.transactionService.SearchTransactions(filter)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transactions"})
+		return
+	}
+
+	// Transform data for admin view
+	adminTransactions := make([]map[string]interface{}, 0, len(page.Transactions))
+	for _, tx := range page.Transactions {
+		adminTx := map[string]interface{}{
+			"id":          tx.ID,
+			"type":        tx.Type,
+			"amount":      tx.Amount,
+			"fee":         tx.Fee,
+			"status":      tx.Status,
+			"description": tx.Description,
+			"created_at":  tx.CreatedAt,
+			"user_id":     tx.UserID,
+		}
+		if tx.FailureReason != "" {
+			adminTx["failure_reason"] = tx.FailureReason
+		}
+		if tx.User != nil {
+			adminTx["from_user"] = tx.User.Username
+		}
+		if tx.ToUser != nil {
+			adminTx["to_user"] = tx.ToUser.Username
+		}
+
+		adminTransactions = append(adminTransactions, adminTx)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"transactions": adminTransactions,
+		"next_cursor":  page.NextCursor,
+	})
+}
+
+// GetPFIDistribution returns PFI score distribution
+func (h *Handler) GetPFIDistribution(c *gin.Context) {
+	var distribution []struct {
+		Range string
+		Count int64
+	}
+
+	// Get counts for different PFI ranges
+	var excellent, good, average, poor int64
+
+	h.userService.GetDB().Model(&models.User{}).Where("pfi >= 90").Count(&excellent)
+	h.userService.GetDB().Model(&models.User{}).Where("pfi >= 70 AND pfi < 90").Count(&good)
+	h.userService.GetDB().Model(&models.User{}).Where("pfi >= 50 AND pfi < 70").Count(&average)
+	h.userService.GetDB().Model(&models.User{}).Where("pfi < 50").Count(&poor)
+
+	// Ensure data is in the correct order for the chart
+	distribution = append(distribution, struct {
+		Range string
+		Count int64
+	}{"excellent", excellent})
+	distribution = append(distribution, struct {
+		Range string
+		Count int64
+	}{"good", good})
+	distribution = append(distribution, struct {
+		Range string
+		Count int64
+	}{"average", average})
+	distribution = append(distribution, struct {
+		Range string
+		Count int64
+	}{"poor", poor})
+
+	c.JSON(http.StatusOK, gin.H{"distribution": distribution})
+}
+
+// GetRecentActivity returns recent system activity
+func (h *Handler) GetRecentActivity(c *gin.Context) {
+	activities := []map[string]interface{}{}
+
+	// Recent user registrations
+	var recentUsers []models.User
+	h.userService.GetDB().Order("created_at DESC").Limit(5).Find(&recentUsers)
+	for _, user := range recentUsers {
+		activities = append(activities, map[string]interface{}{
+			"type":    "user_registration",
+			"message": "New user " + user.Username + " registered",
+			"time":    user.CreatedAt,
+			"icon":    "user-plus",
+			"color":   "#3498db",
+		})
+	}
+
+	// Recent transactions
+	var recentTx []models.Transaction
+	h.transactionService.GetDB().Order("created_at DESC").Limit(5).Find(&recentTx)
+	for _, tx := range recentTx {
+		activities = append(activities, map[string]interface{}{
+			"type":    "transaction",
+			"message": "Transaction: " + tx.Amount.StringFixed(2) + " FC",
+			"time":    tx.CreatedAt,
+			"icon":    "exchange-alt",
+			"color":   "#2ecc71",
+		})
+	}
+
+	// Recent proposals
+	var recentProposals []models.Proposal
+	h.governanceService.GetDB().Order("created_at DESC").Limit(3).Find(&recentProposals)
+	for _, proposal := range recentProposals {
+		activities = append(activities, map[string]interface{}{
+			"type":    "proposal",
+			"message": "New proposal: " + proposal.Title,
+			"time":    proposal.CreatedAt,
+			"icon":    "vote-yea",
+			"color":   "#f39c12",
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"activities": activities})
+}
+
+// UpdateUserStatus allows admin to update user verification status and roles
+func (h *Handler) UpdateUserStatus(c *gin.Context) {
+	userID := c.Param("id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User ID is required"})
+		return
+	}
+
+	// Parse UUID
+	parsedUserID, err := uuid.Parse(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
 		return
 	}
 
 	// Parse request body
 	var req struct {
-		FirstName        *string `json:"first_name"`
-		LastName         *string `json:"last_name"`
-		IsAdmin          *bool   `json:"is_admin"`
-		IsMerchant       *bool   `json:"is_merchant"`
-		IsVerified       *bool   `json:"is_verified"`
-		PFI              *int    `json:"pfi"`
-		TFI              *int    `json:"tfi"`
-		CommunityService *int    `json:"community_service"`
+		FirstName        *string `json:"first_name"`
+		LastName         *string `json:"last_name"`
+		IsAdmin          *bool   `json:"is_admin"`
+		IsMerchant       *bool   `json:"is_merchant"`
+		IsVerified       *bool   `json:"is_verified"`
+		PFI              *int    `json:"pfi"`
+		TFI              *int    `json:"tfi"`
+		CommunityService *int    `json:"community_service"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	// Check if user exists
+	var user models.User
+	if err := h.userService.GetDB().Where("id = ?", parsedUserID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// Build update map
+	updates := make(map[string]interface{})
+	if req.FirstName != nil {
+		updates["first_name"] = *req.FirstName
+	}
+	if req.LastName != nil {
+		updates["last_name"] = *req.LastName
+	}
+	if req.IsAdmin != nil {
+		updates["is_admin"] = *req.IsAdmin
+	}
+	if req.IsMerchant != nil {
+		updates["is_merchant"] = *req.IsMerchant
+	}
+	if req.IsVerified != nil {
+		updates["is_verified"] = *req.IsVerified
+	}
+	if req.PFI != nil {
+		updates["pfi"] = *req.PFI
+	}
+	if req.TFI != nil {
+		updates["tfi"] = *req.TFI
+	}
+	if req.CommunityService != nil {
+		updates["community_service"] = *req.CommunityService
+	}
+
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No updates provided"})
+		return
+	}
+
+	// Update user
+	if err := h.userService.GetDB().Model(&models.User{}).
+		Where("id = ?", parsedUserID).
+		Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update user",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Verification decides the default spending limits, so it goes to the
+	// wallet audit trail
+	if req.IsVerified != nil && *req.IsVerified != user.IsVerified {
+		adminIDStr, _ := c.Get("user_id")
+		adminID, _ := uuid.Parse(adminIDStr.(string))
+		if err := h.walletService.RecordVerificationChange(parsedUserID, adminID, *req.IsVerified); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record audit entry"})
+			return
+		}
+	}
+
+	// Fetch updated user
+	if err := h.userService.GetDB().Where("id = ?", parsedUserID).First(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User updated successfully",
+		"user": gin.H{
+			"id":                user.ID,
+			"username":          user.Username,
+			"email":             user.Email,
+			"first_name":        user.FirstName,
+			"last_name":         user.LastName,
+			"pfi":               user.PFI,
+			"tfi":               user.TFI,
+			"community_service": user.CommunityService,
+			"is_admin":          user.IsAdmin,
+			"is_merchant":       user.IsMerchant,
+			"is_verified":       user.IsVerified,
+		},
+	})
+}
+
+// GetLedgerAccounts returns all ledger accounts with their balances (admin only)
+func (h *Handler) GetLedgerAccounts(c *gin.Context) {
+	accounts, err := h.ledgerService.GetAccountBalances()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get ledger accounts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"accounts": accounts})
+}
+
+// GetLedgerAccountEntries returns recent journal entries for an account (admin only)
+func (h *Handler) GetLedgerAccountEntries(c *gin.Context) {
+	accountID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+	if limit > 500 {
+		limit = 500
+	}
+
+	entries, err := h.ledgerService.GetAccountEntries(accountID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get journal entries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"entries": entries, "limit": limit})
+}
+
+// CreateLock locks part of a user's balance under a vesting schedule or
+// time-lock (admin only)
+func (h *Handler) CreateLock(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	adminIDStr, _ := c.Get("user_id")
+	adminID, err := uuid.Parse(adminIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+
+	var req struct {
+		Kind      string       `json:"kind" binding:"required"`
+		Amount    models.Money `json:"amount" binding:"required,gt=0"`
+		StartTime *time.Time   `json:"start_time"`
+		CliffTime *time.Time   `json:"cliff_time"`
+		EndTime   time.Time    `json:"end_time" binding:"required"`
+		Reason    string       `json:"reason"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	lockReq := services.LockRequest{
+		UserID:    userID,
+		Kind:      models.LockKind(req.Kind),
+		Amount:    req.Amount,
+		EndTime:   req.EndTime,
+		Source:    "admin",
+		Reason:    req.Reason,
+		CreatedBy: &adminID,
+	}
+	if req.StartTime != nil {
+		lockReq.StartTime = *req.StartTime
+	}
+	if req.CliffTime != nil {
+		lockReq.CliffTime = *req.CliffTime
+	}
+
+	schedule, err := h.vestingService.CreateLock(lockReq)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Lock created successfully",
+		"schedule": schedule,
+	})
+}
+
+// GetUserLocks returns a user's lock summary (admin only)
+func (h *Handler) GetUserLocks(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	summary, err := h.vestingService.GetLockSummary(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Wallet not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// ResolveEscrow releases or refunds any open escrow (admin only)
+func (h *Handler) ResolveEscrow(c *gin.Context) {
+	adminIDStr, _ := c.Get("user_id")
+	adminID, err := uuid.Parse(adminIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+
+	escrowID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid escrow ID"})
+		return
+	}
+
+	var escrow *models.Escrow
+	switch c.Param("action") {
+	case "release":
+		escrow, err = h.walletService.ReleaseEscrow(escrowID, &adminID, true)
+	case "refund":
+		escrow, err = h.walletService.RefundEscrow(escrowID, &adminID, true)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Action must be release or refund"})
+		return
+	}
+	if err != nil {
+		c.JSON(escrowErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Escrow resolved successfully",
+		"escrow":  escrow,
+	})
+}
+
+// ReverseTransaction reverses what is left of a payment (admin only)
+func (h *Handler) ReverseTransaction(c *gin.Context) {
+	adminIDStr, _ := c.Get("user_id")
+	adminID, err := uuid.Parse(adminIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+
+	transactionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID"})
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reversal, err := h.walletService.Reverse(transactionID, adminID, req.Reason)
+	if err != nil {
+		c.JSON(transactionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Transaction reversed successfully",
+		"transaction": reversal,
+	})
+}
+
+// FreezeWallet freezes or unfreezes a user's wallet (admin only)
+func (h *Handler) FreezeWallet(c *gin.Context) {
+	adminIDStr, _ := c.Get("user_id")
+	adminID, err := uuid.Parse(adminIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
 	}
 
+	var req struct {
+		Reason string `json:"reason" binding:"required"`
+	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Check if user exists
-	var user models.User
-	if err := h.userService.GetDB().Where("id = ?", parsedUserID).First(&user).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	var wallet *models.Wallet
+	switch c.Param("action") {
+	case "freeze":
+		wallet, err = h.walletService.FreezeWallet(userID, adminID, req.Reason)
+	case "unfreeze":
+		wallet, err = h.walletService.UnfreezeWallet(userID, adminID, req.Reason)
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown action"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Build update map
-	updates := make(map[string]interface{})
-	if req.FirstName != nil {
-		updates["first_name"] = *req.FirstName
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Wallet updated successfully",
+		"wallet":  wallet,
+	})
+}
+
+// SetWalletLimits gives a user's wallet its own send limits (admin only).
+// A null limit returns it to the default and 0 removes it.
+func (h *Handler) SetWalletLimits(c *gin.Context) {
+	adminIDStr, _ := c.Get("user_id")
+	adminID, err := uuid.Parse(adminIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid admin ID"})
+		return
 	}
-	if req.LastName != nil {
-		updates["last_name"] = *req.LastName
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
 	}
-	if req.IsAdmin != nil {
-		updates["is_admin"] = *req.IsAdmin
+
+	var req struct {
+		DailyLimit   *models.Money `json:"daily_limit"`
+		MonthlyLimit *models.Money `json:"monthly_limit"`
+		Reason       string        `json:"reason" binding:"required"`
 	}
-	if req.IsMerchant != nil {
-		updates["is_merchant"] = *req.IsMerchant
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	if req.IsVerified != nil {
-		updates["is_verified"] = *req.IsVerified
+
+	wallet, err := h.walletService.SetWalletLimits(userID, adminID, req.DailyLimit, req.MonthlyLimit, req.Reason)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	if req.PFI != nil {
-		updates["pfi"] = *req.PFI
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Wallet limits updated successfully",
+		"wallet":  wallet,
+	})
+}
+
+// GetWalletControls returns a user's spending status and the audit trail of
+// changes to their wallet controls (admin only)
+func (h *Handler) GetWalletControls(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
 	}
-	if req.TFI != nil {
-		updates["tfi"] = *req.TFI
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		limit = 50
 	}
-	if req.CommunityService != nil {
-		updates["community_service"] = *req.CommunityService
+	if limit > 200 {
+		limit = 200
 	}
 
-	if len(updates) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No updates provided"})
+	status, err := h.walletService.GetSpendingStatus(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	audit, err := h.walletService.GetWalletAudit(userID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get audit trail"})
 		return
 	}
 
-	// Update user
-	if err := h.userService.GetDB().Model(&models.User{}).
-		Where("id = ?", parsedUserID).
-		Updates(updates).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to update user",
-			"details": err.Error(),
-		})
+	c.JSON(http.StatusOK, gin.H{
+		"status": status,
+		"audit":  audit,
+	})
+}
+
+// GetHoldingCapReport lists wallets near or over the holding cap (admin only)
+func (h *Handler) GetHoldingCapReport(c *gin.Context) {
+	threshold, err := strconv.ParseFloat(c.DefaultQuery("threshold", "0.8"), 64)
+	if err != nil || threshold < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid threshold"})
 		return
 	}
 
-	// Fetch updated user
-	if err := h.userService.GetDB().Where("id = ?", parsedUserID).First(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated user"})
+	report, err := h.walletService.GetHoldingCapReport(threshold)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build holding cap report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetCreditExposure reports how much credit the system has extended (admin only)
+func (h *Handler) GetCreditExposure(c *gin.Context) {
+	exposure, err := h.walletService.GetCreditExposure()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build credit exposure report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, exposure)
+}
+
+// SuspendCredit suspends or resumes a user's credit line (admin only)
+func (h *Handler) SuspendCredit(c *gin.Context) {
+	adminIDStr, _ := c.Get("user_id")
+	adminID, err := uuid.Parse(adminIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var line *models.CreditLine
+	switch c.Param("action") {
+	case "suspend":
+		line, err = h.walletService.SuspendCredit(userID, adminID, req.Reason)
+	case "resume":
+		line, err = h.walletService.ResumeCredit(userID, adminID, req.Reason)
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown action"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "User updated successfully",
-		"user": gin.H{
-			"id":                user.ID,
-			"username":          user.Username,
-			"email":             user.Email,
-			"first_name":        user.FirstName,
-			"last_name":         user.LastName,
-			"pfi":               user.PFI,
-			"tfi":               user.TFI,
-			"community_service": user.CommunityService,
-			"is_admin":          user.IsAdmin,
-			"is_merchant":       user.IsMerchant,
-			"is_verified":       user.IsVerified,
-		},
+		"message":     "Credit line updated successfully",
+		"credit_line": line,
+	})
+}
+
+// ExecuteTreasuryProposal pays out a passed treasury spend proposal without
+// waiting for the background job (admin only)
+func (h *Handler) ExecuteTreasuryProposal(c *gin.Context) {
+	proposalID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid proposal ID"})
+		return
+	}
+
+	transaction, err := h.treasuryService.ExecuteProposal(proposalID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Treasury spend executed",
+		"transaction": transaction,
+	})
+}
+
+// ApplyFeeScheduleProposal puts a passed fee schedule proposal into force
+// without waiting for the hourly job
+func (h *Handler) ApplyFeeScheduleProposal(c *gin.Context) {
+	proposalID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid proposal ID"})
+		return
+	}
+
+	schedule, err := h.feeService.ApplyProposal(proposalID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Fee schedule applied",
+		"fee_schedule": schedule,
+	})
+}
+
+// GetDemurrageRuns lists past monthly demurrage charges
+func (h *Handler) GetDemurrageRuns(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "12"))
+	if err != nil || limit <= 0 {
+		limit = 12
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	runs, err := h.demurrageService.GetRuns(limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get demurrage runs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"runs": runs})
+}
+
+// ApplyDemurrageProposal puts the demurrage rate of a passed monetary policy
+// proposal into force without waiting for the hourly job
+func (h *Handler) ApplyDemurrageProposal(c *gin.Context) {
+	proposalID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid proposal ID"})
+		return
+	}
+
+	rate, err := h.demurrageService.ApplyProposal(proposalID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Demurrage rate applied",
+		"demurrage_rate": rate,
 	})
 }
 
@@ -1061,7 +3515,7 @@ func (h *Handler) GetMonetaryPolicyInfo(c *gin.Context) {
 func (h *Handler) GetTransactionVolume(c *gin.Context) {
 	var results []struct {
 		Month  string
-		Volume float64
+		Volume models.Money
 	}
 
 	// Get transaction volume for the last 6 months
@@ -1071,16 +3525,17 @@ func (h *Handler) GetTransactionVolume(c *gin.Context) {
 		monthEnd := monthStart.AddDate(0, 1, 0).Add(-time.Second)
 
 		var monthVolume struct {
-			Total float64
+			Total models.Money
 		}
 
 		h.transactionService.GetDB().Model(&models.Transaction{}).
 			Where("created_at BETWEEN ? AND ? AND type = ?", monthStart, monthEnd, models.TransactionTypeTransfer).
+			Where("status NOT IN (?)", models.VoidTransactionStatuses).
 			Select("COALESCE(SUM(amount), 0) as total").Scan(&monthVolume)
 
 		results = append(results, struct {
 			Month  string
-			Volume float64
+			Volume models.Money
 		}{
 			Month:  date.Format("Jan"),
 			Volume: monthVolume.Total,
@@ -24,6 +24,10 @@ type Config struct {
 	// JWT configuration
 	JWTSecret string
 
+	// Comma-separated hex Ed25519 public keys, besides the one derived from
+	// JWTSecret, whose chain checkpoints are accepted
+	ChainTrustedKeys string
+
 	// Community Basket Index
 	CBIUpdateInterval time.Duration
 	CBIAPIURL         string
@@ -32,11 +36,57 @@ type Config struct {
 	BaseMonthlyIssuance  float64
 	MaxMonthlyGrowthRate float64
 	HoldingCapPercentage float64
+	HoldingCapMinimum    float64 // Cap never drops below this many FC, so small communities can still trade
+	HoldingCapOverflow   string  // "reject" or "treasury"
+	HoldingCapExemptIDs  string  // Comma-separated user IDs exempt from the cap
+
+	// Rewards vest over IssuanceVestingPeriod after an IssuanceVestingCliff (0 disables)
+	IssuanceVestingCliff  time.Duration
+	IssuanceVestingPeriod time.Duration
+
+	// Transfer fees, recorded as a new fee schedule version when they change.
+	// A schedule adopted by governance takes precedence over these.
+	FeeRateBPS         int     // Base rate in basis points
+	FeeMinimum         float64 // FC
+	FeeMaximum         float64 // FC, 0 means no cap
+	FeeTiers           string  // Comma-separated "up_to:rate_bps[:fixed]" brackets
+	FeeMerchantRates   string  // Comma-separated "user_id:rate_bps" pairs
+	FeeFreeBelowPFI    int     // Senders below this PFI pay no fee, 0 disables
+	FeeFreeForVerified bool
+
+	// Default send limits per wallet in FC, 0 means no limit. Admins can set
+	// limits for individual wallets.
+	SendLimitDaily             float64
+	SendLimitMonthly           float64
+	SendLimitDailyUnverified   float64
+	SendLimitMonthlyUnverified float64
+
+	// Mutual credit: members with enough PFI may spend below zero, up to a
+	// limit that grows with PFI and membership history. The council can
+	// approve larger limits up to CreditCouncilMaxLimit.
+	CreditMinPFI          int
+	CreditBaseLimit       float64 // FC at CreditMinPFI
+	CreditPerPFIPoint     float64 // FC per PFI point above CreditMinPFI
+	CreditMaxLimit        float64 // FC, 0 disables formula credit
+	CreditHistoryMonths   int     // Months of membership for the full limit
+	CreditCouncilMaxLimit float64 // FC, 0 disables council-approved credit
+
+	// Demurrage: a monthly holding fee on unlocked balances above the
+	// threshold. A rate set by governance takes precedence over DemurrageRate.
+	DemurrageRate        float64 // Share of the chargeable balance per month, 0 disables
+	DemurrageThreshold   float64 // FC
+	DemurrageDestination string  // "burn" or "treasury"
+
+	// Proof of reserves: how often a Merkle tree over all wallet balances
+	// is published
+	ReservesSnapshotInterval time.Duration
 
 	// Fairness System
-	MinPFIForProposals       int
-	MinTFIForMerchant        int
-	AttestationRequiredCount int
+	MinPFIForProposals        int
+	MinTFIForMerchant         int
+	AttestationRequiredCount  int    // Peer reviews that verify or reject an attestation
+	AttestationReviewerMinPFI int    // PFI a peer needs to review attestations
+	AttestationAging          string // Comma-separated "type:expire|decay:days" rules
 
 	// Security
 	BcryptCost        int
@@ -64,6 +114,8 @@ func Load() *Config {
 		// JWT
 		JWTSecret: getEnv("JWT_SECRET", "your-super-secret-jwt-key-change-this-in-production"),
 
+		ChainTrustedKeys: getEnv("CHAIN_TRUSTED_KEYS", ""),
+
 		// CBI
 		CBIUpdateInterval: getEnvDuration("CBI_UPDATE_INTERVAL", 24*time.Hour),
 		CBIAPIURL:         getEnv("CBI_API_URL", "http://localhost:8080/api/v1/cbi"),
@@ -72,11 +124,50 @@ func Load() *Config {
 		BaseMonthlyIssuance:  getEnvFloat("BASE_MONTHLY_ISSUANCE", 1000.0),
 		MaxMonthlyGrowthRate: getEnvFloat("MAX_MONTHLY_GROWTH_RATE", 0.02),
 		HoldingCapPercentage: getEnvFloat("HOLDING_CAP_PERCENTAGE", 0.02),
+		HoldingCapMinimum:    getEnvFloat("HOLDING_CAP_MINIMUM", 1000.0),
+		HoldingCapOverflow:   getEnv("HOLDING_CAP_OVERFLOW", "reject"),
+		HoldingCapExemptIDs:  getEnv("HOLDING_CAP_EXEMPT_IDS", ""),
+
+		IssuanceVestingCliff:  getEnvDuration("ISSUANCE_VESTING_CLIFF", 0),
+		IssuanceVestingPeriod: getEnvDuration("ISSUANCE_VESTING_PERIOD", 0),
+
+		// Transfer fees
+		FeeRateBPS:         getEnvInt("FEE_RATE_BPS", 10),
+		FeeMinimum:         getEnvFloat("FEE_MINIMUM", 0.01),
+		FeeMaximum:         getEnvFloat("FEE_MAXIMUM", 0),
+		FeeTiers:           getEnv("FEE_TIERS", ""),
+		FeeMerchantRates:   getEnv("FEE_MERCHANT_RATES", ""),
+		FeeFreeBelowPFI:    getEnvInt("FEE_FREE_BELOW_PFI", 0),
+		FeeFreeForVerified: getEnvBool("FEE_FREE_FOR_VERIFIED", false),
+
+		// Send limits
+		SendLimitDaily:             getEnvFloat("SEND_LIMIT_DAILY", 5000.0),
+		SendLimitMonthly:           getEnvFloat("SEND_LIMIT_MONTHLY", 50000.0),
+		SendLimitDailyUnverified:   getEnvFloat("SEND_LIMIT_DAILY_UNVERIFIED", 250.0),
+		SendLimitMonthlyUnverified: getEnvFloat("SEND_LIMIT_MONTHLY_UNVERIFIED", 2500.0),
+
+		// Mutual credit
+		CreditMinPFI:          getEnvInt("CREDIT_MIN_PFI", 70),
+		CreditBaseLimit:       getEnvFloat("CREDIT_BASE_LIMIT", 50.0),
+		CreditPerPFIPoint:     getEnvFloat("CREDIT_PER_PFI_POINT", 5.0),
+		CreditMaxLimit:        getEnvFloat("CREDIT_MAX_LIMIT", 200.0),
+		CreditHistoryMonths:   getEnvInt("CREDIT_HISTORY_MONTHS", 12),
+		CreditCouncilMaxLimit: getEnvFloat("CREDIT_COUNCIL_MAX_LIMIT", 1000.0),
+
+		// Demurrage
+		DemurrageRate:        getEnvFloat("DEMURRAGE_RATE", 0),
+		DemurrageThreshold:   getEnvFloat("DEMURRAGE_THRESHOLD", 1000.0),
+		DemurrageDestination: getEnv("DEMURRAGE_DESTINATION", "burn"),
+
+		// Proof of reserves
+		ReservesSnapshotInterval: getEnvDuration("RESERVES_SNAPSHOT_INTERVAL", 24*time.Hour),
 
 		// Fairness System
-		MinPFIForProposals:       getEnvInt("MIN_PFI_FOR_PROPOSALS", 50),
-		MinTFIForMerchant:        getEnvInt("MIN_TFI_FOR_MERCHANT", 30),
-		AttestationRequiredCount: getEnvInt("ATTESTATION_REQUIRED_COUNT", 3),
+		MinPFIForProposals:        getEnvInt("MIN_PFI_FOR_PROPOSALS", 50),
+		MinTFIForMerchant:         getEnvInt("MIN_TFI_FOR_MERCHANT", 30),
+		AttestationRequiredCount:  getEnvInt("ATTESTATION_REQUIRED_COUNT", 3),
+		AttestationReviewerMinPFI: getEnvInt("ATTESTATION_REVIEWER_MIN_PFI", 80),
+		AttestationAging:          getEnv("ATTESTATION_AGING", "community_service:expire:365,dispute_resolution:expire:730,peer_rating:decay:180"),
 
 		// Security
 		BcryptCost:        getEnvInt("BCRYPT_COST", 12),
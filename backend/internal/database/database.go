@@ -1,9 +1,11 @@
 package database
 
 import (
+	"database/sql"
 	"faircoin/internal/config"
 	"faircoin/internal/models"
 	"fmt"
+	"strings"
 
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/postgres"
@@ -49,8 +51,10 @@ func Migrate(db *gorm.DB) error {
 		db.Exec("CREATE EXTENSION IF NOT EXISTS \"uuid-ossp\";")
 	}
 
-	// For SQLite, handle migration more carefully due to GORM v1 limitations
-	if db.Dialect().GetName() == "sqlite3" {
+	// For SQLite, handle migration more carefully due to GORM v1 limitations.
+	// The pure-Go "sqlite" driver runs under GORM's common dialect, so treat
+	// anything that is not PostgreSQL as SQLite.
+	if db.Dialect().GetName() != "postgres" {
 		fmt.Println("Running SQLite database migration...")
 
 		// Try to create tables, but ignore "table exists" errors
@@ -70,6 +74,31 @@ func Migrate(db *gorm.DB) error {
 			&models.FairnessMetrics{},
 			&models.MerchantRanking{},
 			&models.FairnessAlert{},
+			&models.LedgerAccount{},
+			&models.JournalEntry{},
+			&models.Posting{},
+			&models.IdempotencyRecord{},
+			&models.LockSchedule{},
+			&models.Escrow{},
+			&models.StandingOrder{},
+			&models.StandingOrderRun{},
+			&models.Invoice{},
+			&models.InvoiceLineItem{},
+			&models.FeeSchedule{},
+			&models.DemurrageRate{},
+			&models.DemurrageRun{},
+			&models.WalletAuditEntry{},
+			&models.SavingsPot{},
+			&models.Voucher{},
+			&models.CreditLine{},
+			&models.CreditRequest{},
+			&models.CreditVote{},
+			&models.CreditMovement{},
+			&models.ChainCheckpoint{},
+			&models.BalanceSnapshot{},
+			&models.BalanceSnapshotLeaf{},
+			&models.TransactionStatusChange{},
+			&models.AttestationReview{},
 		}
 
 		for _, table := range tables {
@@ -78,6 +107,43 @@ func Migrate(db *gorm.DB) error {
 
 		// Ensure critical columns exist
 		ensureColumn(db, "users", "is_admin", "BOOLEAN DEFAULT false")
+		ensureColumn(db, "proposals", "treasury_amount", "BIGINT DEFAULT 0")
+		ensureColumn(db, "proposals", "treasury_recipient_id", "VARCHAR(36)")
+		ensureColumn(db, "proposals", "executed_at", "DATETIME")
+		ensureColumn(db, "transactions", "related_transaction_id", "VARCHAR(36)")
+		ensureColumn(db, "transactions", "fee_schedule_version", "INTEGER DEFAULT 0")
+		ensureColumn(db, "proposals", "fee_policy", "TEXT")
+		ensureColumn(db, "proposals", "demurrage_rate", "REAL")
+		ensureColumn(db, "wallets", "daily_limit", "BIGINT")
+		ensureColumn(db, "wallets", "monthly_limit", "BIGINT")
+		ensureColumn(db, "wallets", "frozen_at", "DATETIME")
+		ensureColumn(db, "wallets", "frozen_reason", "VARCHAR(255)")
+		ensureColumn(db, "wallets", "potted_fc", "BIGINT DEFAULT 0")
+		ensureColumn(db, "transactions", "chain_seq", "BIGINT")
+		ensureColumn(db, "transactions", "prev_hash", "VARCHAR(64)")
+		ensureColumn(db, "transactions", "hash", "VARCHAR(64)")
+		ensureColumn(db, "transactions", "status_changed_at", "DATETIME")
+		ensureColumn(db, "transactions", "failure_reason", "VARCHAR(255)")
+		ensureColumn(db, "attestations", "status", "VARCHAR(255) DEFAULT 'pending'")
+		ensureColumn(db, "attestations", "reviews_for", "INTEGER DEFAULT 0")
+		ensureColumn(db, "attestations", "reviews_against", "INTEGER DEFAULT 0")
+		ensureColumn(db, "attestations", "decided_at", "DATETIME")
+		ensureColumn(db, "attestations", "revoked_at", "DATETIME")
+		ensureColumn(db, "attestations", "revoked_by", "VARCHAR(36)")
+		ensureColumn(db, "attestations", "revocation_reason", "VARCHAR(255)")
+		ensureColumn(db, "transaction_status_changes", "chain_seq", "BIGINT")
+		ensureColumn(db, "transaction_status_changes", "prev_hash", "VARCHAR(64)")
+		ensureColumn(db, "transaction_status_changes", "hash", "VARCHAR(64)")
+		ensureColumn(db, "chain_checkpoints", "status_chain_seq", "BIGINT DEFAULT 0")
+		ensureColumn(db, "chain_checkpoints", "status_hash", "VARCHAR(64)")
+		ensureColumn(db, "idempotency_records", "attempt", "INTEGER DEFAULT 1")
+		ensureColumn(db, "idempotency_records", "resource_type", "VARCHAR(255)")
+		ensureColumn(db, "idempotency_records", "resource_id", "VARCHAR(36)")
+
+		// AutoMigrate does not add indexes to existing SQLite tables, and
+		// concurrent chain appends rely on these
+		ensureUniqueIndex(db, "transactions", "uix_transactions_chain_seq", "chain_seq")
+		ensureUniqueIndex(db, "transaction_status_changes", "uix_transaction_status_changes_chain_seq", "chain_seq")
 		fmt.Println("Database schema update completed")
 	} else {
 		// For PostgreSQL, AutoMigrate works reliably
@@ -95,20 +161,288 @@ func Migrate(db *gorm.DB) error {
 			&models.FairnessMetrics{},
 			&models.MerchantRanking{},
 			&models.FairnessAlert{},
+			&models.LedgerAccount{},
+			&models.JournalEntry{},
+			&models.Posting{},
+			&models.IdempotencyRecord{},
+			&models.LockSchedule{},
+			&models.Escrow{},
+			&models.StandingOrder{},
+			&models.StandingOrderRun{},
+			&models.Invoice{},
+			&models.InvoiceLineItem{},
+			&models.FeeSchedule{},
+			&models.DemurrageRate{},
+			&models.DemurrageRun{},
+			&models.WalletAuditEntry{},
+			&models.SavingsPot{},
+			&models.Voucher{},
+			&models.CreditLine{},
+			&models.CreditRequest{},
+			&models.CreditVote{},
+			&models.CreditMovement{},
+			&models.ChainCheckpoint{},
+			&models.BalanceSnapshot{},
+			&models.BalanceSnapshotLeaf{},
+			&models.TransactionStatusChange{},
+			&models.AttestationReview{},
 		).Error; err != nil {
 			return fmt.Errorf("failed to migrate database: %w", err)
 		}
 	}
 
+	// Convert legacy float64 money columns to fixed-point minor units
+	if err := MigrateMoneyColumns(db); err != nil {
+		return fmt.Errorf("failed to migrate money columns: %w", err)
+	}
+
+	// Each user has one wallet; merge copies before the ledger is opened
+	if err := MergeDuplicateWallets(db); err != nil {
+		return fmt.Errorf("failed to merge duplicate wallets: %w", err)
+	}
+
+	// Bring statuses from before the transaction state machine in line with it
+	if err := MigrateTransactionStatuses(db); err != nil {
+		return fmt.Errorf("failed to migrate transaction statuses: %w", err)
+	}
+
+	// Attestations verified before the review queue were verified on creation
+	if err := db.Model(&models.Attestation{}).
+		Where("verified = ? AND status = ?", true, models.AttestationStatusPending).
+		Update("status", models.AttestationStatusVerified).Error; err != nil {
+		return fmt.Errorf("failed to migrate attestation statuses: %w", err)
+	}
+
 	fmt.Println("Database migration completed successfully")
 	return nil
 }
 
+// moneyColumns lists every column that holds a models.Money amount
+var moneyColumns = []struct {
+	table  string
+	column string
+}{
+	{"wallets", "balance"},
+	{"wallets", "locked_fc"},
+	{"transactions", "amount"},
+	{"transactions", "fee"},
+	{"monetary_policies", "base_issuance"},
+	{"monetary_policies", "total_issuance"},
+	{"monetary_policies", "circulating_supply"},
+}
+
+// MigrateMoneyColumns converts money columns that still store FairCoins as
+// floating point into BIGINT minor units (see models.Money). Each value is
+// converted with models.MoneyFromFloat, i.e. rounded half to even at
+// models.MoneyDecimals. Columns that are already BIGINT are left untouched,
+// so the migration is safe to run on every startup.
+func MigrateMoneyColumns(db *gorm.DB) error {
+	for _, mc := range moneyColumns {
+		colType, err := columnType(db, mc.table, mc.column)
+		if err != nil {
+			return err
+		}
+		if colType == "" || strings.EqualFold(colType, "bigint") {
+			continue // Missing table/column or already migrated
+		}
+
+		fmt.Printf("Converting %s.%s (%s) to fixed-point minor units...\n", mc.table, mc.column, colType)
+		if err := convertMoneyColumn(db, mc.table, mc.column); err != nil {
+			return fmt.Errorf("failed to convert %s.%s: %w", mc.table, mc.column, err)
+		}
+	}
+	return nil
+}
+
+// convertMoneyColumn rebuilds a single float column as BIGINT minor units
+func convertMoneyColumn(db *gorm.DB, table, column string) error {
+	tmpColumn := column + "_minor"
+
+	tx := db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	if err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s BIGINT DEFAULT 0", table, tmpColumn)).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	rows, err := tx.Raw(fmt.Sprintf("SELECT id, %s FROM %s", column, table)).Rows()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	type legacyRow struct {
+		id    string
+		value models.Money
+	}
+	var converted []legacyRow
+	for rows.Next() {
+		var id string
+		var value sql.NullFloat64
+		if err := rows.Scan(&id, &value); err != nil {
+			rows.Close()
+			tx.Rollback()
+			return err
+		}
+		converted = append(converted, legacyRow{id: id, value: models.MoneyFromFloat(value.Float64)})
+	}
+	rows.Close()
+
+	for _, row := range converted {
+		if err := tx.Exec(fmt.Sprintf("UPDATE %s SET %s = ? WHERE id = ?", table, tmpColumn), row.value, row.id).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Exec(fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, column)).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Exec(fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", table, tmpColumn, column)).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// MergeDuplicateWallets folds extra wallets of a user into the first one (by
+// id, the one every lookup by user returns) and then enforces one wallet per
+// user with a unique index. The kept wallet's balance becomes the sum of all
+// of them, so no coins are lost when the ledger is opened from it; its
+// locked, potted and spending control fields are kept, since every update of
+// those already went to all of a user's wallets. Users whose ledger account
+// is already open are rejected rather than merged, as the ledger only knows
+// the first wallet and the copies would have to be issued by hand. Safe to run
+// on every startup.
+func MergeDuplicateWallets(db *gorm.DB) error {
+	var duplicated []struct {
+		UserID string
+	}
+	if err := db.Table("wallets").Select("user_id").Group("user_id").
+		Having("COUNT(*) > 1").Scan(&duplicated).Error; err != nil {
+		return err
+	}
+
+	var opened []string
+	for _, row := range duplicated {
+		var accounts int
+		if err := db.Model(&models.LedgerAccount{}).Where("user_id = ?", row.UserID).Count(&accounts).Error; err != nil {
+			return err
+		}
+		if accounts > 0 {
+			opened = append(opened, row.UserID)
+			continue
+		}
+
+		tx := db.Begin()
+		if tx.Error != nil {
+			return tx.Error
+		}
+		var wallets []models.Wallet
+		if err := tx.Where("user_id = ?", row.UserID).Order("id ASC").Find(&wallets).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+		keep := wallets[0]
+		total := keep.Balance
+		for _, wallet := range wallets[1:] {
+			total = total.Add(wallet.Balance)
+			if err := tx.Where("id = ?", wallet.ID).Delete(&models.Wallet{}).Error; err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+		if err := tx.Model(&models.Wallet{}).Where("id = ?", keep.ID).
+			UpdateColumn("balance", total).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit().Error; err != nil {
+			return err
+		}
+		fmt.Printf("Merged %d wallets of user %s into %s (%s FC)\n", len(wallets), row.UserID, keep.ID, total)
+	}
+	if len(opened) > 0 {
+		return fmt.Errorf("users %s have several wallets but their ledger accounts are already open", strings.Join(opened, ", "))
+	}
+
+	ensureUniqueIndex(db, "wallets", "uix_wallets_user_id", "user_id")
+	return nil
+}
+
+// MigrateTransactionStatuses moves transactions recorded before the state
+// machine (see models.TransactionStatus) to the statuses it would have given
+// them: escrows and vouchers holding funds are authorized rather than
+// pending, and those whose funds went back to the payer are cancelled rather
+// than refunded. Transactions without a status change time get their
+// creation time. Each step only touches rows still in the old form, so the
+// migration is safe to run on every startup.
+func MigrateTransactionStatuses(db *gorm.DB) error {
+	steps := []struct {
+		from, to models.TransactionStatus
+		holders  string // Records holding the funds, with a transaction_id
+		held     []string
+	}{
+		{models.TransactionStatusPending, models.TransactionStatusAuthorized,
+			"escrows", []string{string(models.EscrowStatusOpen)}},
+		{models.TransactionStatusPending, models.TransactionStatusAuthorized,
+			"vouchers", []string{string(models.VoucherStatusOutstanding)}},
+		{models.TransactionStatusRefunded, models.TransactionStatusCancelled,
+			"escrows", []string{string(models.EscrowStatusRefunded)}},
+		{models.TransactionStatusRefunded, models.TransactionStatusCancelled,
+			"vouchers", []string{string(models.VoucherStatusCancelled), string(models.VoucherStatusExpired)}},
+	}
+
+	tx := db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	for _, step := range steps {
+		held := tx.Table(step.holders).Select("transaction_id").Where("status IN (?)", step.held).QueryExpr()
+		if err := tx.Model(&models.Transaction{}).Where("status = ? AND id IN (?)", step.from, held).
+			Update("status", step.to).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Exec("UPDATE transactions SET status_changed_at = created_at WHERE status_changed_at IS NULL").Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// columnType returns the declared SQL type of a column, or "" if it does not exist
+func columnType(db *gorm.DB, tableName, columnName string) (string, error) {
+	var result struct {
+		Type string
+	}
+
+	var err error
+	if db.Dialect().GetName() == "postgres" {
+		err = db.Raw("SELECT data_type AS type FROM information_schema.columns WHERE table_name = ? AND column_name = ?",
+			tableName, columnName).Scan(&result).Error
+	} else {
+		err = db.Raw(fmt.Sprintf("SELECT type FROM pragma_table_info('%s') WHERE name = ?", tableName),
+			columnName).Scan(&result).Error
+	}
+
+	if gorm.IsRecordNotFoundError(err) {
+		return "", nil
+	}
+	return result.Type, err
+}
+
 // ensureColumn adds a column to a table if it doesn't exist
 func ensureColumn(db *gorm.DB, tableName, columnName, columnType string) {
 	// Check if column exists by trying to query it
 	var count int
-	err := db.Raw(fmt.Sprintf("SELECT COUNT(*) as count FROM pragma_table_info('%s') WHERE name='%s'", tableName, columnName)).Scan(&count).Error
+	err := db.Raw(fmt.Sprintf("SELECT COUNT(*) as count FROM pragma_table_info('%s') WHERE name='%s'", tableName, columnName)).Row().Scan(&count)
 	if err != nil || count == 0 {
 		// Column doesn't exist, add it
 		sql := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", tableName, columnName, columnType)
@@ -118,7 +452,18 @@ func ensureColumn(db *gorm.DB, tableName, columnName, columnType string) {
 			fmt.Printf("Added column %s to table %s\n", columnName, tableName)
 		}
 	}
-} // CreateIndices creates database indices for better performance
+}
+
+// ensureUniqueIndex creates a unique index on an existing table if it does not
+// exist yet
+func ensureUniqueIndex(db *gorm.DB, tableName, indexName, columnName string) {
+	sql := fmt.Sprintf("CREATE UNIQUE INDEX IF NOT EXISTS %s ON %s(%s)", indexName, tableName, columnName)
+	if err := db.Exec(sql).Error; err != nil {
+		fmt.Printf("Warning: Could not add index %s to %s: %v\n", indexName, tableName, err)
+	}
+}
+
+// CreateIndices creates database indices for better performance
 func CreateIndices(db *gorm.DB) error {
 	// Users indices
 	if err := db.Model(&models.User{}).AddIndex("idx_user_username", "username").Error; err != nil {
@@ -174,5 +519,16 @@ func CreateIndices(db *gorm.DB) error {
 		return err
 	}
 
+	// Ledger indices
+	if err := db.Model(&models.Posting{}).AddIndex("idx_posting_account_id", "account_id").Error; err != nil {
+		return err
+	}
+	if err := db.Model(&models.Posting{}).AddIndex("idx_posting_entry_id", "entry_id").Error; err != nil {
+		return err
+	}
+	if err := db.Model(&models.JournalEntry{}).AddIndex("idx_journal_entry_transaction_id", "transaction_id").Error; err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"faircoin/internal/models"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+)
+
+// newTestDB returns a migrated SQLite database whose wallets table accepts
+// several wallets per user again, as it did before the unique index
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	db.LogMode(false)
+	if err := Migrate(db); err != nil {
+		t.Fatalf("migrate database: %v", err)
+	}
+	if err := db.Exec("DROP INDEX uix_wallets_user_id").Error; err != nil {
+		t.Fatalf("drop wallet index: %v", err)
+	}
+	return db
+}
+
+func createWallets(t *testing.T, db *gorm.DB, userID uuid.UUID, balances ...models.Money) {
+	t.Helper()
+	for _, balance := range balances {
+		if err := db.Create(&models.Wallet{ID: uuid.New(), UserID: userID, Balance: balance}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMergeDuplicateWallets(t *testing.T) {
+	db := newTestDB(t)
+	duplicated, single := uuid.New(), uuid.New()
+	createWallets(t, db, duplicated, models.FC(100), models.FC(150), models.MoneyFromFloat(0.5))
+	createWallets(t, db, single, models.FC(7))
+
+	var first models.Wallet
+	db.Where("user_id = ?", duplicated).Order("id ASC").First(&first)
+
+	for i := 0; i < 2; i++ {
+		if err := MergeDuplicateWallets(db); err != nil {
+			t.Fatalf("run %d: %v", i+1, err)
+		}
+	}
+
+	tests := []struct {
+		userID uuid.UUID
+		want   models.Money
+	}{
+		{duplicated, models.MoneyFromFloat(250.5)},
+		{single, models.FC(7)},
+	}
+	for _, tt := range tests {
+		var wallets []models.Wallet
+		db.Where("user_id = ?", tt.userID).Find(&wallets)
+		if len(wallets) != 1 {
+			t.Fatalf("user has %d wallets after the merge, want 1", len(wallets))
+		}
+		if wallets[0].Balance != tt.want {
+			t.Errorf("merged balance = %s, want %s", wallets[0].Balance, tt.want)
+		}
+	}
+
+	var kept models.Wallet
+	db.Where("user_id = ?", duplicated).First(&kept)
+	if kept.ID != first.ID {
+		t.Errorf("kept wallet %s, want the first one %s", kept.ID, first.ID)
+	}
+
+	if err := db.Create(&models.Wallet{ID: uuid.New(), UserID: single}).Error; err == nil {
+		t.Error("a second wallet was accepted after the merge")
+	}
+}
+
+func TestMergeDuplicateWalletsRejectsOpenLedgerAccounts(t *testing.T) {
+	db := newTestDB(t)
+	userID := uuid.New()
+	createWallets(t, db, userID, models.FC(10), models.FC(20))
+	if err := db.Create(&models.LedgerAccount{Code: "user:" + userID.String(), Type: models.AccountTypeUser,
+		UserID: &userID}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := MergeDuplicateWallets(db); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	var count int
+	db.Model(&models.Wallet{}).Where("user_id = ?", userID).Count(&count)
+	if count != 2 {
+		t.Errorf("user has %d wallets, want both left in place", count)
+	}
+}
@@ -0,0 +1,213 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Transactions form a hash chain: each one is numbered by ChainSeq when it
+// is created and carries
+//
+//	Hash = SHA-256(PrevHash + "\n" + canonical JSON of its content)
+//
+// where PrevHash is the Hash of the transaction before it (empty for the
+// first). Editing, deleting or inserting a row outside the application
+// breaks the chain at that point.
+//
+// Status changes legitimately as payments complete, are refunded or
+// reversed, so it is not part of a transaction's hash. Instead every status
+// change is a TransactionStatusChange row, and those rows form a second
+// chain of their own, hashed the same way. The verifier checks that each
+// transaction's status is the one its last recorded change moved it to, so
+// editing a status in place shows up as a mismatch, and editing the history
+// breaks the status chain. StatusChangedAt and FailureReason are copies of
+// the last change. The recipient of a voucher is not covered either: it is
+// only known on redemption and is recorded on the voucher itself.
+//
+// Appends to both chains are serialized: on Postgres by a transaction-scoped
+// advisory lock taken before the head is read, so the next append waits for
+// this one to commit and then sees it; SQLite allows a single writer at a
+// time anyway. The unique indexes on the sequence numbers back this up.
+
+// chainLockKey identifies the Postgres advisory lock held while appending
+const chainLockKey int64 = 0x46434841494e // "FCHAIN"
+
+// chainContent is the hashed content of a transaction. Field order is fixed
+// by the struct, so the encoding is canonical.
+type chainContent struct {
+	Seq                  int64   `json:"seq"`
+	ID                   string  `json:"id"`
+	UserID               string  `json:"user_id"`
+	ToUserID             *string `json:"to_user_id"`
+	Type                 string  `json:"type"`
+	Amount               int64   `json:"amount"`
+	Fee                  int64   `json:"fee"`
+	Description          string  `json:"description"`
+	Metadata             string  `json:"metadata"`
+	RelatedTransactionID *string `json:"related_transaction_id"`
+	FeeScheduleVersion   int     `json:"fee_schedule_version"`
+	CreatedAt            string  `json:"created_at"`
+}
+
+// ChainHash computes the transaction's hash from its content, ChainSeq and
+// PrevHash. It returns "" for a transaction outside the chain.
+func (t *Transaction) ChainHash() string {
+	if t.ChainSeq == nil {
+		return ""
+	}
+	content := chainContent{
+		Seq:                *t.ChainSeq,
+		ID:                 t.ID.String(),
+		UserID:             t.UserID.String(),
+		Type:               string(t.Type),
+		Amount:             t.Amount.Units(),
+		Fee:                t.Fee.Units(),
+		Description:        t.Description,
+		Metadata:           t.Metadata,
+		FeeScheduleVersion: t.FeeScheduleVersion,
+		// Databases keep at most microseconds
+		CreatedAt: t.CreatedAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+	}
+	if t.ToUserID != nil && t.Type != TransactionTypeVoucher {
+		toUserID := t.ToUserID.String()
+		content.ToUserID = &toUserID
+	}
+	if t.RelatedTransactionID != nil {
+		relatedID := t.RelatedTransactionID.String()
+		content.RelatedTransactionID = &relatedID
+	}
+
+	encoded, _ := json.Marshal(content)
+	sum := sha256.Sum256(append([]byte(t.PrevHash+"\n"), encoded...))
+	return hex.EncodeToString(sum[:])
+}
+
+// statusChainContent is the hashed content of a status change
+type statusChainContent struct {
+	Seq           int64   `json:"seq"`
+	ID            string  `json:"id"`
+	TransactionID string  `json:"transaction_id"`
+	From          string  `json:"from"`
+	To            string  `json:"to"`
+	Reason        string  `json:"reason"`
+	ActorID       *string `json:"actor_id"`
+	CreatedAt     string  `json:"created_at"`
+}
+
+// ChainHash computes the status change's hash from its content, ChainSeq and
+// PrevHash. It returns "" for a change outside the status chain.
+func (c *TransactionStatusChange) ChainHash() string {
+	if c.ChainSeq == nil {
+		return ""
+	}
+	content := statusChainContent{
+		Seq:           *c.ChainSeq,
+		ID:            c.ID.String(),
+		TransactionID: c.TransactionID.String(),
+		From:          string(c.From),
+		To:            string(c.To),
+		Reason:        c.Reason,
+		CreatedAt:     c.CreatedAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+	}
+	if c.ActorID != nil {
+		actorID := c.ActorID.String()
+		content.ActorID = &actorID
+	}
+
+	encoded, _ := json.Marshal(content)
+	sum := sha256.Sum256(append([]byte(c.PrevHash+"\n"), encoded...))
+	return hex.EncodeToString(sum[:])
+}
+
+// lockChain serializes appends to the chains until the database transaction
+// creating the row ends. Only Postgres needs it.
+func lockChain(scope *gorm.Scope) error {
+	if scope.Dialect().GetName() != "postgres" {
+		return nil
+	}
+	if err := scope.NewDB().Exec("SELECT pg_advisory_xact_lock(?)", chainLockKey).Error; err != nil {
+		return fmt.Errorf("failed to lock transaction chain: %w", err)
+	}
+	return nil
+}
+
+// sealTransaction appends a new transaction to the chain inside the
+// transaction creating it
+func sealTransaction(scope *gorm.Scope, t *Transaction) error {
+	if err := lockChain(scope); err != nil {
+		return err
+	}
+	var head Transaction
+	err := scope.NewDB().Select("chain_seq, hash").Where("chain_seq IS NOT NULL").
+		Order("chain_seq DESC").First(&head).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return fmt.Errorf("failed to read transaction chain head: %w", err)
+	}
+
+	seq := int64(1)
+	if head.ChainSeq != nil {
+		seq = *head.ChainSeq + 1
+	}
+	if t.CreatedAt.IsZero() {
+		// Set it now so the hash covers the stored value
+		if err := scope.SetColumn("CreatedAt", time.Now()); err != nil {
+			return err
+		}
+	}
+	if err := scope.SetColumn("ChainSeq", &seq); err != nil {
+		return err
+	}
+	if err := scope.SetColumn("PrevHash", head.Hash); err != nil {
+		return err
+	}
+	return scope.SetColumn("Hash", t.ChainHash())
+}
+
+// sealStatusChange appends a new status change to the status chain inside
+// the transaction creating it
+func sealStatusChange(scope *gorm.Scope, c *TransactionStatusChange) error {
+	if err := lockChain(scope); err != nil {
+		return err
+	}
+	var head TransactionStatusChange
+	err := scope.NewDB().Select("chain_seq, hash").Where("chain_seq IS NOT NULL").
+		Order("chain_seq DESC").First(&head).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return fmt.Errorf("failed to read status chain head: %w", err)
+	}
+
+	seq := int64(1)
+	if head.ChainSeq != nil {
+		seq = *head.ChainSeq + 1
+	}
+	if c.CreatedAt.IsZero() {
+		if err := scope.SetColumn("CreatedAt", time.Now()); err != nil {
+			return err
+		}
+	}
+	if err := scope.SetColumn("ChainSeq", &seq); err != nil {
+		return err
+	}
+	if err := scope.SetColumn("PrevHash", head.Hash); err != nil {
+		return err
+	}
+	return scope.SetColumn("Hash", c.ChainHash())
+}
+
+// CheckpointMessage is the text a checkpoint's signature covers. The status
+// chain head is appended when there is one; checkpoints taken before the
+// status chain existed have none.
+func CheckpointMessage(seq int64, hash string, statusSeq int64, statusHash string, createdAt time.Time) []byte {
+	message := "faircoin transaction chain checkpoint\n" + strconv.FormatInt(seq, 10) + "\n" + hash + "\n" +
+		createdAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano)
+	if statusSeq > 0 {
+		message += "\n" + strconv.FormatInt(statusSeq, 10) + "\n" + statusHash
+	}
+	return []byte(message)
+}
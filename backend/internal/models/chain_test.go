@@ -0,0 +1,132 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func chainedTransaction() *Transaction {
+	seq := int64(7)
+	toUserID := uuid.MustParse("22222222-2222-2222-2222-222222222222")
+	return &Transaction{
+		ID:          uuid.MustParse("11111111-1111-1111-1111-111111111111"),
+		UserID:      uuid.MustParse("33333333-3333-3333-3333-333333333333"),
+		ToUserID:    &toUserID,
+		Type:        TransactionTypeTransfer,
+		Amount:      FC(10),
+		Fee:         FC(1).MulFrac(1, 100),
+		Description: "Rent",
+		Status:      TransactionStatusCompleted,
+		CreatedAt:   time.Date(2026, 3, 1, 12, 0, 0, 123456789, time.UTC),
+		ChainSeq:    &seq,
+		PrevHash:    strings.Repeat("a", 64),
+	}
+}
+
+func TestTransactionChainHash(t *testing.T) {
+	base := chainedTransaction()
+	hash := base.ChainHash()
+	if len(hash) != 64 {
+		t.Fatalf("hash %q is not hex SHA-256", hash)
+	}
+	if again := chainedTransaction().ChainHash(); again != hash {
+		t.Fatalf("hash is not deterministic: %s != %s", again, hash)
+	}
+
+	tests := []struct {
+		name    string
+		edit    func(*Transaction)
+		changes bool
+	}{
+		{"amount", func(tx *Transaction) { tx.Amount = tx.Amount.Add(1) }, true},
+		{"fee", func(tx *Transaction) { tx.Fee = 0 }, true},
+		{"recipient", func(tx *Transaction) { tx.ToUserID = &tx.UserID }, true},
+		{"description", func(tx *Transaction) { tx.Description = "Rent!" }, true},
+		{"metadata", func(tx *Transaction) { tx.Metadata = "{}" }, true},
+		{"previous hash", func(tx *Transaction) { tx.PrevHash = strings.Repeat("b", 64) }, true},
+		{"sequence", func(tx *Transaction) { seq := int64(8); tx.ChainSeq = &seq }, true},
+		{"created at", func(tx *Transaction) { tx.CreatedAt = tx.CreatedAt.Add(time.Microsecond) }, true},
+		{"status", func(tx *Transaction) { tx.Status = TransactionStatusRefunded }, false},
+		{"below microseconds", func(tx *Transaction) { tx.CreatedAt = tx.CreatedAt.Add(100 * time.Nanosecond) }, false},
+		{"time zone", func(tx *Transaction) { tx.CreatedAt = tx.CreatedAt.In(time.FixedZone("", 7*3600)) }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := chainedTransaction()
+			tt.edit(tx)
+			if changed := tx.ChainHash() != hash; changed != tt.changes {
+				t.Errorf("editing %s changed the hash = %v, want %v", tt.name, changed, tt.changes)
+			}
+		})
+	}
+
+	unsealed := chainedTransaction()
+	unsealed.ChainSeq = nil
+	if got := unsealed.ChainHash(); got != "" {
+		t.Errorf("unsealed transaction hash = %q, want empty", got)
+	}
+}
+
+func TestVoucherRecipientIsNotHashed(t *testing.T) {
+	voucher := chainedTransaction()
+	voucher.Type = TransactionTypeVoucher
+	hash := voucher.ChainHash()
+
+	voucher.ToUserID = nil
+	if voucher.ChainHash() != hash {
+		t.Error("the voucher recipient changed the hash")
+	}
+}
+
+func TestStatusChangeChainHash(t *testing.T) {
+	seq := int64(3)
+	change := func() *TransactionStatusChange {
+		return &TransactionStatusChange{
+			ID:            uuid.MustParse("44444444-4444-4444-4444-444444444444"),
+			TransactionID: uuid.MustParse("11111111-1111-1111-1111-111111111111"),
+			From:          TransactionStatusCompleted,
+			To:            TransactionStatusRefunded,
+			Reason:        "Refund",
+			CreatedAt:     time.Date(2026, 3, 2, 9, 30, 0, 0, time.UTC),
+			ChainSeq:      &seq,
+		}
+	}
+	hash := change().ChainHash()
+
+	tests := []struct {
+		name string
+		edit func(*TransactionStatusChange)
+	}{
+		{"to", func(c *TransactionStatusChange) { c.To = TransactionStatusReversed }},
+		{"from", func(c *TransactionStatusChange) { c.From = TransactionStatusPartiallyRefunded }},
+		{"transaction", func(c *TransactionStatusChange) { c.TransactionID = uuid.Nil }},
+		{"actor", func(c *TransactionStatusChange) { c.ActorID = &c.ID }},
+		{"reason", func(c *TransactionStatusChange) { c.Reason = "" }},
+		{"previous hash", func(c *TransactionStatusChange) { c.PrevHash = hash }},
+	}
+	for _, tt := range tests {
+		c := change()
+		tt.edit(c)
+		if c.ChainHash() == hash {
+			t.Errorf("editing %s did not change the hash", tt.name)
+		}
+	}
+}
+
+func TestCheckpointMessage(t *testing.T) {
+	createdAt := time.Date(2026, 3, 3, 0, 0, 0, 0, time.FixedZone("", 3600))
+
+	legacy := string(CheckpointMessage(12, "abc", 0, "", createdAt))
+	want := "faircoin transaction chain checkpoint\n12\nabc\n2026-03-02T23:00:00Z"
+	if legacy != want {
+		t.Errorf("message without status chain = %q, want %q", legacy, want)
+	}
+
+	withStatus := string(CheckpointMessage(12, "abc", 30, "def", createdAt))
+	if withStatus != want+"\n30\ndef" {
+		t.Errorf("message with status chain = %q", withStatus)
+	}
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+)
+
+// IdempotencyClaimKey is the gorm setting that carries the ID of the
+// idempotency claim a request runs under. Writes through a *gorm.DB with it
+// set record the first resource they create on the claim, in the same
+// database transaction. If the process dies after the request committed but
+// before its response was stored, the claim still shows what was done.
+const IdempotencyClaimKey = "faircoin:idempotency_claim"
+
+// claimedResources are the tables whose rows answer an idempotent request
+var claimedResources = map[string]bool{
+	"transactions":        true,
+	"invoices":            true,
+	"standing_orders":     true,
+	"ratings":             true,
+	"attestations":        true,
+	"attestation_reviews": true,
+	"votes":               true,
+	"credit_votes":        true,
+}
+
+func init() {
+	gorm.DefaultCallback.Create().After("gorm:create").Register("faircoin:record_idempotency_resource", recordIdempotencyResource)
+}
+
+// recordIdempotencyResource stores the created row on the request's claim,
+// unless an earlier write of the request already did
+func recordIdempotencyResource(scope *gorm.Scope) {
+	if scope.HasError() {
+		return
+	}
+	value, ok := scope.Get(IdempotencyClaimKey)
+	if !ok {
+		return
+	}
+	claimID, ok := value.(uuid.UUID)
+	if !ok || !claimedResources[scope.TableName()] {
+		return
+	}
+	resourceID, ok := scope.PrimaryKeyValue().(uuid.UUID)
+	if !ok {
+		return
+	}
+	scope.Err(scope.NewDB().Model(&IdempotencyRecord{}).
+		Where("id = ? AND resource_id IS NULL", claimID).
+		Updates(map[string]interface{}{"resource_type": scope.TableName(), "resource_id": resourceID}).Error)
+}
@@ -34,32 +34,60 @@ type User struct {
 	Votes        []Vote        `json:"votes,omitempty" gorm:"foreignkey:UserID"`
 }
 
-// Wallet represents a user's FairCoin wallet
+// Wallet represents a user's FairCoin wallet. Each user has exactly one,
+// enforced by the uix_wallets_user_id index (see database.MergeDuplicateWallets).
 type Wallet struct {
 	ID        uuid.UUID `json:"id" gorm:"type:varchar(36);primary_key"`
 	UserID    uuid.UUID `json:"user_id" gorm:"type:varchar(36);not null"`
-	Balance   float64   `json:"balance" gorm:"default:0"`
-	LockedFC  float64   `json:"locked_fc" gorm:"default:0"` // Locked FairCoins (vesting, etc.)
+	Balance   Money     `json:"balance" gorm:"type:bigint;default:0"`
+	LockedFC  Money     `json:"locked_fc" gorm:"type:bigint;default:0"` // Locked FairCoins (vesting, etc.)
+	PottedFC  Money     `json:"potted_fc" gorm:"type:bigint;default:0"` // Sum of the savings pot balances
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	// Spending controls set by admins; nil limits fall back to the defaults
+	DailyLimit   *Money     `json:"daily_limit,omitempty" gorm:"type:bigint"`
+	MonthlyLimit *Money     `json:"monthly_limit,omitempty" gorm:"type:bigint"`
+	FrozenAt     *time.Time `json:"frozen_at,omitempty"`
+	FrozenReason string     `json:"frozen_reason,omitempty"`
+
+	// Savings pots, loaded by WalletService.GetBalance
+	Pots []SavingsPot `json:"pots,omitempty" gorm:"-"`
 }
 
 // Transaction represents a FairCoin transaction
 type Transaction struct {
-	ID          uuid.UUID       `json:"id" gorm:"type:varchar(36);primary_key"`
-	UserID      uuid.UUID       `json:"user_id" gorm:"type:varchar(36);not null"`
-	ToUserID    *uuid.UUID      `json:"to_user_id" gorm:"type:varchar(36)"`
-	Type        TransactionType `json:"type" gorm:"not null"`
-	Amount      float64         `json:"amount" gorm:"not null"`
-	Fee         float64         `json:"fee" gorm:"default:0"`
-	Description string          `json:"description"`
-	Status      string          `json:"status" gorm:"default:pending"`
-	Metadata    string          `json:"metadata" gorm:"type:text"` // JSON metadata
-	CreatedAt   time.Time       `json:"created_at"`
+	ID          uuid.UUID         `json:"id" gorm:"type:varchar(36);primary_key"`
+	UserID      uuid.UUID         `json:"user_id" gorm:"type:varchar(36);not null"`
+	ToUserID    *uuid.UUID        `json:"to_user_id" gorm:"type:varchar(36)"`
+	Type        TransactionType   `json:"type" gorm:"not null"`
+	Amount      Money             `json:"amount" gorm:"type:bigint;not null"`
+	Fee         Money             `json:"fee" gorm:"type:bigint;default:0"`
+	Description string            `json:"description"`
+	Status      TransactionStatus `json:"status" gorm:"default:pending"`
+	Metadata    string            `json:"metadata" gorm:"type:text"` // JSON metadata
+	CreatedAt   time.Time         `json:"created_at"`
+
+	// When the status last changed, and why a failed or cancelled
+	// transaction did not go through (see transaction_state.go)
+	StatusChangedAt time.Time `json:"status_changed_at"`
+	FailureReason   string    `json:"failure_reason,omitempty"`
+
+	// Refunds and reversals point at the transaction they undo
+	RelatedTransactionID *uuid.UUID `json:"related_transaction_id,omitempty" gorm:"type:varchar(36);index"`
+
+	// Version of the fee schedule the fee was computed with (0 for the built-in default)
+	FeeScheduleVersion int `json:"fee_schedule_version" gorm:"default:0"`
+
+	// Position in the tamper-evident transaction chain (see chain.go)
+	ChainSeq *int64 `json:"chain_seq,omitempty" gorm:"unique_index"`
+	PrevHash string `json:"prev_hash,omitempty" gorm:"type:varchar(64)"`
+	Hash     string `json:"hash,omitempty" gorm:"type:varchar(64)"`
 
 	// Relations
-	User   *User `json:"user,omitempty" gorm:"foreignkey:UserID"`
-	ToUser *User `json:"to_user,omitempty" gorm:"foreignkey:ToUserID"`
+	User          *User                     `json:"user,omitempty" gorm:"foreignkey:UserID"`
+	ToUser        *User                     `json:"to_user,omitempty" gorm:"foreignkey:ToUserID"`
+	StatusHistory []TransactionStatusChange `json:"status_history,omitempty" gorm:"foreignkey:TransactionID"`
 }
 
 // TransactionType defines the types of transactions
@@ -72,6 +100,11 @@ const (
 	TransactionTypeMonthlyIssuance   TransactionType = "monthly_issuance"
 	TransactionTypeFee               TransactionType = "fee"
 	TransactionTypeBurn              TransactionType = "burn"
+	TransactionTypeTreasurySpend     TransactionType = "treasury_spend"
+	TransactionTypeEscrow            TransactionType = "escrow"
+	TransactionTypeRefund            TransactionType = "refund"
+	TransactionTypeReversal          TransactionType = "reversal"
+	TransactionTypeVoucher           TransactionType = "voucher"
 )
 
 // Attestation represents peer attestations for PFI calculation
@@ -82,8 +115,48 @@ type Attestation struct {
 	Type        string    `json:"type" gorm:"not null"`                         // e.g., "community_service", "dispute_resolution", "peer_rating"
 	Value       int       `json:"value" gorm:"not null"`                        // 1-10 scale
 	Description string    `json:"description"`
-	Verified    bool      `json:"verified" gorm:"default:false"`
+	Verified    bool      `json:"verified" gorm:"default:false"` // Counts toward PFI; set with Status verified
 	CreatedAt   time.Time `json:"created_at"`
+
+	// Review by the council or trusted peers (see attestations.go in services)
+	Status         AttestationStatus `json:"status" gorm:"default:pending;index"`
+	ReviewsFor     int               `json:"reviews_for" gorm:"default:0"`
+	ReviewsAgainst int               `json:"reviews_against" gorm:"default:0"`
+	DecidedAt      *time.Time        `json:"decided_at,omitempty"`
+
+	// Revocation by the attester or an admin
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	RevokedBy        *uuid.UUID `json:"revoked_by,omitempty" gorm:"type:varchar(36)"`
+	RevocationReason string     `json:"revocation_reason,omitempty"`
+
+	// Standing by age, filled in by the fairness service rather than stored
+	Weight    *float64   `json:"weight,omitempty" gorm:"-"`     // Share of its value that counts toward PFI
+	ExpiresAt *time.Time `json:"expires_at,omitempty" gorm:"-"` // Only for types that expire
+
+	// Relations
+	Reviews []AttestationReview `json:"reviews,omitempty" gorm:"foreignkey:AttestationID"`
+}
+
+// AttestationStatus defines the review status of an attestation
+type AttestationStatus string
+
+const (
+	AttestationStatusPending  AttestationStatus = "pending"
+	AttestationStatusVerified AttestationStatus = "verified"
+	AttestationStatusRejected AttestationStatus = "rejected"
+	AttestationStatusRevoked  AttestationStatus = "revoked" // Withdrawn by the attester or an admin
+)
+
+// AttestationReview is one reviewer's decision on a pending attestation
+type AttestationReview struct {
+	ID            uuid.UUID `json:"id" gorm:"type:varchar(36);primary_key"`
+	AttestationID uuid.UUID `json:"attestation_id" gorm:"type:varchar(36);not null;unique_index:idx_attestation_review_reviewer"`
+	ReviewerID    uuid.UUID `json:"reviewer_id" gorm:"type:varchar(36);not null;unique_index:idx_attestation_review_reviewer"`
+	Approve       bool      `json:"approve"`
+	Council       bool      `json:"council"`      // Reviewed as a council member
+	ReviewerPFI   int       `json:"reviewer_pfi"` // At the time of the review
+	Comment       string    `json:"comment"`
+	CreatedAt     time.Time `json:"created_at"`
 }
 
 // Rating represents merchant ratings for TFI calculation
@@ -115,6 +188,17 @@ type Proposal struct {
 	EndTime      time.Time      `json:"end_time"`
 	CreatedAt    time.Time      `json:"created_at"`
 
+	// Treasury spend proposals only
+	TreasuryAmount      Money      `json:"treasury_amount,omitempty" gorm:"type:bigint;default:0"`
+	TreasuryRecipientID *uuid.UUID `json:"treasury_recipient_id,omitempty" gorm:"type:varchar(36)"`
+	ExecutedAt          *time.Time `json:"executed_at,omitempty"`
+
+	// Fee schedule proposals only: the proposed fee policy as JSON
+	FeePolicy string `json:"fee_policy,omitempty" gorm:"type:text"`
+
+	// Monetary policy proposals may set a new monthly demurrage rate
+	DemurrageRate *float64 `json:"demurrage_rate,omitempty"`
+
 	// Relations
 	Proposer *User  `json:"proposer,omitempty" gorm:"foreignkey:ProposerID"`
 	Votes    []Vote `json:"votes,omitempty" gorm:"foreignkey:ProposalID"`
@@ -128,6 +212,8 @@ const (
 	ProposalTypeGovernance     ProposalType = "governance"
 	ProposalTypeTechnical      ProposalType = "technical"
 	ProposalTypeCommunity      ProposalType = "community"
+	ProposalTypeTreasurySpend  ProposalType = "treasury_spend"
+	ProposalTypeFeeSchedule    ProposalType = "fee_schedule"
 )
 
 // ProposalStatus defines the status of proposals
@@ -168,11 +254,11 @@ type CommunityBasketIndex struct {
 type MonetaryPolicy struct {
 	ID                uuid.UUID `json:"id" gorm:"type:varchar(36);primary_key"`
 	Month             string    `json:"month" gorm:"not null"` // Format: "2023-10"
-	BaseIssuance      float64   `json:"base_issuance" gorm:"not null"`
+	BaseIssuance      Money     `json:"base_issuance" gorm:"type:bigint;not null"`
 	ActivityFactor    float64   `json:"activity_factor" gorm:"not null"`
 	FairnessFactor    float64   `json:"fairness_factor" gorm:"not null"`
-	TotalIssuance     float64   `json:"total_issuance" gorm:"not null"`
-	CirculatingSupply float64   `json:"circulating_supply" gorm:"not null"`
+	TotalIssuance     Money     `json:"total_issuance" gorm:"type:bigint;not null"`
+	CirculatingSupply Money     `json:"circulating_supply" gorm:"type:bigint;not null"`
 	AveragePFI        float64   `json:"average_pfi" gorm:"not null"`
 	TotalTransactions int       `json:"total_transactions" gorm:"not null"`
 	CreatedAt         time.Time `json:"created_at"`
@@ -231,6 +317,485 @@ type FairnessAlert struct {
 	ResolvedAt  *time.Time `json:"resolved_at"`
 }
 
+// AccountType defines the kinds of ledger accounts
+type AccountType string
+
+const (
+	AccountTypeUser      AccountType = "user"       // A member's wallet
+	AccountTypeTreasury  AccountType = "treasury"   // Community treasury
+	AccountTypeFeeIncome AccountType = "fee_income" // Legacy fee account, swept into the treasury
+	AccountTypeIssuance  AccountType = "issuance"   // Source of all minted FairCoins (runs negative)
+	AccountTypeBurn      AccountType = "burn"       // Sink for destroyed FairCoins
+	AccountTypeEscrow    AccountType = "escrow"     // Funds held for open escrows
+	AccountTypeVoucher   AccountType = "voucher"    // Funds reserved for outstanding vouchers
+)
+
+// LedgerAccount is an account in the double-entry ledger. Balance is a cache
+// of the sum of the account's postings; for user accounts it is mirrored into
+// Wallet.Balance.
+type LedgerAccount struct {
+	ID        uuid.UUID   `json:"id" gorm:"type:varchar(36);primary_key"`
+	Code      string      `json:"code" gorm:"unique;not null"` // "treasury", "issuance", "user:<id>", ...
+	Type      AccountType `json:"type" gorm:"not null"`
+	UserID    *uuid.UUID  `json:"user_id" gorm:"type:varchar(36)"` // Set for user accounts only
+	Balance   Money       `json:"balance" gorm:"type:bigint;default:0"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// JournalEntry groups postings that move value between ledger accounts.
+// The postings of an entry always sum to zero.
+type JournalEntry struct {
+	ID            uuid.UUID  `json:"id" gorm:"type:varchar(36);primary_key"`
+	TransactionID *uuid.UUID `json:"transaction_id" gorm:"type:varchar(36)"` // Related user-facing transaction
+	Description   string     `json:"description"`
+	CreatedAt     time.Time  `json:"created_at"`
+
+	// Relations
+	Postings []Posting `json:"postings,omitempty" gorm:"foreignkey:EntryID"`
+}
+
+// Posting is one leg of a journal entry. Positive amounts credit the account
+// (increase its balance), negative amounts debit it.
+type Posting struct {
+	ID        uuid.UUID `json:"id" gorm:"type:varchar(36);primary_key"`
+	EntryID   uuid.UUID `json:"entry_id" gorm:"type:varchar(36);not null"`
+	AccountID uuid.UUID `json:"account_id" gorm:"type:varchar(36);not null"`
+	Amount    Money     `json:"amount" gorm:"type:bigint;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IdempotencyRecord stores the outcome of a request made with an
+// Idempotency-Key header so that retries replay the original response
+type IdempotencyRecord struct {
+	ID           uuid.UUID `json:"id" gorm:"type:varchar(36);primary_key"`
+	UserID       uuid.UUID `json:"user_id" gorm:"type:varchar(36);not null;unique_index:idx_idempotency_user_key"`
+	Key          string    `json:"key" gorm:"column:idempotency_key;not null;unique_index:idx_idempotency_user_key"`
+	Method       string    `json:"method" gorm:"not null"`
+	Path         string    `json:"path" gorm:"not null"`
+	Fingerprint  string    `json:"fingerprint" gorm:"not null"` // SHA-256 of method, path and body
+	StatusCode   int       `json:"status_code" gorm:"default:0"` // 0 while the request is in flight
+	ResponseBody string    `json:"-" gorm:"type:text"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+
+	// Claims are leased (see idempotency.go). Attempt counts takeovers of an
+	// expired lease; the resource is what the request created, recorded in
+	// the database transaction that created it.
+	Attempt      int        `json:"attempt" gorm:"default:1"`
+	ResourceType string     `json:"resource_type,omitempty"`
+	ResourceID   *uuid.UUID `json:"resource_id,omitempty" gorm:"type:varchar(36)"`
+}
+
+// LockKind defines how a lock schedule releases its funds
+type LockKind string
+
+const (
+	LockKindVesting  LockKind = "vesting"  // Nothing before the cliff, then linear until EndTime
+	LockKindTimeLock LockKind = "timelock" // Everything at EndTime
+)
+
+// LockStatus defines the status of a lock schedule
+type LockStatus string
+
+const (
+	LockStatusActive    LockStatus = "active"
+	LockStatusCompleted LockStatus = "completed"
+)
+
+// LockSchedule locks part of a wallet's balance until it vests. The locked
+// funds stay in Wallet.Balance; Wallet.LockedFC holds the sum of what active
+// schedules have not released yet.
+type LockSchedule struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:varchar(36);primary_key"`
+	UserID    uuid.UUID  `json:"user_id" gorm:"type:varchar(36);not null;index"`
+	Kind      LockKind   `json:"kind" gorm:"not null"`
+	Amount    Money      `json:"amount" gorm:"type:bigint;not null"`
+	Released  Money      `json:"released" gorm:"type:bigint;default:0"`
+	StartTime time.Time  `json:"start_time"`
+	CliffTime time.Time  `json:"cliff_time"` // Equals EndTime for time-locks
+	EndTime   time.Time  `json:"end_time"`
+	Status    LockStatus `json:"status" gorm:"default:active;index"`
+	Source    string     `json:"source"` // "admin" or the issuance rule that created it
+	Reason    string     `json:"reason"`
+	CreatedBy *uuid.UUID `json:"created_by,omitempty" gorm:"type:varchar(36)"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// EscrowStatus defines the status of an escrow
+type EscrowStatus string
+
+const (
+	EscrowStatusOpen     EscrowStatus = "open"
+	EscrowStatusReleased EscrowStatus = "released"
+	EscrowStatusRefunded EscrowStatus = "refunded"
+)
+
+// EscrowTimeoutAction defines how an escrow resolves when it times out
+type EscrowTimeoutAction string
+
+const (
+	EscrowTimeoutRelease EscrowTimeoutAction = "release"
+	EscrowTimeoutRefund  EscrowTimeoutAction = "refund"
+)
+
+// Escrow holds a buyer's payment until the merchant's delivery is confirmed.
+// The funds sit in the escrow ledger account; the linked transaction stays
+// authorized until the escrow is released or refunded.
+type Escrow struct {
+	ID            uuid.UUID           `json:"id" gorm:"type:varchar(36);primary_key"`
+	TransactionID uuid.UUID           `json:"transaction_id" gorm:"type:varchar(36);not null;unique_index"`
+	BuyerID       uuid.UUID           `json:"buyer_id" gorm:"type:varchar(36);not null;index"`
+	MerchantID    uuid.UUID           `json:"merchant_id" gorm:"type:varchar(36);not null;index"`
+	Amount        Money               `json:"amount" gorm:"type:bigint;not null"`
+	Fee           Money               `json:"fee" gorm:"type:bigint;default:0"`
+	Status        EscrowStatus        `json:"status" gorm:"default:open;index"`
+	TimeoutAction EscrowTimeoutAction `json:"timeout_action" gorm:"default:release"`
+	ExpiresAt     time.Time           `json:"expires_at"`
+	ResolvedAt    *time.Time          `json:"resolved_at,omitempty"`
+	ResolvedBy    *uuid.UUID          `json:"resolved_by,omitempty" gorm:"type:varchar(36)"` // Nil when resolved by timeout
+	Note          string              `json:"note"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+
+	// Relations
+	Transaction *Transaction `json:"transaction,omitempty" gorm:"foreignkey:TransactionID"`
+}
+
+// VoucherStatus defines the status of a voucher
+type VoucherStatus string
+
+const (
+	VoucherStatusOutstanding VoucherStatus = "outstanding"
+	VoucherStatusRedeemed    VoucherStatus = "redeemed"
+	VoucherStatusCancelled   VoucherStatus = "cancelled"
+	VoucherStatusExpired     VoucherStatus = "expired"
+)
+
+// Voucher is a signed bearer cheque. Issuing it reserves the amount plus fee
+// in the voucher account; whoever presents the code first is paid, and the
+// issuer gets everything back if it is cancelled or expires.
+type Voucher struct {
+	ID            uuid.UUID     `json:"id" gorm:"type:varchar(36);primary_key"`
+	TransactionID uuid.UUID     `json:"transaction_id" gorm:"type:varchar(36);not null;unique_index"`
+	IssuerID      uuid.UUID     `json:"issuer_id" gorm:"type:varchar(36);not null;index"`
+	Amount        Money         `json:"amount" gorm:"type:bigint;not null"`
+	Fee           Money         `json:"fee" gorm:"type:bigint;default:0"`
+	Nonce         string        `json:"-" gorm:"not null;unique_index"`
+	Memo          string        `json:"memo"`
+	Status        VoucherStatus `json:"status" gorm:"default:outstanding;index"`
+	ExpiresAt     time.Time     `json:"expires_at"`
+	RedeemedBy    *uuid.UUID    `json:"redeemed_by,omitempty" gorm:"type:varchar(36)"`
+	RedeemedAt    *time.Time    `json:"redeemed_at,omitempty"`
+	RefundedAt    *time.Time    `json:"refunded_at,omitempty"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+
+	// Signed code, only filled in for the issuer
+	Code string `json:"code,omitempty" gorm:"-"`
+
+	// Relations
+	Transaction *Transaction `json:"transaction,omitempty" gorm:"foreignkey:TransactionID"`
+}
+
+// StandingOrderFrequency defines how often a standing order pays out
+type StandingOrderFrequency string
+
+const (
+	StandingOrderOnce    StandingOrderFrequency = "once"
+	StandingOrderDaily   StandingOrderFrequency = "daily"
+	StandingOrderWeekly  StandingOrderFrequency = "weekly"
+	StandingOrderMonthly StandingOrderFrequency = "monthly"
+	StandingOrderCron    StandingOrderFrequency = "cron" // Uses CronExpr, evaluated in UTC
+)
+
+// StandingOrderStatus defines the status of a standing order
+type StandingOrderStatus string
+
+const (
+	StandingOrderStatusActive    StandingOrderStatus = "active"
+	StandingOrderStatusPaused    StandingOrderStatus = "paused"
+	StandingOrderStatusCancelled StandingOrderStatus = "cancelled"
+	StandingOrderStatusCompleted StandingOrderStatus = "completed" // No occurrences left
+	StandingOrderStatusFailed    StandingOrderStatus = "failed"    // A one-off order that could not be paid
+)
+
+// StandingOrder is a future-dated or recurring transfer executed by the
+// scheduler. NextRunAt is the next scheduled occurrence; while a failed
+// occurrence is being retried RetryAt holds the time of the next attempt.
+type StandingOrder struct {
+	ID          uuid.UUID              `json:"id" gorm:"type:varchar(36);primary_key"`
+	UserID      uuid.UUID              `json:"user_id" gorm:"type:varchar(36);not null;index"`
+	ToUserID    uuid.UUID              `json:"to_user_id" gorm:"type:varchar(36);not null;index"`
+	Amount      Money                  `json:"amount" gorm:"type:bigint;not null"`
+	Description string                 `json:"description"`
+	Frequency   StandingOrderFrequency `json:"frequency" gorm:"not null"`
+	CronExpr    string                 `json:"cron_expr,omitempty"`
+	StartAt     time.Time              `json:"start_at"`
+	EndAt       *time.Time             `json:"end_at,omitempty"`
+	NextRunAt   time.Time              `json:"next_run_at" gorm:"index"`
+	RetryAt     *time.Time             `json:"retry_at,omitempty"`
+	Attempts    int                    `json:"attempts" gorm:"default:0"` // Attempts at the current occurrence
+	MaxRetries  int                    `json:"max_retries" gorm:"default:3"`
+	RunCount    int                    `json:"run_count" gorm:"default:0"`
+	LastRunAt   *time.Time             `json:"last_run_at,omitempty"`
+	LastError   string                 `json:"last_error,omitempty"`
+	Status      StandingOrderStatus    `json:"status" gorm:"default:active;index"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+
+	// Relations
+	ToUser *User              `json:"to_user,omitempty" gorm:"foreignkey:ToUserID"`
+	Runs   []StandingOrderRun `json:"runs,omitempty" gorm:"foreignkey:OrderID"`
+}
+
+// StandingOrderRunStatus defines the outcome of one standing order attempt
+type StandingOrderRunStatus string
+
+const (
+	StandingOrderRunSucceeded StandingOrderRunStatus = "succeeded"
+	StandingOrderRunRetrying  StandingOrderRunStatus = "retrying" // Failed, will be retried
+	StandingOrderRunFailed    StandingOrderRunStatus = "failed"   // Failed, occurrence skipped
+)
+
+// StandingOrderRun records one attempt at paying a standing order occurrence
+type StandingOrderRun struct {
+	ID            uuid.UUID              `json:"id" gorm:"type:varchar(36);primary_key"`
+	OrderID       uuid.UUID              `json:"order_id" gorm:"type:varchar(36);not null;index"`
+	ScheduledFor  time.Time              `json:"scheduled_for"`
+	Attempt       int                    `json:"attempt"`
+	Status        StandingOrderRunStatus `json:"status"`
+	TransactionID *uuid.UUID             `json:"transaction_id,omitempty" gorm:"type:varchar(36)"`
+	Error         string                 `json:"error,omitempty"`
+	CreatedAt     time.Time              `json:"created_at"`
+}
+
+// InvoiceStatus defines the status of a payment request
+type InvoiceStatus string
+
+const (
+	InvoiceStatusOpen      InvoiceStatus = "open"
+	InvoiceStatusPaid      InvoiceStatus = "paid"
+	InvoiceStatusExpired   InvoiceStatus = "expired"
+	InvoiceStatusCancelled InvoiceStatus = "cancelled"
+)
+
+// Invoice is a payment request from an issuer to a payer. Paying it creates
+// a transfer linked through TransactionID.
+type Invoice struct {
+	ID            uuid.UUID     `json:"id" gorm:"type:varchar(36);primary_key"`
+	IssuerID      uuid.UUID     `json:"issuer_id" gorm:"type:varchar(36);not null;index"`
+	PayerID       uuid.UUID     `json:"payer_id" gorm:"type:varchar(36);not null;index"`
+	Amount        Money         `json:"amount" gorm:"type:bigint;not null"`
+	Description   string        `json:"description"`
+	Status        InvoiceStatus `json:"status" gorm:"default:open;index"`
+	ExpiresAt     time.Time     `json:"expires_at"`
+	TransactionID *uuid.UUID    `json:"transaction_id,omitempty" gorm:"type:varchar(36)"`
+	PaidAt        *time.Time    `json:"paid_at,omitempty"`
+	CancelledAt   *time.Time    `json:"cancelled_at,omitempty"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+
+	// Relations
+	Issuer      *User             `json:"issuer,omitempty" gorm:"foreignkey:IssuerID"`
+	Payer       *User             `json:"payer,omitempty" gorm:"foreignkey:PayerID"`
+	LineItems   []InvoiceLineItem `json:"line_items,omitempty" gorm:"foreignkey:InvoiceID"`
+	Transaction *Transaction      `json:"transaction,omitempty" gorm:"foreignkey:TransactionID"`
+}
+
+// InvoiceLineItem is one line of an invoice; Amount is Quantity x UnitPrice
+type InvoiceLineItem struct {
+	ID          uuid.UUID `json:"id" gorm:"type:varchar(36);primary_key"`
+	InvoiceID   uuid.UUID `json:"invoice_id" gorm:"type:varchar(36);not null;index"`
+	Position    int       `json:"position"`
+	Description string    `json:"description"`
+	Quantity    int64     `json:"quantity"`
+	UnitPrice   Money     `json:"unit_price" gorm:"type:bigint;not null"`
+	Amount      Money     `json:"amount" gorm:"type:bigint;not null"`
+}
+
+// FeeSchedule is one version of the transfer fee policy. The schedule with
+// the highest version is in force; older versions are kept so every
+// transaction's fee can be traced back to the rules it was charged under.
+type FeeSchedule struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:varchar(36);primary_key"`
+	Version    int        `json:"version" gorm:"unique;not null"`
+	Policy     string     `json:"policy" gorm:"type:text;not null"` // JSON fee policy
+	Source     string     `json:"source" gorm:"not null"`           // "config" or "governance"
+	ProposalID *uuid.UUID `json:"proposal_id,omitempty" gorm:"type:varchar(36)"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// DemurrageRate is one setting of the monthly demurrage rate. The most
+// recent row is in force.
+type DemurrageRate struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:varchar(36);primary_key"`
+	Rate       float64    `json:"rate" gorm:"not null"`   // Share of the chargeable balance per month
+	Source     string     `json:"source" gorm:"not null"` // "config" or "governance"
+	ProposalID *uuid.UUID `json:"proposal_id,omitempty" gorm:"type:varchar(36)"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// DemurrageRun records the demurrage charged for one month
+type DemurrageRun struct {
+	ID             uuid.UUID `json:"id" gorm:"type:varchar(36);primary_key"`
+	Period         string    `json:"period" gorm:"unique;not null"` // Format: "2023-10"
+	Rate           float64   `json:"rate"`
+	Threshold      Money     `json:"threshold" gorm:"type:bigint;not null"`
+	Destination    string    `json:"destination" gorm:"not null"` // "burn" or "treasury"
+	WalletsCharged int       `json:"wallets_charged"`
+	TotalCharged   Money     `json:"total_charged" gorm:"type:bigint;default:0"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// WalletAuditAction names a change to a wallet's spending controls
+type WalletAuditAction string
+
+const (
+	WalletAuditFreeze        WalletAuditAction = "freeze"
+	WalletAuditUnfreeze      WalletAuditAction = "unfreeze"
+	WalletAuditSetLimits     WalletAuditAction = "set_limits"
+	WalletAuditVerify        WalletAuditAction = "verify"
+	WalletAuditUnverify      WalletAuditAction = "unverify"
+	WalletAuditSuspendCredit WalletAuditAction = "suspend_credit"
+	WalletAuditResumeCredit  WalletAuditAction = "resume_credit"
+)
+
+// WalletAuditEntry records who changed a wallet's spending controls, when and why
+type WalletAuditEntry struct {
+	ID        uuid.UUID         `json:"id" gorm:"type:varchar(36);primary_key"`
+	UserID    uuid.UUID         `json:"user_id" gorm:"type:varchar(36);not null;index"` // Wallet owner
+	ActorID   uuid.UUID         `json:"actor_id" gorm:"type:varchar(36);not null"`
+	Action    WalletAuditAction `json:"action" gorm:"not null"`
+	Reason    string            `json:"reason"`
+	Details   string            `json:"details" gorm:"type:text"` // JSON with the values before and after
+	CreatedAt time.Time         `json:"created_at"`
+
+	// Relations
+	Actor *User `json:"actor,omitempty" gorm:"foreignkey:ActorID"`
+}
+
+// SavingsPot is a named part of a wallet's balance. Pot money stays in
+// Wallet.Balance; Wallet.PottedFC holds the sum of all pot balances, so only
+// Balance - LockedFC - PottedFC can be spent.
+type SavingsPot struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:varchar(36);primary_key"`
+	UserID      uuid.UUID  `json:"user_id" gorm:"type:varchar(36);not null;unique_index:idx_savings_pot_user_name"`
+	Name        string     `json:"name" gorm:"not null;unique_index:idx_savings_pot_user_name"`
+	Balance     Money      `json:"balance" gorm:"type:bigint;default:0"`
+	Goal        *Money     `json:"goal,omitempty" gorm:"type:bigint"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"` // Nothing can be taken out before this time
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// CreditLine holds what a member's credit rests on besides the PFI formula:
+// a limit approved by the council and an admin suspension. Members without
+// a row get the formula limit.
+type CreditLine struct {
+	ID              uuid.UUID  `json:"id" gorm:"type:varchar(36);primary_key"`
+	UserID          uuid.UUID  `json:"user_id" gorm:"type:varchar(36);not null;unique_index"`
+	CouncilLimit    Money      `json:"council_limit" gorm:"type:bigint;default:0"`
+	RequestID       *uuid.UUID `json:"request_id,omitempty" gorm:"type:varchar(36)"` // Request the council approved
+	ApprovedAt      *time.Time `json:"approved_at,omitempty"`
+	SuspendedAt     *time.Time `json:"suspended_at,omitempty"`
+	SuspendedReason string     `json:"suspended_reason,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// CreditRequestStatus defines the status of a credit line request
+type CreditRequestStatus string
+
+const (
+	CreditRequestPending  CreditRequestStatus = "pending"
+	CreditRequestApproved CreditRequestStatus = "approved"
+	CreditRequestRejected CreditRequestStatus = "rejected"
+)
+
+// CreditRequest asks the council for a credit limit above the formula's
+type CreditRequest struct {
+	ID           uuid.UUID           `json:"id" gorm:"type:varchar(36);primary_key"`
+	UserID       uuid.UUID           `json:"user_id" gorm:"type:varchar(36);not null;index"`
+	Limit        Money               `json:"limit" gorm:"type:bigint;not null"`
+	Reason       string              `json:"reason" gorm:"type:text"`
+	Status       CreditRequestStatus `json:"status" gorm:"default:pending;index"`
+	VotesFor     int                 `json:"votes_for" gorm:"default:0"`
+	VotesAgainst int                 `json:"votes_against" gorm:"default:0"`
+	DecidedAt    *time.Time          `json:"decided_at,omitempty"`
+	CreatedAt    time.Time           `json:"created_at"`
+	UpdatedAt    time.Time           `json:"updated_at"`
+
+	// Relations
+	User  *User        `json:"user,omitempty" gorm:"foreignkey:UserID"`
+	Votes []CreditVote `json:"votes,omitempty" gorm:"foreignkey:RequestID"`
+}
+
+// CreditVote is one council member's decision on a credit request
+type CreditVote struct {
+	ID        uuid.UUID `json:"id" gorm:"type:varchar(36);primary_key"`
+	RequestID uuid.UUID `json:"request_id" gorm:"type:varchar(36);not null;unique_index:idx_credit_vote_member"`
+	MemberID  uuid.UUID `json:"member_id" gorm:"type:varchar(36);not null;unique_index:idx_credit_vote_member"`
+	Approve   bool      `json:"approve"`
+	Comment   string    `json:"comment"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreditMovement records a ledger posting that changed how much a member
+// owes: Drawn when the balance went further below zero, Repaid when it came
+// back towards zero. Outstanding is what is owed afterwards. Credit is
+// interest-free, so these are the only changes.
+type CreditMovement struct {
+	ID            uuid.UUID  `json:"id" gorm:"type:varchar(36);primary_key"`
+	UserID        uuid.UUID  `json:"user_id" gorm:"type:varchar(36);not null;index"`
+	EntryID       uuid.UUID  `json:"entry_id" gorm:"type:varchar(36);not null"`
+	TransactionID *uuid.UUID `json:"transaction_id,omitempty" gorm:"type:varchar(36)"`
+	Drawn         Money      `json:"drawn" gorm:"type:bigint;default:0"`
+	Repaid        Money      `json:"repaid" gorm:"type:bigint;default:0"`
+	Outstanding   Money      `json:"outstanding" gorm:"type:bigint;default:0"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// ChainCheckpoint is a signed statement of the transaction and status
+// chains' heads at a point in time. Exported checkpoints let auditors detect
+// a chain that was rewritten wholesale after they were taken.
+type ChainCheckpoint struct {
+	ID               uuid.UUID `json:"id" gorm:"type:varchar(36);primary_key"`
+	ChainSeq         int64     `json:"chain_seq" gorm:"not null;index"`
+	Hash             string    `json:"hash" gorm:"type:varchar(64);not null"`
+	TransactionCount int64     `json:"transaction_count"`
+	StatusChainSeq   int64     `json:"status_chain_seq"` // 0 for checkpoints from before the status chain
+	StatusHash       string    `json:"status_hash,omitempty" gorm:"type:varchar(64)"`
+	PublicKey        string    `json:"public_key" gorm:"type:varchar(64)"` // Hex Ed25519 key the signature verifies with
+	Signature        string    `json:"signature" gorm:"type:varchar(128)"` // Hex Ed25519 signature of CheckpointMessage
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// BalanceSnapshot is a published Merkle sum tree over every wallet balance
+// at one moment. Members check their balance is in it with an inclusion
+// proof (see services.ReservesService).
+type BalanceSnapshot struct {
+	ID            uuid.UUID `json:"id" gorm:"type:varchar(36);primary_key"`
+	Root          string    `json:"root" gorm:"type:varchar(64);not null"` // Hex hash of the tree's root
+	TotalHoldings Money     `json:"total_holdings" gorm:"type:bigint;default:0"`
+	TotalOwed     Money     `json:"total_owed" gorm:"type:bigint;default:0"` // Negative balances on credit lines
+	Total         Money     `json:"total" gorm:"type:bigint;default:0"`      // Holdings minus owed
+	Members       int       `json:"members"`                                 // Leaves: one per member, over all their wallets
+	CreatedAt     time.Time `json:"created_at" gorm:"index"`
+}
+
+// BalanceSnapshotLeaf is one member's balance in a snapshot. Salt keeps the
+// leaf hash from revealing the balance to anyone who can guess it.
+type BalanceSnapshotLeaf struct {
+	ID         uuid.UUID `json:"id" gorm:"type:varchar(36);primary_key"`
+	SnapshotID uuid.UUID `json:"snapshot_id" gorm:"type:varchar(36);not null;unique_index:idx_snapshot_leaf_user"`
+	UserID     uuid.UUID `json:"user_id" gorm:"type:varchar(36);not null;unique_index:idx_snapshot_leaf_user"`
+	Position   int       `json:"position" gorm:"not null"`
+	Balance    Money     `json:"balance" gorm:"type:bigint;not null"`
+	Salt       string    `json:"salt" gorm:"type:varchar(32);not null"`
+}
+
 // BeforeCreate sets UUID for models
 func (u *User) BeforeCreate(scope *gorm.Scope) error {
 	if u.ID == uuid.Nil {
@@ -250,7 +815,14 @@ func (t *Transaction) BeforeCreate(scope *gorm.Scope) error {
 	if t.ID == uuid.Nil {
 		t.ID = uuid.New()
 	}
-	return nil
+	if err := initStatus(scope, t); err != nil {
+		return err
+	}
+	return sealTransaction(scope, t)
+}
+
+func (t *Transaction) AfterCreate(scope *gorm.Scope) error {
+	return recordInitialStatus(scope, t)
 }
 
 func (a *Attestation) BeforeCreate(scope *gorm.Scope) error {
@@ -260,6 +832,13 @@ func (a *Attestation) BeforeCreate(scope *gorm.Scope) error {
 	return nil
 }
 
+func (r *AttestationReview) BeforeCreate(scope *gorm.Scope) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
 func (r *Rating) BeforeCreate(scope *gorm.Scope) error {
 	if r.ID == uuid.Nil {
 		r.ID = uuid.New()
@@ -316,6 +895,167 @@ func (fa *FairnessAlert) BeforeCreate(scope *gorm.Scope) error {
 	return nil
 }
 
+func (la *LedgerAccount) BeforeCreate(scope *gorm.Scope) error {
+	if la.ID == uuid.Nil {
+		la.ID = uuid.New()
+	}
+	return nil
+}
+
+func (je *JournalEntry) BeforeCreate(scope *gorm.Scope) error {
+	if je.ID == uuid.Nil {
+		je.ID = uuid.New()
+	}
+	return nil
+}
+
+func (p *Posting) BeforeCreate(scope *gorm.Scope) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+func (ir *IdempotencyRecord) BeforeCreate(scope *gorm.Scope) error {
+	if ir.ID == uuid.Nil {
+		ir.ID = uuid.New()
+	}
+	return nil
+}
+
+func (ls *LockSchedule) BeforeCreate(scope *gorm.Scope) error {
+	if ls.ID == uuid.Nil {
+		ls.ID = uuid.New()
+	}
+	return nil
+}
+
+func (e *Escrow) BeforeCreate(scope *gorm.Scope) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
+
+func (so *StandingOrder) BeforeCreate(scope *gorm.Scope) error {
+	if so.ID == uuid.Nil {
+		so.ID = uuid.New()
+	}
+	return nil
+}
+
+func (r *StandingOrderRun) BeforeCreate(scope *gorm.Scope) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+func (i *Invoice) BeforeCreate(scope *gorm.Scope) error {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return nil
+}
+
+func (li *InvoiceLineItem) BeforeCreate(scope *gorm.Scope) error {
+	if li.ID == uuid.Nil {
+		li.ID = uuid.New()
+	}
+	return nil
+}
+
+func (fs *FeeSchedule) BeforeCreate(scope *gorm.Scope) error {
+	if fs.ID == uuid.Nil {
+		fs.ID = uuid.New()
+	}
+	return nil
+}
+
+func (dr *DemurrageRate) BeforeCreate(scope *gorm.Scope) error {
+	if dr.ID == uuid.Nil {
+		dr.ID = uuid.New()
+	}
+	return nil
+}
+
+func (dr *DemurrageRun) BeforeCreate(scope *gorm.Scope) error {
+	if dr.ID == uuid.Nil {
+		dr.ID = uuid.New()
+	}
+	return nil
+}
+
+func (wa *WalletAuditEntry) BeforeCreate(scope *gorm.Scope) error {
+	if wa.ID == uuid.Nil {
+		wa.ID = uuid.New()
+	}
+	return nil
+}
+
+func (sp *SavingsPot) BeforeCreate(scope *gorm.Scope) error {
+	if sp.ID == uuid.Nil {
+		sp.ID = uuid.New()
+	}
+	return nil
+}
+
+func (v *Voucher) BeforeCreate(scope *gorm.Scope) error {
+	if v.ID == uuid.Nil {
+		v.ID = uuid.New()
+	}
+	return nil
+}
+
+func (cl *CreditLine) BeforeCreate(scope *gorm.Scope) error {
+	if cl.ID == uuid.Nil {
+		cl.ID = uuid.New()
+	}
+	return nil
+}
+
+func (cr *CreditRequest) BeforeCreate(scope *gorm.Scope) error {
+	if cr.ID == uuid.Nil {
+		cr.ID = uuid.New()
+	}
+	return nil
+}
+
+func (cv *CreditVote) BeforeCreate(scope *gorm.Scope) error {
+	if cv.ID == uuid.Nil {
+		cv.ID = uuid.New()
+	}
+	return nil
+}
+
+func (cm *CreditMovement) BeforeCreate(scope *gorm.Scope) error {
+	if cm.ID == uuid.Nil {
+		cm.ID = uuid.New()
+	}
+	return nil
+}
+
+func (cc *ChainCheckpoint) BeforeCreate(scope *gorm.Scope) error {
+	if cc.ID == uuid.Nil {
+		cc.ID = uuid.New()
+	}
+	return nil
+}
+
+func (bs *BalanceSnapshot) BeforeCreate(scope *gorm.Scope) error {
+	if bs.ID == uuid.Nil {
+		bs.ID = uuid.New()
+	}
+	return nil
+}
+
+func (bl *BalanceSnapshotLeaf) BeforeCreate(scope *gorm.Scope) error {
+	if bl.ID == uuid.Nil {
+		bl.ID = uuid.New()
+	}
+	return nil
+}
+
 // SetPassword hashes and sets the user's password
 func (u *User) SetPassword(password string) error {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -332,11 +1072,47 @@ func (u *User) CheckPassword(password string) error {
 }
 
 // CalculateVotingPower calculates a user's voting power based on stake and PFI
-func (u *User) CalculateVotingPower(walletBalance, totalSupply float64) float64 {
+func (u *User) CalculateVotingPower(walletBalance, totalSupply Money) float64 {
 	// Get user's wallet balance (stake)
-	stakePercentage := walletBalance / totalSupply
+	var stakePercentage float64
+	if totalSupply > 0 {
+		stakePercentage = walletBalance.Float64() / totalSupply.Float64()
+	}
 	pfiPercentage := float64(u.PFI) / 100.0
 
 	// Voting power = 60% stake + 40% PFI
 	return 0.6*stakePercentage + 0.4*pfiPercentage
 }
+
+// Spendable returns the part of the balance that is neither locked nor in a savings pot
+func (w *Wallet) Spendable() Money {
+	return MaxMoney(w.Balance.Sub(w.LockedFC).Sub(w.PottedFC), 0)
+}
+
+// Withdrawable returns how much can be taken out of the pot at t
+func (sp *SavingsPot) Withdrawable(t time.Time) Money {
+	if sp.LockedUntil != nil && t.Before(*sp.LockedUntil) {
+		return 0
+	}
+	return sp.Balance
+}
+
+// VestedAt returns how much of the schedule has vested by t
+func (ls *LockSchedule) VestedAt(t time.Time) Money {
+	if t.Before(ls.CliffTime) {
+		return 0
+	}
+	total := int64(ls.EndTime.Sub(ls.StartTime) / time.Second)
+	if !t.Before(ls.EndTime) || total <= 0 {
+		return ls.Amount
+	}
+
+	// Linear from StartTime; the cliff only delays the first release
+	elapsed := int64(t.Sub(ls.StartTime) / time.Second)
+	return ls.Amount.MulFrac(elapsed, total)
+}
+
+// Locked returns what the schedule still holds back
+func (ls *LockSchedule) Locked() Money {
+	return ls.Amount.Sub(ls.Released)
+}
@@ -0,0 +1,357 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Money is an exact fixed-point FairCoin amount stored as an integer number
+// of minor units (1 FC = MoneyScale minor units). All balances, amounts and
+// fees use Money so that sums and splits never drift the way float64 does.
+//
+// Rounding mode: every operation that has to drop precision (converting from
+// float64, multiplying by a fraction) rounds half to even ("banker's
+// rounding"). Parsing never rounds; amounts with more than MoneyDecimals
+// digits are rejected.
+type Money int64
+
+const (
+	// MoneyDecimals is the number of decimal places a Money value carries
+	MoneyDecimals = 8
+	// MoneyScale is the number of minor units in one FairCoin
+	MoneyScale = 100000000
+)
+
+// FC returns a Money value for a whole number of FairCoins
+func FC(whole int64) Money {
+	return Money(whole * MoneyScale)
+}
+
+// MoneyFromFloat converts a float64 FairCoin amount to Money, rounding half
+// to even at MoneyDecimals. It is intended for legacy data and for values
+// produced by policy formulas, never for arithmetic on existing balances.
+func MoneyFromFloat(f float64) Money {
+	return Money(math.RoundToEven(f * MoneyScale))
+}
+
+// moneyPattern matches the plain decimals ParseMoney accepts;
+// tooPrecisePattern matches those that only fail by having too many decimals
+var (
+	moneyPattern      = regexp.MustCompile(`^-?\d+(\.\d{1,8})?$`)
+	tooPrecisePattern = regexp.MustCompile(`^-?\d+\.\d{9,}$`)
+)
+
+// ParseMoney parses a plain decimal string such as "12.5" or "-0.00000001".
+// Fractions ("1/3"), exponents ("1e3") and more than MoneyDecimals digits
+// are rejected.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("invalid amount: empty")
+	}
+	if !moneyPattern.MatchString(s) {
+		if tooPrecisePattern.MatchString(s) {
+			return 0, fmt.Errorf("invalid amount: %q has more than %d decimals", s, MoneyDecimals)
+		}
+		return 0, fmt.Errorf("invalid amount: %q", s)
+	}
+
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, fmt.Errorf("invalid amount: %q", s)
+	}
+
+	r.Mul(r, new(big.Rat).SetInt64(MoneyScale))
+	units := roundRatHalfEven(r)
+	if !units.IsInt64() {
+		return 0, fmt.Errorf("amount out of range: %q", s)
+	}
+	return Money(units.Int64()), nil
+}
+
+// Float64 returns the amount in FairCoins as a float64. Use it only for
+// display, ratios and statistics.
+func (m Money) Float64() float64 {
+	return float64(m) / MoneyScale
+}
+
+// Units returns the raw number of minor units
+func (m Money) Units() int64 {
+	return int64(m)
+}
+
+// String formats the amount as a plain decimal without trailing zeros
+func (m Money) String() string {
+	neg := m < 0
+	u := uint64(m)
+	if neg {
+		u = uint64(-m)
+	}
+
+	whole := u / MoneyScale
+	frac := u % MoneyScale
+
+	s := strconv.FormatUint(whole, 10)
+	if frac != 0 {
+		fs := fmt.Sprintf("%0*d", MoneyDecimals, frac)
+		s += "." + strings.TrimRight(fs, "0")
+	}
+	if neg {
+		s = "-" + s
+	}
+	return s
+}
+
+// StringFixed formats the amount with a fixed number of decimals (at most
+// MoneyDecimals), rounding half to even
+func (m Money) StringFixed(decimals int) string {
+	if decimals > MoneyDecimals {
+		decimals = MoneyDecimals
+	}
+	if decimals < 0 {
+		decimals = 0
+	}
+
+	rounded := m.MulFrac(1, int64(math.Pow10(MoneyDecimals-decimals))).Units()
+	neg := rounded < 0
+	if neg {
+		rounded = -rounded
+	}
+
+	pow := int64(math.Pow10(decimals))
+	s := strconv.FormatInt(rounded/pow, 10)
+	if decimals > 0 {
+		s += fmt.Sprintf(".%0*d", decimals, rounded%pow)
+	}
+	if neg {
+		s = "-" + s
+	}
+	return s
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool { return m == 0 }
+
+// IsNegative reports whether the amount is below zero
+func (m Money) IsNegative() bool { return m < 0 }
+
+// IsPositive reports whether the amount is above zero
+func (m Money) IsPositive() bool { return m > 0 }
+
+// Neg returns the negated amount
+func (m Money) Neg() Money { return -m }
+
+// Abs returns the absolute amount
+func (m Money) Abs() Money {
+	if m < 0 {
+		return -m
+	}
+	return m
+}
+
+// Add returns m + o
+func (m Money) Add(o Money) Money { return m + o }
+
+// Sub returns m - o
+func (m Money) Sub(o Money) Money { return m - o }
+
+// MulInt returns m * n
+func (m Money) MulInt(n int64) Money { return m * Money(n) }
+
+// MulFrac returns m * num / den rounded half to even. It is the exact way to
+// apply percentages and rates, e.g. MulFrac(1, 1000) for 0.1%. It panics if
+// the result does not fit in a Money.
+func (m Money) MulFrac(num, den int64) Money {
+	if den == 0 {
+		panic("models: Money.MulFrac with zero denominator")
+	}
+	r := new(big.Rat).SetFrac(
+		new(big.Int).Mul(big.NewInt(int64(m)), big.NewInt(num)),
+		big.NewInt(den),
+	)
+	units := roundRatHalfEven(r)
+	if !units.IsInt64() {
+		panic(fmt.Sprintf("models: Money.MulFrac overflow: %s * %d / %d", m, num, den))
+	}
+	return Money(units.Int64())
+}
+
+// MulRate multiplies by a float64 rate, rounding half to even. Rates are
+// first converted to an exact fraction with MoneyDecimals precision so the
+// result does not depend on binary float representation of the amount.
+func (m Money) MulRate(rate float64) Money {
+	return m.MulFrac(int64(math.RoundToEven(rate*MoneyScale)), MoneyScale)
+}
+
+// Allocate splits m pro rata across the given weights using the largest
+// remainder method. The returned parts always sum exactly to m. Zero or
+// negative weights receive nothing; if every weight is zero all parts are 0.
+func (m Money) Allocate(weights []int64) []Money {
+	parts := make([]Money, len(weights))
+
+	var total int64
+	for _, w := range weights {
+		if w > 0 {
+			total += w
+		}
+	}
+	if total == 0 || len(weights) == 0 {
+		return parts
+	}
+
+	type remainder struct {
+		index int
+		rem   *big.Int
+	}
+	remainders := make([]remainder, 0, len(weights))
+
+	bigM := big.NewInt(int64(m))
+	bigTotal := big.NewInt(total)
+	var allocated Money
+	for i, w := range weights {
+		if w <= 0 {
+			continue
+		}
+		q, r := new(big.Int).QuoRem(new(big.Int).Mul(bigM, big.NewInt(w)), bigTotal, new(big.Int))
+		parts[i] = Money(q.Int64())
+		allocated += parts[i]
+		remainders = append(remainders, remainder{index: i, rem: r.Abs(r)})
+	}
+
+	// Hand out the leftover minor units to the largest remainders first;
+	// ties go to the earlier index so the split is deterministic.
+	leftover := m - allocated
+	step := Money(1)
+	if leftover < 0 {
+		step = -1
+		leftover = -leftover
+	}
+	for n := Money(0); n < leftover; n++ {
+		best := -1
+		for j, rm := range remainders {
+			if rm.rem == nil {
+				continue
+			}
+			if best == -1 || rm.rem.Cmp(remainders[best].rem) > 0 {
+				best = j
+			}
+		}
+		if best == -1 {
+			break
+		}
+		parts[remainders[best].index] += step
+		remainders[best].rem = nil
+	}
+
+	return parts
+}
+
+// MinMoney returns the smaller of two amounts
+func MinMoney(a, b Money) Money {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// MaxMoney returns the larger of two amounts
+func MaxMoney(a, b Money) Money {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// MarshalJSON encodes the amount as an exact JSON number, e.g. 12.5
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a quoted decimal string
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := strings.TrimSpace(string(data))
+	if s == "null" {
+		*m = 0
+		return nil
+	}
+	s = strings.Trim(s, `"`)
+
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value stores the amount as an integer number of minor units
+func (m Money) Value() (driver.Value, error) {
+	return int64(m), nil
+}
+
+// Scan reads an amount stored as minor units. Aggregates such as SUM may
+// come back as float64, []byte or string depending on the driver.
+func (m *Money) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*m = 0
+	case int64:
+		*m = Money(v)
+	case float64:
+		*m = Money(math.RoundToEven(v))
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Money", value)
+	}
+	return nil
+}
+
+func (m *Money) scanString(s string) error {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok {
+		return fmt.Errorf("cannot scan %q into Money", s)
+	}
+	units := roundRatHalfEven(r)
+	if !units.IsInt64() {
+		return fmt.Errorf("cannot scan %q into Money: out of range", s)
+	}
+	*m = Money(units.Int64())
+	return nil
+}
+
+// roundRatHalfEven rounds a rational to the nearest integer, ties to even
+func roundRatHalfEven(r *big.Rat) *big.Int {
+	num := new(big.Int).Set(r.Num())
+	den := r.Denom()
+
+	q, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	// Compare 2*|rem| with den to decide rounding direction
+	twice := new(big.Int).Abs(rem)
+	twice.Lsh(twice, 1)
+
+	switch twice.Cmp(den) {
+	case 1:
+		if num.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	case 0:
+		if q.Bit(0) == 1 {
+			if num.Sign() < 0 {
+				q.Sub(q, big.NewInt(1))
+			} else {
+				q.Add(q, big.NewInt(1))
+			}
+		}
+	}
+	return q
+}
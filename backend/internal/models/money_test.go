@@ -0,0 +1,248 @@
+package models
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		{"0", 0, false},
+		{"12", FC(12), false},
+		{"12.5", Money(1250000000), false},
+		{" 3.25 ", Money(325000000), false},
+		{"-0.00000001", Money(-1), false},
+		{"0.12345678", Money(12345678), false},
+		{"92233720368.54775807", Money(math.MaxInt64), false},
+		{"", 0, true},
+		{"abc", 0, true},
+		{"1/3", 0, true},
+		{"1e3", 0, true},
+		{"1E-2", 0, true},
+		{"+1", 0, true},
+		{"1.", 0, true},
+		{".5", 0, true},
+		{"1,5", 0, true},
+		{"0.123456789", 0, true},
+		{"92233720368.54775808", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseMoney(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseMoney(%q) = %s, want an error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseMoney(%q): %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseMoney(%q) = %d, want %d", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		in       Money
+		want     string
+		decimals int
+		fixed    string
+	}{
+		{0, "0", 2, "0.00"},
+		{FC(12), "12", 2, "12.00"},
+		{Money(1250000000), "12.5", 0, "12"},
+		{Money(1350000000), "13.5", 0, "14"},
+		{Money(-1), "-0.00000001", 8, "-0.00000001"},
+		{Money(-150000000), "-1.5", 0, "-2"},
+		{Money(12345), "0.00012345", 4, "0.0001"},
+		{Money(125000), "0.00125", 4, "0.0012"},
+		{Money(135000), "0.00135", 4, "0.0014"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("Money(%d).String() = %q, want %q", tt.in, got, tt.want)
+		}
+		if got := tt.in.StringFixed(tt.decimals); got != tt.fixed {
+			t.Errorf("Money(%d).StringFixed(%d) = %q, want %q", tt.in, tt.decimals, got, tt.fixed)
+		}
+	}
+}
+
+func TestMoneyRoundsHalfToEven(t *testing.T) {
+	tests := []struct {
+		name     string
+		m        Money
+		num, den int64
+		want     Money
+	}{
+		{"exact", Money(100), 1, 4, Money(25)},
+		{"half down to even", Money(5), 1, 2, Money(2)},
+		{"half up to even", Money(7), 1, 2, Money(4)},
+		{"above half", Money(7), 2, 3, Money(5)},
+		{"below half", Money(4), 1, 3, Money(1)},
+		{"negative half", Money(-5), 1, 2, Money(-2)},
+		{"negative half up", Money(-7), 1, 2, Money(-4)},
+		{"negative denominator", Money(10), 1, -4, Money(-2)},
+		{"tenth of a percent", FC(1000), 1, 1000, FC(1)},
+		{"large intermediate", Money(math.MaxInt64), 3, 3, Money(math.MaxInt64)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.m.MulFrac(tt.num, tt.den); got != tt.want {
+				t.Errorf("Money(%d).MulFrac(%d, %d) = %d, want %d", tt.m, tt.num, tt.den, got, tt.want)
+			}
+		})
+	}
+
+	if got := MoneyFromFloat(0.125); got != Money(12500000) {
+		t.Errorf("MoneyFromFloat(0.125) = %d, want 12500000", got)
+	}
+	if got := MoneyFromFloat(0.1); got != Money(10000000) {
+		t.Errorf("MoneyFromFloat(0.1) = %d, want 10000000", got)
+	}
+	if got := FC(3).MulRate(0.5); got != Money(150000000) {
+		t.Errorf("FC(3).MulRate(0.5) = %d, want 150000000", got)
+	}
+}
+
+func TestMulFracPanics(t *testing.T) {
+	tests := []struct {
+		name     string
+		m        Money
+		num, den int64
+	}{
+		{"zero denominator", FC(1), 1, 0},
+		{"overflow", Money(math.MaxInt64), 2, 1},
+		{"negative overflow", Money(math.MinInt64), -1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Money(%d).MulFrac(%d, %d) did not panic", tt.m, tt.num, tt.den)
+				}
+			}()
+			tt.m.MulFrac(tt.num, tt.den)
+		})
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name    string
+		m       Money
+		weights []int64
+		want    []Money
+	}{
+		{"even", Money(100), []int64{1, 1}, []Money{50, 50}},
+		{"remainder to largest", Money(100), []int64{1, 1, 1}, []Money{34, 33, 33}},
+		{"remainder by size", Money(10), []int64{1, 2, 4}, []Money{1, 3, 6}},
+		{"issuance split", FC(1000).Add(1), []int64{50, 25, 15, 10},
+			[]Money{Money(50000000001), Money(25000000000), Money(15000000000), Money(10000000000)}},
+		{"zero and negative weights", Money(9), []int64{0, 3, -1, 6}, []Money{0, 3, 0, 6}},
+		{"all zero weights", Money(9), []int64{0, 0}, []Money{0, 0}},
+		{"no weights", Money(9), nil, []Money{}},
+		{"negative amount", Money(-100), []int64{1, 1, 1}, []Money{-34, -33, -33}},
+		{"less than one unit each", Money(2), []int64{1, 1, 1}, []Money{1, 1, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.m.Allocate(tt.weights)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Allocate returned %d parts, want %d", len(got), len(tt.want))
+			}
+			var sum Money
+			for i := range got {
+				sum += got[i]
+				if got[i] != tt.want[i] {
+					t.Errorf("part %d = %d, want %d", i, got[i], tt.want[i])
+				}
+			}
+			if hasPositive(tt.weights) && sum != tt.m {
+				t.Errorf("parts sum to %d, want %d", sum, tt.m)
+			}
+		})
+	}
+}
+
+func hasPositive(weights []int64) bool {
+	for _, w := range weights {
+		if w > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func TestMoneyJSON(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		{`12.5`, Money(1250000000), false},
+		{`"0.00000001"`, Money(1), false},
+		{`null`, 0, false},
+		{`1e3`, 0, true},
+		{`"1/3"`, 0, true},
+		{`0.000000001`, 0, true},
+	}
+	for _, tt := range tests {
+		var got Money
+		err := json.Unmarshal([]byte(tt.in), &got)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Unmarshal(%s) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Unmarshal(%s) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+
+	encoded, err := json.Marshal(struct {
+		Amount Money `json:"amount"`
+	}{Money(1250000001)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(encoded) != `{"amount":12.50000001}` {
+		t.Errorf("Marshal = %s", encoded)
+	}
+}
+
+func TestMoneyScan(t *testing.T) {
+	tests := []struct {
+		in      interface{}
+		want    Money
+		wantErr bool
+	}{
+		{nil, 0, false},
+		{int64(42), Money(42), false},
+		{float64(42.5), Money(42), false},
+		{[]byte("17"), Money(17), false},
+		{"-3", Money(-3), false},
+		{"1e30", 0, true},
+		{"abc", 0, true},
+		{true, 0, true},
+	}
+	for _, tt := range tests {
+		var got Money
+		err := got.Scan(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Scan(%v) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Scan(%v) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+)
+
+// TransactionStatus is where a transaction is in its lifecycle
+type TransactionStatus string
+
+const (
+	TransactionStatusPending           TransactionStatus = "pending"            // Recorded, no funds moved yet
+	TransactionStatusAuthorized        TransactionStatus = "authorized"         // Funds held (escrow, voucher) until it settles
+	TransactionStatusCompleted         TransactionStatus = "completed"          // Funds delivered
+	TransactionStatusFailed            TransactionStatus = "failed"             // Declined; no funds moved
+	TransactionStatusCancelled         TransactionStatus = "cancelled"          // Held funds returned to the payer
+	TransactionStatusPartiallyRefunded TransactionStatus = "partially_refunded" // Part returned by refunds
+	TransactionStatusRefunded          TransactionStatus = "refunded"           // Fully returned by refunds
+	TransactionStatusReversed          TransactionStatus = "reversed"           // Undone by an admin
+)
+
+// Transactions follow a fixed state machine. Each status lists the statuses
+// it may move to; the empty status stands for a transaction being created.
+// Balances change only on these transitions:
+//
+//	pending -> authorized          the payer's funds are held
+//	authorized -> completed        held funds are paid out
+//	authorized -> cancelled        held funds go back to the payer
+//	"" -> completed                funds are paid out directly
+//	completed -> *refunded         the recipient returns funds
+//	completed -> reversed          an admin returns the rest
+//
+// A failed transaction never moved funds. Failed, cancelled, refunded and
+// reversed are final.
+var transactionTransitions = map[TransactionStatus][]TransactionStatus{
+	"": {TransactionStatusPending, TransactionStatusCompleted, TransactionStatusFailed},
+	TransactionStatusPending: {TransactionStatusAuthorized, TransactionStatusCompleted,
+		TransactionStatusFailed, TransactionStatusCancelled},
+	TransactionStatusAuthorized: {TransactionStatusCompleted, TransactionStatusFailed, TransactionStatusCancelled},
+	TransactionStatusCompleted: {TransactionStatusPartiallyRefunded, TransactionStatusRefunded,
+		TransactionStatusReversed},
+	TransactionStatusPartiallyRefunded: {TransactionStatusPartiallyRefunded, TransactionStatusRefunded,
+		TransactionStatusReversed},
+}
+
+// VoidTransactionStatuses are the statuses of transactions that moved no
+// funds in the end. Volumes, limits and activity leave them out.
+var VoidTransactionStatuses = []TransactionStatus{TransactionStatusFailed, TransactionStatusCancelled}
+
+// CanTransitionTo reports whether a transaction in status s may move to next
+func (s TransactionStatus) CanTransitionTo(next TransactionStatus) bool {
+	for _, allowed := range transactionTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFinal reports whether no further status change is possible
+func (s TransactionStatus) IsFinal() bool {
+	return len(transactionTransitions[s]) == 0
+}
+
+// TransactionStatusChange records one transition of a transaction, including
+// the status it was created with (From is then empty)
+type TransactionStatusChange struct {
+	ID            uuid.UUID         `json:"id" gorm:"type:varchar(36);primary_key"`
+	TransactionID uuid.UUID         `json:"transaction_id" gorm:"type:varchar(36);not null;index"`
+	From          TransactionStatus `json:"from"`
+	To            TransactionStatus `json:"to" gorm:"not null"`
+	Reason        string            `json:"reason,omitempty"`
+	ActorID       *uuid.UUID        `json:"actor_id,omitempty" gorm:"type:varchar(36)"` // Nil for the system
+	CreatedAt     time.Time         `json:"created_at"`
+
+	// Position in the tamper-evident status chain (see chain.go)
+	ChainSeq *int64 `json:"chain_seq,omitempty" gorm:"unique_index"`
+	PrevHash string `json:"prev_hash,omitempty" gorm:"type:varchar(64)"`
+	Hash     string `json:"hash,omitempty" gorm:"type:varchar(64)"`
+}
+
+func (c *TransactionStatusChange) BeforeCreate(scope *gorm.Scope) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return sealStatusChange(scope, c)
+}
+
+// initStatus checks the status a transaction is created with and stamps it
+func initStatus(scope *gorm.Scope, t *Transaction) error {
+	if t.Status == "" {
+		if err := scope.SetColumn("Status", TransactionStatusPending); err != nil {
+			return err
+		}
+	}
+	if !TransactionStatus("").CanTransitionTo(t.Status) {
+		return fmt.Errorf("a transaction cannot be created as %s", t.Status)
+	}
+	if t.CreatedAt.IsZero() {
+		if err := scope.SetColumn("CreatedAt", time.Now()); err != nil {
+			return err
+		}
+	}
+	if t.StatusChangedAt.IsZero() {
+		return scope.SetColumn("StatusChangedAt", t.CreatedAt)
+	}
+	return nil
+}
+
+// recordInitialStatus starts the status history of a new transaction
+func recordInitialStatus(scope *gorm.Scope, t *Transaction) error {
+	change := &TransactionStatusChange{
+		TransactionID: t.ID,
+		To:            t.Status,
+		Reason:        t.FailureReason,
+		CreatedAt:     t.StatusChangedAt,
+	}
+	if t.UserID != uuid.Nil {
+		change.ActorID = &t.UserID
+	}
+	return scope.NewDB().Create(change).Error
+}
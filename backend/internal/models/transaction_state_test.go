@@ -0,0 +1,65 @@
+package models
+
+import "testing"
+
+func TestTransactionStatusTransitions(t *testing.T) {
+	tests := []struct {
+		from, to TransactionStatus
+		want     bool
+	}{
+		{"", TransactionStatusPending, true},
+		{"", TransactionStatusCompleted, true},
+		{"", TransactionStatusFailed, true},
+		{"", TransactionStatusAuthorized, false},
+		{"", TransactionStatusRefunded, false},
+		{TransactionStatusPending, TransactionStatusAuthorized, true},
+		{TransactionStatusPending, TransactionStatusCompleted, true},
+		{TransactionStatusPending, TransactionStatusFailed, true},
+		{TransactionStatusPending, TransactionStatusCancelled, true},
+		{TransactionStatusPending, TransactionStatusRefunded, false},
+		{TransactionStatusAuthorized, TransactionStatusCompleted, true},
+		{TransactionStatusAuthorized, TransactionStatusCancelled, true},
+		{TransactionStatusAuthorized, TransactionStatusPending, false},
+		{TransactionStatusAuthorized, TransactionStatusReversed, false},
+		{TransactionStatusCompleted, TransactionStatusPartiallyRefunded, true},
+		{TransactionStatusCompleted, TransactionStatusRefunded, true},
+		{TransactionStatusCompleted, TransactionStatusReversed, true},
+		{TransactionStatusCompleted, TransactionStatusCancelled, false},
+		{TransactionStatusCompleted, TransactionStatusFailed, false},
+		{TransactionStatusCompleted, TransactionStatusCompleted, false},
+		{TransactionStatusPartiallyRefunded, TransactionStatusPartiallyRefunded, true},
+		{TransactionStatusPartiallyRefunded, TransactionStatusRefunded, true},
+		{TransactionStatusPartiallyRefunded, TransactionStatusReversed, true},
+		{TransactionStatusPartiallyRefunded, TransactionStatusCompleted, false},
+		{TransactionStatusRefunded, TransactionStatusReversed, false},
+		{TransactionStatusReversed, TransactionStatusRefunded, false},
+		{TransactionStatusFailed, TransactionStatusCompleted, false},
+		{TransactionStatusCancelled, TransactionStatusCompleted, false},
+	}
+	for _, tt := range tests {
+		if got := tt.from.CanTransitionTo(tt.to); got != tt.want {
+			t.Errorf("%q -> %q allowed = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestTransactionStatusIsFinal(t *testing.T) {
+	tests := []struct {
+		status TransactionStatus
+		want   bool
+	}{
+		{TransactionStatusPending, false},
+		{TransactionStatusAuthorized, false},
+		{TransactionStatusCompleted, false},
+		{TransactionStatusPartiallyRefunded, false},
+		{TransactionStatusFailed, true},
+		{TransactionStatusCancelled, true},
+		{TransactionStatusRefunded, true},
+		{TransactionStatusReversed, true},
+	}
+	for _, tt := range tests {
+		if got := tt.status.IsFinal(); got != tt.want {
+			t.Errorf("%q.IsFinal() = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"faircoin/internal/models"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AttestationAgingMode is how an attestation of some type loses weight by age
+type AttestationAgingMode string
+
+const (
+	AttestationExpire AttestationAgingMode = "expire" // Counts in full until Period, then not at all
+	AttestationDecay  AttestationAgingMode = "decay"  // Loses half its weight every Period
+)
+
+// AttestationAging is how attestations of one type age. Age is counted from
+// when the attestation was given.
+type AttestationAging struct {
+	Mode   AttestationAgingMode
+	Period time.Duration
+}
+
+// DefaultAttestationAging is the aging used unless configured otherwise:
+// service and dispute work expire, peer ratings fade, identity checks last
+const DefaultAttestationAging = "community_service:expire:365,dispute_resolution:expire:730,peer_rating:decay:180"
+
+// ParseAttestationAging reads comma-separated "type:mode:days" rules, e.g.
+// "peer_rating:decay:180". Days is the expiry age for expire and the
+// half-life for decay. A "*" type applies to every type without its own rule;
+// types without any rule never age. "none" means no rules.
+func ParseAttestationAging(spec string) (map[string]AttestationAging, error) {
+	rules := make(map[string]AttestationAging)
+	if strings.TrimSpace(spec) == "none" {
+		return rules, nil
+	}
+	for _, rule := range strings.Split(spec, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		parts := strings.Split(rule, ":")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid attestation aging rule %q, expected type:mode:days", rule)
+		}
+		attestationType := strings.TrimSpace(parts[0])
+		if attestationType == "" {
+			return nil, fmt.Errorf("invalid attestation aging rule %q: missing type", rule)
+		}
+		mode := AttestationAgingMode(strings.TrimSpace(parts[1]))
+		if mode != AttestationExpire && mode != AttestationDecay {
+			return nil, fmt.Errorf("invalid attestation aging mode %q, expected expire or decay", parts[1])
+		}
+		days, err := strconv.Atoi(strings.TrimSpace(parts[2]))
+		if err != nil || days <= 0 {
+			return nil, fmt.Errorf("invalid attestation aging period %q, expected a positive number of days", parts[2])
+		}
+		if _, ok := rules[attestationType]; ok {
+			return nil, fmt.Errorf("duplicate attestation aging rule for %s", attestationType)
+		}
+		rules[attestationType] = AttestationAging{Mode: mode, Period: time.Duration(days) * 24 * time.Hour}
+	}
+	return rules, nil
+}
+
+// SetAttestationAging sets how attestations age from rules in the format
+// ParseAttestationAging reads. An empty spec or "none" turns aging off.
+func (s *FairnessService) SetAttestationAging(spec string) error {
+	rules, err := ParseAttestationAging(spec)
+	if err != nil {
+		return err
+	}
+	s.aging = rules
+	return nil
+}
+
+// agingFor returns the aging rule for an attestation type, if any
+func (s *FairnessService) agingFor(attestationType string) (AttestationAging, bool) {
+	if rule, ok := s.aging[attestationType]; ok {
+		return rule, true
+	}
+	rule, ok := s.aging["*"]
+	return rule, ok
+}
+
+// attestationWeight returns the share of an attestation's value that still
+// counts toward PFI at now, from 1 for a fresh one down to 0 once expired
+func (s *FairnessService) attestationWeight(att *models.Attestation, now time.Time) float64 {
+	rule, ok := s.agingFor(att.Type)
+	if !ok {
+		return 1
+	}
+	age := now.Sub(att.CreatedAt)
+	if age < 0 {
+		age = 0
+	}
+	switch rule.Mode {
+	case AttestationExpire:
+		if age >= rule.Period {
+			return 0
+		}
+		return 1
+	case AttestationDecay:
+		return math.Pow(0.5, age.Hours()/rule.Period.Hours())
+	}
+	return 1
+}
+
+// describeStanding fills in the weight of a verified attestation and, for
+// types that expire, when it does
+func (s *FairnessService) describeStanding(att *models.Attestation, now time.Time) {
+	if att.Status != models.AttestationStatusVerified {
+		return
+	}
+	weight := math.Round(s.attestationWeight(att, now)*1000) / 1000
+	att.Weight = &weight
+	if rule, ok := s.agingFor(att.Type); ok && rule.Mode == AttestationExpire {
+		expiresAt := att.CreatedAt.Add(rule.Period)
+		att.ExpiresAt = &expiresAt
+	}
+}
@@ -0,0 +1,292 @@
+package services
+
+import (
+	"errors"
+	"faircoin/internal/models"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// DefaultAttestationRequiredReviews is how many agreeing peer reviews
+	// verify or reject an attestation
+	DefaultAttestationRequiredReviews = 3
+	// DefaultAttestationReviewerMinPFI is the PFI a peer needs to review
+	DefaultAttestationReviewerMinPFI = 80
+)
+
+// ErrAttestationNotFound is returned when an attestation does not exist
+var ErrAttestationNotFound = errors.New("attestation not found")
+
+// ErrAttestationDecided is returned when an attestation is no longer pending
+var ErrAttestationDecided = errors.New("attestation has already been decided")
+
+// ErrNotAttestationReviewer is returned when someone who may not review
+// attestations tries to
+var ErrNotAttestationReviewer = errors.New("only council members and trusted peers can review attestations")
+
+// ErrNotAttestationAttester is returned when someone other than the attester
+// or an admin tries to revoke an attestation
+var ErrNotAttestationAttester = errors.New("only the attester or an admin can revoke an attestation")
+
+// ErrAttestationRevoked is returned for an attestation that was already revoked
+var ErrAttestationRevoked = errors.New("attestation has already been revoked")
+
+// SetAttestationReview sets how many agreeing peer reviews decide an
+// attestation and the PFI a peer needs to review
+func (s *FairnessService) SetAttestationReview(requiredReviews, reviewerMinPFI int) error {
+	if requiredReviews < 1 {
+		return fmt.Errorf("at least one review must be required")
+	}
+	if reviewerMinPFI < 0 || reviewerMinPFI > 100 {
+		return fmt.Errorf("reviewer PFI must be between 0 and 100")
+	}
+	s.requiredReviews = requiredReviews
+	s.reviewerMinPFI = reviewerMinPFI
+	return nil
+}
+
+// Pending attestations are decided by review. A council member's review
+// decides on its own; otherwise requiredReviews agreeing reviews from peers
+// with at least reviewerMinPFI do, whichever side gets there first. Nobody
+// reviews an attestation they gave or received, or reviews twice.
+
+// isAttestationReviewer reports whether the user may review attestations,
+// and whether as a council member
+func (s *FairnessService) isAttestationReviewer(db *gorm.DB, reviewer *models.User) (bool, bool, error) {
+	members, err := councilMembers(db)
+	if err != nil {
+		return false, false, fmt.Errorf("failed to get council members: %w", err)
+	}
+	for _, member := range members {
+		if member.ID == reviewer.ID {
+			return true, true, nil
+		}
+	}
+	return reviewer.PFI >= s.reviewerMinPFI, false, nil
+}
+
+// GetAttestationQueue returns the pending attestations the reviewer can
+// still review, oldest first
+func (s *FairnessService) GetAttestationQueue(reviewerID uuid.UUID, limit int) ([]models.Attestation, error) {
+	var reviewer models.User
+	if err := s.db.First(&reviewer, "id = ?", reviewerID).Error; err != nil {
+		return nil, fmt.Errorf("reviewer not found: %w", err)
+	}
+	allowed, _, err := s.isAttestationReviewer(s.db, &reviewer)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrNotAttestationReviewer
+	}
+
+	reviewed := s.db.Table("attestation_reviews").Select("attestation_id").
+		Where("reviewer_id = ?", reviewerID).QueryExpr()
+	var attestations []models.Attestation
+	if err := s.db.Preload("Reviews").
+		Where("status = ? AND user_id <> ? AND attester_id <> ?", models.AttestationStatusPending, reviewerID, reviewerID).
+		Where("id NOT IN (?)", reviewed).
+		Order("created_at ASC").Limit(limit).Find(&attestations).Error; err != nil {
+		return nil, fmt.Errorf("failed to get attestation queue: %w", err)
+	}
+	return attestations, nil
+}
+
+// ReviewAttestation records a review of a pending attestation and decides
+// it once a council member has reviewed it or enough peers agree. A decided
+// attestation updates the PFI of the member it is about.
+func (s *FairnessService) ReviewAttestation(attestationID, reviewerID uuid.UUID, approve bool, comment string) (*models.Attestation, error) {
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	var reviewer models.User
+	if err := tx.First(&reviewer, "id = ?", reviewerID).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("reviewer not found: %w", err)
+	}
+	allowed, council, err := s.isAttestationReviewer(tx, &reviewer)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if !allowed {
+		tx.Rollback()
+		return nil, ErrNotAttestationReviewer
+	}
+
+	var attestation models.Attestation
+	if err := tx.First(&attestation, "id = ?", attestationID).Error; err != nil {
+		tx.Rollback()
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrAttestationNotFound
+		}
+		return nil, fmt.Errorf("failed to get attestation: %w", err)
+	}
+	if attestation.Status != models.AttestationStatusPending {
+		tx.Rollback()
+		return nil, fmt.Errorf("%w: it is %s", ErrAttestationDecided, attestation.Status)
+	}
+	if attestation.UserID == reviewerID || attestation.AttesterID == reviewerID {
+		tx.Rollback()
+		return nil, fmt.Errorf("cannot review an attestation you gave or received")
+	}
+
+	var existing int
+	if err := tx.Model(&models.AttestationReview{}).
+		Where("attestation_id = ? AND reviewer_id = ?", attestationID, reviewerID).Count(&existing).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to check existing review: %w", err)
+	}
+	if existing > 0 {
+		tx.Rollback()
+		return nil, fmt.Errorf("you have already reviewed this attestation")
+	}
+	if err := tx.Create(&models.AttestationReview{
+		AttestationID: attestationID,
+		ReviewerID:    reviewerID,
+		Approve:       approve,
+		Council:       council,
+		ReviewerPFI:   reviewer.PFI,
+		Comment:       strings.TrimSpace(comment),
+		CreatedAt:     time.Now(),
+	}).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to record review: %w", err)
+	}
+
+	column := "reviews_against"
+	if approve {
+		column = "reviews_for"
+		attestation.ReviewsFor++
+	} else {
+		attestation.ReviewsAgainst++
+	}
+	updates := map[string]interface{}{column: gorm.Expr(column + " + 1")}
+
+	switch {
+	case council && approve, attestation.ReviewsFor >= s.requiredReviews:
+		attestation.Status = models.AttestationStatusVerified
+	case council, attestation.ReviewsAgainst >= s.requiredReviews:
+		attestation.Status = models.AttestationStatusRejected
+	}
+	if attestation.Status != models.AttestationStatusPending {
+		now := time.Now().UTC()
+		attestation.Verified = attestation.Status == models.AttestationStatusVerified
+		attestation.DecidedAt = &now
+		updates["status"] = attestation.Status
+		updates["verified"] = attestation.Verified
+		updates["decided_at"] = now
+	}
+
+	// Only a still-pending attestation can take the review
+	result := tx.Model(&models.Attestation{}).
+		Where("id = ? AND status = ?", attestationID, models.AttestationStatusPending).Updates(updates)
+	if result.Error != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to update attestation: %w", result.Error)
+	}
+	if result.RowsAffected != 1 {
+		tx.Rollback()
+		return nil, ErrAttestationDecided
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit review: %w", err)
+	}
+
+	if attestation.Status != models.AttestationStatusPending {
+		if err := s.UpdateUserPFI(attestation.UserID); err != nil {
+			// The decision stands; the hourly job recalculates PFI anyway
+			fmt.Printf("Warning: Failed to update PFI for user %s: %v\n", attestation.UserID, err)
+		}
+	}
+
+	s.db.Preload("Reviews").First(&attestation, "id = ?", attestationID)
+	return &attestation, nil
+}
+
+// RevokeAttestation withdraws a pending or verified attestation. Attesters
+// can revoke their own, for instance when it no longer holds; admins can
+// revoke any, for instance when it was fraudulent. A revoked attestation no
+// longer counts, so the PFI of the member it is about is recalculated.
+func (s *FairnessService) RevokeAttestation(attestationID, actorID uuid.UUID, asAdmin bool, reason string) (*models.Attestation, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, fmt.Errorf("a reason is required to revoke an attestation")
+	}
+
+	var attestation models.Attestation
+	if err := s.db.First(&attestation, "id = ?", attestationID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrAttestationNotFound
+		}
+		return nil, fmt.Errorf("failed to get attestation: %w", err)
+	}
+	if !asAdmin && attestation.AttesterID != actorID {
+		return nil, ErrNotAttestationAttester
+	}
+	switch attestation.Status {
+	case models.AttestationStatusRevoked:
+		return nil, ErrAttestationRevoked
+	case models.AttestationStatusRejected:
+		return nil, fmt.Errorf("%w: it is %s", ErrAttestationDecided, attestation.Status)
+	}
+	counted := attestation.Status == models.AttestationStatusVerified
+
+	// Only a pending or verified attestation can be revoked
+	now := time.Now().UTC()
+	result := s.db.Model(&models.Attestation{}).
+		Where("id = ? AND status IN (?)", attestationID,
+			[]models.AttestationStatus{models.AttestationStatusPending, models.AttestationStatusVerified}).
+		Updates(map[string]interface{}{
+			"status":            models.AttestationStatusRevoked,
+			"verified":          false,
+			"revoked_at":        now,
+			"revoked_by":        actorID,
+			"revocation_reason": reason,
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to revoke attestation: %w", result.Error)
+	}
+	if result.RowsAffected != 1 {
+		return nil, ErrAttestationRevoked
+	}
+
+	if counted {
+		if err := s.UpdateUserPFI(attestation.UserID); err != nil {
+			// The revocation stands; the hourly job recalculates PFI anyway
+			fmt.Printf("Warning: Failed to update PFI for user %s: %v\n", attestation.UserID, err)
+		}
+	}
+
+	s.db.First(&attestation, "id = ?", attestationID)
+	return &attestation, nil
+}
+
+// GetUserAttestations returns the attestations a user gave and received,
+// newest first, with the weight verified ones still carry
+func (s *FairnessService) GetUserAttestations(userID uuid.UUID) ([]models.Attestation, []models.Attestation, error) {
+	var given, received []models.Attestation
+	if err := s.db.Where("attester_id = ?", userID).Order("created_at DESC").Find(&given).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to get given attestations: %w", err)
+	}
+	if err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&received).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to get received attestations: %w", err)
+	}
+
+	now := time.Now()
+	for i := range given {
+		s.describeStanding(&given[i], now)
+	}
+	for i := range received {
+		s.describeStanding(&received[i], now)
+	}
+	return given, received, nil
+}
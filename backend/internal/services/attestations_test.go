@@ -0,0 +1,208 @@
+package services
+
+import (
+	"errors"
+	"faircoin/internal/models"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// setPFI gives a user a PFI; verified users with a PFI of 70 or more sit on the council
+func setPFI(t *testing.T, db *gorm.DB, user *models.User, pfi int, verified bool) {
+	t.Helper()
+	if err := db.Model(&models.User{}).Where("id = ?", user.ID).
+		Updates(map[string]interface{}{"pfi": pfi, "is_verified": verified}).Error; err != nil {
+		t.Fatal(err)
+	}
+	user.PFI = pfi
+}
+
+func TestReviewAttestation(t *testing.T) {
+	tests := []struct {
+		name    string
+		reviews []string // Reviewer usernames, prefixed with ! to reject
+		want    models.AttestationStatus
+	}{
+		{"peer quorum approves", []string{"peer1", "!peer2", "peer3"}, models.AttestationStatusVerified},
+		{"peer quorum rejects", []string{"!peer1", "peer2", "!peer3"}, models.AttestationStatusRejected},
+		{"council approves alone", []string{"council"}, models.AttestationStatusVerified},
+		{"council rejects alone", []string{"peer1", "!council"}, models.AttestationStatusRejected},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			subject := newTestUser(t, db, "subject")
+			attester := newTestUser(t, db, "attester")
+			setPFI(t, db, attester, 50, false)
+			users := map[string]*models.User{}
+			for _, username := range []string{"council", "peer1", "peer2", "peer3", "newcomer"} {
+				users[username] = newTestUser(t, db, username)
+			}
+			setPFI(t, db, users["council"], 90, true)
+			for _, username := range []string{"peer1", "peer2", "peer3"} {
+				setPFI(t, db, users[username], 60, false)
+			}
+			setPFI(t, db, users["newcomer"], 10, false)
+
+			fairness := NewFairnessService(db)
+			if err := fairness.SetAttestationReview(2, 50); err != nil {
+				t.Fatal(err)
+			}
+			attestation, err := fairness.CreateAttestation(subject.ID, attester.ID, "community", 80, "helps out")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if attestation.Status != models.AttestationStatusPending {
+				t.Fatalf("attestation is %s, want pending", attestation.Status)
+			}
+
+			if _, err := fairness.ReviewAttestation(attestation.ID, users["newcomer"].ID, true, ""); !errors.Is(err, ErrNotAttestationReviewer) {
+				t.Errorf("review below the reviewer PFI: err = %v", err)
+			}
+			if _, err := fairness.ReviewAttestation(attestation.ID, subject.ID, true, ""); err == nil {
+				t.Error("the subject reviewed their own attestation")
+			}
+
+			for i, review := range tt.reviews {
+				username, approve := review, true
+				if review[0] == '!' {
+					username, approve = review[1:], false
+				}
+				reviewer := users[username]
+				queue, err := fairness.GetAttestationQueue(reviewer.ID, 10)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(queue) != 1 || queue[0].ID != attestation.ID {
+					t.Errorf("%s's queue holds %d attestations, want the pending one", username, len(queue))
+				}
+
+				reviewed, err := fairness.ReviewAttestation(attestation.ID, reviewer.ID, approve, "")
+				if err != nil {
+					t.Fatalf("review by %s: %v", username, err)
+				}
+				if _, err := fairness.ReviewAttestation(attestation.ID, reviewer.ID, approve, ""); err == nil {
+					t.Errorf("%s reviewed twice", username)
+				}
+				if last := i == len(tt.reviews)-1; last != (reviewed.Status != models.AttestationStatusPending) {
+					t.Errorf("after review %d the attestation is %s", i+1, reviewed.Status)
+				}
+			}
+
+			var stored models.Attestation
+			db.First(&stored, "id = ?", attestation.ID)
+			if stored.Status != tt.want || stored.Verified != (tt.want == models.AttestationStatusVerified) || stored.DecidedAt == nil {
+				t.Errorf("attestation is %s, verified %v, want %s", stored.Status, stored.Verified, tt.want)
+			}
+			for _, username := range []string{"peer1", "peer2", "peer3", "council"} {
+				if _, err := fairness.ReviewAttestation(attestation.ID, users[username].ID, true, ""); err == nil {
+					t.Errorf("%s reviewed a decided attestation", username)
+				}
+			}
+		})
+	}
+}
+
+func TestRevokeAttestation(t *testing.T) {
+	db := newTestDB(t)
+	subject := newTestUser(t, db, "subject")
+	attester := newTestUser(t, db, "attester")
+	other := newTestUser(t, db, "other")
+	admin := newTestUser(t, db, "admin")
+	setPFI(t, db, attester, 85, false)
+	setPFI(t, db, other, 50, false)
+
+	fairness := NewFairnessService(db)
+	if err := fairness.UpdateUserPFI(subject.ID); err != nil {
+		t.Fatal(err)
+	}
+	var before models.User
+	db.First(&before, "id = ?", subject.ID)
+
+	// An attester with a PFI of 80 or more is verified straight away
+	verified, err := fairness.CreateAttestation(subject.ID, attester.ID, "identity_verification", 10, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var attested models.User
+	db.First(&attested, "id = ?", subject.ID)
+	if attested.PFI <= before.PFI {
+		t.Fatalf("PFI %d did not rise from %d with a verified attestation", attested.PFI, before.PFI)
+	}
+
+	if _, err := fairness.RevokeAttestation(verified.ID, other.ID, false, "wrong"); !errors.Is(err, ErrNotAttestationAttester) {
+		t.Errorf("revoke by another member: err = %v", err)
+	}
+	if _, err := fairness.RevokeAttestation(verified.ID, attester.ID, false, " "); err == nil {
+		t.Error("revoked without a reason")
+	}
+	revoked, err := fairness.RevokeAttestation(verified.ID, attester.ID, false, "moved away")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revoked.Status != models.AttestationStatusRevoked || revoked.Verified || revoked.RevokedBy == nil ||
+		*revoked.RevokedBy != attester.ID || revoked.RevocationReason != "moved away" {
+		t.Errorf("revoked attestation = %+v", revoked)
+	}
+	if _, err := fairness.RevokeAttestation(verified.ID, admin.ID, true, "again"); !errors.Is(err, ErrAttestationRevoked) {
+		t.Errorf("second revocation: err = %v", err)
+	}
+	var after models.User
+	db.First(&after, "id = ?", subject.ID)
+	if after.PFI != before.PFI {
+		t.Errorf("PFI is %d after the revocation, want %d", after.PFI, before.PFI)
+	}
+
+	// The attester can give an attestation they revoked again, and an admin
+	// can revoke a pending one
+	if _, err := fairness.CreateAttestation(subject.ID, attester.ID, "identity_verification", 10, ""); err != nil {
+		t.Errorf("attesting again after revoking: %v", err)
+	}
+	pending, err := fairness.CreateAttestation(subject.ID, other.ID, "identity_verification", 10, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fairness.RevokeAttestation(pending.ID, admin.ID, true, "fraud"); err != nil {
+		t.Errorf("admin revoking a pending attestation: %v", err)
+	}
+	if _, err := fairness.CreateAttestation(subject.ID, other.ID, "identity_verification", 10, ""); err == nil {
+		t.Error("an attestation an admin revoked was given again")
+	}
+}
+
+func TestAttestationWeight(t *testing.T) {
+	fairness := NewFairnessService(nil)
+	if err := fairness.SetAttestationAging("service:expire:30,rating:decay:10"); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	days := func(n int) time.Time { return now.Add(-time.Duration(n) * 24 * time.Hour) }
+
+	tests := []struct {
+		kind    string
+		created time.Time
+		want    float64
+	}{
+		{"service", days(29), 1},
+		{"service", days(30), 0},
+		{"rating", now, 1},
+		{"rating", days(10), 0.5},
+		{"rating", days(20), 0.25},
+		{"identity", days(3650), 1},
+	}
+	for _, tt := range tests {
+		att := &models.Attestation{Type: tt.kind, CreatedAt: tt.created}
+		if got := fairness.attestationWeight(att, now); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s attestation from %s ago weighs %v, want %v", tt.kind, now.Sub(tt.created), got, tt.want)
+		}
+	}
+
+	for _, spec := range []string{"service:fade:30", "service:expire:0", "service:expire:30,service:decay:10", ":expire:30"} {
+		if _, err := ParseAttestationAging(spec); err == nil {
+			t.Errorf("ParseAttestationAging(%q) accepted an invalid spec", spec)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"faircoin/internal/models"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// MaxBatchLines caps the number of transfers in one batch
+const MaxBatchLines = 1000
+
+// BatchMode defines how a batch handles failing lines
+type BatchMode string
+
+const (
+	// BatchAllOrNothing commits the batch only if every line succeeds
+	BatchAllOrNothing BatchMode = "all_or_nothing"
+	// BatchBestEffort commits the lines that succeed and reports the rest
+	BatchBestEffort BatchMode = "best_effort"
+)
+
+// ErrBatchFailed is returned when an all-or-nothing batch was rolled back
+var ErrBatchFailed = errors.New("batch failed")
+
+// BatchLine is one transfer in a batch
+type BatchLine struct {
+	ToUsername  string       `json:"to_username"`
+	Amount      models.Money `json:"amount"`
+	Description string       `json:"description"`
+}
+
+// BatchLineResult reports what happened to one line
+type BatchLineResult struct {
+	Line          int          `json:"line"` // 1-based position in the request
+	ToUsername    string       `json:"to_username"`
+	Amount        models.Money `json:"amount"`
+	Status        string       `json:"status"` // "completed", "failed" or "skipped"
+	Error         string       `json:"error,omitempty"`
+	TransactionID *uuid.UUID   `json:"transaction_id,omitempty"`
+}
+
+// BatchResult summarises a batch transfer
+type BatchResult struct {
+	BatchID   uuid.UUID         `json:"batch_id"`
+	Mode      BatchMode         `json:"mode"`
+	Status    string            `json:"status"` // "completed", "partial" or "failed"
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Total     models.Money      `json:"total"` // Sum of the amounts transferred
+	Fees      models.Money      `json:"fees"`
+	Lines     []BatchLineResult `json:"lines"`
+}
+
+// ParseBatchCSV reads batch lines from CSV with the columns username,
+// amount and an optional description. A header row starting with
+// "username" or "to_username" is skipped.
+func ParseBatchCSV(r io.Reader) ([]BatchLine, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var lines []BatchLine
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		if row == 1 && len(record) > 0 {
+			header := strings.ToLower(strings.TrimSpace(record[0]))
+			if header == "username" || header == "to_username" {
+				continue
+			}
+		}
+		if len(record) < 2 || len(record) > 3 {
+			return nil, fmt.Errorf("CSV row %d: expected username,amount[,description]", row)
+		}
+
+		amount, err := models.ParseMoney(strings.TrimSpace(record[1]))
+		if err != nil {
+			return nil, fmt.Errorf("CSV row %d: invalid amount %q", row, record[1])
+		}
+		line := BatchLine{ToUsername: strings.TrimSpace(record[0]), Amount: amount}
+		if len(record) == 3 {
+			line.Description = strings.TrimSpace(record[2])
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// BatchTransfer sends many transfers from one sender in a single database
+// transaction. Every line is validated before anything moves. In
+// all-or-nothing mode any failing line rolls back the whole batch and
+// ErrBatchFailed is returned with the per-line results; in best-effort mode
+// each line runs in its own savepoint, so failing lines are skipped and the
+// rest are committed.
+func (s *WalletService) BatchTransfer(senderID uuid.UUID, lines []BatchLine, mode BatchMode) (*BatchResult, error) {
+	if mode == "" {
+		mode = BatchAllOrNothing
+	}
+	if mode != BatchAllOrNothing && mode != BatchBestEffort {
+		return nil, fmt.Errorf("unknown batch mode: %s", mode)
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("batch has no lines")
+	}
+	if len(lines) > MaxBatchLines {
+		return nil, fmt.Errorf("batch has %d lines, at most %d are allowed", len(lines), MaxBatchLines)
+	}
+
+	result := &BatchResult{
+		BatchID: uuid.New(),
+		Mode:    mode,
+		Lines:   make([]BatchLineResult, len(lines)),
+	}
+
+	// Resolve every recipient with one query
+	usernames := make([]string, 0, len(lines))
+	for _, line := range lines {
+		usernames = append(usernames, line.ToUsername)
+	}
+	var users []models.User
+	if err := s.db.Where("username IN (?)", usernames).Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to look up recipients: %w", err)
+	}
+	userIDs := make(map[string]uuid.UUID, len(users))
+	for _, user := range users {
+		userIDs[user.Username] = user.ID
+	}
+
+	// Validate the whole batch before moving anything
+	recipients := make([]uuid.UUID, len(lines))
+	var required models.Money
+	invalid := 0
+	for i, line := range lines {
+		lineResult := &result.Lines[i]
+		lineResult.Line = i + 1
+		lineResult.ToUsername = line.ToUsername
+		lineResult.Amount = line.Amount
+
+		userID, found := userIDs[line.ToUsername]
+		switch {
+		case line.ToUsername == "":
+			lineResult.Error = "recipient is required"
+		case !found:
+			lineResult.Error = "recipient not found"
+		case userID == senderID:
+			lineResult.Error = "cannot send FairCoins to yourself"
+		case !line.Amount.IsPositive():
+			lineResult.Error = "amount must be positive"
+		}
+		if lineResult.Error != "" {
+			lineResult.Status = "failed"
+			invalid++
+			continue
+		}
+		recipients[i] = userID
+		if mode == BatchAllOrNothing {
+			quote, err := s.fees.Quote(s.db, senderID, userID, line.Amount)
+			if err != nil {
+				return nil, err
+			}
+			required = required.Add(quote.Total)
+		}
+	}
+
+	if mode == BatchAllOrNothing {
+		if invalid > 0 {
+			return s.failBatch(result, "batch not run because other lines are invalid"),
+				fmt.Errorf("%w: %d of %d lines are invalid", ErrBatchFailed, invalid, len(lines))
+		}
+
+		var wallet models.Wallet
+		if err := s.db.Where("user_id = ?", senderID).First(&wallet).Error; err != nil {
+			return nil, fmt.Errorf("sender wallet not found: %w", err)
+		}
+		available, _, err := s.availableToSpend(s.db, &wallet)
+		if err != nil {
+			return nil, err
+		}
+		if available < required {
+			return s.failBatch(result, "batch not run because the sender cannot cover it"),
+				fmt.Errorf("%w: %s FC needed including fees, %s FC available", ErrBatchFailed, required, available)
+		}
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	for i, line := range lines {
+		lineResult := &result.Lines[i]
+		if lineResult.Status == "failed" {
+			result.Failed++
+			continue
+		}
+
+		if mode == BatchBestEffort {
+			if err := tx.Exec("SAVEPOINT batch_line").Error; err != nil {
+				tx.Rollback()
+				return nil, fmt.Errorf("failed to create savepoint: %w", err)
+			}
+		}
+
+		description := line.Description
+		if description == "" {
+			description = "Batch payout"
+		}
+		transaction, err := s.transfer(tx, senderID, recipients[i], line.Amount, description,
+			map[string]interface{}{"batch_id": result.BatchID, "batch_line": i + 1})
+		if err != nil {
+			lineResult.Status = "failed"
+			lineResult.Error = err.Error()
+			result.Failed++
+
+			if mode == BatchAllOrNothing {
+				tx.Rollback()
+				return s.failBatch(result, "rolled back because another line failed"),
+					fmt.Errorf("%w: line %d: %v", ErrBatchFailed, i+1, err)
+			}
+			if err := tx.Exec("ROLLBACK TO SAVEPOINT batch_line").Error; err != nil {
+				tx.Rollback()
+				return nil, fmt.Errorf("failed to roll back line %d: %w", i+1, err)
+			}
+			continue
+		}
+
+		if mode == BatchBestEffort {
+			if err := tx.Exec("RELEASE SAVEPOINT batch_line").Error; err != nil {
+				tx.Rollback()
+				return nil, fmt.Errorf("failed to release savepoint: %w", err)
+			}
+		}
+
+		lineResult.Status = "completed"
+		lineResult.TransactionID = &transaction.ID
+		result.Succeeded++
+		result.Total = result.Total.Add(transaction.Amount)
+		result.Fees = result.Fees.Add(transaction.Fee)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit batch: %w", err)
+	}
+
+	switch {
+	case result.Failed == 0:
+		result.Status = "completed"
+	case result.Succeeded == 0:
+		result.Status = "failed"
+	default:
+		result.Status = "partial"
+	}
+	return result, nil
+}
+
+// failBatch marks every line that has not failed itself as skipped and
+// clears the totals of a batch that did not run
+func (s *WalletService) failBatch(result *BatchResult, reason string) *BatchResult {
+	result.Status = "failed"
+	result.Succeeded = 0
+	result.Failed = 0
+	result.Total = 0
+	result.Fees = 0
+	for i := range result.Lines {
+		line := &result.Lines[i]
+		if line.Status == "failed" {
+			result.Failed++
+			continue
+		}
+		line.Status = "skipped"
+		line.Error = reason
+		line.TransactionID = nil
+	}
+	return result
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"errors"
+	"faircoin/internal/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBatchTransfer(t *testing.T) {
+	lines := []BatchLine{
+		{ToUsername: "bob", Amount: models.FC(10)},
+		{ToUsername: "carol", Amount: models.FC(5)}, // Frozen, so the transfer itself fails
+		{ToUsername: "dave", Amount: models.FC(7)},
+	}
+	tests := []struct {
+		name       string
+		lines      []BatchLine
+		mode       BatchMode
+		wantErr    bool
+		wantStatus []string
+		wantBob    models.Money
+		wantDave   models.Money
+	}{
+		{"all or nothing rolls back", lines, BatchAllOrNothing, true,
+			[]string{"skipped", "failed", "skipped"}, models.FC(100), models.FC(100)},
+		{"all or nothing with an invalid line", append([]BatchLine{{ToUsername: "nobody", Amount: models.FC(1)}}, lines[0]),
+			BatchAllOrNothing, true, []string{"failed", "skipped"}, models.FC(100), models.FC(100)},
+		{"all or nothing", []BatchLine{lines[0], lines[2]}, BatchAllOrNothing, false,
+			[]string{"completed", "completed"}, models.FC(110), models.FC(107)},
+		{"best effort skips failing lines", append(lines, BatchLine{ToUsername: "nobody", Amount: models.FC(1)}), BatchBestEffort, false,
+			[]string{"completed", "failed", "completed", "failed"}, models.FC(110), models.FC(107)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			alice := newTestUser(t, db, "alice")
+			bob := newTestUser(t, db, "bob")
+			carol := newTestUser(t, db, "carol")
+			dave := newTestUser(t, db, "dave")
+			db.Model(&models.Wallet{}).Where("user_id = ?", carol.ID).Update("frozen_at", time.Now())
+
+			wallets := NewWalletService(db)
+			result, err := wallets.BatchTransfer(alice.ID, tt.lines, tt.mode)
+			if tt.wantErr != errors.Is(err, ErrBatchFailed) {
+				t.Fatalf("BatchTransfer() = %v", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatal(err)
+			}
+
+			var statuses []string
+			for _, line := range result.Lines {
+				statuses = append(statuses, line.Status)
+			}
+			if strings.Join(statuses, ",") != strings.Join(tt.wantStatus, ",") {
+				t.Errorf("line statuses %v, want %v", statuses, tt.wantStatus)
+			}
+
+			if balance := walletBalance(t, db, bob.ID); balance != tt.wantBob {
+				t.Errorf("bob holds %s, want %s", balance, tt.wantBob)
+			}
+			if balance := walletBalance(t, db, dave.ID); balance != tt.wantDave {
+				t.Errorf("dave holds %s, want %s", balance, tt.wantDave)
+			}
+			want := models.FC(100).Sub(result.Total).Sub(result.Fees)
+			if balance := walletBalance(t, db, alice.ID); balance != want {
+				t.Errorf("alice holds %s, want %s", balance, want)
+			}
+
+			proof, err := NewLedgerService(db).GetSupplyProof()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if proof["ledger_balanced"] != true || proof["cache_matches_postings"] != true {
+				t.Errorf("ledger out of balance after the batch: %v", proof)
+			}
+		})
+	}
+}
+
+func TestParseBatchCSV(t *testing.T) {
+	lines, err := ParseBatchCSV(strings.NewReader("username,amount,description\nbob,10.5,Rent\ncarol,2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 || lines[0].ToUsername != "bob" || lines[0].Amount != models.MoneyFromFloat(10.5) ||
+		lines[0].Description != "Rent" || lines[1].Amount != models.FC(2) {
+		t.Errorf("parsed %+v", lines)
+	}
+
+	if _, err := ParseBatchCSV(strings.NewReader("bob,ten\n")); err == nil {
+		t.Error("accepted a non-numeric amount")
+	}
+}
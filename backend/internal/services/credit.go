@@ -0,0 +1,649 @@
+package services
+
+import (
+	"errors"
+	"faircoin/internal/models"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+)
+
+// MaxCreditReasonLength caps the length of a credit request's reason
+const MaxCreditReasonLength = 500
+
+// ErrCreditRequestNotFound is returned when a credit request does not exist
+var ErrCreditRequestNotFound = errors.New("credit request not found")
+
+// ErrCreditRequestDecided is returned when a credit request is no longer pending
+var ErrCreditRequestDecided = errors.New("credit request has already been decided")
+
+// ErrNotCouncilMember is returned when someone outside the council votes on
+// a credit request
+var ErrNotCouncilMember = errors.New("only council members can vote on credit requests")
+
+// CreditPolicy decides how far trusted members may spend below zero. The
+// formula limit grows with PFI above MinPFI and reaches its full size after
+// HistoryMonths of membership; the council can approve a larger limit up to
+// CouncilMaxLimit. Credit is interest-free: members repay it simply by
+// receiving payments.
+type CreditPolicy struct {
+	MinPFI          int          // Members below this PFI get no formula credit
+	BaseLimit       models.Money // Formula limit at MinPFI
+	PerPFIPoint     models.Money // Added for each PFI point above MinPFI
+	MaxLimit        models.Money // Cap on the formula limit, 0 disables formula credit
+	HistoryMonths   int          // Months of membership for the full formula limit, 0 for none
+	CouncilMaxLimit models.Money // Largest limit the council can approve, 0 disables council credit
+}
+
+// DefaultCreditPolicy returns the policy used when none is configured
+func DefaultCreditPolicy() CreditPolicy {
+	return CreditPolicy{
+		MinPFI:          70,
+		BaseLimit:       models.FC(50),
+		PerPFIPoint:     models.FC(5),
+		MaxLimit:        models.FC(200),
+		HistoryMonths:   12,
+		CouncilMaxLimit: models.FC(1000),
+	}
+}
+
+// NewCreditPolicy builds a credit policy from configuration values
+func NewCreditPolicy(minPFI int, baseLimit, perPFIPoint, maxLimit float64, historyMonths int,
+	councilMaxLimit float64) (CreditPolicy, error) {
+	if minPFI < 0 || minPFI > 100 {
+		return CreditPolicy{}, fmt.Errorf("credit minimum PFI must be between 0 and 100")
+	}
+	if baseLimit < 0 || perPFIPoint < 0 || maxLimit < 0 || councilMaxLimit < 0 {
+		return CreditPolicy{}, fmt.Errorf("credit limits cannot be negative")
+	}
+	if historyMonths < 0 {
+		return CreditPolicy{}, fmt.Errorf("credit history months cannot be negative")
+	}
+	return CreditPolicy{
+		MinPFI:          minPFI,
+		BaseLimit:       models.MoneyFromFloat(baseLimit),
+		PerPFIPoint:     models.MoneyFromFloat(perPFIPoint),
+		MaxLimit:        models.MoneyFromFloat(maxLimit),
+		HistoryMonths:   historyMonths,
+		CouncilMaxLimit: models.MoneyFromFloat(councilMaxLimit),
+	}, nil
+}
+
+// FormulaLimit returns the credit limit the user's PFI and membership
+// history earn at time t
+func (p CreditPolicy) FormulaLimit(user *models.User, t time.Time) models.Money {
+	if p.MaxLimit.IsZero() || user.PFI < p.MinPFI {
+		return 0
+	}
+	limit := models.MinMoney(p.BaseLimit.Add(p.PerPFIPoint.MulInt(int64(user.PFI-p.MinPFI))), p.MaxLimit)
+	if p.HistoryMonths > 0 {
+		if months := monthsBetween(user.CreatedAt, t); months < p.HistoryMonths {
+			limit = limit.MulFrac(int64(months), int64(p.HistoryMonths))
+		}
+	}
+	return limit
+}
+
+// monthsBetween counts the whole months from start to end
+func monthsBetween(start, end time.Time) int {
+	start, end = start.UTC(), end.UTC()
+	months := (end.Year()-start.Year())*12 + int(end.Month()-start.Month())
+	if end.Day() < start.Day() {
+		months--
+	}
+	if months < 0 {
+		return 0
+	}
+	return months
+}
+
+// SetCreditPolicy replaces the credit policy
+func (s *WalletService) SetCreditPolicy(policy CreditPolicy) {
+	s.credit = policy
+}
+
+// creditLine returns the user's credit line, or nil if they have none
+func creditLine(tx *gorm.DB, userID uuid.UUID) (*models.CreditLine, error) {
+	var line models.CreditLine
+	if err := tx.Where("user_id = ?", userID).First(&line).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get credit line: %w", err)
+	}
+	return &line, nil
+}
+
+// creditLimit returns the larger of the user's formula and council limits,
+// or zero while their credit is suspended
+func (s *WalletService) creditLimit(tx *gorm.DB, userID uuid.UUID) (models.Money, error) {
+	var user models.User
+	if err := tx.First(&user, "id = ?", userID).Error; err != nil {
+		return 0, fmt.Errorf("user not found: %w", err)
+	}
+	line, err := creditLine(tx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if line != nil && line.SuspendedAt != nil {
+		return 0, nil
+	}
+	limit := s.credit.FormulaLimit(&user, time.Now())
+	if line != nil {
+		limit = models.MaxMoney(limit, line.CouncilLimit)
+	}
+	return limit, nil
+}
+
+// availableToSpend returns what the wallet can spend including its credit
+// line, and the credit limit. Locked funds and savings pots stay reserved,
+// so a wallet holding them has that much less credit to draw on.
+func (s *WalletService) availableToSpend(tx *gorm.DB, wallet *models.Wallet) (available, limit models.Money, err error) {
+	if limit, err = s.creditLimit(tx, wallet.UserID); err != nil {
+		return 0, 0, err
+	}
+	own := wallet.Balance.Sub(wallet.LockedFC).Sub(wallet.PottedFC)
+	return models.MaxMoney(own.Add(limit), 0), limit, nil
+}
+
+// AvailableToSpend returns what the wallet can spend including its credit line
+func (s *WalletService) AvailableToSpend(wallet *models.Wallet) (models.Money, error) {
+	available, _, err := s.availableToSpend(s.db, wallet)
+	return available, err
+}
+
+// recordCreditMovement notes how a posting of amount to a user's ledger
+// account changed what they owe. Postings that leave the balance at or
+// above zero on both sides do not touch credit and are not recorded.
+func recordCreditMovement(tx *gorm.DB, userID, accountID uuid.UUID, entry *models.JournalEntry, amount models.Money) error {
+	var account models.LedgerAccount
+	if err := tx.Select("balance").Where("id = ?", accountID).First(&account).Error; err != nil {
+		return fmt.Errorf("failed to get account balance: %w", err)
+	}
+	after := account.Balance
+	before := after.Sub(amount)
+	if !before.IsNegative() && !after.IsNegative() {
+		return nil
+	}
+
+	owedBefore := models.MaxMoney(before.Neg(), 0)
+	owedAfter := models.MaxMoney(after.Neg(), 0)
+	movement := &models.CreditMovement{
+		UserID:        userID,
+		EntryID:       entry.ID,
+		TransactionID: entry.TransactionID,
+		Outstanding:   owedAfter,
+		CreatedAt:     entry.CreatedAt.UTC(),
+	}
+	if owedAfter > owedBefore {
+		movement.Drawn = owedAfter.Sub(owedBefore)
+	} else {
+		movement.Repaid = owedBefore.Sub(owedAfter)
+	}
+	if err := tx.Create(movement).Error; err != nil {
+		return fmt.Errorf("failed to record credit movement: %w", err)
+	}
+	return nil
+}
+
+// CreditStatus describes a member's credit line and what they owe on it
+type CreditStatus struct {
+	Limit           models.Money            `json:"limit"` // The larger of the formula and council limits
+	FormulaLimit    models.Money            `json:"formula_limit"`
+	CouncilLimit    models.Money            `json:"council_limit"`
+	Suspended       bool                    `json:"suspended"`
+	SuspendedAt     *time.Time              `json:"suspended_at,omitempty"`
+	SuspendedReason string                  `json:"suspended_reason,omitempty"`
+	Balance         models.Money            `json:"balance"`
+	Outstanding     models.Money            `json:"outstanding"` // Owed on the credit line
+	Available       models.Money            `json:"available"`   // Spendable including credit
+	TotalDrawn      models.Money            `json:"total_drawn"`
+	TotalRepaid     models.Money            `json:"total_repaid"`
+	Request         *models.CreditRequest   `json:"request,omitempty"` // Latest council request
+	Movements       []models.CreditMovement `json:"movements"`         // Most recent first
+}
+
+// GetCreditStatus returns the user's credit line, repayment history and
+// latest council request
+func (s *WalletService) GetCreditStatus(userID uuid.UUID) (*CreditStatus, error) {
+	var user models.User
+	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+	var wallet models.Wallet
+	if err := s.db.Where("user_id = ?", userID).First(&wallet).Error; err != nil {
+		return nil, fmt.Errorf("wallet not found: %w", err)
+	}
+	line, err := creditLine(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &CreditStatus{
+		FormulaLimit: s.credit.FormulaLimit(&user, time.Now()),
+		Balance:      wallet.Balance,
+		Outstanding:  models.MaxMoney(wallet.Balance.Neg(), 0),
+		Movements:    []models.CreditMovement{},
+	}
+	if line != nil {
+		status.CouncilLimit = line.CouncilLimit
+		status.Suspended = line.SuspendedAt != nil
+		status.SuspendedAt = line.SuspendedAt
+		status.SuspendedReason = line.SuspendedReason
+	}
+	if status.Available, status.Limit, err = s.availableToSpend(s.db, &wallet); err != nil {
+		return nil, err
+	}
+
+	var totals struct {
+		Drawn  models.Money
+		Repaid models.Money
+	}
+	if err := s.db.Model(&models.CreditMovement{}).Where("user_id = ?", userID).
+		Select("COALESCE(SUM(drawn), 0) as drawn, COALESCE(SUM(repaid), 0) as repaid").Scan(&totals).Error; err != nil {
+		return nil, fmt.Errorf("failed to total credit movements: %w", err)
+	}
+	status.TotalDrawn, status.TotalRepaid = totals.Drawn, totals.Repaid
+
+	if err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Limit(20).
+		Find(&status.Movements).Error; err != nil {
+		return nil, fmt.Errorf("failed to get credit movements: %w", err)
+	}
+
+	var request models.CreditRequest
+	if err := s.db.Preload("Votes").Where("user_id = ?", userID).Order("created_at DESC").
+		First(&request).Error; err == nil {
+		status.Request = &request
+	} else if !gorm.IsRecordNotFoundError(err) {
+		return nil, fmt.Errorf("failed to get credit request: %w", err)
+	}
+	return status, nil
+}
+
+// RequestCredit asks the council for a credit limit. Members can have one
+// pending request at a time.
+func (s *WalletService) RequestCredit(userID uuid.UUID, limit models.Money, reason string) (*models.CreditRequest, error) {
+	if s.credit.CouncilMaxLimit.IsZero() {
+		return nil, fmt.Errorf("council-approved credit is disabled")
+	}
+	if !limit.IsPositive() {
+		return nil, fmt.Errorf("limit must be positive")
+	}
+	if limit > s.credit.CouncilMaxLimit {
+		return nil, fmt.Errorf("limit can be at most %s FC", s.credit.CouncilMaxLimit)
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, fmt.Errorf("a reason is required")
+	}
+	if len(reason) > MaxCreditReasonLength {
+		return nil, fmt.Errorf("reason must be at most %d characters", MaxCreditReasonLength)
+	}
+
+	var pending int
+	if err := s.db.Model(&models.CreditRequest{}).
+		Where("user_id = ? AND status = ?", userID, models.CreditRequestPending).Count(&pending).Error; err != nil {
+		return nil, fmt.Errorf("failed to check pending requests: %w", err)
+	}
+	if pending > 0 {
+		return nil, fmt.Errorf("you already have a pending credit request")
+	}
+
+	request := &models.CreditRequest{
+		UserID: userID,
+		Limit:  limit,
+		Reason: reason,
+		Status: models.CreditRequestPending,
+	}
+	if err := s.db.Create(request).Error; err != nil {
+		return nil, fmt.Errorf("failed to create credit request: %w", err)
+	}
+	return request, nil
+}
+
+// GetCreditRequests returns credit requests with the given status, newest
+// first, or all of them when status is empty
+func (s *WalletService) GetCreditRequests(status models.CreditRequestStatus, limit int) ([]models.CreditRequest, error) {
+	query := s.db.Preload("User").Preload("Votes")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var requests []models.CreditRequest
+	if err := query.Order("created_at DESC").Limit(limit).Find(&requests).Error; err != nil {
+		return nil, fmt.Errorf("failed to get credit requests: %w", err)
+	}
+	return requests, nil
+}
+
+// VoteOnCreditRequest records a council member's vote. A majority of the
+// current council decides the request; on approval the member's council
+// limit becomes the requested limit.
+func (s *WalletService) VoteOnCreditRequest(requestID, memberID uuid.UUID, approve bool, comment string) (*models.CreditRequest, error) {
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	members, err := councilMembers(tx)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to get council members: %w", err)
+	}
+	isMember := false
+	for _, member := range members {
+		if member.ID == memberID {
+			isMember = true
+			break
+		}
+	}
+	if !isMember {
+		tx.Rollback()
+		return nil, ErrNotCouncilMember
+	}
+
+	var request models.CreditRequest
+	if err := tx.First(&request, "id = ?", requestID).Error; err != nil {
+		tx.Rollback()
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrCreditRequestNotFound
+		}
+		return nil, fmt.Errorf("failed to get credit request: %w", err)
+	}
+	if request.Status != models.CreditRequestPending {
+		tx.Rollback()
+		return nil, fmt.Errorf("%w: it is %s", ErrCreditRequestDecided, request.Status)
+	}
+	if request.UserID == memberID {
+		tx.Rollback()
+		return nil, fmt.Errorf("cannot vote on your own credit request")
+	}
+
+	var existing int
+	if err := tx.Model(&models.CreditVote{}).
+		Where("request_id = ? AND member_id = ?", requestID, memberID).Count(&existing).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to check existing vote: %w", err)
+	}
+	if existing > 0 {
+		tx.Rollback()
+		return nil, fmt.Errorf("you have already voted on this request")
+	}
+	if err := tx.Create(&models.CreditVote{
+		RequestID: requestID,
+		MemberID:  memberID,
+		Approve:   approve,
+		Comment:   strings.TrimSpace(comment),
+		CreatedAt: time.Now(),
+	}).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to record vote: %w", err)
+	}
+
+	column := "votes_against"
+	if approve {
+		column = "votes_for"
+		request.VotesFor++
+	} else {
+		request.VotesAgainst++
+	}
+	updates := map[string]interface{}{column: gorm.Expr(column + " + 1")}
+
+	// The applicant does not vote, so the majority is of the rest of the council
+	voters := len(members)
+	for _, member := range members {
+		if member.ID == request.UserID {
+			voters--
+		}
+	}
+	majority := voters/2 + 1
+	now := time.Now().UTC()
+	switch {
+	case request.VotesFor >= majority:
+		request.Status = models.CreditRequestApproved
+	case request.VotesAgainst >= majority:
+		request.Status = models.CreditRequestRejected
+	}
+	if request.Status != models.CreditRequestPending {
+		updates["status"] = request.Status
+		updates["decided_at"] = now
+		request.DecidedAt = &now
+	}
+
+	// Only a still-pending request can take the vote
+	result := tx.Model(&models.CreditRequest{}).
+		Where("id = ? AND status = ?", requestID, models.CreditRequestPending).Updates(updates)
+	if result.Error != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to update credit request: %w", result.Error)
+	}
+	if result.RowsAffected != 1 {
+		tx.Rollback()
+		return nil, ErrCreditRequestDecided
+	}
+
+	if request.Status == models.CreditRequestApproved {
+		if err := s.grantCouncilLimit(tx, &request, now); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit vote: %w", err)
+	}
+	return &request, nil
+}
+
+// grantCouncilLimit sets the council limit of an approved request's member
+func (s *WalletService) grantCouncilLimit(tx *gorm.DB, request *models.CreditRequest, now time.Time) error {
+	line, err := creditLine(tx, request.UserID)
+	if err != nil {
+		return err
+	}
+	if line == nil {
+		line = &models.CreditLine{UserID: request.UserID}
+	}
+	line.CouncilLimit = request.Limit
+	line.RequestID = &request.ID
+	line.ApprovedAt = &now
+	return saveCreditLine(tx, line)
+}
+
+// saveCreditLine creates the line or updates an existing one
+func saveCreditLine(tx *gorm.DB, line *models.CreditLine) error {
+	save := tx.Save
+	if line.ID == uuid.Nil {
+		save = tx.Create
+	}
+	if err := save(line).Error; err != nil {
+		return fmt.Errorf("failed to save credit line: %w", err)
+	}
+	return nil
+}
+
+// SuspendCredit stops a member from drawing on credit. What they already
+// owe stays owed and is repaid as usual.
+func (s *WalletService) SuspendCredit(userID, adminID uuid.UUID, reason string) (*models.CreditLine, error) {
+	if reason == "" {
+		return nil, fmt.Errorf("a reason is required to suspend credit")
+	}
+	now := time.Now().UTC()
+	return s.updateCreditLine(userID, adminID, models.WalletAuditSuspendCredit, reason,
+		func(line *models.CreditLine) error {
+			if line.SuspendedAt != nil {
+				return fmt.Errorf("credit is already suspended")
+			}
+			line.SuspendedAt = &now
+			line.SuspendedReason = reason
+			return nil
+		})
+}
+
+// ResumeCredit lifts a credit suspension
+func (s *WalletService) ResumeCredit(userID, adminID uuid.UUID, reason string) (*models.CreditLine, error) {
+	if reason == "" {
+		return nil, fmt.Errorf("a reason is required to resume credit")
+	}
+	return s.updateCreditLine(userID, adminID, models.WalletAuditResumeCredit, reason,
+		func(line *models.CreditLine) error {
+			if line.SuspendedAt == nil {
+				return fmt.Errorf("credit is not suspended")
+			}
+			line.SuspendedAt = nil
+			line.SuspendedReason = ""
+			return nil
+		})
+}
+
+// updateCreditLine applies change to the user's credit line, creating it if
+// needed, and records the change in the wallet audit trail
+func (s *WalletService) updateCreditLine(userID, adminID uuid.UUID, action models.WalletAuditAction, reason string,
+	change func(line *models.CreditLine) error) (*models.CreditLine, error) {
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	var user models.User
+	if err := tx.First(&user, "id = ?", userID).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+	line, err := creditLine(tx, userID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if line == nil {
+		line = &models.CreditLine{UserID: userID}
+	}
+
+	before := line.SuspendedAt
+	if err := change(line); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := saveCreditLine(tx, line); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := s.audit(tx, userID, adminID, action, reason, map[string]interface{}{
+		"credit_suspended_at": map[string]interface{}{"before": before, "after": line.SuspendedAt},
+	}); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit credit line change: %w", err)
+	}
+	return line, nil
+}
+
+// CreditExposure sums up how much credit the system has extended
+type CreditExposure struct {
+	UsersInCredit      int          `json:"users_in_credit"`
+	TotalOutstanding   models.Money `json:"total_outstanding"`
+	LargestOutstanding models.Money `json:"largest_outstanding"`
+	UsersWithCredit    int          `json:"users_with_credit"` // Members with a positive limit
+	TotalLimits        models.Money `json:"total_limits"`
+	Utilization        float64      `json:"utilization"`      // Outstanding share of total limits
+	UsersOverLimit     int          `json:"users_over_limit"` // Owing more than their current limit
+	CouncilLines       int          `json:"council_lines"`
+	SuspendedLines     int          `json:"suspended_lines"`
+	PendingRequests    int          `json:"pending_requests"`
+	DrawnThisMonth     models.Money `json:"drawn_this_month"`
+	RepaidThisMonth    models.Money `json:"repaid_this_month"`
+	GeneratedAt        time.Time    `json:"generated_at"`
+}
+
+// GetCreditExposure reports system-wide credit: what is owed, how much more
+// could be drawn, and this month's drawing and repayment
+func (s *WalletService) GetCreditExposure() (*CreditExposure, error) {
+	now := time.Now().UTC()
+	exposure := &CreditExposure{GeneratedAt: now}
+
+	var lines []models.CreditLine
+	if err := s.db.Find(&lines).Error; err != nil {
+		return nil, fmt.Errorf("failed to get credit lines: %w", err)
+	}
+	linesByUser := make(map[uuid.UUID]models.CreditLine, len(lines))
+	candidateIDs := make([]uuid.UUID, 0, len(lines))
+	for _, line := range lines {
+		linesByUser[line.UserID] = line
+		candidateIDs = append(candidateIDs, line.UserID)
+		if line.CouncilLimit.IsPositive() {
+			exposure.CouncilLines++
+		}
+		if line.SuspendedAt != nil {
+			exposure.SuspendedLines++
+		}
+	}
+
+	var debtors []models.Wallet
+	if err := s.db.Where("balance < 0").Find(&debtors).Error; err != nil {
+		return nil, fmt.Errorf("failed to get wallets in credit: %w", err)
+	}
+	owed := make(map[uuid.UUID]models.Money, len(debtors))
+	for _, wallet := range debtors {
+		outstanding := wallet.Balance.Neg()
+		owed[wallet.UserID] = outstanding
+		candidateIDs = append(candidateIDs, wallet.UserID)
+		exposure.UsersInCredit++
+		exposure.TotalOutstanding = exposure.TotalOutstanding.Add(outstanding)
+		exposure.LargestOutstanding = models.MaxMoney(exposure.LargestOutstanding, outstanding)
+	}
+
+	// Everyone who could have a limit: formula-eligible members, council
+	// lines and anyone who owes
+	query := s.db.Where("id IN (?)", candidateIDs)
+	if !s.credit.MaxLimit.IsZero() {
+		query = query.Or("pfi >= ?", s.credit.MinPFI)
+	}
+	var users []models.User
+	if len(candidateIDs) > 0 || !s.credit.MaxLimit.IsZero() {
+		if err := query.Find(&users).Error; err != nil {
+			return nil, fmt.Errorf("failed to get credit users: %w", err)
+		}
+	}
+	for i := range users {
+		limit := s.credit.FormulaLimit(&users[i], now)
+		if line, ok := linesByUser[users[i].ID]; ok {
+			if line.SuspendedAt != nil {
+				limit = 0
+			} else {
+				limit = models.MaxMoney(limit, line.CouncilLimit)
+			}
+		}
+		if limit.IsPositive() {
+			exposure.UsersWithCredit++
+			exposure.TotalLimits = exposure.TotalLimits.Add(limit)
+		}
+		if owed[users[i].ID] > limit {
+			exposure.UsersOverLimit++
+		}
+	}
+	if exposure.TotalLimits.IsPositive() {
+		exposure.Utilization = exposure.TotalOutstanding.Float64() / exposure.TotalLimits.Float64()
+	}
+
+	if err := s.db.Model(&models.CreditRequest{}).Where("status = ?", models.CreditRequestPending).
+		Count(&exposure.PendingRequests).Error; err != nil {
+		return nil, fmt.Errorf("failed to count credit requests: %w", err)
+	}
+
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	var month struct {
+		Drawn  models.Money
+		Repaid models.Money
+	}
+	if err := s.db.Model(&models.CreditMovement{}).Where("created_at >= ?", monthStart).
+		Select("COALESCE(SUM(drawn), 0) as drawn, COALESCE(SUM(repaid), 0) as repaid").Scan(&month).Error; err != nil {
+		return nil, fmt.Errorf("failed to total credit movements: %w", err)
+	}
+	exposure.DrawnThisMonth, exposure.RepaidThisMonth = month.Drawn, month.Repaid
+	return exposure, nil
+}
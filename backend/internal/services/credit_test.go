@@ -0,0 +1,77 @@
+package services
+
+import (
+	"errors"
+	"faircoin/internal/models"
+	"testing"
+)
+
+func TestCreditDrawAndRepay(t *testing.T) {
+	db := newTestDB(t)
+	alice := newTestUser(t, db, "alice")
+	bob := newTestUser(t, db, "bob")
+	admin := newTestUser(t, db, "admin")
+
+	policy, err := NewCreditPolicy(0, 50, 0, 50, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wallets := NewWalletService(db)
+	wallets.SetCreditPolicy(policy)
+
+	// 130 FC plus the 0.13 FC fee takes alice 30.13 FC below zero
+	if _, err := wallets.Transfer(alice.ID, bob.ID, models.FC(130), "test"); err != nil {
+		t.Fatal(err)
+	}
+	drawn := models.MoneyFromFloat(30.13)
+	if balance := walletBalance(t, db, alice.ID); balance != drawn.Neg() {
+		t.Fatalf("alice holds %s, want -%s", balance, drawn)
+	}
+	if _, err := wallets.Transfer(alice.ID, bob.ID, models.FC(20), "test"); !errors.Is(err, ErrInsufficientBalance) {
+		t.Errorf("drawing past the limit: err = %v", err)
+	}
+
+	status, err := wallets.GetCreditStatus(alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Limit != models.FC(50) || status.Outstanding != drawn || status.Available != models.FC(50).Sub(drawn) {
+		t.Errorf("limit %s, outstanding %s, available %s, want 50, %s and %s",
+			status.Limit, status.Outstanding, status.Available, drawn, models.FC(50).Sub(drawn))
+	}
+
+	// Suspended credit stops new draws but not repayments
+	if _, err := wallets.SuspendCredit(alice.ID, admin.ID, "review"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wallets.Transfer(alice.ID, bob.ID, models.FC(1), "test"); !errors.Is(err, ErrInsufficientBalance) {
+		t.Errorf("drawing on suspended credit: err = %v", err)
+	}
+	if _, err := wallets.Transfer(bob.ID, alice.ID, models.FC(20), "test"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wallets.Transfer(bob.ID, alice.ID, models.FC(20), "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err = wallets.GetCreditStatus(alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Balance != models.FC(40).Sub(drawn) || !status.Outstanding.IsZero() {
+		t.Errorf("balance %s, outstanding %s after repaying, want %s and 0",
+			status.Balance, status.Outstanding, models.FC(40).Sub(drawn))
+	}
+	if status.TotalDrawn != drawn || status.TotalRepaid != drawn || len(status.Movements) != 3 {
+		t.Errorf("drawn %s, repaid %s in %d movements, want %s, %s and 3",
+			status.TotalDrawn, status.TotalRepaid, len(status.Movements), drawn, drawn)
+	}
+
+	proof, err := NewLedgerService(db).GetSupplyProof()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proof["ledger_balanced"] != true || proof["supply_matches"] != true {
+		t.Errorf("ledger out of balance after drawing credit: %v", proof)
+	}
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronRejects(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"a * * * *",
+		"1-b * * * *",
+		"1,,2 * * * *",
+	}
+	for _, expr := range tests {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("parseCron(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// A Wednesday
+	from := time.Date(2026, 4, 15, 10, 30, 45, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"* * * * *", from, time.Date(2026, 4, 15, 10, 31, 0, 0, time.UTC)},
+		{"30 10 * * *", from, time.Date(2026, 4, 16, 10, 30, 0, 0, time.UTC)},
+		{"*/15 * * * *", from, time.Date(2026, 4, 15, 10, 45, 0, 0, time.UTC)},
+		{"5/20 * * * *", from, time.Date(2026, 4, 15, 10, 45, 0, 0, time.UTC)},
+		{"0 9 1 * *", from, time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", from, time.Date(2026, 4, 15, 13, 0, 0, 0, time.UTC)},
+		{"0 0 * * 1-5", from, time.Date(2026, 4, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 0", from, time.Date(2026, 4, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", from, time.Date(2026, 4, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1,15 * *", from, time.Date(2026, 4, 15, 12, 0, 0, 0, time.UTC)},
+		{"0 0 31 * *", from, time.Date(2026, 5, 31, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", from, time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 1 *", from, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		// Restricted day of month and day of week match if either does
+		{"0 0 20 * 5", from, time.Date(2026, 4, 17, 0, 0, 0, 0, time.UTC)},
+		{"0 0 16 * 0", from, time.Date(2026, 4, 16, 0, 0, 0, 0, time.UTC)},
+		// Exactly on a match moves to the next one
+		{"30 10 * * *", time.Date(2026, 4, 15, 10, 30, 0, 0, time.UTC), time.Date(2026, 4, 16, 10, 30, 0, 0, time.UTC)},
+		{"59 23 31 12 *", time.Date(2026, 12, 31, 23, 58, 0, 0, time.UTC), time.Date(2026, 12, 31, 23, 59, 0, 0, time.UTC)},
+		{"0 0 30 2 *", from, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			schedule, err := parseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("parseCron(%q): %v", tt.expr, err)
+			}
+			if got := schedule.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCronNextKeepsLocation(t *testing.T) {
+	zone := time.FixedZone("UTC+7", 7*3600)
+	schedule, err := parseCron("0 9 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := schedule.Next(time.Date(2026, 4, 15, 10, 0, 0, 0, zone))
+	if want := time.Date(2026, 4, 16, 9, 0, 0, 0, zone); !got.Equal(want) || got.Location() != zone {
+		t.Errorf("Next = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,387 @@
+package services
+
+import (
+	"encoding/json"
+	"faircoin/internal/models"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+)
+
+// MaxDemurrageRate is the highest monthly demurrage rate that can be set
+const MaxDemurrageRate = 0.1
+
+// DemurrageDestination decides where charged demurrage goes
+type DemurrageDestination string
+
+const (
+	// DemurrageBurn destroys the charged FairCoins
+	DemurrageBurn DemurrageDestination = "burn"
+	// DemurrageTreasury pays the charged FairCoins into the community treasury
+	DemurrageTreasury DemurrageDestination = "treasury"
+)
+
+// DemurrageService charges a monthly holding fee on the part of each wallet
+// above a threshold. Locked funds are never charged and system ledger
+// accounts have no wallet, so they are exempt too.
+type DemurrageService struct {
+	db          *gorm.DB
+	ledger      *LedgerService
+	threshold   models.Money
+	destination DemurrageDestination
+}
+
+// NewDemurrageService creates a new demurrage service. It charges nothing
+// until a rate is recorded with Configure or a monetary policy proposal.
+func NewDemurrageService(db *gorm.DB) *DemurrageService {
+	return &DemurrageService{
+		db:          db,
+		ledger:      NewLedgerService(db),
+		threshold:   models.FC(1000),
+		destination: DemurrageBurn,
+	}
+}
+
+// GetDB returns the database connection
+func (s *DemurrageService) GetDB() *gorm.DB {
+	return s.db
+}
+
+// Configure sets the threshold and destination from configuration and
+// records rate as the current rate if it changed. A rate set by governance
+// takes precedence and is left alone.
+func (s *DemurrageService) Configure(rate, threshold float64, destination string) error {
+	if err := validateDemurrageRate(rate); err != nil {
+		return err
+	}
+	if threshold < 0 {
+		return fmt.Errorf("demurrage threshold cannot be negative")
+	}
+	dest := DemurrageDestination(destination)
+	if dest != DemurrageBurn && dest != DemurrageTreasury {
+		return fmt.Errorf("unknown demurrage destination: %q", destination)
+	}
+	s.threshold = models.MoneyFromFloat(threshold)
+	s.destination = dest
+
+	current, err := s.CurrentRate(s.db)
+	if err != nil {
+		return err
+	}
+	if current != nil && (current.Source == PolicySourceGovernance || current.Rate == rate) {
+		return nil
+	}
+	if current == nil && rate == 0 {
+		return nil
+	}
+	return s.db.Create(&models.DemurrageRate{
+		Rate:      rate,
+		Source:    PolicySourceConfig,
+		CreatedAt: time.Now().UTC(),
+	}).Error
+}
+
+// CurrentRate returns the rate setting in force, or nil if none was recorded
+func (s *DemurrageService) CurrentRate(tx *gorm.DB) (*models.DemurrageRate, error) {
+	var rate models.DemurrageRate
+	if err := tx.Order("created_at DESC").First(&rate).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load demurrage rate: %w", err)
+	}
+	return &rate, nil
+}
+
+// rate returns the monthly rate in force, 0 when demurrage is off
+func (s *DemurrageService) rate(tx *gorm.DB) (float64, error) {
+	current, err := s.CurrentRate(tx)
+	if err != nil || current == nil {
+		return 0, err
+	}
+	return current.Rate, nil
+}
+
+// charge returns the demurrage on a wallet with the given balance and
+// locked amount
+func (s *DemurrageService) charge(balance, locked models.Money, rate float64) (chargeable, charge models.Money) {
+	chargeable = balance.Sub(locked).Sub(s.threshold)
+	if !chargeable.IsPositive() {
+		return 0, 0
+	}
+	return chargeable, chargeable.MulRate(rate)
+}
+
+// ProcessMonthlyDemurrage charges this month's demurrage once. Each charge
+// is recorded as a burn or, when the treasury is the destination, as a fee.
+func (s *DemurrageService) ProcessMonthlyDemurrage() (*models.DemurrageRun, error) {
+	period := time.Now().Format("2006-01")
+
+	var existing models.DemurrageRun
+	if err := s.db.Where("period = ?", period).First(&existing).Error; err == nil {
+		return nil, nil // Already processed
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	rate, err := s.rate(tx)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if rate == 0 {
+		tx.Rollback()
+		return nil, nil
+	}
+
+	// The unique period claims the month so a concurrent run cannot charge twice
+	run := &models.DemurrageRun{
+		Period:      period,
+		Rate:        rate,
+		Threshold:   s.threshold,
+		Destination: string(s.destination),
+		CreatedAt:   time.Now(),
+	}
+	if err := tx.Create(run).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to record demurrage run: %w", err)
+	}
+
+	accountType, transactionType := models.AccountTypeBurn, models.TransactionTypeBurn
+	if s.destination == DemurrageTreasury {
+		accountType, transactionType = models.AccountTypeTreasury, models.TransactionTypeFee
+	}
+	sink, err := s.ledger.SystemAccount(tx, accountType)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	var wallets []models.Wallet
+	if err := tx.Where("balance - locked_fc > ?", s.threshold).Find(&wallets).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to find wallets: %w", err)
+	}
+
+	description := fmt.Sprintf("Demurrage for %s", period)
+	for _, wallet := range wallets {
+		chargeable, charge := s.charge(wallet.Balance, wallet.LockedFC, rate)
+		if !charge.IsPositive() {
+			continue
+		}
+		// Pot money is idle money too; cover what the spendable balance cannot
+		if spendable := wallet.Spendable(); charge > spendable {
+			if err := takeFromPots(tx, wallet.UserID, charge.Sub(spendable)); err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		}
+
+		metadata, _ := json.Marshal(map[string]interface{}{
+			"demurrage_period": period,
+			"demurrage_rate":   rate,
+			"chargeable":       chargeable,
+		})
+		transaction := &models.Transaction{
+			UserID:      wallet.UserID,
+			Type:        transactionType,
+			Amount:      charge,
+			Description: description,
+			Status:      models.TransactionStatusCompleted,
+			Metadata:    string(metadata),
+			CreatedAt:   time.Now(),
+		}
+		if err := tx.Create(transaction).Error; err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to create transaction: %w", err)
+		}
+
+		account, err := s.ledger.UserAccount(tx, wallet.UserID)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if _, err := s.ledger.Move(tx, &transaction.ID, description, account, sink, charge); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to post demurrage: %w", err)
+		}
+
+		run.WalletsCharged++
+		run.TotalCharged = run.TotalCharged.Add(charge)
+	}
+
+	if err := tx.Save(run).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to update demurrage run: %w", err)
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit demurrage: %w", err)
+	}
+	return run, nil
+}
+
+// DemurrageProjectionMonth is the projected charge for one month
+type DemurrageProjectionMonth struct {
+	Period       string       `json:"period"`
+	Charge       models.Money `json:"charge"`
+	BalanceAfter models.Money `json:"balance_after"`
+}
+
+// DemurrageProjection shows a user what demurrage will cost them
+type DemurrageProjection struct {
+	Enabled     bool                       `json:"enabled"`
+	Rate        float64                    `json:"rate"`
+	Threshold   models.Money               `json:"threshold"`
+	Destination DemurrageDestination       `json:"destination"`
+	Balance     models.Money               `json:"balance"`
+	Locked      models.Money               `json:"locked"`
+	Chargeable  models.Money               `json:"chargeable"`
+	NextPeriod  string                     `json:"next_period"`
+	NextCharge  models.Money               `json:"next_charge"`
+	Months      []DemurrageProjectionMonth `json:"months"`
+}
+
+// Project returns the user's next demurrage charge and the charges over the
+// following months, assuming the balance and locks stay as they are
+func (s *DemurrageService) Project(userID uuid.UUID, months int) (*DemurrageProjection, error) {
+	var wallet models.Wallet
+	if err := s.db.Where("user_id = ?", userID).First(&wallet).Error; err != nil {
+		return nil, fmt.Errorf("wallet not found: %w", err)
+	}
+
+	rate, err := s.rate(s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	// This month's charge is still due if it has not run yet
+	now := time.Now()
+	next := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	var existing models.DemurrageRun
+	if err := s.db.Where("period = ?", next.Format("2006-01")).First(&existing).Error; err == nil {
+		next = next.AddDate(0, 1, 0)
+	}
+
+	projection := &DemurrageProjection{
+		Enabled:     rate > 0,
+		Rate:        rate,
+		Threshold:   s.threshold,
+		Destination: s.destination,
+		Balance:     wallet.Balance,
+		Locked:      wallet.LockedFC,
+		NextPeriod:  next.Format("2006-01"),
+		Months:      []DemurrageProjectionMonth{},
+	}
+	projection.Chargeable, projection.NextCharge = s.charge(wallet.Balance, wallet.LockedFC, rate)
+
+	balance := wallet.Balance
+	for i := 0; i < months; i++ {
+		_, charge := s.charge(balance, wallet.LockedFC, rate)
+		balance = balance.Sub(charge)
+		projection.Months = append(projection.Months, DemurrageProjectionMonth{
+			Period:       next.AddDate(0, i, 0).Format("2006-01"),
+			Charge:       charge,
+			BalanceAfter: balance,
+		})
+	}
+	return projection, nil
+}
+
+// GetRuns returns the most recent demurrage runs, newest first
+func (s *DemurrageService) GetRuns(limit int) ([]models.DemurrageRun, error) {
+	var runs []models.DemurrageRun
+	err := s.db.Order("period DESC").Limit(limit).Find(&runs).Error
+	return runs, err
+}
+
+// ApplyProposal puts the demurrage rate of a passed monetary policy
+// proposal into force
+func (s *DemurrageService) ApplyProposal(proposalID uuid.UUID) (*models.DemurrageRate, error) {
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	var proposal models.Proposal
+	if err := tx.First(&proposal, "id = ?", proposalID).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("proposal not found: %w", err)
+	}
+	if proposal.Type != models.ProposalTypeMonetaryPolicy || proposal.DemurrageRate == nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("proposal does not set a demurrage rate")
+	}
+	if proposal.Status != models.ProposalStatusPassed {
+		tx.Rollback()
+		return nil, fmt.Errorf("proposal has not passed")
+	}
+	if err := validateDemurrageRate(*proposal.DemurrageRate); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// Claim the proposal first so a concurrent run cannot apply it twice
+	now := time.Now().UTC()
+	result := tx.Model(&models.Proposal{}).
+		Where("id = ? AND executed_at IS NULL", proposal.ID).
+		Update("executed_at", now)
+	if result.Error != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to mark proposal executed: %w", result.Error)
+	}
+	if result.RowsAffected != 1 {
+		tx.Rollback()
+		return nil, fmt.Errorf("proposal has already been executed")
+	}
+
+	rate := &models.DemurrageRate{
+		Rate:       *proposal.DemurrageRate,
+		Source:     PolicySourceGovernance,
+		ProposalID: &proposal.ID,
+		CreatedAt:  now,
+	}
+	if err := tx.Create(rate).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to record demurrage rate: %w", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit demurrage rate: %w", err)
+	}
+	return rate, nil
+}
+
+// ApplyPassedProposals puts every passed, unapplied demurrage rate change
+// into force, oldest first
+func (s *DemurrageService) ApplyPassedProposals() error {
+	var proposals []models.Proposal
+	if err := s.db.Where("type = ? AND status = ? AND demurrage_rate IS NOT NULL AND executed_at IS NULL",
+		models.ProposalTypeMonetaryPolicy, models.ProposalStatusPassed).
+		Order("end_time ASC").Find(&proposals).Error; err != nil {
+		return err
+	}
+
+	var failed int
+	for _, proposal := range proposals {
+		if _, err := s.ApplyProposal(proposal.ID); err != nil {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d demurrage rate proposals could not be applied", failed, len(proposals))
+	}
+	return nil
+}
+
+func validateDemurrageRate(rate float64) error {
+	if rate < 0 || rate > MaxDemurrageRate {
+		return fmt.Errorf("demurrage rate must be between 0 and %v", MaxDemurrageRate)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"faircoin/internal/models"
+	"testing"
+	"time"
+)
+
+func TestProcessMonthlyDemurrage(t *testing.T) {
+	tests := []struct {
+		destination  DemurrageDestination
+		wantTreasury models.Money
+	}{
+		{DemurrageBurn, 0},
+		{DemurrageTreasury, models.MoneyFromFloat(0.8)},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.destination), func(t *testing.T) {
+			db := newTestDB(t)
+			alice := newTestUser(t, db, "alice")
+			bob := newTestUser(t, db, "bob")
+
+			// Locked funds are never charged: alice pays on 100 - 20 - 50
+			if _, err := NewVestingService(db).CreateLock(LockRequest{UserID: alice.ID, Kind: models.LockKindTimeLock,
+				Amount: models.FC(20), EndTime: time.Now().Add(24 * time.Hour)}); err != nil {
+				t.Fatal(err)
+			}
+
+			demurrage := NewDemurrageService(db)
+			if run, err := demurrage.ProcessMonthlyDemurrage(); err != nil || run != nil {
+				t.Fatalf("run without a rate = %v, %v", run, err)
+			}
+			if err := demurrage.Configure(0.01, 50, string(tt.destination)); err != nil {
+				t.Fatal(err)
+			}
+
+			run, err := demurrage.ProcessMonthlyDemurrage()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if run.WalletsCharged != 2 || run.TotalCharged != models.MoneyFromFloat(0.8) {
+				t.Errorf("charged %d wallets %s FC, want 2 and 0.8", run.WalletsCharged, run.TotalCharged)
+			}
+			if again, err := demurrage.ProcessMonthlyDemurrage(); err != nil || again != nil {
+				t.Errorf("second run in the month = %v, %v", again, err)
+			}
+
+			if balance := walletBalance(t, db, alice.ID); balance != models.MoneyFromFloat(99.7) {
+				t.Errorf("alice holds %s, want 99.7", balance)
+			}
+			if balance := walletBalance(t, db, bob.ID); balance != models.MoneyFromFloat(99.5) {
+				t.Errorf("bob holds %s, want 99.5", balance)
+			}
+			treasury, err := NewTreasuryService(db).GetBalance()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if treasury != tt.wantTreasury {
+				t.Errorf("treasury holds %s, want %s", treasury, tt.wantTreasury)
+			}
+
+			proof, err := NewLedgerService(db).GetSupplyProof()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if proof["ledger_balanced"] != true || proof["supply_matches"] != true {
+				t.Errorf("ledger out of balance after demurrage: %v", proof)
+			}
+		})
+	}
+}
@@ -0,0 +1,367 @@
+package services
+
+import (
+	"errors"
+	"faircoin/internal/models"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultEscrowTimeout is how long an escrow stays open when no timeout is given
+	DefaultEscrowTimeout = 14 * 24 * time.Hour
+	// MaxEscrowTimeout is the longest an escrow may stay open
+	MaxEscrowTimeout = 90 * 24 * time.Hour
+)
+
+// ErrEscrowNotOpen is returned when an escrow has already been resolved
+var ErrEscrowNotOpen = errors.New("escrow is not open")
+
+// OpenEscrow moves amount plus the transfer fee from the buyer's wallet into
+// the escrow account. The linked transaction is authorized once the funds
+// are held, and completed or cancelled when the escrow is released to the
+// merchant or refunded to the buyer.
+func (s *WalletService) OpenEscrow(buyerID, merchantID uuid.UUID, amount models.Money, description string,
+	timeout time.Duration, timeoutAction models.EscrowTimeoutAction) (*models.Escrow, error) {
+	if !amount.IsPositive() {
+		return nil, fmt.Errorf("amount must be positive")
+	}
+	if buyerID == merchantID {
+		return nil, fmt.Errorf("cannot open an escrow with yourself")
+	}
+	if timeout == 0 {
+		timeout = DefaultEscrowTimeout
+	}
+	if timeout < time.Hour || timeout > MaxEscrowTimeout {
+		return nil, fmt.Errorf("escrow timeout must be between 1 hour and %d days", int(MaxEscrowTimeout.Hours()/24))
+	}
+	if timeoutAction == "" {
+		timeoutAction = models.EscrowTimeoutRelease
+	}
+	if timeoutAction != models.EscrowTimeoutRelease && timeoutAction != models.EscrowTimeoutRefund {
+		return nil, fmt.Errorf("unknown escrow timeout action: %s", timeoutAction)
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	var merchant models.User
+	if err := tx.First(&merchant, "id = ? AND is_merchant = ?", merchantID, true).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("merchant not found: %w", err)
+	}
+
+	quote, err := s.fees.Quote(tx, buyerID, merchantID, amount)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	fee := quote.Fee
+
+	var buyerWallet models.Wallet
+	if err := tx.Where("user_id = ?", buyerID).First(&buyerWallet).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("buyer wallet not found: %w", err)
+	}
+	if buyerWallet.Spendable() < amount+fee {
+		tx.Rollback()
+		return nil, fmt.Errorf("insufficient spendable balance")
+	}
+	if err := s.checkSend(tx, &buyerWallet, amount); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	var merchantWallet models.Wallet
+	if err := tx.Where("user_id = ?", merchantID).First(&merchantWallet).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("merchant wallet not found: %w", err)
+	}
+	if err := checkReceive(&merchantWallet); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// Refuse up front if the merchant could not receive the payment
+	if s.holdingCap.Overflow == HoldingCapOverflowReject {
+		accepted, overflow, limit, err := s.holdingCap.Split(tx, s.ledger, merchantID, amount)
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to check holding cap: %w", err)
+		}
+		if overflow.IsPositive() {
+			tx.Rollback()
+			return nil, fmt.Errorf("%w: the merchant can receive at most %s FC more (limit %s FC)",
+				ErrHoldingCapExceeded, accepted, limit)
+		}
+	}
+
+	now := time.Now()
+	transaction := &models.Transaction{
+		UserID:             buyerID,
+		ToUserID:           &merchantID,
+		Type:               models.TransactionTypeEscrow,
+		Amount:             amount,
+		Fee:                fee,
+		FeeScheduleVersion: quote.ScheduleVersion,
+		Description:        description,
+		Status:             models.TransactionStatusPending,
+		CreatedAt:          now,
+	}
+	if err := tx.Create(transaction).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	escrow := &models.Escrow{
+		TransactionID: transaction.ID,
+		BuyerID:       buyerID,
+		MerchantID:    merchantID,
+		Amount:        amount,
+		Fee:           fee,
+		Status:        models.EscrowStatusOpen,
+		TimeoutAction: timeoutAction,
+		ExpiresAt:     now.Add(timeout),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := tx.Create(escrow).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to create escrow: %w", err)
+	}
+
+	buyerAccount, err := s.ledger.UserAccount(tx, buyerID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	escrowAccount, err := s.ledger.SystemAccount(tx, models.AccountTypeEscrow)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if _, err := s.ledger.Move(tx, &transaction.ID, "Escrow opened: "+description,
+		buyerAccount, escrowAccount, amount.Add(fee)); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to post escrow: %w", err)
+	}
+	if err := setTransactionStatus(tx, transaction, models.TransactionStatusAuthorized,
+		"Funds held in escrow", &buyerID); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit escrow: %w", err)
+	}
+
+	escrow.Transaction = transaction
+	return escrow, nil
+}
+
+// ReleaseEscrow pays an open escrow out to the merchant and the fee to the
+// treasury. Only the buyer, or an admin when asAdmin is set, may release it.
+// A nil actorID means the escrow timed out.
+func (s *WalletService) ReleaseEscrow(escrowID uuid.UUID, actorID *uuid.UUID, asAdmin bool) (*models.Escrow, error) {
+	return s.resolveEscrow(escrowID, actorID, asAdmin, models.EscrowStatusReleased)
+}
+
+// RefundEscrow returns an open escrow, fee included, to the buyer. Only the
+// merchant, or an admin when asAdmin is set, may refund it. A nil actorID
+// means the escrow timed out.
+func (s *WalletService) RefundEscrow(escrowID uuid.UUID, actorID *uuid.UUID, asAdmin bool) (*models.Escrow, error) {
+	return s.resolveEscrow(escrowID, actorID, asAdmin, models.EscrowStatusRefunded)
+}
+
+func (s *WalletService) resolveEscrow(escrowID uuid.UUID, actorID *uuid.UUID, asAdmin bool, outcome models.EscrowStatus) (*models.Escrow, error) {
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	var escrow models.Escrow
+	if err := tx.First(&escrow, "id = ?", escrowID).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("escrow not found: %w", err)
+	}
+
+	if actorID != nil && !asAdmin {
+		if outcome == models.EscrowStatusReleased && *actorID != escrow.BuyerID {
+			tx.Rollback()
+			return nil, fmt.Errorf("only the buyer can release this escrow")
+		}
+		if outcome == models.EscrowStatusRefunded && *actorID != escrow.MerchantID {
+			tx.Rollback()
+			return nil, fmt.Errorf("only the merchant can refund this escrow")
+		}
+	}
+
+	// Claim the escrow so it can only be resolved once
+	now := time.Now()
+	note := string(outcome)
+	switch {
+	case actorID == nil:
+		note += " after timeout"
+	case asAdmin:
+		note += " by admin"
+	case outcome == models.EscrowStatusReleased:
+		note += " by buyer"
+	default:
+		note += " by merchant"
+	}
+	result := tx.Model(&models.Escrow{}).
+		Where("id = ? AND status = ?", escrow.ID, models.EscrowStatusOpen).
+		Updates(map[string]interface{}{
+			"status":      outcome,
+			"resolved_at": now,
+			"resolved_by": actorID,
+			"note":        note,
+			"updated_at":  now,
+		})
+	if result.Error != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to update escrow: %w", result.Error)
+	}
+	if result.RowsAffected != 1 {
+		tx.Rollback()
+		return nil, ErrEscrowNotOpen
+	}
+
+	var transaction models.Transaction
+	if err := tx.First(&transaction, "id = ?", escrow.TransactionID).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("escrow transaction not found: %w", err)
+	}
+
+	escrowAccount, err := s.ledger.SystemAccount(tx, models.AccountTypeEscrow)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	transactionStatus := models.TransactionStatusCompleted
+	if outcome == models.EscrowStatusReleased {
+		// The merchant receives the payment only now, so a wallet frozen
+		// since the escrow opened keeps it open
+		var merchantWallet models.Wallet
+		if err := tx.Where("user_id = ?", escrow.MerchantID).First(&merchantWallet).Error; err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("merchant wallet not found: %w", err)
+		}
+		if err := checkReceive(&merchantWallet); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		accepted, overflow, limit, err := s.holdingCap.Split(tx, s.ledger, escrow.MerchantID, escrow.Amount)
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to check holding cap: %w", err)
+		}
+		if overflow.IsPositive() && s.holdingCap.Overflow == HoldingCapOverflowReject {
+			tx.Rollback()
+			return nil, fmt.Errorf("%w: the merchant can receive at most %s FC more (limit %s FC)",
+				ErrHoldingCapExceeded, accepted, limit)
+		}
+
+		merchantAccount, err := s.ledger.UserAccount(tx, escrow.MerchantID)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		treasury, err := s.ledger.SystemAccount(tx, models.AccountTypeTreasury)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		if _, err := s.ledger.Post(tx, &escrow.TransactionID, "Escrow released",
+			Leg{Account: escrowAccount, Amount: escrow.Amount.Add(escrow.Fee).Neg()},
+			Leg{Account: merchantAccount, Amount: accepted},
+			Leg{Account: treasury, Amount: escrow.Fee.Add(overflow)},
+		); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to post escrow release: %w", err)
+		}
+	} else {
+		buyerAccount, err := s.ledger.UserAccount(tx, escrow.BuyerID)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		if _, err := s.ledger.Move(tx, &escrow.TransactionID, "Escrow refunded",
+			escrowAccount, buyerAccount, escrow.Amount.Add(escrow.Fee)); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to post escrow refund: %w", err)
+		}
+		transactionStatus = models.TransactionStatusCancelled
+	}
+
+	if err := setTransactionStatus(tx, &transaction, transactionStatus, "Escrow "+note, actorID); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit escrow: %w", err)
+	}
+
+	return s.GetEscrow(escrow.ID)
+}
+
+// ResolveExpiredEscrows applies the timeout action of every open escrow past
+// its expiry. An escrow that cannot be released because the merchant is at
+// the holding cap or their wallet is frozen is refunded instead.
+func (s *WalletService) ResolveExpiredEscrows() error {
+	var escrows []models.Escrow
+	if err := s.db.Where("status = ? AND expires_at <= ?", models.EscrowStatusOpen, time.Now()).
+		Find(&escrows).Error; err != nil {
+		return err
+	}
+
+	var failed int
+	for _, escrow := range escrows {
+		var err error
+		if escrow.TimeoutAction == models.EscrowTimeoutRefund {
+			_, err = s.RefundEscrow(escrow.ID, nil, false)
+		} else {
+			_, err = s.ReleaseEscrow(escrow.ID, nil, false)
+			if errors.Is(err, ErrHoldingCapExceeded) || errors.Is(err, ErrWalletFrozen) {
+				_, err = s.RefundEscrow(escrow.ID, nil, false)
+			}
+		}
+		if err != nil && !errors.Is(err, ErrEscrowNotOpen) {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d expired escrows could not be resolved", failed, len(escrows))
+	}
+	return nil
+}
+
+// GetEscrow returns an escrow with its transaction
+func (s *WalletService) GetEscrow(escrowID uuid.UUID) (*models.Escrow, error) {
+	var escrow models.Escrow
+	err := s.db.Preload("Transaction").First(&escrow, "id = ?", escrowID).Error
+	return &escrow, err
+}
+
+// GetUserEscrows returns escrows where the user is buyer or merchant,
+// optionally filtered by status
+func (s *WalletService) GetUserEscrows(userID uuid.UUID, status string) ([]models.Escrow, error) {
+	query := s.db.Preload("Transaction").Where("buyer_id = ? OR merchant_id = ?", userID, userID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var escrows []models.Escrow
+	err := query.Order("created_at DESC").Find(&escrows).Error
+	return escrows, err
+}
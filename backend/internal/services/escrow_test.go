@@ -0,0 +1,111 @@
+package services
+
+import (
+	"errors"
+	"faircoin/internal/models"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+func TestEscrowResolution(t *testing.T) {
+	tests := []struct {
+		name          string
+		timeoutAction models.EscrowTimeoutAction
+		resolve       func(wallets *WalletService, escrow *models.Escrow, buyer, merchant *models.User) error
+		wantEscrow    models.EscrowStatus
+		wantStatus    models.TransactionStatus
+		paid          bool
+	}{
+		{"released by the buyer", models.EscrowTimeoutRelease,
+			func(wallets *WalletService, escrow *models.Escrow, buyer, merchant *models.User) error {
+				if _, err := wallets.ReleaseEscrow(escrow.ID, &merchant.ID, false); err == nil {
+					t.Error("the merchant released the escrow")
+				}
+				_, err := wallets.ReleaseEscrow(escrow.ID, &buyer.ID, false)
+				return err
+			}, models.EscrowStatusReleased, models.TransactionStatusCompleted, true},
+		{"refunded by the merchant", models.EscrowTimeoutRelease,
+			func(wallets *WalletService, escrow *models.Escrow, buyer, merchant *models.User) error {
+				if _, err := wallets.RefundEscrow(escrow.ID, &buyer.ID, false); err == nil {
+					t.Error("the buyer refunded the escrow")
+				}
+				_, err := wallets.RefundEscrow(escrow.ID, &merchant.ID, false)
+				return err
+			}, models.EscrowStatusRefunded, models.TransactionStatusCancelled, false},
+		{"released on timeout", models.EscrowTimeoutRelease,
+			func(wallets *WalletService, escrow *models.Escrow, buyer, merchant *models.User) error {
+				return expireEscrow(wallets.GetDB(), escrow)
+			}, models.EscrowStatusReleased, models.TransactionStatusCompleted, true},
+		{"refunded on timeout", models.EscrowTimeoutRefund,
+			func(wallets *WalletService, escrow *models.Escrow, buyer, merchant *models.User) error {
+				return expireEscrow(wallets.GetDB(), escrow)
+			}, models.EscrowStatusRefunded, models.TransactionStatusCancelled, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			buyer := newTestUser(t, db, "alice")
+			merchant := newTestUser(t, db, "bob")
+			db.Model(&models.User{}).Where("id = ?", merchant.ID).Update("is_merchant", true)
+			wallets := NewWalletService(db)
+
+			escrow, err := wallets.OpenEscrow(buyer.ID, merchant.ID, models.FC(20), "bike", 0, tt.timeoutAction)
+			if err != nil {
+				t.Fatal(err)
+			}
+			held := models.FC(20).Add(escrow.Fee)
+			if balance := walletBalance(t, db, buyer.ID); balance != models.FC(100).Sub(held) {
+				t.Fatalf("buyer holds %s while the escrow is open, want %s", balance, models.FC(100).Sub(held))
+			}
+			if status := mustTransaction(t, db, escrow.TransactionID).Status; status != models.TransactionStatusAuthorized {
+				t.Fatalf("transaction is %s while the escrow is open, want authorized", status)
+			}
+
+			if err := tt.resolve(wallets, escrow, buyer, merchant); err != nil {
+				t.Fatal(err)
+			}
+			if err := wallets.ResolveExpiredEscrows(); err != nil {
+				t.Fatal(err)
+			}
+
+			if escrow, err = wallets.GetEscrow(escrow.ID); err != nil {
+				t.Fatal(err)
+			}
+			if escrow.Status != tt.wantEscrow {
+				t.Errorf("escrow is %s, want %s", escrow.Status, tt.wantEscrow)
+			}
+			if status := mustTransaction(t, db, escrow.TransactionID).Status; status != tt.wantStatus {
+				t.Errorf("transaction is %s, want %s", status, tt.wantStatus)
+			}
+
+			wantBuyer, wantMerchant := models.FC(100), models.FC(100)
+			if tt.paid {
+				wantBuyer, wantMerchant = models.FC(100).Sub(held), models.FC(120)
+			}
+			if balance := walletBalance(t, db, buyer.ID); balance != wantBuyer {
+				t.Errorf("buyer holds %s, want %s", balance, wantBuyer)
+			}
+			if balance := walletBalance(t, db, merchant.ID); balance != wantMerchant {
+				t.Errorf("merchant holds %s, want %s", balance, wantMerchant)
+			}
+			escrowAccount, err := NewLedgerService(db).SystemAccount(db, models.AccountTypeEscrow)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !escrowAccount.Balance.IsZero() {
+				t.Errorf("escrow account holds %s after resolution", escrowAccount.Balance)
+			}
+
+			if _, err := wallets.RefundEscrow(escrow.ID, nil, true); !errors.Is(err, ErrEscrowNotOpen) {
+				t.Errorf("second resolution: %v, want ErrEscrowNotOpen", err)
+			}
+		})
+	}
+}
+
+// expireEscrow moves an escrow's expiry into the past
+func expireEscrow(db *gorm.DB, escrow *models.Escrow) error {
+	return db.Model(&models.Escrow{}).Where("id = ?", escrow.ID).Update("expires_at", time.Now().Add(-time.Minute)).Error
+}
@@ -13,11 +13,32 @@ import (
 // FairnessService handles PFI and TFI calculations
 type FairnessService struct {
 	db *gorm.DB
+
+	// Attestation review (see attestations.go)
+	requiredReviews int
+	reviewerMinPFI  int
+
+	// Attestation aging by type (see attestation_aging.go)
+	aging map[string]AttestationAging
 }
 
 // NewFairnessService creates a new fairness service
 func NewFairnessService(db *gorm.DB) *FairnessService {
-	return &FairnessService{db: db}
+	aging, _ := ParseAttestationAging(DefaultAttestationAging)
+	return &FairnessService{
+		db:              db,
+		requiredReviews: DefaultAttestationRequiredReviews,
+		reviewerMinPFI:  DefaultAttestationReviewerMinPFI,
+		aging:           aging,
+	}
+}
+
+// WithIdempotency returns a copy of the service whose writes record what
+// they create on the given idempotency claim (see models.IdempotencyClaimKey)
+func (s *FairnessService) WithIdempotency(claim *models.IdempotencyRecord) *FairnessService {
+	scoped := *s
+	scoped.db = s.db.Set(models.IdempotencyClaimKey, claim.ID)
+	return &scoped
 }
 
 // CreateAttestation creates a new attestation for PFI calculation
@@ -32,13 +53,20 @@ func (s *FairnessService) CreateAttestation(userID, attesterID uuid.UUID, attest
 		return nil, fmt.Errorf("attester PFI too low to provide attestations")
 	}
 
-	// Check for duplicate attestations
-	var existingCount int64
-	s.db.Model(&models.Attestation{}).
-		Where("user_id = ? AND attester_id = ? AND type = ?", userID, attesterID, attestationType).
-		Count(&existingCount)
+	// Check for duplicate attestations. One that has expired can be renewed,
+	// and one the attester revoked can be given again.
+	now := time.Now()
+	var existing []models.Attestation
+	s.db.Where("user_id = ? AND attester_id = ? AND type = ?", userID, attesterID, attestationType).
+		Find(&existing)
 
-	if existingCount > 0 {
+	for _, att := range existing {
+		if att.Status == models.AttestationStatusRevoked && att.RevokedBy != nil && *att.RevokedBy == attesterID {
+			continue
+		}
+		if att.Status == models.AttestationStatusVerified && s.attestationWeight(&att, now) == 0 {
+			continue
+		}
 		return nil, fmt.Errorf("attestation already exists")
 	}
 
@@ -48,18 +76,20 @@ func (s *FairnessService) CreateAttestation(userID, attesterID uuid.UUID, attest
 		Type:        attestationType,
 		Value:       value,
 		Description: description,
-		Verified:    false, // Requires community verification
-		CreatedAt:   time.Now(),
-	}
-
-	if err := s.db.Create(attestation).Error; err != nil {
-		return nil, fmt.Errorf("failed to create attestation: %w", err)
+		Verified:    false, // Requires review, see ReviewAttestation
+		Status:      models.AttestationStatusPending,
+		CreatedAt:   now,
 	}
 
 	// Auto-verify if attester has very high PFI
 	if attester.PFI >= 80 {
 		attestation.Verified = true
-		s.db.Save(attestation)
+		attestation.Status = models.AttestationStatusVerified
+		attestation.DecidedAt = &now
+	}
+
+	if err := s.db.Create(attestation).Error; err != nil {
+		return nil, fmt.Errorf("failed to create attestation: %w", err)
 	}
 
 	// Recalculate PFI for the user (synchronously to avoid database locking)
@@ -97,19 +127,22 @@ func (s *FairnessService) calculatePFIScore(user *models.User, attestations []mo
 	// Community service hours (up to 30 points)
 	servicePoints := math.Min(30, float64(user.CommunityService)*0.5)
 
-	// Peer attestations (up to 40 points)
+	// Peer attestations (up to 40 points), weighted by age
+	now := time.Now()
 	var attestationPoints float64
 	for _, att := range attestations {
+		var factor float64
 		switch att.Type {
 		case "community_service":
-			attestationPoints += float64(att.Value) * 2.0
+			factor = 2.0
 		case "dispute_resolution":
-			attestationPoints += float64(att.Value) * 1.5
+			factor = 1.5
 		case "peer_rating":
-			attestationPoints += float64(att.Value) * 0.8
+			factor = 0.8
 		case "identity_verification":
-			attestationPoints += float64(att.Value) * 1.0
+			factor = 1.0
 		}
+		attestationPoints += float64(att.Value) * factor * s.attestationWeight(&att, now)
 	}
 	attestationPoints = math.Min(40, attestationPoints)
 
@@ -120,7 +153,8 @@ func (s *FairnessService) calculatePFIScore(user *models.User, attestations []mo
 	// Transaction behavior (up to 20 points)
 	var transactionPoints float64
 	var transactionCount int64
-	s.db.Model(&models.Transaction{}).Where("user_id = ?", user.ID).Count(&transactionCount)
+	s.db.Model(&models.Transaction{}).Where("user_id = ? AND status NOT IN (?)", user.ID, models.VoidTransactionStatuses).
+		Count(&transactionCount)
 
 	if transactionCount > 0 {
 		// Points for regular transactions
@@ -149,6 +183,23 @@ func (s *FairnessService) CreateRating(userID, merchantID uuid.UUID, transaction
 		return nil, fmt.Errorf("merchant not found: %w", err)
 	}
 
+	// A rating tied to an escrow must come from its buyer after release,
+	// so the delivery rating reflects a confirmed delivery
+	if transactionID != nil {
+		var transaction models.Transaction
+		if err := s.db.First(&transaction, "id = ?", *transactionID).Error; err != nil {
+			return nil, fmt.Errorf("transaction not found: %w", err)
+		}
+		if transaction.Type == models.TransactionTypeEscrow {
+			if transaction.UserID != userID || transaction.ToUserID == nil || *transaction.ToUserID != merchantID {
+				return nil, fmt.Errorf("escrow does not belong to this buyer and merchant")
+			}
+			if transaction.Status != models.TransactionStatusCompleted {
+				return nil, fmt.Errorf("escrow must be released before it can be rated")
+			}
+		}
+	}
+
 	// Check if user has already rated this merchant recently
 	var existingCount int64
 	oneWeekAgo := time.Now().AddDate(0, 0, -7)
@@ -303,6 +354,26 @@ func (s *FairnessService) GetUserPFIBreakdown(userID uuid.UUID) (map[string]inte
 	breakdown["current_pfi"] = user.PFI
 	breakdown["community_service_hours"] = user.CommunityService
 	breakdown["total_attestations"] = len(attestations)
+	now := time.Now()
+	var expired int
+	var weight float64
+	for _, att := range attestations {
+		w := s.attestationWeight(&att, now)
+		if w == 0 {
+			expired++
+		}
+		weight += w
+	}
+	breakdown["expired_attestations"] = expired
+	breakdown["attestation_weight"] = math.Round(weight*100) / 100
+	var pending int
+	s.db.Model(&models.Attestation{}).
+		Where("user_id = ? AND status = ?", userID, models.AttestationStatusPending).Count(&pending)
+	breakdown["pending_attestations"] = pending
+	var revoked int
+	s.db.Model(&models.Attestation{}).
+		Where("user_id = ? AND status = ?", userID, models.AttestationStatusRevoked).Count(&revoked)
+	breakdown["revoked_attestations"] = revoked
 	breakdown["account_age_days"] = int(time.Since(user.CreatedAt).Hours() / 24)
 
 	// Count attestations by type
@@ -0,0 +1,420 @@
+package services
+
+import (
+	"encoding/json"
+	"faircoin/internal/models"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+)
+
+// Where a fee schedule or demurrage rate came from
+const (
+	PolicySourceConfig     = "config"
+	PolicySourceGovernance = "governance"
+)
+
+// maxFeeRateBPS is the highest rate a policy may charge (10%)
+const maxFeeRateBPS = 1000
+
+// FeeTier is one bracket of a tiered fee. The first tier whose UpTo is at
+// least the amount applies; an UpTo of zero has no upper bound.
+type FeeTier struct {
+	UpTo    models.Money `json:"up_to"`
+	RateBPS int64        `json:"rate_bps"` // Basis points of the amount
+	Fixed   models.Money `json:"fixed"`    // Flat part of the fee
+}
+
+// FeePolicy describes how the fee on a transfer is computed. Rules are tried
+// in order: sender exemptions, the recipient merchant's rate, the matching
+// tier, then the base rate. The result is clamped to Minimum and Maximum
+// unless the sender is exempt.
+type FeePolicy struct {
+	RateBPS         int64            `json:"rate_bps"`
+	Fixed           models.Money     `json:"fixed"`
+	Minimum         models.Money     `json:"minimum"`
+	Maximum         models.Money     `json:"maximum"` // 0 means no cap
+	Tiers           []FeeTier        `json:"tiers,omitempty"`
+	FreeBelowPFI    int              `json:"free_below_pfi"` // Senders with a lower PFI pay nothing, 0 disables
+	FreeForVerified bool             `json:"free_for_verified"`
+	MerchantRates   map[string]int64 `json:"merchant_rates,omitempty"` // Basis points keyed by merchant user ID
+}
+
+// DefaultFeePolicy returns the policy used when no schedule has been
+// recorded: 0.1% with a minimum of 0.01 FC
+func DefaultFeePolicy() FeePolicy {
+	return FeePolicy{
+		RateBPS: 10,
+		Minimum: models.FC(1).MulFrac(1, 100),
+	}
+}
+
+// NewFeePolicy builds a fee policy from configuration values. Tiers are
+// written as comma-separated "up_to:rate_bps[:fixed]" brackets with an empty
+// up_to for the open-ended one, e.g. "100:20,1000:10,:5". Merchant rates are
+// comma-separated "user_id:rate_bps" pairs.
+func NewFeePolicy(rateBPS int, minimum, maximum float64, tiers, merchantRates string,
+	freeBelowPFI int, freeForVerified bool) (FeePolicy, error) {
+	policy := FeePolicy{
+		RateBPS:         int64(rateBPS),
+		Minimum:         models.MoneyFromFloat(minimum),
+		Maximum:         models.MoneyFromFloat(maximum),
+		FreeBelowPFI:    freeBelowPFI,
+		FreeForVerified: freeForVerified,
+	}
+
+	for _, spec := range strings.Split(tiers, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		parts := strings.Split(spec, ":")
+		if len(parts) < 2 || len(parts) > 3 {
+			return policy, fmt.Errorf("invalid fee tier %q, expected up_to:rate_bps[:fixed]", spec)
+		}
+		var tier FeeTier
+		var err error
+		if strings.TrimSpace(parts[0]) != "" {
+			if tier.UpTo, err = models.ParseMoney(strings.TrimSpace(parts[0])); err != nil {
+				return policy, fmt.Errorf("invalid fee tier bound %q: %w", parts[0], err)
+			}
+		}
+		if tier.RateBPS, err = strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 64); err != nil {
+			return policy, fmt.Errorf("invalid fee tier rate %q: %w", parts[1], err)
+		}
+		if len(parts) == 3 {
+			if tier.Fixed, err = models.ParseMoney(strings.TrimSpace(parts[2])); err != nil {
+				return policy, fmt.Errorf("invalid fee tier fixed fee %q: %w", parts[2], err)
+			}
+		}
+		policy.Tiers = append(policy.Tiers, tier)
+	}
+
+	for _, spec := range strings.Split(merchantRates, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		parts := strings.Split(spec, ":")
+		if len(parts) != 2 {
+			return policy, fmt.Errorf("invalid merchant rate %q, expected user_id:rate_bps", spec)
+		}
+		rate, err := strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 64)
+		if err != nil {
+			return policy, fmt.Errorf("invalid merchant rate %q: %w", parts[1], err)
+		}
+		if policy.MerchantRates == nil {
+			policy.MerchantRates = make(map[string]int64)
+		}
+		policy.MerchantRates[strings.TrimSpace(parts[0])] = rate
+	}
+
+	err := policy.Validate()
+	return policy, err
+}
+
+// Validate checks that the policy is internally consistent and sorts its
+// tiers. The open-ended tier, if any, must be the last.
+func (p *FeePolicy) Validate() error {
+	if p.RateBPS < 0 || p.RateBPS > maxFeeRateBPS {
+		return fmt.Errorf("fee rate must be between 0 and %d basis points", maxFeeRateBPS)
+	}
+	if p.Fixed.IsNegative() || p.Minimum.IsNegative() || p.Maximum.IsNegative() {
+		return fmt.Errorf("fixed fee, minimum and maximum cannot be negative")
+	}
+	if p.Maximum.IsPositive() && p.Maximum < p.Minimum {
+		return fmt.Errorf("maximum fee %s FC is below the minimum of %s FC", p.Maximum, p.Minimum)
+	}
+	if p.FreeBelowPFI < 0 || p.FreeBelowPFI > 100 {
+		return fmt.Errorf("free_below_pfi must be between 0 and 100")
+	}
+
+	sort.SliceStable(p.Tiers, func(i, j int) bool {
+		// Open-ended tiers sort last
+		if p.Tiers[i].UpTo.IsZero() || p.Tiers[j].UpTo.IsZero() {
+			return !p.Tiers[i].UpTo.IsZero()
+		}
+		return p.Tiers[i].UpTo < p.Tiers[j].UpTo
+	})
+	for i, tier := range p.Tiers {
+		if tier.RateBPS < 0 || tier.RateBPS > maxFeeRateBPS {
+			return fmt.Errorf("tier %d rate must be between 0 and %d basis points", i+1, maxFeeRateBPS)
+		}
+		if tier.UpTo.IsNegative() || tier.Fixed.IsNegative() {
+			return fmt.Errorf("tier %d bound and fixed fee cannot be negative", i+1)
+		}
+		if tier.UpTo.IsZero() && i != len(p.Tiers)-1 {
+			return fmt.Errorf("only one tier can be open-ended")
+		}
+		if i > 0 && tier.UpTo == p.Tiers[i-1].UpTo {
+			return fmt.Errorf("two tiers share the bound %s FC", tier.UpTo)
+		}
+	}
+
+	for id, rate := range p.MerchantRates {
+		if _, err := uuid.Parse(id); err != nil {
+			return fmt.Errorf("invalid merchant ID %q in merchant rates", id)
+		}
+		if rate < 0 || rate > maxFeeRateBPS {
+			return fmt.Errorf("merchant rate for %s must be between 0 and %d basis points", id, maxFeeRateBPS)
+		}
+	}
+	return nil
+}
+
+// Compute returns the fee sender pays to send amount to recipient and the
+// name of the rule that decided it
+func (p FeePolicy) Compute(amount models.Money, sender, recipient *models.User) (models.Money, string) {
+	if p.FreeForVerified && sender.IsVerified {
+		return 0, "verified_exempt"
+	}
+	if p.FreeBelowPFI > 0 && sender.PFI < p.FreeBelowPFI {
+		return 0, "low_pfi_exempt"
+	}
+
+	rate, fixed, rule := p.RateBPS, p.Fixed, "base"
+	if merchantRate, ok := p.MerchantRates[recipient.ID.String()]; ok && recipient.IsMerchant {
+		rate, fixed, rule = merchantRate, 0, "merchant"
+	} else {
+		for i, tier := range p.Tiers {
+			if tier.UpTo.IsZero() || amount <= tier.UpTo {
+				rate, fixed, rule = tier.RateBPS, tier.Fixed, fmt.Sprintf("tier_%d", i+1)
+				break
+			}
+		}
+	}
+
+	fee := fixed.Add(amount.MulFrac(rate, 10000))
+	if fee < p.Minimum {
+		fee = p.Minimum
+	}
+	if p.Maximum.IsPositive() && fee > p.Maximum {
+		fee = p.Maximum
+	}
+	return fee, rule
+}
+
+// ParseFeePolicy decodes and validates a JSON fee policy
+func ParseFeePolicy(data string) (FeePolicy, error) {
+	var policy FeePolicy
+	if err := json.Unmarshal([]byte(data), &policy); err != nil {
+		return policy, fmt.Errorf("invalid fee policy: %w", err)
+	}
+	err := policy.Validate()
+	return policy, err
+}
+
+// FeeQuote is the fee for one prospective transfer
+type FeeQuote struct {
+	Amount          models.Money `json:"amount"`
+	Fee             models.Money `json:"fee"`
+	Total           models.Money `json:"total"` // What the sender pays
+	Rule            string       `json:"rule"`
+	ScheduleVersion int          `json:"schedule_version"`
+}
+
+// FeeService manages versioned fee schedules and quotes transfer fees
+type FeeService struct {
+	db *gorm.DB
+}
+
+// NewFeeService creates a new fee service
+func NewFeeService(db *gorm.DB) *FeeService {
+	return &FeeService{db: db}
+}
+
+// GetDB returns the database connection
+func (s *FeeService) GetDB() *gorm.DB {
+	return s.db
+}
+
+// Current returns the schedule in force inside tx along with its decoded
+// policy. Before any schedule is recorded it returns the default policy
+// and a nil schedule.
+func (s *FeeService) Current(tx *gorm.DB) (*models.FeeSchedule, FeePolicy, error) {
+	var schedule models.FeeSchedule
+	if err := tx.Order("version DESC").First(&schedule).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, DefaultFeePolicy(), nil
+		}
+		return nil, FeePolicy{}, fmt.Errorf("failed to load fee schedule: %w", err)
+	}
+	policy, err := ParseFeePolicy(schedule.Policy)
+	if err != nil {
+		return nil, FeePolicy{}, fmt.Errorf("fee schedule version %d: %w", schedule.Version, err)
+	}
+	return &schedule, policy, nil
+}
+
+// Quote computes the fee for sending amount from fromUserID to toUserID
+// under the schedule in force inside tx. A nil toUserID quotes a payment
+// whose recipient is not known yet, such as a voucher.
+func (s *FeeService) Quote(tx *gorm.DB, fromUserID, toUserID uuid.UUID, amount models.Money) (*FeeQuote, error) {
+	var sender, recipient models.User
+	if err := tx.First(&sender, "id = ?", fromUserID).Error; err != nil {
+		return nil, fmt.Errorf("sender not found: %w", err)
+	}
+	if toUserID != uuid.Nil {
+		if err := tx.First(&recipient, "id = ?", toUserID).Error; err != nil {
+			return nil, fmt.Errorf("recipient not found: %w", err)
+		}
+	}
+
+	schedule, policy, err := s.Current(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	fee, rule := policy.Compute(amount, &sender, &recipient)
+	quote := &FeeQuote{Amount: amount, Fee: fee, Total: amount.Add(fee), Rule: rule}
+	if schedule != nil {
+		quote.ScheduleVersion = schedule.Version
+	}
+	return quote, nil
+}
+
+// EnsureConfigSchedule records the configured policy as a new schedule
+// version when it differs from the one in force. A schedule adopted by
+// governance takes precedence and is left alone until governance replaces it.
+func (s *FeeService) EnsureConfigSchedule(policy FeePolicy) (*models.FeeSchedule, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	current, currentPolicy, err := s.Current(s.db)
+	if err != nil {
+		return nil, err
+	}
+	if current != nil {
+		if current.Source == PolicySourceGovernance {
+			return current, nil
+		}
+		if samePolicy(currentPolicy, policy) {
+			return current, nil
+		}
+	}
+
+	return s.activate(s.db, policy, PolicySourceConfig, nil)
+}
+
+// ApplyProposal puts the fee policy of a passed fee schedule proposal into force
+func (s *FeeService) ApplyProposal(proposalID uuid.UUID) (*models.FeeSchedule, error) {
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	var proposal models.Proposal
+	if err := tx.First(&proposal, "id = ?", proposalID).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("proposal not found: %w", err)
+	}
+	if proposal.Type != models.ProposalTypeFeeSchedule {
+		tx.Rollback()
+		return nil, fmt.Errorf("proposal is not a fee schedule change")
+	}
+	if proposal.Status != models.ProposalStatusPassed {
+		tx.Rollback()
+		return nil, fmt.Errorf("proposal has not passed")
+	}
+
+	policy, err := ParseFeePolicy(proposal.FeePolicy)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// Claim the proposal first so a concurrent run cannot apply it twice
+	result := tx.Model(&models.Proposal{}).
+		Where("id = ? AND executed_at IS NULL", proposal.ID).
+		Update("executed_at", time.Now())
+	if result.Error != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to mark proposal executed: %w", result.Error)
+	}
+	if result.RowsAffected != 1 {
+		tx.Rollback()
+		return nil, fmt.Errorf("proposal has already been executed")
+	}
+
+	schedule, err := s.activate(tx, policy, PolicySourceGovernance, &proposal.ID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit fee schedule: %w", err)
+	}
+	return schedule, nil
+}
+
+// ApplyPassedProposals puts every passed, unapplied fee schedule proposal
+// into force, oldest first, so the most recently decided one ends up current
+func (s *FeeService) ApplyPassedProposals() error {
+	var proposals []models.Proposal
+	if err := s.db.Where("type = ? AND status = ? AND executed_at IS NULL",
+		models.ProposalTypeFeeSchedule, models.ProposalStatusPassed).
+		Order("end_time ASC").Find(&proposals).Error; err != nil {
+		return err
+	}
+
+	var failed int
+	for _, proposal := range proposals {
+		if _, err := s.ApplyProposal(proposal.ID); err != nil {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d fee schedule proposals could not be applied", failed, len(proposals))
+	}
+	return nil
+}
+
+// GetSchedules returns the most recent fee schedule versions, newest first
+func (s *FeeService) GetSchedules(limit int) ([]models.FeeSchedule, error) {
+	var schedules []models.FeeSchedule
+	err := s.db.Order("version DESC").Limit(limit).Find(&schedules).Error
+	return schedules, err
+}
+
+// activate records policy as the next schedule version
+func (s *FeeService) activate(tx *gorm.DB, policy FeePolicy, source string, proposalID *uuid.UUID) (*models.FeeSchedule, error) {
+	encoded, err := json.Marshal(policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode fee policy: %w", err)
+	}
+
+	var latest struct {
+		Version int
+	}
+	if err := tx.Model(&models.FeeSchedule{}).Select("COALESCE(MAX(version), 0) as version").
+		Scan(&latest).Error; err != nil {
+		return nil, fmt.Errorf("failed to find latest fee schedule: %w", err)
+	}
+
+	schedule := &models.FeeSchedule{
+		Version:    latest.Version + 1,
+		Policy:     string(encoded),
+		Source:     source,
+		ProposalID: proposalID,
+		CreatedAt:  time.Now(),
+	}
+	if err := tx.Create(schedule).Error; err != nil {
+		return nil, fmt.Errorf("failed to record fee schedule: %w", err)
+	}
+	return schedule, nil
+}
+
+// samePolicy reports whether two policies encode identically
+func samePolicy(a, b FeePolicy) bool {
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(encodedA) == string(encodedB)
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"faircoin/internal/models"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestFeePolicyCompute(t *testing.T) {
+	merchant := &models.User{ID: uuid.New(), IsMerchant: true, PFI: 60}
+	notMerchant := &models.User{ID: merchant.ID, PFI: 60}
+	stranger := &models.User{ID: uuid.New(), PFI: 60}
+	sender := &models.User{ID: uuid.New(), PFI: 60}
+	verified := &models.User{ID: uuid.New(), PFI: 60, IsVerified: true}
+	lowPFI := &models.User{ID: uuid.New(), PFI: 20}
+
+	tiered := FeePolicy{
+		RateBPS: 10,
+		Minimum: models.MoneyFromFloat(0.01),
+		Tiers: []FeeTier{
+			{UpTo: models.FC(100), RateBPS: 20},
+			{UpTo: models.FC(1000), RateBPS: 10, Fixed: models.MoneyFromFloat(0.5)},
+			{RateBPS: 5},
+		},
+		MerchantRates: map[string]int64{merchant.ID.String(): 50},
+	}
+	capped := FeePolicy{RateBPS: 100, Minimum: models.FC(1), Maximum: models.FC(5)}
+	exempting := FeePolicy{RateBPS: 10, Minimum: models.FC(1), FreeBelowPFI: 30, FreeForVerified: true}
+
+	tests := []struct {
+		name      string
+		policy    FeePolicy
+		amount    models.Money
+		sender    *models.User
+		recipient *models.User
+		want      models.Money
+		rule      string
+	}{
+		{"default rate", DefaultFeePolicy(), models.FC(100), sender, stranger, models.MoneyFromFloat(0.1), "base"},
+		{"default minimum", DefaultFeePolicy(), models.FC(1), sender, stranger, models.MoneyFromFloat(0.01), "base"},
+		{"default rounds half to even", DefaultFeePolicy(), models.Money(1500000005), sender, stranger,
+			models.Money(1500000), "base"},
+		{"first tier", tiered, models.FC(50), sender, stranger, models.MoneyFromFloat(0.1), "tier_1"},
+		{"first tier bound", tiered, models.FC(100), sender, stranger, models.MoneyFromFloat(0.2), "tier_1"},
+		{"second tier with fixed part", tiered, models.FC(500), sender, stranger, models.FC(1), "tier_2"},
+		{"open-ended tier", tiered, models.FC(10000), sender, stranger, models.FC(5), "tier_3"},
+		{"tier minimum", tiered, models.FC(1), sender, stranger, models.MoneyFromFloat(0.01), "tier_1"},
+		{"merchant rate", tiered, models.FC(500), sender, merchant, models.MoneyFromFloat(2.5), "merchant"},
+		{"merchant rate needs merchant", tiered, models.FC(500), sender, notMerchant, models.FC(1), "tier_2"},
+		{"maximum", capped, models.FC(1000), sender, stranger, models.FC(5), "base"},
+		{"minimum", capped, models.FC(10), sender, stranger, models.FC(1), "base"},
+		{"verified exempt", exempting, models.FC(10), verified, stranger, 0, "verified_exempt"},
+		{"low PFI exempt", exempting, models.FC(10), lowPFI, stranger, 0, "low_pfi_exempt"},
+		{"not exempt", exempting, models.FC(10), sender, stranger, models.FC(1), "base"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.Validate(); err != nil {
+				t.Fatalf("policy is invalid: %v", err)
+			}
+			fee, rule := tt.policy.Compute(tt.amount, tt.sender, tt.recipient)
+			if fee != tt.want || rule != tt.rule {
+				t.Errorf("Compute(%s) = %s (%s), want %s (%s)", tt.amount, fee, rule, tt.want, tt.rule)
+			}
+		})
+	}
+}
+
+func TestNewFeePolicy(t *testing.T) {
+	merchantID := uuid.New().String()
+
+	policy, err := NewFeePolicy(10, 0.01, 0, ":5,1000:10:0.5,100:20", merchantID+":30", 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FeeTier{
+		{UpTo: models.FC(100), RateBPS: 20},
+		{UpTo: models.FC(1000), RateBPS: 10, Fixed: models.MoneyFromFloat(0.5)},
+		{RateBPS: 5},
+	}
+	if len(policy.Tiers) != len(want) {
+		t.Fatalf("got %d tiers, want %d", len(policy.Tiers), len(want))
+	}
+	for i := range want {
+		if policy.Tiers[i] != want[i] {
+			t.Errorf("tier %d = %+v, want %+v", i+1, policy.Tiers[i], want[i])
+		}
+	}
+	if policy.MerchantRates[merchantID] != 30 {
+		t.Errorf("merchant rate = %d, want 30", policy.MerchantRates[merchantID])
+	}
+
+	invalid := []struct {
+		name          string
+		rateBPS       int
+		minimum       float64
+		maximum       float64
+		tiers         string
+		merchantRates string
+		freeBelowPFI  int
+	}{
+		{"rate too high", 1001, 0, 0, "", "", 0},
+		{"negative rate", -1, 0, 0, "", "", 0},
+		{"maximum below minimum", 10, 2, 1, "", "", 0},
+		{"malformed tier", 10, 0, 0, "100", "", 0},
+		{"tier amount with exponent", 10, 0, 0, "1e3:10", "", 0},
+		{"tier rate too high", 10, 0, 0, "100:2000", "", 0},
+		{"two open-ended tiers", 10, 0, 0, ":10,:20", "", 0},
+		{"duplicate tier bound", 10, 0, 0, "100:10,100:20", "", 0},
+		{"merchant ID", 10, 0, 0, "", "bob:10", 0},
+		{"merchant rate", 10, 0, 0, "", merchantID + ":x", 0},
+		{"free below PFI", 10, 0, 0, "", "", 101},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewFeePolicy(tt.rateBPS, tt.minimum, tt.maximum, tt.tiers, tt.merchantRates,
+				tt.freeBelowPFI, false); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestParseFeePolicy(t *testing.T) {
+	policy, err := ParseFeePolicy(`{"rate_bps":25,"minimum":"0.05","tiers":[{"up_to":0,"rate_bps":5},{"up_to":10,"rate_bps":50}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.RateBPS != 25 || policy.Minimum != models.MoneyFromFloat(0.05) {
+		t.Errorf("policy = %+v", policy)
+	}
+	if policy.Tiers[0].UpTo != models.FC(10) || !policy.Tiers[1].UpTo.IsZero() {
+		t.Errorf("tiers are not sorted with the open-ended one last: %+v", policy.Tiers)
+	}
+
+	for _, data := range []string{`{"rate_bps":`, `{"rate_bps":5000}`, `{"minimum":"1/2"}`} {
+		if _, err := ParseFeePolicy(data); err == nil {
+			t.Errorf("ParseFeePolicy(%s) succeeded, want an error", data)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"encoding/json"
 	"faircoin/internal/models"
 	"fmt"
 	"math"
@@ -25,8 +26,108 @@ func (s *GovernanceService) GetDB() *gorm.DB {
 	return s.db
 }
 
+// WithIdempotency returns a copy of the service whose writes record what
+// they create on the given idempotency claim (see models.IdempotencyClaimKey)
+func (s *GovernanceService) WithIdempotency(claim *models.IdempotencyRecord) *GovernanceService {
+	scoped := *s
+	scoped.db = s.db.Set(models.IdempotencyClaimKey, claim.ID)
+	return &scoped
+}
+
 // CreateProposal creates a new governance proposal
 func (s *GovernanceService) CreateProposal(proposerID uuid.UUID, title, description string, proposalType models.ProposalType) (*models.Proposal, error) {
+	if proposalType == models.ProposalTypeTreasurySpend {
+		return nil, fmt.Errorf("treasury spend proposals need a recipient and an amount")
+	}
+	if proposalType == models.ProposalTypeFeeSchedule {
+		return nil, fmt.Errorf("fee schedule proposals need a fee policy")
+	}
+
+	proposal, err := s.newProposal(proposerID, title, description, proposalType)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Create(proposal).Error; err != nil {
+		return nil, fmt.Errorf("failed to create proposal: %w", err)
+	}
+
+	return proposal, nil
+}
+
+// CreateTreasuryProposal creates a proposal to pay amount from the community
+// treasury to recipientID. The payment is only made once the proposal passes.
+func (s *GovernanceService) CreateTreasuryProposal(proposerID uuid.UUID, title, description string, recipientID uuid.UUID, amount models.Money) (*models.Proposal, error) {
+	if !amount.IsPositive() {
+		return nil, fmt.Errorf("amount must be positive")
+	}
+
+	var recipient models.User
+	if err := s.db.First(&recipient, "id = ?", recipientID).Error; err != nil {
+		return nil, fmt.Errorf("recipient not found: %w", err)
+	}
+
+	proposal, err := s.newProposal(proposerID, title, description, models.ProposalTypeTreasurySpend)
+	if err != nil {
+		return nil, err
+	}
+	proposal.TreasuryAmount = amount
+	proposal.TreasuryRecipientID = &recipientID
+
+	if err := s.db.Create(proposal).Error; err != nil {
+		return nil, fmt.Errorf("failed to create proposal: %w", err)
+	}
+
+	return proposal, nil
+}
+
+// CreateFeeScheduleProposal creates a proposal to replace the transfer fee
+// policy. The policy becomes a new fee schedule version once the proposal passes.
+func (s *GovernanceService) CreateFeeScheduleProposal(proposerID uuid.UUID, title, description string, policy FeePolicy) (*models.Proposal, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	encoded, err := json.Marshal(policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode fee policy: %w", err)
+	}
+
+	proposal, err := s.newProposal(proposerID, title, description, models.ProposalTypeFeeSchedule)
+	if err != nil {
+		return nil, err
+	}
+	proposal.FeePolicy = string(encoded)
+
+	if err := s.db.Create(proposal).Error; err != nil {
+		return nil, fmt.Errorf("failed to create proposal: %w", err)
+	}
+
+	return proposal, nil
+}
+
+// CreateDemurrageProposal creates a monetary policy proposal that sets the
+// monthly demurrage rate once it passes
+func (s *GovernanceService) CreateDemurrageProposal(proposerID uuid.UUID, title, description string, rate float64) (*models.Proposal, error) {
+	if err := validateDemurrageRate(rate); err != nil {
+		return nil, err
+	}
+
+	proposal, err := s.newProposal(proposerID, title, description, models.ProposalTypeMonetaryPolicy)
+	if err != nil {
+		return nil, err
+	}
+	proposal.DemurrageRate = &rate
+
+	if err := s.db.Create(proposal).Error; err != nil {
+		return nil, fmt.Errorf("failed to create proposal: %w", err)
+	}
+
+	return proposal, nil
+}
+
+// newProposal checks that the proposer may create proposals and builds an
+// active proposal with the standard voting period
+func (s *GovernanceService) newProposal(proposerID uuid.UUID, title, description string, proposalType models.ProposalType) (*models.Proposal, error) {
 	// Check if proposer has sufficient PFI
 	var proposer models.User
 	if err := s.db.First(&proposer, "id = ?", proposerID).Error; err != nil {
@@ -37,7 +138,7 @@ func (s *GovernanceService) CreateProposal(proposerID uuid.UUID, title, descript
 		return nil, fmt.Errorf("insufficient PFI to create proposals (minimum: 50, current: %d)", proposer.PFI)
 	}
 
-	proposal := &models.Proposal{
+	return &models.Proposal{
 		ProposerID:  proposerID,
 		Title:       title,
 		Description: description,
@@ -46,13 +147,7 @@ func (s *GovernanceService) CreateProposal(proposerID uuid.UUID, title, descript
 		StartTime:   time.Now(),
 		EndTime:     time.Now().AddDate(0, 0, 7), // 7 days voting period
 		CreatedAt:   time.Now(),
-	}
-
-	if err := s.db.Create(proposal).Error; err != nil {
-		return nil, fmt.Errorf("failed to create proposal: %w", err)
-	}
-
-	return proposal, nil
+	}, nil
 }
 
 // VoteOnProposal allows a user to vote on a proposal
@@ -87,7 +182,7 @@ func (s *GovernanceService) VoteOnProposal(userID, proposalID uuid.UUID, vote bo
 
 	// Calculate total supply for voting power calculation
 	var totalSupply struct {
-		Total float64
+		Total models.Money
 	}
 	s.db.Model(&models.Wallet{}).Select("SUM(balance) as total").Scan(&totalSupply)
 
@@ -173,21 +268,38 @@ func (s *GovernanceService) ProcessExpiredProposals() error {
 
 // GetCouncilMembers returns the current community council members
 func (s *GovernanceService) GetCouncilMembers() ([]models.User, error) {
-	// Council members are the top PFI users who are active
-	var councilMembers []models.User
-	err := s.db.Where("pfi >= ? AND is_verified = ?", 70, true).
-		Order("pfi DESC").Limit(7).Find(&councilMembers).Error
-	return councilMembers, err
+	return councilMembers(s.db)
+}
+
+// councilMembers returns the top PFI users who are active
+func councilMembers(db *gorm.DB) ([]models.User, error) {
+	var members []models.User
+	err := db.Where("pfi >= ? AND is_verified = ?", 70, true).
+		Order("pfi DESC").Limit(7).Find(&members).Error
+	return members, err
 }
 
 // MonetaryService handles monetary policy and issuance
 type MonetaryService struct {
-	db *gorm.DB
+	db         *gorm.DB
+	ledger     *LedgerService
+	vesting    *VestingService
+	holdingCap HoldingCap
+
+	// Fairness rewards and merchant incentives vest over rewardVestingPeriod
+	// after rewardVestingCliff; a zero period pays them out unlocked
+	rewardVestingCliff  time.Duration
+	rewardVestingPeriod time.Duration
 }
 
 // NewMonetaryService creates a new monetary service
 func NewMonetaryService(db *gorm.DB) *MonetaryService {
-	return &MonetaryService{db: db}
+	return &MonetaryService{
+		db:         db,
+		ledger:     NewLedgerService(db),
+		vesting:    NewVestingService(db),
+		holdingCap: DefaultHoldingCap(),
+	}
 }
 
 // GetDB returns the database connection
@@ -195,6 +307,18 @@ func (s *MonetaryService) GetDB() *gorm.DB {
 	return s.db
 }
 
+// SetHoldingCap replaces the holding cap enforced on issuance distributions
+func (s *MonetaryService) SetHoldingCap(holdingCap HoldingCap) {
+	s.holdingCap = holdingCap
+}
+
+// SetRewardVesting makes fairness rewards and merchant incentives vest
+// linearly over period, with nothing released before cliff
+func (s *MonetaryService) SetRewardVesting(cliff, period time.Duration) {
+	s.rewardVestingCliff = cliff
+	s.rewardVestingPeriod = period
+}
+
 // ProcessMonthlyIssuance processes the monthly FairCoin issuance
 func (s *MonetaryService) ProcessMonthlyIssuance() error {
 	currentMonth := time.Now().Format("2006-01")
@@ -206,7 +330,7 @@ func (s *MonetaryService) ProcessMonthlyIssuance() error {
 	}
 
 	// Calculate factors for issuance
-	baseIssuance := 1000.0 // Base monthly issuance
+	baseIssuance := models.FC(1000) // Base monthly issuance
 
 	// Activity Factor: based on transaction volume
 	activityFactor := s.calculateActivityFactor()
@@ -215,23 +339,26 @@ func (s *MonetaryService) ProcessMonthlyIssuance() error {
 	fairnessFactor := s.calculateFairnessFactor()
 
 	// Calculate total issuance
-	totalIssuance := baseIssuance * activityFactor * fairnessFactor
+	totalIssuance := baseIssuance.MulRate(activityFactor * fairnessFactor)
 
 	// Apply maximum growth rate cap (2% per month)
 	var totalSupply struct {
-		Total float64
+		Total models.Money
 	}
 	s.db.Model(&models.Wallet{}).Select("SUM(balance) as total").Scan(&totalSupply)
 
-	maxIssuance := totalSupply.Total * 0.02 // 2% monthly cap
+	maxIssuance := totalSupply.Total.MulFrac(2, 100) // 2% monthly cap
 	if totalIssuance > maxIssuance {
 		totalIssuance = maxIssuance
 	}
 
-	// Distribute the new issuance
-	if err := s.distributeIssuance(totalIssuance); err != nil {
+	// Distribute the new issuance; shares above the holding cap or without
+	// recipients that were not minted are not part of this month's issuance
+	withheld, err := s.distributeIssuance(totalIssuance)
+	if err != nil {
 		return fmt.Errorf("failed to distribute issuance: %w", err)
 	}
+	totalIssuance = totalIssuance.Sub(withheld)
 
 	// Record monetary policy
 	policy := &models.MonetaryPolicy{
@@ -263,16 +390,17 @@ func (s *MonetaryService) calculateActivityFactor() float64 {
 	// Get transaction volume for the last 30 days
 	thirtyDaysAgo := time.Now().AddDate(0, 0, -30)
 	var volume struct {
-		Total float64
+		Total models.Money
 		Count int64
 	}
 	s.db.Model(&models.Transaction{}).
 		Where("created_at > ? AND type = ?", thirtyDaysAgo, models.TransactionTypeTransfer).
+		Where("status NOT IN (?)", models.VoidTransactionStatuses).
 		Select("SUM(amount) as total, COUNT(*) as count").Scan(&volume)
 
 	// Baseline activity: 1000 FC volume per month
-	baselineVolume := 1000.0
-	activityFactor := volume.Total / baselineVolume
+	baselineVolume := models.FC(1000)
+	activityFactor := volume.Total.Float64() / baselineVolume.Float64()
 
 	// Cap between 0.5 and 1.5
 	return math.Max(0.5, math.Min(1.5, activityFactor))
@@ -293,177 +421,269 @@ func (s *MonetaryService) calculateFairnessFactor() float64 {
 	return math.Max(0.5, math.Min(1.5, fairnessFactor))
 }
 
-// distributeIssuance distributes newly minted FairCoins according to policy
-func (s *MonetaryService) distributeIssuance(totalIssuance float64) error {
+// distributeIssuance distributes newly minted FairCoins according to policy.
+// It returns the amount withheld because recipients were at the holding cap
+// or frozen, or because a share had no recipients at all.
+func (s *MonetaryService) distributeIssuance(totalIssuance models.Money) (models.Money, error) {
 	tx := s.db.Begin()
 	if tx.Error != nil {
-		return tx.Error
+		return 0, tx.Error
 	}
 
 	// Distribution: 50% liquidity, 25% fairness rewards, 15% merchant incentives, 10% maintenance
-	liquidityAmount := totalIssuance * 0.5
-	fairnessAmount := totalIssuance * 0.25
-	merchantAmount := totalIssuance * 0.15
-	maintenanceAmount := totalIssuance * 0.1
+	// Allocate guarantees the four parts add up to exactly totalIssuance
+	shares := totalIssuance.Allocate([]int64{50, 25, 15, 10})
+	liquidityAmount := shares[0]
+	fairnessAmount := shares[1]
+	merchantAmount := shares[2]
+	maintenanceAmount := shares[3]
+
+	var withheld models.Money
 
 	// 1. Distribute liquidity to active users
-	if err := s.distributeLiquidity(tx, liquidityAmount); err != nil {
+	liquidityWithheld, err := s.distributeLiquidity(tx, liquidityAmount)
+	if err != nil {
 		tx.Rollback()
-		return err
+		return 0, err
 	}
+	withheld = withheld.Add(liquidityWithheld)
 
 	// 2. Distribute fairness rewards to high-PFI users
-	if err := s.distributeFairnessRewards(tx, fairnessAmount); err != nil {
+	fairnessWithheld, err := s.distributeFairnessRewards(tx, fairnessAmount)
+	if err != nil {
 		tx.Rollback()
-		return err
+		return 0, err
 	}
+	withheld = withheld.Add(fairnessWithheld)
 
 	// 3. Distribute merchant incentives to high-TFI merchants
-	if err := s.distributeMerchantIncentives(tx, merchantAmount); err != nil {
+	merchantWithheld, err := s.distributeMerchantIncentives(tx, merchantAmount)
+	if err != nil {
 		tx.Rollback()
-		return err
+		return 0, err
 	}
+	withheld = withheld.Add(merchantWithheld)
 
-	// 4. Maintenance fund (could be sent to a treasury wallet)
-	// For now, just create a record
+	// 4. Maintenance fund is minted into the treasury account
 	maintenanceTransaction := &models.Transaction{
 		Type:        models.TransactionTypeMonthlyIssuance,
 		Amount:      maintenanceAmount,
 		Description: "Monthly maintenance fund allocation",
-		Status:      "completed",
+		Status:      models.TransactionStatusCompleted,
 		CreatedAt:   time.Now(),
 	}
 	if err := tx.Create(maintenanceTransaction).Error; err != nil {
 		tx.Rollback()
+		return 0, err
+	}
+
+	treasury, err := s.ledger.SystemAccount(tx, models.AccountTypeTreasury)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if err := s.mint(tx, &maintenanceTransaction.ID, maintenanceTransaction.Description, treasury, maintenanceAmount); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	return withheld, tx.Commit().Error
+}
+
+// mint posts newly issued FairCoins from the issuance account to an account
+func (s *MonetaryService) mint(tx *gorm.DB, transactionID *uuid.UUID, description string, to *models.LedgerAccount, amount models.Money) error {
+	if amount.IsZero() {
+		return nil
+	}
+
+	issuance, err := s.ledger.SystemAccount(tx, models.AccountTypeIssuance)
+	if err != nil {
 		return err
 	}
 
-	return tx.Commit().Error
+	_, err = s.ledger.Move(tx, transactionID, description, issuance, to, amount)
+	return err
+}
+
+// issueToUser records an issuance transaction and mints amount into a
+// user's wallet, respecting the holding cap. Under the treasury overflow
+// policy the part above the cap is minted into the treasury instead; under
+// the reject policy it is not minted and is returned as withheld, as is
+// everything issued to a frozen wallet.
+func (s *MonetaryService) issueToUser(tx *gorm.DB, transactionType models.TransactionType, description string, userID uuid.UUID, amount models.Money) (models.Money, error) {
+	var wallet models.Wallet
+	if err := tx.Where("user_id = ?", userID).First(&wallet).Error; err != nil {
+		return 0, fmt.Errorf("wallet not found: %w", err)
+	}
+	if wallet.FrozenAt != nil {
+		return amount, nil
+	}
+
+	accepted, overflow, _, err := s.holdingCap.Split(tx, s.ledger, userID, amount)
+	if err != nil {
+		return 0, err
+	}
+
+	var withheld models.Money
+	if overflow.IsPositive() {
+		if s.holdingCap.Overflow == HoldingCapOverflowTreasury {
+			treasury, err := s.ledger.SystemAccount(tx, models.AccountTypeTreasury)
+			if err != nil {
+				return 0, err
+			}
+			if err := s.mint(tx, nil, "Holding cap overflow: "+description, treasury, overflow); err != nil {
+				return 0, err
+			}
+		} else {
+			withheld = overflow
+		}
+	}
+
+	if accepted.IsZero() {
+		return withheld, nil
+	}
+
+	// Create transaction record
+	transaction := &models.Transaction{
+		UserID:      userID,
+		Type:        transactionType,
+		Amount:      accepted,
+		Description: description,
+		Status:      models.TransactionStatusCompleted,
+		CreatedAt:   time.Now(),
+	}
+	if err := tx.Create(transaction).Error; err != nil {
+		return 0, err
+	}
+
+	account, err := s.ledger.UserAccount(tx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.mint(tx, &transaction.ID, description, account, accepted); err != nil {
+		return 0, err
+	}
+
+	// Rewards may vest instead of being spendable right away
+	isReward := transactionType == models.TransactionTypeFairnessReward ||
+		transactionType == models.TransactionTypeMerchantIncentive
+	if isReward && s.rewardVestingPeriod > 0 {
+		now := time.Now()
+		if _, err := s.vesting.Lock(tx, LockRequest{
+			UserID:    userID,
+			Kind:      models.LockKindVesting,
+			Amount:    accepted,
+			StartTime: now,
+			CliffTime: now.Add(s.rewardVestingCliff),
+			EndTime:   now.Add(s.rewardVestingPeriod),
+			Source:    "issuance:" + string(transactionType),
+			Reason:    description,
+		}); err != nil {
+			return 0, fmt.Errorf("failed to lock reward: %w", err)
+		}
+	}
+
+	return withheld, nil
 }
 
 // distributeLiquidity distributes liquidity allocation to active users
-func (s *MonetaryService) distributeLiquidity(tx *gorm.DB, amount float64) error {
-	// Get active users (had transactions in last 30 days)
+func (s *MonetaryService) distributeLiquidity(tx *gorm.DB, amount models.Money) (models.Money, error) {
+	// Get active users (had transactions in last 30 days); system-side
+	// records such as the maintenance allocation have no user
 	thirtyDaysAgo := time.Now().AddDate(0, 0, -30)
 	var activeUserIDs []uuid.UUID
-	tx.Model(&models.Transaction{}).Where("created_at > ?", thirtyDaysAgo).
+	tx.Model(&models.Transaction{}).Where("created_at > ? AND user_id <> ?", thirtyDaysAgo, uuid.Nil).
+		Where("status NOT IN (?)", models.VoidTransactionStatuses).
 		Select("DISTINCT user_id").Pluck("user_id", &activeUserIDs)
 
+	// Nothing is minted without recipients, so the share is withheld
 	if len(activeUserIDs) == 0 {
-		return nil
+		return amount, nil
 	}
 
 	// Equal distribution among active users
-	amountPerUser := amount / float64(len(activeUserIDs))
-
-	for _, userID := range activeUserIDs {
-		// Update wallet balance
-		if err := tx.Model(&models.Wallet{}).Where("user_id = ?", userID).
-			Update("balance", gorm.Expr("balance + ?", amountPerUser)).Error; err != nil {
-			return err
-		}
-
-		// Create transaction record
-		transaction := &models.Transaction{
-			UserID:      userID,
-			Type:        models.TransactionTypeMonthlyIssuance,
-			Amount:      amountPerUser,
-			Description: "Monthly liquidity distribution",
-			Status:      "completed",
-			CreatedAt:   time.Now(),
-		}
-		if err := tx.Create(transaction).Error; err != nil {
-			return err
+	weights := make([]int64, len(activeUserIDs))
+	for i := range weights {
+		weights[i] = 1
+	}
+	userShares := amount.Allocate(weights)
+
+	var withheld models.Money
+	for i, userID := range activeUserIDs {
+		// Mint the share into the wallet through the ledger
+		userWithheld, err := s.issueToUser(tx, models.TransactionTypeMonthlyIssuance,
+			"Monthly liquidity distribution", userID, userShares[i])
+		if err != nil {
+			return 0, err
 		}
+		withheld = withheld.Add(userWithheld)
 	}
 
-	return nil
+	return withheld, nil
 }
 
 // distributeFairnessRewards distributes fairness rewards based on PFI
-func (s *MonetaryService) distributeFairnessRewards(tx *gorm.DB, amount float64) error {
+func (s *MonetaryService) distributeFairnessRewards(tx *gorm.DB, amount models.Money) (models.Money, error) {
 	// Get users with PFI >= 50
 	var users []models.User
 	tx.Where("pfi >= ?", 50).Find(&users)
 
+	// Nothing is minted without recipients, so the share is withheld
 	if len(users) == 0 {
-		return nil
+		return amount, nil
 	}
 
-	// Calculate total PFI points
-	var totalPFI float64
-	for _, user := range users {
-		totalPFI += float64(user.PFI)
+	// Distribute proportionally to PFI; shares always sum to the full amount
+	weights := make([]int64, len(users))
+	for i, user := range users {
+		weights[i] = int64(user.PFI)
 	}
+	userShares := amount.Allocate(weights)
 
-	// Distribute proportionally to PFI
-	for _, user := range users {
-		userShare := (float64(user.PFI) / totalPFI) * amount
-
-		// Update wallet balance
-		if err := tx.Model(&models.Wallet{}).Where("user_id = ?", user.ID).
-			Update("balance", gorm.Expr("balance + ?", userShare)).Error; err != nil {
-			return err
-		}
-
-		// Create transaction record
-		transaction := &models.Transaction{
-			UserID:      user.ID,
-			Type:        models.TransactionTypeFairnessReward,
-			Amount:      userShare,
-			Description: fmt.Sprintf("Monthly fairness reward (PFI: %d)", user.PFI),
-			Status:      "completed",
-			CreatedAt:   time.Now(),
-		}
-		if err := tx.Create(transaction).Error; err != nil {
-			return err
+	var withheld models.Money
+	for i, user := range users {
+		// Mint the share into the wallet through the ledger
+		userWithheld, err := s.issueToUser(tx, models.TransactionTypeFairnessReward,
+			fmt.Sprintf("Monthly fairness reward (PFI: %d)", user.PFI), user.ID, userShares[i])
+		if err != nil {
+			return 0, err
 		}
+		withheld = withheld.Add(userWithheld)
 	}
 
-	return nil
+	return withheld, nil
 }
 
 // distributeMerchantIncentives distributes merchant incentives based on TFI
-func (s *MonetaryService) distributeMerchantIncentives(tx *gorm.DB, amount float64) error {
+func (s *MonetaryService) distributeMerchantIncentives(tx *gorm.DB, amount models.Money) (models.Money, error) {
 	// Get merchants with TFI >= 40
 	var merchants []models.User
 	tx.Where("is_merchant = ? AND tfi >= ?", true, 40).Find(&merchants)
 
+	// Nothing is minted without recipients, so the share is withheld
 	if len(merchants) == 0 {
-		return nil
+		return amount, nil
 	}
 
-	// Calculate total TFI points
-	var totalTFI float64
-	for _, merchant := range merchants {
-		totalTFI += float64(merchant.TFI)
+	// Distribute proportionally to TFI; shares always sum to the full amount
+	weights := make([]int64, len(merchants))
+	for i, merchant := range merchants {
+		weights[i] = int64(merchant.TFI)
 	}
+	merchantShares := amount.Allocate(weights)
 
-	// Distribute proportionally to TFI
-	for _, merchant := range merchants {
-		merchantShare := (float64(merchant.TFI) / totalTFI) * amount
-
-		// Update wallet balance
-		if err := tx.Model(&models.Wallet{}).Where("user_id = ?", merchant.ID).
-			Update("balance", gorm.Expr("balance + ?", merchantShare)).Error; err != nil {
-			return err
-		}
-
-		// Create transaction record
-		transaction := &models.Transaction{
-			UserID:      merchant.ID,
-			Type:        models.TransactionTypeMerchantIncentive,
-			Amount:      merchantShare,
-			Description: fmt.Sprintf("Monthly merchant incentive (TFI: %d)", merchant.TFI),
-			Status:      "completed",
-			CreatedAt:   time.Now(),
-		}
-		if err := tx.Create(transaction).Error; err != nil {
-			return err
+	var withheld models.Money
+	for i, merchant := range merchants {
+		// Mint the share into the wallet through the ledger
+		merchantWithheld, err := s.issueToUser(tx, models.TransactionTypeMerchantIncentive,
+			fmt.Sprintf("Monthly merchant incentive (TFI: %d)", merchant.TFI), merchant.ID, merchantShares[i])
+		if err != nil {
+			return 0, err
 		}
+		withheld = withheld.Add(merchantWithheld)
 	}
 
-	return nil
+	return withheld, nil
 }
 
 // GetCommunityBasketIndex returns the current CBI
@@ -531,9 +751,10 @@ func (s *MonetaryService) GetCurrentMonthStats() (map[string]interface{}, error)
 
 	// Get current month transaction volume
 	var monthlyVolume struct {
-		Total float64
+		Total models.Money
 	}
 	s.db.Model(&models.Transaction{}).Where("created_at >= ? AND type = ?", startOfMonth, models.TransactionTypeTransfer).
+		Where("status NOT IN (?)", models.VoidTransactionStatuses).
 		Select("SUM(amount) as total").Scan(&monthlyVolume)
 	stats["monthly_volume"] = monthlyVolume.Total
 
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"faircoin/internal/models"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+)
+
+// mintedTotal returns everything the issuance account has ever issued
+func mintedTotal(t *testing.T, db *gorm.DB) models.Money {
+	t.Helper()
+	var minted struct {
+		Total models.Money
+	}
+	if err := db.Table("postings").Select("SUM(postings.amount) as total").
+		Joins("JOIN ledger_accounts ON ledger_accounts.id = postings.account_id").
+		Where("ledger_accounts.type = ?", models.AccountTypeIssuance).Scan(&minted).Error; err != nil {
+		t.Fatal(err)
+	}
+	return minted.Total.Neg()
+}
+
+func TestMonthlyIssuanceMatchesLedger(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, db *gorm.DB)
+	}{
+		// Nobody is active, qualifies for rewards or is a merchant, so only
+		// the maintenance share is minted
+		{"no recipients", func(t *testing.T, db *gorm.DB) {}},
+		{"every share has recipients", func(t *testing.T, db *gorm.DB) {
+			alice := newTestUser(t, db, "alice")
+			bob := newTestUser(t, db, "bob")
+			db.Model(&models.User{}).Where("id = ?", alice.ID).Updates(map[string]interface{}{"pfi": 80})
+			db.Model(&models.User{}).Where("id = ?", bob.ID).Updates(map[string]interface{}{"is_merchant": true, "tfi": 60})
+			if _, err := NewWalletService(db).Transfer(alice.ID, bob.ID, models.FC(5), "test"); err != nil {
+				t.Fatal(err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			for _, username := range []string{"carol", "dave"} {
+				newTestUser(t, db, username)
+			}
+			tt.setup(t, db)
+
+			before := mintedTotal(t, db)
+			if err := NewMonetaryService(db).ProcessMonthlyIssuance(); err != nil {
+				t.Fatal(err)
+			}
+			minted := mintedTotal(t, db).Sub(before)
+
+			var policy models.MonetaryPolicy
+			if err := db.First(&policy).Error; err != nil {
+				t.Fatal(err)
+			}
+			if !minted.IsPositive() {
+				t.Fatal("nothing was minted")
+			}
+			if policy.TotalIssuance != minted {
+				t.Errorf("policy records %s FC issued, ledger minted %s FC", policy.TotalIssuance, minted)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"faircoin/internal/database"
+	"faircoin/internal/models"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+	_ "modernc.org/sqlite"
+)
+
+// newTestDB returns a migrated SQLite database that lives for the test
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	db.LogMode(false)
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("migrate database: %v", err)
+	}
+	return db
+}
+
+// newTestUser registers a user, which funds the wallet with the starting
+// balance through the ledger
+func newTestUser(t *testing.T, db *gorm.DB, username string) *models.User {
+	t.Helper()
+	user, err := NewUserService(db).CreateUser(username, username+"@example.com", "password123", "Test", "User")
+	if err != nil {
+		t.Fatalf("create user %s: %v", username, err)
+	}
+	return user
+}
+
+// walletBalance returns the balance of a user's wallet
+func walletBalance(t *testing.T, db *gorm.DB, userID uuid.UUID) models.Money {
+	t.Helper()
+	var wallet models.Wallet
+	if err := db.Where("user_id = ?", userID).First(&wallet).Error; err != nil {
+		t.Fatal(err)
+	}
+	return wallet.Balance
+}
+
+// mustTransaction loads a transaction by ID
+func mustTransaction(t *testing.T, db *gorm.DB, id uuid.UUID) *models.Transaction {
+	t.Helper()
+	var transaction models.Transaction
+	if err := db.First(&transaction, "id = ?", id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &transaction
+}
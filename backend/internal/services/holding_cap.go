@@ -0,0 +1,116 @@
+package services
+
+import (
+	"errors"
+	"faircoin/internal/models"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+)
+
+// ErrHoldingCapExceeded is returned when a payment would push a wallet over
+// the holding cap and the overflow policy is to reject it
+var ErrHoldingCapExceeded = errors.New("holding cap exceeded")
+
+// HoldingCapOverflow decides what happens to the part of an incoming amount
+// that would push a wallet over the holding cap
+type HoldingCapOverflow string
+
+const (
+	// HoldingCapOverflowReject refuses transfers over the cap; issuance over
+	// the cap is simply not minted
+	HoldingCapOverflowReject HoldingCapOverflow = "reject"
+	// HoldingCapOverflowTreasury credits the wallet up to the cap and sends
+	// the rest to the community treasury
+	HoldingCapOverflowTreasury HoldingCapOverflow = "treasury"
+)
+
+// HoldingCap limits the share of circulating supply a single wallet may hold.
+// System ledger accounts (treasury, issuance, burn) are never capped.
+type HoldingCap struct {
+	Percentage    float64      // Maximum share of circulating supply, 0 disables the cap
+	Minimum       models.Money // The cap never drops below this amount
+	Overflow      HoldingCapOverflow
+	ExemptUserIDs map[uuid.UUID]bool
+}
+
+// DefaultHoldingCap returns the cap used when none is configured
+func DefaultHoldingCap() HoldingCap {
+	return HoldingCap{
+		Percentage: 0.02,
+		Minimum:    models.FC(1000),
+		Overflow:   HoldingCapOverflowReject,
+	}
+}
+
+// NewHoldingCap builds a holding cap from configuration values
+func NewHoldingCap(percentage, minimum float64, overflow, exemptIDs string) (HoldingCap, error) {
+	holdingCap := HoldingCap{
+		Percentage:    percentage,
+		Minimum:       models.MoneyFromFloat(minimum),
+		Overflow:      HoldingCapOverflow(overflow),
+		ExemptUserIDs: make(map[uuid.UUID]bool),
+	}
+
+	if percentage < 0 || percentage > 1 {
+		return holdingCap, fmt.Errorf("holding cap percentage must be between 0 and 1, got %v", percentage)
+	}
+	if holdingCap.Overflow != HoldingCapOverflowReject && holdingCap.Overflow != HoldingCapOverflowTreasury {
+		return holdingCap, fmt.Errorf("unknown holding cap overflow policy: %q", overflow)
+	}
+
+	for _, idStr := range strings.Split(exemptIDs, ",") {
+		idStr = strings.TrimSpace(idStr)
+		if idStr == "" {
+			continue
+		}
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			return holdingCap, fmt.Errorf("invalid exempt user ID %q: %w", idStr, err)
+		}
+		holdingCap.ExemptUserIDs[id] = true
+	}
+
+	return holdingCap, nil
+}
+
+// Enabled reports whether the cap is enforced at all
+func (c HoldingCap) Enabled() bool {
+	return c.Percentage > 0
+}
+
+// IsExempt reports whether a user's wallet is exempt from the cap
+func (c HoldingCap) IsExempt(userID uuid.UUID) bool {
+	return !c.Enabled() || c.ExemptUserIDs[userID]
+}
+
+// Limit returns the largest balance a wallet may hold for a given
+// circulating supply
+func (c HoldingCap) Limit(supply models.Money) models.Money {
+	return models.MaxMoney(supply.MulRate(c.Percentage), c.Minimum)
+}
+
+// Split divides an amount about to be credited to userID into the part the
+// wallet can accept and the overflow above the cap. limit is the cap that
+// was applied.
+func (c HoldingCap) Split(tx *gorm.DB, ledger *LedgerService, userID uuid.UUID, amount models.Money) (accepted, overflow, limit models.Money, err error) {
+	if c.IsExempt(userID) || !amount.IsPositive() {
+		return amount, 0, 0, nil
+	}
+
+	supply, err := ledger.CirculatingSupply(tx)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	account, err := ledger.UserAccount(tx, userID)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	limit = c.Limit(supply)
+	room := models.MaxMoney(limit.Sub(account.Balance), 0)
+	accepted = models.MinMoney(amount, room)
+	return accepted, amount.Sub(accepted), limit, nil
+}
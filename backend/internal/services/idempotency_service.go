@@ -0,0 +1,210 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"faircoin/internal/models"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// IdempotencyKeyTTL is how long a stored response can be replayed
+	IdempotencyKeyTTL = 24 * time.Hour
+	// IdempotencyLease is how long a claim stays in progress before a retry
+	// may reconcile it. It is well beyond any request's running time, so an
+	// expired claim belongs to a request that died.
+	IdempotencyLease = 5 * time.Minute
+)
+
+var (
+	// ErrIdempotencyKeyReused is returned when a key is sent again with a different request
+	ErrIdempotencyKeyReused = errors.New("idempotency key was already used for a different request")
+	// ErrIdempotencyInProgress is returned while the original request is still running
+	ErrIdempotencyInProgress = errors.New("a request with this idempotency key is still in progress")
+)
+
+// IdempotencyService stores responses of money-moving requests so that a
+// retried request returns the original result instead of running twice
+type IdempotencyService struct {
+	db *gorm.DB
+}
+
+// NewIdempotencyService creates a new idempotency service
+func NewIdempotencyService(db *gorm.DB) *IdempotencyService {
+	return &IdempotencyService{db: db}
+}
+
+// GetDB returns the database connection
+func (s *IdempotencyService) GetDB() *gorm.DB {
+	return s.db
+}
+
+// Begin claims an idempotency key for a request. If the key was already used
+// for the same request and finished, the stored record is returned with
+// replay set to true and the caller must return the stored response.
+//
+// The response is stored after the request's own database transaction has
+// committed, so a crash in between leaves the claim in progress. Once its
+// lease has expired, a retry reconciles it: if the request recorded what it
+// created (see models.IdempotencyClaimKey), the retry gets a response naming
+// that; otherwise nothing was committed and the retry takes over the claim
+// and runs the request.
+func (s *IdempotencyService) Begin(userID uuid.UUID, key, method, path, fingerprint string) (record *models.IdempotencyRecord, replay bool, err error) {
+	var existing models.IdempotencyRecord
+	err = s.db.Where("user_id = ? AND idempotency_key = ? AND created_at > ?", userID, key, time.Now().Add(-IdempotencyKeyTTL)).
+		First(&existing).Error
+	if err == nil {
+		if existing.Fingerprint != fingerprint {
+			return nil, false, ErrIdempotencyKeyReused
+		}
+		if existing.StatusCode == 0 {
+			if time.Since(existing.UpdatedAt) < IdempotencyLease {
+				return nil, false, ErrIdempotencyInProgress
+			}
+			return s.reconcile(&existing)
+		}
+		return &existing, true, nil
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return nil, false, err
+	}
+
+	// Drop an expired record with the same key so it can be reused
+	s.db.Where("user_id = ? AND idempotency_key = ?", userID, key).Delete(&models.IdempotencyRecord{})
+
+	record = &models.IdempotencyRecord{
+		UserID:      userID,
+		Key:         key,
+		Method:      method,
+		Path:        path,
+		Fingerprint: fingerprint,
+		Attempt:     1,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	if err := s.db.Create(record).Error; err != nil {
+		// The unique index rejects a concurrent request with the same key
+		return nil, false, ErrIdempotencyInProgress
+	}
+
+	return record, false, nil
+}
+
+// rebuiltResponses rebuild a lost response from the resource the request
+// recorded on its claim, in the shape its handler returns. Other resources
+// get a response that only names them.
+var rebuiltResponses = map[string]func(db *gorm.DB, id uuid.UUID) (int, map[string]interface{}, error){
+	"ratings": func(db *gorm.DB, id uuid.UUID) (int, map[string]interface{}, error) {
+		var rating models.Rating
+		if err := db.First(&rating, "id = ?", id).Error; err != nil {
+			return 0, nil, err
+		}
+		return http.StatusCreated, map[string]interface{}{"message": "Rating created successfully", "rating": rating}, nil
+	},
+	"attestations": func(db *gorm.DB, id uuid.UUID) (int, map[string]interface{}, error) {
+		var attestation models.Attestation
+		if err := db.First(&attestation, "id = ?", id).Error; err != nil {
+			return 0, nil, err
+		}
+		return http.StatusCreated, map[string]interface{}{"message": "Attestation created successfully", "attestation": attestation}, nil
+	},
+	"attestation_reviews": func(db *gorm.DB, id uuid.UUID) (int, map[string]interface{}, error) {
+		var review models.AttestationReview
+		if err := db.First(&review, "id = ?", id).Error; err != nil {
+			return 0, nil, err
+		}
+		var attestation models.Attestation
+		if err := db.Preload("Reviews").First(&attestation, "id = ?", review.AttestationID).Error; err != nil {
+			return 0, nil, err
+		}
+		return http.StatusOK, map[string]interface{}{"message": "Review recorded successfully", "attestation": attestation}, nil
+	},
+	"votes": func(db *gorm.DB, id uuid.UUID) (int, map[string]interface{}, error) {
+		var vote models.Vote
+		if err := db.First(&vote, "id = ?", id).Error; err != nil {
+			return 0, nil, err
+		}
+		return http.StatusOK, map[string]interface{}{"message": "Vote recorded successfully"}, nil
+	},
+	"credit_votes": func(db *gorm.DB, id uuid.UUID) (int, map[string]interface{}, error) {
+		var vote models.CreditVote
+		if err := db.First(&vote, "id = ?", id).Error; err != nil {
+			return 0, nil, err
+		}
+		var request models.CreditRequest
+		if err := db.First(&request, "id = ?", vote.RequestID).Error; err != nil {
+			return 0, nil, err
+		}
+		return http.StatusOK, map[string]interface{}{"message": "Vote recorded successfully", "request": request}, nil
+	},
+}
+
+// reconcile settles a claim whose lease expired while it was in progress
+func (s *IdempotencyService) reconcile(claim *models.IdempotencyRecord) (*models.IdempotencyRecord, bool, error) {
+	if claim.ResourceID != nil {
+		status := http.StatusOK
+		response := map[string]interface{}{
+			"message":       "This request was already processed; its original response was lost",
+			"resource_type": claim.ResourceType,
+			"resource_id":   claim.ResourceID,
+		}
+		if rebuild, ok := rebuiltResponses[claim.ResourceType]; ok {
+			var err error
+			if status, response, err = rebuild(s.db, *claim.ResourceID); err != nil {
+				return nil, false, fmt.Errorf("failed to rebuild idempotent response: %w", err)
+			}
+		}
+		body, _ := json.Marshal(response)
+		if err := s.Complete(claim, status, string(body)); err != nil {
+			return nil, false, err
+		}
+		claim.StatusCode = status
+		claim.ResponseBody = string(body)
+		return claim, true, nil
+	}
+
+	// Nothing was committed. Of several retries only one takes over.
+	result := s.db.Model(&models.IdempotencyRecord{}).
+		Where("id = ? AND attempt = ? AND status_code = 0 AND resource_id IS NULL", claim.ID, claim.Attempt).
+		Updates(map[string]interface{}{"attempt": claim.Attempt + 1, "updated_at": time.Now()})
+	if result.Error != nil {
+		return nil, false, fmt.Errorf("failed to take over idempotency key: %w", result.Error)
+	}
+	if result.RowsAffected != 1 {
+		return nil, false, ErrIdempotencyInProgress
+	}
+	claim.Attempt++
+	return claim, false, nil
+}
+
+// Complete stores the final response for a claimed key. A claim taken over
+// by a retry is left to the retry.
+func (s *IdempotencyService) Complete(record *models.IdempotencyRecord, statusCode int, body string) error {
+	return s.db.Model(&models.IdempotencyRecord{}).
+		Where("id = ? AND attempt = ?", record.ID, record.Attempt).
+		Updates(map[string]interface{}{
+			"status_code":   statusCode,
+			"response_body": body,
+			"updated_at":    time.Now(),
+		}).Error
+}
+
+// Abandon releases a claimed key so the request can be retried, e.g. after
+// a server error where nothing was committed
+func (s *IdempotencyService) Abandon(record *models.IdempotencyRecord) error {
+	return s.db.Where("id = ? AND attempt = ?", record.ID, record.Attempt).Delete(&models.IdempotencyRecord{}).Error
+}
+
+// PurgeExpired deletes records older than IdempotencyKeyTTL
+func (s *IdempotencyService) PurgeExpired() error {
+	if err := s.db.Where("created_at < ?", time.Now().Add(-IdempotencyKeyTTL)).
+		Delete(&models.IdempotencyRecord{}).Error; err != nil {
+		return fmt.Errorf("failed to purge idempotency records: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"faircoin/internal/models"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// expireLease makes a claim look like its request died
+func expireLease(t *testing.T, db *gorm.DB, claim *models.IdempotencyRecord) {
+	t.Helper()
+	if err := db.Model(&models.IdempotencyRecord{}).Where("id = ?", claim.ID).
+		UpdateColumn("updated_at", time.Now().Add(-2*IdempotencyLease)).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIdempotencyLease(t *testing.T) {
+	db := newTestDB(t)
+	alice := newTestUser(t, db, "alice")
+	idempotency := NewIdempotencyService(db)
+
+	claim, replay, err := idempotency.Begin(alice.ID, "key", "POST", "/wallet/send", "body")
+	if err != nil || replay {
+		t.Fatalf("Begin() = %v, %v", replay, err)
+	}
+	if _, _, err := idempotency.Begin(alice.ID, "key", "POST", "/wallet/send", "body"); !errors.Is(err, ErrIdempotencyInProgress) {
+		t.Errorf("retry during the lease: %v, want ErrIdempotencyInProgress", err)
+	}
+	if _, _, err := idempotency.Begin(alice.ID, "key", "POST", "/wallet/send", "other body"); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Errorf("other request with the key: %v, want ErrIdempotencyKeyReused", err)
+	}
+
+	// Nothing was recorded, so a retry after the lease takes over the claim
+	// and the original request can no longer complete it
+	expireLease(t, db, claim)
+	takeover, replay, err := idempotency.Begin(alice.ID, "key", "POST", "/wallet/send", "body")
+	if err != nil || replay {
+		t.Fatalf("retry after the lease: %v, %v", replay, err)
+	}
+	if takeover.Attempt != 2 {
+		t.Errorf("attempt %d, want 2", takeover.Attempt)
+	}
+	if err := idempotency.Complete(claim, http.StatusCreated, `{"from":"first"}`); err != nil {
+		t.Fatal(err)
+	}
+	if err := idempotency.Complete(takeover, http.StatusCreated, `{"from":"retry"}`); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, replay, err := idempotency.Begin(alice.ID, "key", "POST", "/wallet/send", "body")
+	if err != nil || !replay {
+		t.Fatalf("Begin() after completion = %v, %v", replay, err)
+	}
+	if stored.ResponseBody != `{"from":"retry"}` {
+		t.Errorf("replayed %s, want the retry's response", stored.ResponseBody)
+	}
+}
+
+func TestIdempotencyReconcileRebuildsResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		run        func(t *testing.T, db *gorm.DB, claim *models.IdempotencyRecord, alice, bob *models.User) error
+		wantType   string
+		wantStatus int
+		wantKey    string
+	}{
+		{"transfer", func(t *testing.T, db *gorm.DB, claim *models.IdempotencyRecord, alice, bob *models.User) error {
+			_, err := NewWalletService(db).WithIdempotency(claim).Transfer(alice.ID, bob.ID, models.FC(5), "test")
+			return err
+		}, "transactions", http.StatusOK, "resource_id"},
+		{"rating", func(t *testing.T, db *gorm.DB, claim *models.IdempotencyRecord, alice, bob *models.User) error {
+			db.Model(&models.User{}).Where("id = ?", bob.ID).Update("is_merchant", true)
+			_, err := NewFairnessService(db).WithIdempotency(claim).CreateRating(alice.ID, bob.ID, nil, 8, 8, 8, 8, "")
+			return err
+		}, "ratings", http.StatusCreated, "rating"},
+		{"attestation", func(t *testing.T, db *gorm.DB, claim *models.IdempotencyRecord, alice, bob *models.User) error {
+			db.Model(&models.User{}).Where("id = ?", alice.ID).Update("pfi", 50)
+			_, err := NewFairnessService(db).WithIdempotency(claim).CreateAttestation(bob.ID, alice.ID, "community_service", 7, "")
+			return err
+		}, "attestations", http.StatusCreated, "attestation"},
+		{"vote", func(t *testing.T, db *gorm.DB, claim *models.IdempotencyRecord, alice, bob *models.User) error {
+			proposal := &models.Proposal{ProposerID: bob.ID, Title: "Garden", Description: "Seeds",
+				Type: models.ProposalTypeTreasurySpend, Status: models.ProposalStatusActive,
+				StartTime: time.Now(), EndTime: time.Now().Add(time.Hour)}
+			if err := db.Create(proposal).Error; err != nil {
+				t.Fatal(err)
+			}
+			return NewGovernanceService(db).WithIdempotency(claim).VoteOnProposal(alice.ID, proposal.ID, true)
+		}, "votes", http.StatusOK, "message"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			alice := newTestUser(t, db, "alice")
+			bob := newTestUser(t, db, "bob")
+			idempotency := NewIdempotencyService(db)
+
+			claim, _, err := idempotency.Begin(alice.ID, "key", "POST", "/"+tt.name, "body")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.run(t, db, claim, alice, bob); err != nil {
+				t.Fatal(err)
+			}
+
+			// The request committed but died before storing its response
+			expireLease(t, db, claim)
+			record, replay, err := idempotency.Begin(alice.ID, "key", "POST", "/"+tt.name, "body")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !replay {
+				t.Fatal("a request that committed ran again")
+			}
+			if record.ResourceType != tt.wantType {
+				t.Errorf("resource type %q, want %q", record.ResourceType, tt.wantType)
+			}
+			if record.StatusCode != tt.wantStatus {
+				t.Errorf("status %d, want %d", record.StatusCode, tt.wantStatus)
+			}
+			var body map[string]interface{}
+			if err := json.Unmarshal([]byte(record.ResponseBody), &body); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := body[tt.wantKey]; !ok {
+				t.Errorf("response %s has no %q", record.ResponseBody, tt.wantKey)
+			}
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"errors"
+	"faircoin/internal/models"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// DefaultInvoiceExpiry is how long an invoice stays payable when no expiry is given
+	DefaultInvoiceExpiry = 30 * 24 * time.Hour
+	// MaxInvoiceExpiry is the longest an invoice may stay payable
+	MaxInvoiceExpiry = 365 * 24 * time.Hour
+	// MaxInvoiceLineItems caps the number of lines on one invoice
+	MaxInvoiceLineItems = 100
+)
+
+// ErrInvoiceNotFound is returned when an invoice does not exist or the user
+// is not a party to it
+var ErrInvoiceNotFound = errors.New("invoice not found")
+
+// ErrInvoiceNotOpen is returned when an invoice has been paid, cancelled or has expired
+var ErrInvoiceNotOpen = errors.New("invoice is not open")
+
+// CreateInvoice asks payerID to pay issuerID. When line items are given the
+// invoice amount is their total and amount must be zero or equal to it.
+func (s *WalletService) CreateInvoice(issuerID, payerID uuid.UUID, amount models.Money, description string,
+	expiresAt time.Time, items []models.InvoiceLineItem) (*models.Invoice, error) {
+	if issuerID == payerID {
+		return nil, fmt.Errorf("cannot send a payment request to yourself")
+	}
+	if len(items) > MaxInvoiceLineItems {
+		return nil, fmt.Errorf("an invoice can have at most %d line items", MaxInvoiceLineItems)
+	}
+
+	var total models.Money
+	for i := range items {
+		item := &items[i]
+		if item.Quantity == 0 {
+			item.Quantity = 1
+		}
+		if item.Quantity < 0 || !item.UnitPrice.IsPositive() {
+			return nil, fmt.Errorf("line item %d must have a positive quantity and unit price", i+1)
+		}
+		item.ID = uuid.Nil
+		item.Position = i + 1
+		item.Amount = item.UnitPrice.MulInt(item.Quantity)
+		total = total.Add(item.Amount)
+	}
+	if len(items) > 0 {
+		if !amount.IsZero() && amount != total {
+			return nil, fmt.Errorf("amount %s FC does not match the line item total of %s FC", amount, total)
+		}
+		amount = total
+	}
+	if !amount.IsPositive() {
+		return nil, fmt.Errorf("amount must be positive")
+	}
+
+	now := time.Now()
+	if expiresAt.IsZero() {
+		expiresAt = now.Add(DefaultInvoiceExpiry)
+	}
+	expiresAt = expiresAt.UTC()
+	if !expiresAt.After(now) || expiresAt.Sub(now) > MaxInvoiceExpiry {
+		return nil, fmt.Errorf("expiry must be in the future and at most %d days away", int(MaxInvoiceExpiry.Hours()/24))
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	var payer models.User
+	if err := tx.First(&payer, "id = ?", payerID).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("payer not found: %w", err)
+	}
+
+	invoice := &models.Invoice{
+		IssuerID:    issuerID,
+		PayerID:     payerID,
+		Amount:      amount,
+		Description: description,
+		Status:      models.InvoiceStatusOpen,
+		ExpiresAt:   expiresAt,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := tx.Create(invoice).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to create invoice: %w", err)
+	}
+
+	for i := range items {
+		items[i].InvoiceID = invoice.ID
+		if err := tx.Create(&items[i]).Error; err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to create line item: %w", err)
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit invoice: %w", err)
+	}
+
+	return s.GetInvoice(invoice.ID, issuerID)
+}
+
+// PayInvoice pays an open invoice in full with a normal transfer from the
+// payer to the issuer. The transfer records the invoice in its metadata and
+// the invoice records the transfer.
+func (s *WalletService) PayInvoice(invoiceID, payerID uuid.UUID) (*models.Invoice, error) {
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	var invoice models.Invoice
+	if err := tx.First(&invoice, "id = ? AND payer_id = ?", invoiceID, payerID).Error; err != nil {
+		tx.Rollback()
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrInvoiceNotFound
+		}
+		return nil, fmt.Errorf("failed to get invoice: %w", err)
+	}
+	if invoice.Status == models.InvoiceStatusOpen && !invoice.ExpiresAt.After(time.Now()) {
+		tx.Rollback()
+		return nil, fmt.Errorf("%w: it expired at %s", ErrInvoiceNotOpen, invoice.ExpiresAt.Format(time.RFC3339))
+	}
+
+	// Claim the invoice so it can only be paid once
+	now := time.Now()
+	result := tx.Model(&models.Invoice{}).
+		Where("id = ? AND status = ?", invoice.ID, models.InvoiceStatusOpen).
+		Updates(map[string]interface{}{"status": models.InvoiceStatusPaid, "paid_at": now, "updated_at": now})
+	if result.Error != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to update invoice: %w", result.Error)
+	}
+	if result.RowsAffected != 1 {
+		tx.Rollback()
+		return nil, fmt.Errorf("%w: it is %s", ErrInvoiceNotOpen, invoice.Status)
+	}
+
+	description := invoice.Description
+	if description == "" {
+		description = "Invoice payment"
+	}
+	transaction, err := s.transfer(tx, payerID, invoice.IssuerID, invoice.Amount, description,
+		map[string]interface{}{"invoice_id": invoice.ID})
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Model(&models.Invoice{}).Where("id = ?", invoice.ID).
+		Update("transaction_id", transaction.ID).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to link transaction: %w", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit invoice payment: %w", err)
+	}
+
+	return s.GetInvoice(invoice.ID, payerID)
+}
+
+// CancelInvoice withdraws an open invoice. Only the issuer can cancel it.
+func (s *WalletService) CancelInvoice(invoiceID, issuerID uuid.UUID) (*models.Invoice, error) {
+	now := time.Now()
+	result := s.db.Model(&models.Invoice{}).
+		Where("id = ? AND issuer_id = ? AND status = ?", invoiceID, issuerID, models.InvoiceStatusOpen).
+		Updates(map[string]interface{}{"status": models.InvoiceStatusCancelled, "cancelled_at": now, "updated_at": now})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to cancel invoice: %w", result.Error)
+	}
+	if result.RowsAffected != 1 {
+		invoice, err := s.GetInvoice(invoiceID, issuerID)
+		if err != nil {
+			return nil, err
+		}
+		if invoice.IssuerID != issuerID {
+			return nil, fmt.Errorf("only the issuer can cancel an invoice")
+		}
+		return nil, fmt.Errorf("%w: it is %s", ErrInvoiceNotOpen, invoice.Status)
+	}
+
+	return s.GetInvoice(invoiceID, issuerID)
+}
+
+// ExpireInvoices marks open invoices past their expiry as expired
+func (s *WalletService) ExpireInvoices() error {
+	return s.db.Model(&models.Invoice{}).
+		Where("status = ? AND expires_at <= ?", models.InvoiceStatusOpen, time.Now().UTC()).
+		Updates(map[string]interface{}{"status": models.InvoiceStatusExpired, "updated_at": time.Now()}).Error
+}
+
+// GetInvoice returns an invoice the user issued or was asked to pay
+func (s *WalletService) GetInvoice(invoiceID, userID uuid.UUID) (*models.Invoice, error) {
+	var invoice models.Invoice
+	if err := s.db.Preload("Issuer").Preload("Payer").Preload("Transaction").
+		Preload("LineItems", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC") }).
+		Where("issuer_id = ? OR payer_id = ?", userID, userID).
+		First(&invoice, "id = ?", invoiceID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrInvoiceNotFound
+		}
+		return nil, fmt.Errorf("failed to get invoice: %w", err)
+	}
+	return &invoice, nil
+}
+
+// InvoiceFilter narrows an invoice search
+type InvoiceFilter struct {
+	Issued       bool   // Invoices the user issued; otherwise the user's inbox
+	Status       string // Empty for any status
+	Query        string // Matches the description
+	Counterparty string // Username of the other side
+	Limit        int
+	Offset       int
+}
+
+// SearchInvoices returns a page of the user's issued invoices or inbox,
+// newest first, along with the total number of matches
+func (s *WalletService) SearchInvoices(userID uuid.UUID, filter InvoiceFilter) ([]models.Invoice, int, error) {
+	ownColumn, otherColumn := "payer_id", "issuer_id"
+	if filter.Issued {
+		ownColumn, otherColumn = "issuer_id", "payer_id"
+	}
+
+	query := s.db.Model(&models.Invoice{}).Where(ownColumn+" = ?", userID)
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Query != "" {
+		query = query.Where("description LIKE ?", "%"+filter.Query+"%")
+	}
+	if filter.Counterparty != "" {
+		query = query.Where(otherColumn+" IN (?)",
+			s.db.Table("users").Select("id").Where("username = ?", filter.Counterparty).QueryExpr())
+	}
+
+	var total int
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var invoices []models.Invoice
+	err := query.Preload("Issuer").Preload("Payer").
+		Preload("LineItems", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC") }).
+		Order("created_at DESC").Limit(filter.Limit).Offset(filter.Offset).
+		Find(&invoices).Error
+	return invoices, total, err
+}
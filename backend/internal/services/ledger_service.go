@@ -0,0 +1,322 @@
+package services
+
+import (
+	"faircoin/internal/models"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+)
+
+// LedgerService maintains the double-entry ledger behind all wallets.
+// Every movement of FairCoins is a journal entry whose postings sum to zero,
+// so the circulating supply always equals total issuance minus burns.
+type LedgerService struct {
+	db *gorm.DB
+}
+
+// NewLedgerService creates a new ledger service
+func NewLedgerService(db *gorm.DB) *LedgerService {
+	return &LedgerService{db: db}
+}
+
+// GetDB returns the database connection
+func (s *LedgerService) GetDB() *gorm.DB {
+	return s.db
+}
+
+// userAccountCode returns the ledger account code for a user's wallet
+func userAccountCode(userID uuid.UUID) string {
+	return "user:" + userID.String()
+}
+
+// UserAccount returns the ledger account for a user, creating it if needed
+func (s *LedgerService) UserAccount(tx *gorm.DB, userID uuid.UUID) (*models.LedgerAccount, error) {
+	var account models.LedgerAccount
+	err := tx.Where("code = ?", userAccountCode(userID)).First(&account).Error
+	if err == nil {
+		return &account, nil
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return nil, err
+	}
+
+	account = models.LedgerAccount{
+		Code:   userAccountCode(userID),
+		Type:   models.AccountTypeUser,
+		UserID: &userID,
+	}
+	if err := tx.Create(&account).Error; err != nil {
+		return nil, fmt.Errorf("failed to create ledger account: %w", err)
+	}
+	return &account, nil
+}
+
+// SystemAccount returns a system-owned ledger account, creating it if needed
+func (s *LedgerService) SystemAccount(tx *gorm.DB, accountType models.AccountType) (*models.LedgerAccount, error) {
+	if accountType == models.AccountTypeUser {
+		return nil, fmt.Errorf("not a system account type: %s", accountType)
+	}
+
+	var account models.LedgerAccount
+	err := tx.Where("code = ?", string(accountType)).First(&account).Error
+	if err == nil {
+		return &account, nil
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return nil, err
+	}
+
+	account = models.LedgerAccount{
+		Code: string(accountType),
+		Type: accountType,
+	}
+	if err := tx.Create(&account).Error; err != nil {
+		return nil, fmt.Errorf("failed to create ledger account: %w", err)
+	}
+	return &account, nil
+}
+
+// Leg is one side of a journal entry before it is posted
+type Leg struct {
+	Account *models.LedgerAccount
+	Amount  models.Money // Positive credits the account, negative debits it
+}
+
+// Post records a balanced journal entry inside tx and updates the cached
+// balances of every account involved, including Wallet.Balance for user
+// accounts. It fails if the legs do not sum to zero.
+func (s *LedgerService) Post(tx *gorm.DB, transactionID *uuid.UUID, description string, legs ...Leg) (*models.JournalEntry, error) {
+	return s.post(tx, transactionID, description, true, legs)
+}
+
+// Move is a convenience wrapper for the common two-leg entry that moves
+// amount from one account to another
+func (s *LedgerService) Move(tx *gorm.DB, transactionID *uuid.UUID, description string, from, to *models.LedgerAccount, amount models.Money) (*models.JournalEntry, error) {
+	return s.Post(tx, transactionID, description,
+		Leg{Account: from, Amount: amount.Neg()},
+		Leg{Account: to, Amount: amount},
+	)
+}
+
+func (s *LedgerService) post(tx *gorm.DB, transactionID *uuid.UUID, description string, syncWallets bool, legs []Leg) (*models.JournalEntry, error) {
+	if len(legs) < 2 {
+		return nil, fmt.Errorf("journal entry needs at least two postings")
+	}
+
+	var sum models.Money
+	for _, leg := range legs {
+		if leg.Account == nil {
+			return nil, fmt.Errorf("journal entry posting has no account")
+		}
+		sum = sum.Add(leg.Amount)
+	}
+	if !sum.IsZero() {
+		return nil, fmt.Errorf("unbalanced journal entry: postings sum to %s", sum)
+	}
+
+	now := time.Now()
+	entry := &models.JournalEntry{
+		TransactionID: transactionID,
+		Description:   description,
+		CreatedAt:     now,
+	}
+	if err := tx.Create(entry).Error; err != nil {
+		return nil, fmt.Errorf("failed to create journal entry: %w", err)
+	}
+
+	for _, leg := range legs {
+		if leg.Amount.IsZero() {
+			continue
+		}
+
+		posting := models.Posting{
+			EntryID:   entry.ID,
+			AccountID: leg.Account.ID,
+			Amount:    leg.Amount,
+			CreatedAt: now,
+		}
+		if err := tx.Create(&posting).Error; err != nil {
+			return nil, fmt.Errorf("failed to create posting: %w", err)
+		}
+		entry.Postings = append(entry.Postings, posting)
+
+		if err := tx.Model(&models.LedgerAccount{}).Where("id = ?", leg.Account.ID).
+			Update("balance", gorm.Expr("balance + ?", leg.Amount)).Error; err != nil {
+			return nil, fmt.Errorf("failed to update account balance: %w", err)
+		}
+		leg.Account.Balance = leg.Account.Balance.Add(leg.Amount)
+
+		if syncWallets && leg.Account.Type == models.AccountTypeUser && leg.Account.UserID != nil {
+			var wallet models.Wallet
+			if err := tx.Select("id").Where("user_id = ?", *leg.Account.UserID).Order("id ASC").
+				First(&wallet).Error; err != nil {
+				return nil, fmt.Errorf("failed to find wallet for ledger account %s: %w", leg.Account.Code, err)
+			}
+			if err := tx.Model(&models.Wallet{}).Where("id = ?", wallet.ID).
+				Update("balance", gorm.Expr("balance + ?", leg.Amount)).Error; err != nil {
+				return nil, fmt.Errorf("failed to update wallet balance: %w", err)
+			}
+			if err := recordCreditMovement(tx, *leg.Account.UserID, leg.Account.ID, entry, leg.Amount); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return entry, nil
+}
+
+// EnsureOpeningBalances brings wallets that predate the ledger into it.
+// Each wallet without a ledger account gets one, funded from the issuance
+// account with the wallet's current balance. Wallet balances themselves are
+// not changed. Relies on database.MergeDuplicateWallets having left one
+// wallet per user. Safe to call on every startup.
+func (s *LedgerService) EnsureOpeningBalances() error {
+	var wallets []models.Wallet
+	if err := s.db.Where("user_id NOT IN (?)",
+		s.db.Table("ledger_accounts").Select("user_id").Where("user_id IS NOT NULL").QueryExpr()).
+		Order("id ASC").Find(&wallets).Error; err != nil {
+		return err
+	}
+	if len(wallets) == 0 {
+		return nil
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	issuance, err := s.SystemAccount(tx, models.AccountTypeIssuance)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, wallet := range wallets {
+		account, err := s.UserAccount(tx, wallet.UserID)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if wallet.Balance.IsZero() {
+			continue
+		}
+
+		if _, err := s.post(tx, nil, "Opening balance", false, []Leg{
+			{Account: issuance, Amount: wallet.Balance.Neg()},
+			{Account: account, Amount: wallet.Balance},
+		}); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+// CirculatingSupply returns the FairCoins held by users, the treasury, escrow
+// and outstanding vouchers
+func (s *LedgerService) CirculatingSupply(tx *gorm.DB) (models.Money, error) {
+	var supply struct {
+		Total models.Money
+	}
+	err := tx.Model(&models.LedgerAccount{}).
+		Where("type IN (?)", []models.AccountType{models.AccountTypeUser, models.AccountTypeTreasury, models.AccountTypeFeeIncome, models.AccountTypeEscrow,
+			models.AccountTypeVoucher}).
+		Select("SUM(balance) as total").Scan(&supply).Error
+	return supply.Total, err
+}
+
+// GetAccountBalances returns all ledger accounts with their cached balances
+func (s *LedgerService) GetAccountBalances() ([]models.LedgerAccount, error) {
+	var accounts []models.LedgerAccount
+	err := s.db.Order("type ASC, code ASC").Find(&accounts).Error
+	return accounts, err
+}
+
+// GetAccountEntries returns recent journal entries touching an account
+func (s *LedgerService) GetAccountEntries(accountID uuid.UUID, limit int) ([]models.JournalEntry, error) {
+	var entries []models.JournalEntry
+	err := s.db.Preload("Postings").
+		Where("id IN (?)", s.db.Table("postings").Select("entry_id").Where("account_id = ?", accountID).QueryExpr()).
+		Order("created_at DESC").Limit(limit).
+		Find(&entries).Error
+	return entries, err
+}
+
+// GetSupplyProof proves that the FairCoins held by users and system accounts
+// equal everything ever issued minus everything burned. It also checks that
+// the cached balances agree with the postings and with wallet balances.
+func (s *LedgerService) GetSupplyProof() (map[string]interface{}, error) {
+	var byType []struct {
+		Type  models.AccountType
+		Total models.Money
+	}
+	if err := s.db.Table("postings").
+		Select("ledger_accounts.type as type, SUM(postings.amount) as total").
+		Joins("JOIN ledger_accounts ON ledger_accounts.id = postings.account_id").
+		Group("ledger_accounts.type").
+		Scan(&byType).Error; err != nil {
+		return nil, fmt.Errorf("failed to sum postings: %w", err)
+	}
+
+	totals := make(map[models.AccountType]models.Money)
+	var ledgerSum models.Money
+	for _, row := range byType {
+		totals[row.Type] = row.Total
+		ledgerSum = ledgerSum.Add(row.Total)
+	}
+
+	// The issuance account is debited for every minted coin, so it runs negative
+	totalIssued := totals[models.AccountTypeIssuance].Neg()
+	totalBurned := totals[models.AccountTypeBurn]
+	circulating := totals[models.AccountTypeUser].
+		Add(totals[models.AccountTypeTreasury]).
+		Add(totals[models.AccountTypeFeeIncome]).
+		Add(totals[models.AccountTypeEscrow]).
+		Add(totals[models.AccountTypeVoucher])
+
+	var walletSum struct {
+		Total models.Money
+	}
+	s.db.Model(&models.Wallet{}).Select("SUM(balance) as total").Scan(&walletSum)
+
+	var cachedSum struct {
+		Total models.Money
+	}
+	s.db.Model(&models.LedgerAccount{}).Select("SUM(balance) as total").Scan(&cachedSum)
+
+	// Negative user balances are credit lines in use; user_holdings is net
+	// of them
+	var creditSum struct {
+		Total models.Money
+	}
+	s.db.Model(&models.LedgerAccount{}).Where("type = ? AND balance < 0", models.AccountTypeUser).
+		Select("SUM(balance) as total").Scan(&creditSum)
+
+	var mismatchedWallets int64
+	s.db.Table("wallets").
+		Joins("JOIN ledger_accounts ON ledger_accounts.user_id = wallets.user_id").
+		Where("ledger_accounts.balance <> wallets.balance").
+		Count(&mismatchedWallets)
+
+	return map[string]interface{}{
+		"total_issued":           totalIssued,
+		"total_burned":           totalBurned,
+		"circulating_supply":     circulating,
+		"user_holdings":          totals[models.AccountTypeUser],
+		"treasury_holdings":      totals[models.AccountTypeTreasury],
+		"fee_income_holdings":    totals[models.AccountTypeFeeIncome],
+		"escrow_holdings":        totals[models.AccountTypeEscrow],
+		"voucher_holdings":       totals[models.AccountTypeVoucher],
+		"credit_outstanding":     creditSum.Total.Neg(),
+		"wallet_balance_total":   walletSum.Total,
+		"ledger_balanced":        ledgerSum.IsZero(),
+		"supply_matches":         circulating == totalIssued.Sub(totalBurned),
+		"cache_matches_postings": cachedSum.Total == ledgerSum,
+		"mismatched_wallets":     mismatchedWallets,
+		"generated_at":           time.Now(),
+	}, nil
+}
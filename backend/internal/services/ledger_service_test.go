@@ -0,0 +1,102 @@
+package services
+
+import (
+	"faircoin/internal/models"
+	"testing"
+)
+
+func TestLedgerPostRejectsInvalidEntries(t *testing.T) {
+	db := newTestDB(t)
+	ledger := NewLedgerService(db)
+	issuance, err := ledger.SystemAccount(db, models.AccountTypeIssuance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	treasury, err := ledger.SystemAccount(db, models.AccountTypeTreasury)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		legs []Leg
+	}{
+		{"single leg", []Leg{{Account: treasury, Amount: models.FC(1)}}},
+		{"unbalanced", []Leg{{Account: issuance, Amount: models.FC(-1)}, {Account: treasury, Amount: models.FC(2)}}},
+		{"missing account", []Leg{{Account: issuance, Amount: models.FC(-1)}, {Amount: models.FC(1)}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ledger.Post(db, nil, tt.name, tt.legs...); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+
+	var entries int
+	db.Model(&models.JournalEntry{}).Count(&entries)
+	if entries != 0 {
+		t.Errorf("rejected entries were recorded: %d", entries)
+	}
+}
+
+func TestLedgerStaysBalancedAcrossTransfers(t *testing.T) {
+	db := newTestDB(t)
+	alice := newTestUser(t, db, "alice")
+	bob := newTestUser(t, db, "bob")
+
+	wallets := NewWalletService(db)
+	for _, amount := range []models.Money{models.FC(10), models.Money(33333333), models.FC(7).MulFrac(1, 3)} {
+		if _, err := wallets.Transfer(alice.ID, bob.ID, amount, "test"); err != nil {
+			t.Fatalf("transfer %s: %v", amount, err)
+		}
+	}
+	if _, err := wallets.Transfer(bob.ID, alice.ID, models.FC(5), "test"); err != nil {
+		t.Fatalf("transfer back: %v", err)
+	}
+
+	proof, err := NewLedgerService(db).GetSupplyProof()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"ledger_balanced", "supply_matches", "cache_matches_postings"} {
+		if proof[key] != true {
+			t.Errorf("%s = %v, want true", key, proof[key])
+		}
+	}
+	if proof["mismatched_wallets"] != int64(0) {
+		t.Errorf("mismatched_wallets = %v, want 0", proof["mismatched_wallets"])
+	}
+	if want := models.FC(200); proof["total_issued"] != want || proof["circulating_supply"] != want {
+		t.Errorf("issued %v, circulating %v, want %s", proof["total_issued"], proof["circulating_supply"], want)
+	}
+}
+
+func TestEnsureOpeningBalances(t *testing.T) {
+	db := newTestDB(t)
+	user := newTestUser(t, db, "carol")
+
+	// A wallet from before the ledger: no ledger account yet
+	db.Exec("DELETE FROM postings")
+	db.Exec("DELETE FROM journal_entries")
+	db.Exec("DELETE FROM ledger_accounts")
+	db.Model(&models.Wallet{}).Where("user_id = ?", user.ID).Update("balance", models.FC(42))
+
+	ledger := NewLedgerService(db)
+	for i := 0; i < 2; i++ {
+		if err := ledger.EnsureOpeningBalances(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	account, err := ledger.UserAccount(db, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Balance != models.FC(42) {
+		t.Errorf("opening balance = %s, want 42", account.Balance)
+	}
+	if balance := walletBalance(t, db, user.ID); balance != models.FC(42) {
+		t.Errorf("wallet balance = %s, want 42", balance)
+	}
+}
@@ -0,0 +1,310 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"faircoin/internal/models"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// PaymentURIScheme is the URI scheme of FairCoin payment requests
+	PaymentURIScheme = "faircoin"
+	// MaxPaymentReferenceLength caps the length of a payment reference
+	MaxPaymentReferenceLength = 64
+	// MaxPaymentURIExpiry is the longest a payment URI may stay valid
+	MaxPaymentURIExpiry = 365 * 24 * time.Hour
+)
+
+// ErrPaymentURIInvalid is returned when a payment URI is malformed or its
+// signature does not match
+var ErrPaymentURIInvalid = errors.New("invalid payment URI")
+
+// ErrPaymentURIExpired is returned when a payment URI is past its expiry
+var ErrPaymentURIExpired = errors.New("payment URI has expired")
+
+// PaymentRequest is what a faircoin: URI asks the payer to do. It is
+// encoded as
+//
+//	faircoin:<merchant id>?amount=12.5&exp=<unix seconds>&invoice=<id>&ref=<reference>&sig=<signature>
+//
+// where every parameter is optional. sig is an HMAC-SHA256 of the URI
+// without sig, so apps can tell a server-generated request from a hand-made
+// one. A QR code carries the URI as plain text.
+type PaymentRequest struct {
+	MerchantID uuid.UUID     `json:"merchant_id"`
+	Amount     *models.Money `json:"amount,omitempty"` // Nil lets the payer choose
+	Reference  string        `json:"reference,omitempty"`
+	ExpiresAt  *time.Time    `json:"expires_at,omitempty"`
+	InvoiceID  *uuid.UUID    `json:"invoice_id,omitempty"`
+}
+
+// unsigned encodes the request without a signature. url.Values sorts the
+// parameters, so the same request always encodes the same way.
+func (r *PaymentRequest) unsigned() string {
+	params := url.Values{}
+	if r.Amount != nil {
+		params.Set("amount", r.Amount.String())
+	}
+	if r.ExpiresAt != nil {
+		params.Set("exp", strconv.FormatInt(r.ExpiresAt.Unix(), 10))
+	}
+	if r.InvoiceID != nil {
+		params.Set("invoice", r.InvoiceID.String())
+	}
+	if r.Reference != "" {
+		params.Set("ref", r.Reference)
+	}
+
+	uri := PaymentURIScheme + ":" + r.MerchantID.String()
+	if len(params) > 0 {
+		uri += "?" + params.Encode()
+	}
+	return uri
+}
+
+// ParsePaymentURI decodes a faircoin: URI. It returns the signature, if
+// any, without checking it.
+func ParsePaymentURI(uri string) (*PaymentRequest, []byte, error) {
+	uri = strings.TrimSpace(uri)
+	scheme, rest, found := strings.Cut(uri, ":")
+	if !found || !strings.EqualFold(scheme, PaymentURIScheme) {
+		return nil, nil, fmt.Errorf("%w: expected a %s: URI", ErrPaymentURIInvalid, PaymentURIScheme)
+	}
+	rest = strings.TrimPrefix(rest, "//")
+	path, query, _ := strings.Cut(rest, "?")
+
+	merchantID, err := uuid.Parse(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: invalid merchant ID", ErrPaymentURIInvalid)
+	}
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrPaymentURIInvalid, err)
+	}
+
+	request := &PaymentRequest{MerchantID: merchantID, Reference: params.Get("ref")}
+	if value := params.Get("amount"); value != "" {
+		amount, err := models.ParseMoney(value)
+		if err != nil || !amount.IsPositive() {
+			return nil, nil, fmt.Errorf("%w: invalid amount", ErrPaymentURIInvalid)
+		}
+		request.Amount = &amount
+	}
+	if value := params.Get("exp"); value != "" {
+		seconds, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: invalid expiry", ErrPaymentURIInvalid)
+		}
+		expiresAt := time.Unix(seconds, 0).UTC()
+		request.ExpiresAt = &expiresAt
+	}
+	if value := params.Get("invoice"); value != "" {
+		invoiceID, err := uuid.Parse(value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: invalid invoice ID", ErrPaymentURIInvalid)
+		}
+		request.InvoiceID = &invoiceID
+	}
+
+	var signature []byte
+	if value := params.Get("sig"); value != "" {
+		if signature, err = base64.RawURLEncoding.DecodeString(value); err != nil {
+			return nil, nil, fmt.Errorf("%w: malformed signature", ErrPaymentURIInvalid)
+		}
+	}
+	return request, signature, nil
+}
+
+// PaymentURI is a generated payment request with its signed URI
+type PaymentURI struct {
+	PaymentRequest
+	URI string `json:"uri"`
+}
+
+// CreatePaymentURI builds a signed payment URI for a merchant. With an
+// invoice the amount is the invoice's and the URI expires with it at the
+// latest.
+func (s *WalletService) CreatePaymentURI(merchantID uuid.UUID, amount *models.Money, reference string,
+	expiresAt *time.Time, invoiceID *uuid.UUID) (*PaymentURI, error) {
+	if len(s.paymentURIKey) == 0 {
+		return nil, fmt.Errorf("payment URI signing is not configured")
+	}
+
+	var merchant models.User
+	if err := s.db.First(&merchant, "id = ? AND is_merchant = ?", merchantID, true).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, fmt.Errorf("only merchants can create payment URIs")
+		}
+		return nil, fmt.Errorf("failed to get merchant: %w", err)
+	}
+
+	if amount != nil && !amount.IsPositive() {
+		return nil, fmt.Errorf("amount must be positive")
+	}
+	reference = strings.TrimSpace(reference)
+	if len(reference) > MaxPaymentReferenceLength {
+		return nil, fmt.Errorf("reference must be at most %d characters", MaxPaymentReferenceLength)
+	}
+
+	now := time.Now()
+	if expiresAt != nil {
+		if !expiresAt.After(now) || expiresAt.Sub(now) > MaxPaymentURIExpiry {
+			return nil, fmt.Errorf("expiry must be in the future and at most %d days away", int(MaxPaymentURIExpiry.Hours()/24))
+		}
+	}
+
+	if invoiceID != nil {
+		invoice, err := s.GetInvoice(*invoiceID, merchantID)
+		if err != nil {
+			return nil, err
+		}
+		if invoice.IssuerID != merchantID {
+			return nil, ErrInvoiceNotFound
+		}
+		if invoice.Status != models.InvoiceStatusOpen || !invoice.ExpiresAt.After(now) {
+			return nil, fmt.Errorf("%w: it is %s", ErrInvoiceNotOpen, invoice.Status)
+		}
+		if amount != nil && *amount != invoice.Amount {
+			return nil, fmt.Errorf("amount %s FC does not match the invoice amount of %s FC", amount, invoice.Amount)
+		}
+		amount = &invoice.Amount
+		if expiresAt == nil || invoice.ExpiresAt.Before(*expiresAt) {
+			expiresAt = &invoice.ExpiresAt
+		}
+	}
+
+	request := PaymentRequest{
+		MerchantID: merchantID,
+		Amount:     amount,
+		Reference:  reference,
+		InvoiceID:  invoiceID,
+	}
+	if expiresAt != nil {
+		// The URI carries whole seconds
+		expiry := expiresAt.UTC().Truncate(time.Second)
+		request.ExpiresAt = &expiry
+	}
+
+	unsigned := request.unsigned()
+	separator := "?"
+	if strings.Contains(unsigned, "?") {
+		separator = "&"
+	}
+	signature := base64.RawURLEncoding.EncodeToString(s.signPaymentURI(unsigned))
+	return &PaymentURI{PaymentRequest: request, URI: unsigned + separator + "sig=" + signature}, nil
+}
+
+func (s *WalletService) signPaymentURI(unsigned string) []byte {
+	mac := hmac.New(sha256.New, s.paymentURIKey)
+	mac.Write([]byte(unsigned))
+	return mac.Sum(nil)
+}
+
+// PaymentMerchant is the public part of the merchant a URI pays
+type PaymentMerchant struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	TFI       int       `json:"tfi"`
+}
+
+// TransferPrefill holds the fields of a /wallet/send request
+type TransferPrefill struct {
+	ToUsername  string        `json:"to_username"`
+	Amount      *models.Money `json:"amount,omitempty"`
+	Description string        `json:"description,omitempty"`
+}
+
+// ResolvedPayment tells the payer's app what a payment URI asks for. Action
+// is "transfer" with Transfer filled in, or "pay_invoice" with Invoice.
+type ResolvedPayment struct {
+	Request  PaymentRequest   `json:"request"`
+	Signed   bool             `json:"signed"` // Generated by this server and unchanged since
+	Merchant PaymentMerchant  `json:"merchant"`
+	Action   string           `json:"action"`
+	Transfer *TransferPrefill `json:"transfer,omitempty"`
+	Invoice  *models.Invoice  `json:"invoice,omitempty"`
+	Fee      *FeeQuote        `json:"fee,omitempty"` // Set when the amount is known
+}
+
+// ResolvePaymentURI checks a payment URI and turns it into a pre-filled
+// transfer, or an invoice payment when it names one of the payer's
+// invoices. Unsigned URIs are accepted but reported as such; a URI with a
+// signature that does not match is rejected.
+func (s *WalletService) ResolvePaymentURI(uri string, payerID uuid.UUID) (*ResolvedPayment, error) {
+	request, signature, err := ParsePaymentURI(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := &ResolvedPayment{Request: *request}
+	if signature != nil {
+		if len(s.paymentURIKey) == 0 || !hmac.Equal(signature, s.signPaymentURI(request.unsigned())) {
+			return nil, fmt.Errorf("%w: bad signature", ErrPaymentURIInvalid)
+		}
+		resolved.Signed = true
+	}
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w at %s", ErrPaymentURIExpired, request.ExpiresAt.Format(time.RFC3339))
+	}
+	if request.MerchantID == payerID {
+		return nil, fmt.Errorf("cannot pay yourself")
+	}
+
+	var merchant models.User
+	if err := s.db.First(&merchant, "id = ? AND is_merchant = ?", request.MerchantID, true).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, fmt.Errorf("%w: merchant not found", ErrPaymentURIInvalid)
+		}
+		return nil, fmt.Errorf("failed to get merchant: %w", err)
+	}
+	resolved.Merchant = PaymentMerchant{
+		ID:        merchant.ID,
+		Username:  merchant.Username,
+		FirstName: merchant.FirstName,
+		LastName:  merchant.LastName,
+		TFI:       merchant.TFI,
+	}
+
+	amount := request.Amount
+	if request.InvoiceID != nil {
+		invoice, err := s.GetInvoice(*request.InvoiceID, payerID)
+		if err != nil {
+			return nil, err
+		}
+		if invoice.IssuerID != merchant.ID || invoice.PayerID != payerID {
+			return nil, ErrInvoiceNotFound
+		}
+		if invoice.Status != models.InvoiceStatusOpen || !invoice.ExpiresAt.After(time.Now()) {
+			return nil, fmt.Errorf("%w: it is %s", ErrInvoiceNotOpen, invoice.Status)
+		}
+		resolved.Action = "pay_invoice"
+		resolved.Invoice = invoice
+		amount = &invoice.Amount
+	} else {
+		resolved.Action = "transfer"
+		resolved.Transfer = &TransferPrefill{
+			ToUsername:  merchant.Username,
+			Amount:      amount,
+			Description: request.Reference,
+		}
+	}
+
+	if amount != nil {
+		if resolved.Fee, err = s.fees.Quote(s.db, payerID, merchant.ID, *amount); err != nil {
+			return nil, err
+		}
+	}
+	return resolved, nil
+}
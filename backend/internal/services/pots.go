@@ -0,0 +1,395 @@
+package services
+
+import (
+	"errors"
+	"faircoin/internal/models"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// MaxSavingsPots caps the number of pots in one wallet
+	MaxSavingsPots = 20
+	// MaxPotNameLength caps the length of a pot name
+	MaxPotNameLength = 50
+)
+
+// ErrPotNotFound is returned when a pot does not exist or belongs to another user
+var ErrPotNotFound = errors.New("savings pot not found")
+
+// ErrPotLocked is returned when money is taken out of a pot before its lock ends
+var ErrPotLocked = errors.New("savings pot is locked")
+
+// PotSummary splits a wallet's balance into the spendable part, locked funds
+// and the savings pots. Balance is Spendable + Locked + Potted unless the
+// wallet is drawing on a credit line.
+type PotSummary struct {
+	Balance   models.Money        `json:"balance"`
+	Spendable models.Money        `json:"spendable"`
+	Locked    models.Money        `json:"locked"`
+	Potted    models.Money        `json:"potted"`
+	Pots      []models.SavingsPot `json:"pots"`
+}
+
+// PotUpdate changes a pot's settings; nil fields are left as they are
+type PotUpdate struct {
+	Name        *string
+	Goal        *models.Money // Zero removes the goal
+	LockedUntil *time.Time
+}
+
+// GetPots returns the user's pots, oldest first, with the balance breakdown
+func (s *WalletService) GetPots(userID uuid.UUID) (*PotSummary, error) {
+	var wallet models.Wallet
+	if err := s.db.Where("user_id = ?", userID).First(&wallet).Error; err != nil {
+		return nil, fmt.Errorf("wallet not found: %w", err)
+	}
+	pots, err := s.pots(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &PotSummary{
+		Balance:   wallet.Balance,
+		Spendable: wallet.Spendable(),
+		Locked:    wallet.LockedFC,
+		Potted:    wallet.PottedFC,
+		Pots:      pots,
+	}, nil
+}
+
+func (s *WalletService) pots(db *gorm.DB, userID uuid.UUID) ([]models.SavingsPot, error) {
+	pots := []models.SavingsPot{}
+	if err := db.Where("user_id = ?", userID).Order("created_at ASC").Find(&pots).Error; err != nil {
+		return nil, fmt.Errorf("failed to get savings pots: %w", err)
+	}
+	return pots, nil
+}
+
+// CreatePot adds an empty pot to the user's wallet
+func (s *WalletService) CreatePot(userID uuid.UUID, name string, goal *models.Money, lockedUntil *time.Time) (*models.SavingsPot, error) {
+	name, err := validatePotName(name)
+	if err != nil {
+		return nil, err
+	}
+	if goal != nil && !goal.IsPositive() {
+		return nil, fmt.Errorf("goal must be positive")
+	}
+	if lockedUntil != nil {
+		if !lockedUntil.After(time.Now()) {
+			return nil, fmt.Errorf("lock date must be in the future")
+		}
+		utc := lockedUntil.UTC()
+		lockedUntil = &utc
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	var count int
+	if err := tx.Model(&models.SavingsPot{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to count savings pots: %w", err)
+	}
+	if count >= MaxSavingsPots {
+		tx.Rollback()
+		return nil, fmt.Errorf("a wallet can have at most %d savings pots", MaxSavingsPots)
+	}
+	if err := s.checkPotName(tx, userID, uuid.Nil, name); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	pot := &models.SavingsPot{
+		UserID:      userID,
+		Name:        name,
+		Goal:        goal,
+		LockedUntil: lockedUntil,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	if err := tx.Create(pot).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to create savings pot: %w", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit savings pot: %w", err)
+	}
+	return pot, nil
+}
+
+// UpdatePot renames a pot or changes its goal or lock. A lock can be
+// extended at any time but not shortened or removed before it ends.
+func (s *WalletService) UpdatePot(userID, potID uuid.UUID, update PotUpdate) (*models.SavingsPot, error) {
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	pot, err := s.getPot(tx, userID, potID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if update.Name != nil {
+		name, err := validatePotName(*update.Name)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if err := s.checkPotName(tx, userID, pot.ID, name); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		pot.Name = name
+	}
+
+	if update.Goal != nil {
+		switch {
+		case update.Goal.IsZero():
+			pot.Goal = nil
+		case update.Goal.IsPositive():
+			pot.Goal = update.Goal
+		default:
+			tx.Rollback()
+			return nil, fmt.Errorf("goal must be positive")
+		}
+	}
+
+	if update.LockedUntil != nil {
+		lockedUntil := update.LockedUntil.UTC()
+		if !lockedUntil.After(time.Now()) {
+			tx.Rollback()
+			return nil, fmt.Errorf("lock date must be in the future")
+		}
+		if pot.LockedUntil != nil && lockedUntil.Before(*pot.LockedUntil) && time.Now().Before(*pot.LockedUntil) {
+			tx.Rollback()
+			return nil, fmt.Errorf("%w until %s and the lock cannot be shortened",
+				ErrPotLocked, pot.LockedUntil.Format(time.RFC3339))
+		}
+		pot.LockedUntil = &lockedUntil
+	}
+
+	if err := tx.Model(&models.SavingsPot{}).Where("id = ?", pot.ID).Updates(map[string]interface{}{
+		"name":         pot.Name,
+		"goal":         pot.Goal,
+		"locked_until": pot.LockedUntil,
+		"updated_at":   time.Now(),
+	}).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to update savings pot: %w", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit savings pot: %w", err)
+	}
+	return s.getPot(s.db, userID, potID)
+}
+
+// MovePotFunds moves money between the spendable balance and the user's
+// pots, or from one pot to another. A nil pot ID stands for the spendable
+// balance. Moves stay inside the wallet, so they are free and create no
+// transaction.
+func (s *WalletService) MovePotFunds(userID uuid.UUID, fromPotID, toPotID *uuid.UUID, amount models.Money) (*PotSummary, error) {
+	if !amount.IsPositive() {
+		return nil, fmt.Errorf("amount must be positive")
+	}
+	if fromPotID == nil && toPotID == nil {
+		return nil, fmt.Errorf("choose a pot to move money into or out of")
+	}
+	if fromPotID != nil && toPotID != nil && *fromPotID == *toPotID {
+		return nil, fmt.Errorf("cannot move money into the same pot")
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	// Resolve both pots first so a missing target does not leave a half-done move
+	var from, to *models.SavingsPot
+	var err error
+	if fromPotID != nil {
+		if from, err = s.getPot(tx, userID, *fromPotID); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	if toPotID != nil {
+		if to, err = s.getPot(tx, userID, *toPotID); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if from == nil {
+		result := tx.Model(&models.Wallet{}).
+			Where("user_id = ? AND balance - locked_fc - potted_fc >= ?", userID, amount).
+			Update("potted_fc", gorm.Expr("potted_fc + ?", amount))
+		if result.Error != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to update wallet: %w", result.Error)
+		}
+		if result.RowsAffected != 1 {
+			tx.Rollback()
+			return nil, fmt.Errorf("%w: not enough spendable balance to move %s FC", ErrInsufficientBalance, amount)
+		}
+	} else {
+		now := time.Now()
+		if from.Withdrawable(now) < amount {
+			tx.Rollback()
+			if from.Withdrawable(now).IsZero() && from.Balance.IsPositive() {
+				return nil, fmt.Errorf("%w until %s", ErrPotLocked, from.LockedUntil.Format(time.RFC3339))
+			}
+			return nil, fmt.Errorf("%w: pot %q holds %s FC", ErrInsufficientBalance, from.Name, from.Balance)
+		}
+		result := tx.Model(&models.SavingsPot{}).
+			Where("id = ? AND balance >= ? AND (locked_until IS NULL OR locked_until <= ?)", from.ID, amount, now.UTC()).
+			Updates(map[string]interface{}{"balance": gorm.Expr("balance - ?", amount), "updated_at": now})
+		if result.Error != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to update savings pot: %w", result.Error)
+		}
+		if result.RowsAffected != 1 {
+			tx.Rollback()
+			return nil, fmt.Errorf("%w: pot %q changed while moving money", ErrInsufficientBalance, from.Name)
+		}
+		if to == nil {
+			if err := tx.Model(&models.Wallet{}).Where("user_id = ?", userID).
+				Update("potted_fc", gorm.Expr("potted_fc - ?", amount)).Error; err != nil {
+				tx.Rollback()
+				return nil, fmt.Errorf("failed to update wallet: %w", err)
+			}
+		}
+	}
+
+	if to != nil {
+		if err := tx.Model(&models.SavingsPot{}).Where("id = ?", to.ID).
+			Updates(map[string]interface{}{"balance": gorm.Expr("balance + ?", amount), "updated_at": time.Now()}).Error; err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to update savings pot: %w", err)
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit pot move: %w", err)
+	}
+	return s.GetPots(userID)
+}
+
+// DeletePot removes a pot and returns what it holds to the spendable
+// balance. A locked pot that still holds money cannot be deleted.
+func (s *WalletService) DeletePot(userID, potID uuid.UUID) (*PotSummary, error) {
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	pot, err := s.getPot(tx, userID, potID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if pot.Withdrawable(time.Now()) < pot.Balance {
+		tx.Rollback()
+		return nil, fmt.Errorf("%w until %s", ErrPotLocked, pot.LockedUntil.Format(time.RFC3339))
+	}
+
+	result := tx.Where("id = ? AND balance = ?", pot.ID, pot.Balance).Delete(&models.SavingsPot{})
+	if result.Error != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to delete savings pot: %w", result.Error)
+	}
+	if result.RowsAffected != 1 {
+		tx.Rollback()
+		return nil, fmt.Errorf("pot %q changed while deleting it", pot.Name)
+	}
+	if pot.Balance.IsPositive() {
+		if err := tx.Model(&models.Wallet{}).Where("user_id = ?", userID).
+			Update("potted_fc", gorm.Expr("potted_fc - ?", pot.Balance)).Error; err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to update wallet: %w", err)
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit savings pot: %w", err)
+	}
+	return s.GetPots(userID)
+}
+
+func (s *WalletService) getPot(db *gorm.DB, userID, potID uuid.UUID) (*models.SavingsPot, error) {
+	var pot models.SavingsPot
+	if err := db.Where("id = ? AND user_id = ?", potID, userID).First(&pot).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrPotNotFound
+		}
+		return nil, fmt.Errorf("failed to get savings pot: %w", err)
+	}
+	return &pot, nil
+}
+
+// checkPotName rejects a name that another of the user's pots already uses
+func (s *WalletService) checkPotName(tx *gorm.DB, userID, potID uuid.UUID, name string) error {
+	var count int
+	if err := tx.Model(&models.SavingsPot{}).
+		Where("user_id = ? AND name = ? AND id <> ?", userID, name, potID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check pot name: %w", err)
+	}
+	if count > 0 {
+		return fmt.Errorf("a savings pot named %q already exists", name)
+	}
+	return nil
+}
+
+func validatePotName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("pot name is required")
+	}
+	if len(name) > MaxPotNameLength {
+		return "", fmt.Errorf("pot name must be at most %d characters", MaxPotNameLength)
+	}
+	return name, nil
+}
+
+// takeFromPots empties pots, largest first, to cover a system charge the
+// spendable balance cannot, e.g. demurrage. Pot locks do not apply because
+// the owner is not withdrawing the money.
+func takeFromPots(tx *gorm.DB, userID uuid.UUID, amount models.Money) error {
+	var pots []models.SavingsPot
+	if err := tx.Where("user_id = ? AND balance > 0", userID).Order("balance DESC").Find(&pots).Error; err != nil {
+		return fmt.Errorf("failed to get savings pots: %w", err)
+	}
+
+	var taken models.Money
+	for _, pot := range pots {
+		if taken >= amount {
+			break
+		}
+		take := pot.Balance
+		if remaining := amount.Sub(taken); take > remaining {
+			take = remaining
+		}
+		if err := tx.Model(&models.SavingsPot{}).Where("id = ?", pot.ID).
+			Updates(map[string]interface{}{"balance": gorm.Expr("balance - ?", take), "updated_at": time.Now()}).Error; err != nil {
+			return fmt.Errorf("failed to update savings pot: %w", err)
+		}
+		taken = taken.Add(take)
+	}
+
+	if taken.IsPositive() {
+		if err := tx.Model(&models.Wallet{}).Where("user_id = ?", userID).
+			Update("potted_fc", gorm.Expr("potted_fc - ?", taken)).Error; err != nil {
+			return fmt.Errorf("failed to update wallet: %w", err)
+		}
+	}
+	return nil
+}
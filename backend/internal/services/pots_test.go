@@ -0,0 +1,82 @@
+package services
+
+import (
+	"errors"
+	"faircoin/internal/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSavingsPots(t *testing.T) {
+	db := newTestDB(t)
+	alice := newTestUser(t, db, "alice")
+	bob := newTestUser(t, db, "bob")
+	wallets := NewWalletService(db)
+
+	lockedUntil := time.Now().Add(24 * time.Hour)
+	holiday, err := wallets.CreatePot(alice.ID, "Holiday", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	savings, err := wallets.CreatePot(alice.ID, "Savings", nil, &lockedUntil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wallets.CreatePot(alice.ID, " Holiday ", nil, nil); err == nil {
+		t.Error("a second pot with the same name was accepted")
+	}
+
+	if _, err := wallets.MovePotFunds(alice.ID, nil, &holiday.ID, models.FC(70)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wallets.MovePotFunds(alice.ID, &holiday.ID, &savings.ID, models.FC(30)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wallets.MovePotFunds(alice.ID, nil, &holiday.ID, models.FC(31)); !errors.Is(err, ErrInsufficientBalance) {
+		t.Errorf("moving more than the spendable balance: err = %v", err)
+	}
+	if _, err := wallets.MovePotFunds(alice.ID, &savings.ID, nil, models.FC(1)); !errors.Is(err, ErrPotLocked) {
+		t.Errorf("moving out of a locked pot: err = %v", err)
+	}
+	if _, err := wallets.DeletePot(alice.ID, savings.ID); !errors.Is(err, ErrPotLocked) {
+		t.Errorf("deleting a locked pot: err = %v", err)
+	}
+	unknown := uuid.New()
+	if _, err := wallets.MovePotFunds(alice.ID, nil, &unknown, models.FC(1)); !errors.Is(err, ErrPotNotFound) {
+		t.Errorf("moving into a missing pot: err = %v", err)
+	}
+	if _, err := wallets.MovePotFunds(bob.ID, &holiday.ID, nil, models.FC(1)); !errors.Is(err, ErrPotNotFound) {
+		t.Errorf("moving out of another user's pot: err = %v", err)
+	}
+
+	// Potted money cannot be spent
+	if _, err := wallets.Transfer(alice.ID, bob.ID, models.FC(30), "test"); !errors.Is(err, ErrInsufficientBalance) {
+		t.Errorf("spending potted money: err = %v", err)
+	}
+
+	summary, err := wallets.GetPots(alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Balance != models.FC(100) || summary.Spendable != models.FC(30) || summary.Potted != models.FC(70) {
+		t.Errorf("balance %s, spendable %s, potted %s, want 100, 30 and 70",
+			summary.Balance, summary.Spendable, summary.Potted)
+	}
+	if len(summary.Pots) != 2 || summary.Pots[0].Balance != models.FC(40) || summary.Pots[1].Balance != models.FC(30) {
+		t.Errorf("pots = %+v, want Holiday 40 and Savings 30", summary.Pots)
+	}
+
+	summary, err = wallets.DeletePot(alice.ID, holiday.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Spendable != models.FC(70) || summary.Potted != models.FC(30) || len(summary.Pots) != 1 {
+		t.Errorf("after deleting a pot: spendable %s, potted %s, %d pots, want 70, 30 and 1",
+			summary.Spendable, summary.Potted, len(summary.Pots))
+	}
+	if balance := walletBalance(t, db, alice.ID); balance != models.FC(100) {
+		t.Errorf("pot moves changed the balance to %s", balance)
+	}
+}
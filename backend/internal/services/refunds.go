@@ -0,0 +1,248 @@
+package services
+
+import (
+	"encoding/json"
+	"faircoin/internal/models"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+)
+
+// Refund sends part or all of a payment back to the payer. Only the
+// recipient of the original payment can refund it. Refunds carry no fee of
+// their own; the original fee, and any part of the payment that went to the
+// treasury above the recipient's holding cap, is returned from the treasury
+// in proportion to the refunded amount, so a full refund leaves the payer
+// whole. A zero amount refunds everything not yet refunded.
+func (s *WalletService) Refund(originalID, merchantID uuid.UUID, amount models.Money, reason string) (*models.Transaction, error) {
+	if amount.IsNegative() {
+		return nil, fmt.Errorf("amount must be positive")
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	original, refunded, err := s.loadRefundable(tx, originalID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if *original.ToUserID != merchantID {
+		tx.Rollback()
+		return nil, fmt.Errorf("only the recipient of a payment can refund it")
+	}
+
+	remaining := original.Amount.Sub(refunded)
+	if amount.IsZero() {
+		amount = remaining
+	}
+	if amount > remaining {
+		tx.Rollback()
+		return nil, fmt.Errorf("refund exceeds the refundable amount of %s FC", remaining)
+	}
+
+	status := models.TransactionStatusPartiallyRefunded
+	if amount == remaining {
+		status = models.TransactionStatusRefunded
+	}
+
+	transaction, err := s.unwind(tx, original, refunded, amount, models.TransactionTypeRefund, status, reason,
+		&merchantID, nil)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit refund: %w", err)
+	}
+	return transaction, nil
+}
+
+// Reverse undoes whatever is left of a payment (admin only): the recipient
+// returns the unrefunded amount and the treasury returns the matching fee
+// and holding cap overflow.
+func (s *WalletService) Reverse(originalID, adminID uuid.UUID, reason string) (*models.Transaction, error) {
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	original, refunded, err := s.loadRefundable(tx, originalID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	remaining := original.Amount.Sub(refunded)
+	if reason == "" {
+		reason = "Reversed by admin"
+	}
+
+	transaction, err := s.unwind(tx, original, refunded, remaining, models.TransactionTypeReversal,
+		models.TransactionStatusReversed, reason, &adminID, map[string]interface{}{"reversed_by": adminID})
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit reversal: %w", err)
+	}
+	return transaction, nil
+}
+
+// loadRefundable loads a completed payment between two users and how much
+// of it has already been refunded
+func (s *WalletService) loadRefundable(tx *gorm.DB, originalID uuid.UUID) (*models.Transaction, models.Money, error) {
+	var original models.Transaction
+	if err := tx.First(&original, "id = ?", originalID).Error; err != nil {
+		return nil, 0, fmt.Errorf("transaction not found: %w", err)
+	}
+
+	if original.Type != models.TransactionTypeTransfer && original.Type != models.TransactionTypeEscrow {
+		return nil, 0, fmt.Errorf("only transfers and released escrows can be refunded or reversed")
+	}
+	if original.ToUserID == nil {
+		return nil, 0, fmt.Errorf("transaction has no recipient")
+	}
+	if original.Status != models.TransactionStatusCompleted && original.Status != models.TransactionStatusPartiallyRefunded {
+		return nil, 0, fmt.Errorf("transaction is %s and cannot be refunded or reversed", original.Status)
+	}
+
+	var refunded struct {
+		Total models.Money
+	}
+	if err := tx.Model(&models.Transaction{}).
+		Where("related_transaction_id = ? AND type IN (?)", original.ID,
+			[]models.TransactionType{models.TransactionTypeRefund, models.TransactionTypeReversal}).
+		Select("SUM(amount) as total").Scan(&refunded).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to sum refunds: %w", err)
+	}
+
+	return &original, refunded.Total, nil
+}
+
+// unwind moves amount back to the payer, records the linked transaction and
+// moves the original to status on behalf of actorID. The treasury returns the
+// matching share of the original fee and of any holding cap overflow it
+// received; the recipient returns the rest.
+func (s *WalletService) unwind(tx *gorm.DB, original *models.Transaction, refunded, amount models.Money,
+	transactionType models.TransactionType, status models.TransactionStatus, reason string, actorID *uuid.UUID,
+	metadata map[string]interface{}) (*models.Transaction, error) {
+	payerID := original.UserID
+	recipientID := *original.ToUserID
+
+	treasury, err := s.ledger.SystemAccount(tx, models.AccountTypeTreasury)
+	if err != nil {
+		return nil, err
+	}
+	overflow, err := s.holdingCapOverflow(tx, original, treasury)
+	if err != nil {
+		return nil, err
+	}
+
+	// Shares for everything returned so far minus what was already
+	// returned, so partial refunds add up to exactly the original fee and
+	// overflow
+	share := func(total models.Money) models.Money {
+		before := total.MulFrac(refunded.Units(), original.Amount.Units())
+		after := total.MulFrac(refunded.Add(amount).Units(), original.Amount.Units())
+		return after.Sub(before)
+	}
+	feeReturned := share(original.Fee)
+	overflowReturned := share(overflow)
+	recipientReturned := amount.Sub(overflowReturned)
+
+	var recipientWallet models.Wallet
+	if err := tx.Where("user_id = ?", recipientID).First(&recipientWallet).Error; err != nil {
+		return nil, fmt.Errorf("recipient wallet not found: %w", err)
+	}
+	if recipientWallet.Spendable() < recipientReturned {
+		return nil, fmt.Errorf("recipient has insufficient spendable balance to return %s FC", recipientReturned)
+	}
+	if treasury.Balance < feeReturned.Add(overflowReturned) {
+		return nil, fmt.Errorf("treasury cannot return the %s FC fee and %s FC holding cap overflow",
+			feeReturned, overflowReturned)
+	}
+
+	description := reason
+	if description == "" {
+		description = fmt.Sprintf("Refund of %s", original.Description)
+	}
+
+	transaction := &models.Transaction{
+		UserID:               recipientID,
+		ToUserID:             &payerID,
+		Type:                 transactionType,
+		Amount:               amount,
+		Fee:                  feeReturned,
+		Description:          description,
+		Status:               models.TransactionStatusCompleted,
+		RelatedTransactionID: &original.ID,
+		CreatedAt:            time.Now(),
+	}
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+	}
+	metadata["fee_returned"] = feeReturned
+	if overflowReturned.IsPositive() {
+		metadata["holding_cap_overflow_returned"] = overflowReturned
+	}
+	encoded, _ := json.Marshal(metadata)
+	transaction.Metadata = string(encoded)
+
+	if err := tx.Create(transaction).Error; err != nil {
+		return nil, fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	payerAccount, err := s.ledger.UserAccount(tx, payerID)
+	if err != nil {
+		return nil, err
+	}
+	recipientAccount, err := s.ledger.UserAccount(tx, recipientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.ledger.Post(tx, &transaction.ID, description,
+		Leg{Account: recipientAccount, Amount: recipientReturned.Neg()},
+		Leg{Account: treasury, Amount: feeReturned.Add(overflowReturned).Neg()},
+		Leg{Account: payerAccount, Amount: amount.Add(feeReturned)},
+	); err != nil {
+		return nil, fmt.Errorf("failed to post %s: %w", transactionType, err)
+	}
+
+	// Guarded on the status we read, so a concurrent refund or reversal
+	// cannot unwind the same payment twice
+	if err := setTransactionStatus(tx, original, status, description, actorID); err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// holdingCapOverflow returns the part of a payment that went to the treasury
+// instead of the recipient because it was above the recipient's holding cap.
+// The treasury's postings for the payment are its fee plus that overflow.
+func (s *WalletService) holdingCapOverflow(tx *gorm.DB, original *models.Transaction, treasury *models.LedgerAccount) (models.Money, error) {
+	var received struct {
+		Total models.Money
+	}
+	if err := tx.Table("postings").
+		Joins("JOIN journal_entries ON journal_entries.id = postings.entry_id").
+		Where("journal_entries.transaction_id = ? AND postings.account_id = ?", original.ID, treasury.ID).
+		Select("SUM(postings.amount) as total").Scan(&received).Error; err != nil {
+		return 0, fmt.Errorf("failed to sum treasury postings: %w", err)
+	}
+
+	overflow := received.Total.Sub(original.Fee)
+	if overflow.IsNegative() {
+		return 0, nil
+	}
+	return overflow, nil
+}
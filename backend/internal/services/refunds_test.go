@@ -0,0 +1,53 @@
+package services
+
+import (
+	"faircoin/internal/models"
+	"testing"
+)
+
+func TestRefundReturnsHoldingCapOverflow(t *testing.T) {
+	db := newTestDB(t)
+	alice := newTestUser(t, db, "alice")
+	bob := newTestUser(t, db, "bob")
+
+	// Bob starts at 100 FC and may hold 105, so 15 of a 20 FC payment
+	// overflows to the treasury
+	holdingCap, err := NewHoldingCap(0.01, 105, string(HoldingCapOverflowTreasury), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wallets := NewWalletService(db)
+	wallets.SetHoldingCap(holdingCap)
+
+	payment, err := wallets.Transfer(alice.ID, bob.ID, models.FC(20), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance := walletBalance(t, db, bob.ID); balance != models.FC(105) {
+		t.Fatalf("recipient holds %s, want 105", balance)
+	}
+
+	// Partial refunds return the overflow pro rata and add up exactly
+	for _, amount := range []models.Money{models.FC(7), models.FC(20).MulFrac(1, 3), 0} {
+		if _, err := wallets.Refund(payment.ID, bob.ID, amount, ""); err != nil {
+			t.Fatalf("refund %s: %v", amount, err)
+		}
+	}
+
+	if balance := walletBalance(t, db, alice.ID); balance != models.FC(100) {
+		t.Errorf("payer holds %s after a full refund, want 100", balance)
+	}
+	if balance := walletBalance(t, db, bob.ID); balance != models.FC(100) {
+		t.Errorf("recipient holds %s after a full refund, want 100", balance)
+	}
+	treasury, err := NewLedgerService(db).SystemAccount(db, models.AccountTypeTreasury)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !treasury.Balance.IsZero() {
+		t.Errorf("treasury holds %s after a full refund, want 0", treasury.Balance)
+	}
+	if status := mustTransaction(t, db, payment.ID).Status; status != models.TransactionStatusRefunded {
+		t.Errorf("payment is %s, want refunded", status)
+	}
+}
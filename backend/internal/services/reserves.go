@@ -0,0 +1,353 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"faircoin/internal/models"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+)
+
+// DefaultSnapshotInterval is how often balance snapshots are taken unless
+// configured otherwise
+const DefaultSnapshotInterval = 24 * time.Hour
+
+// ErrSnapshotNotFound is returned when a balance snapshot does not exist
+var ErrSnapshotNotFound = errors.New("balance snapshot not found")
+
+// ErrNotInSnapshot is returned when a member had no wallet when a snapshot was taken
+var ErrNotInSnapshot = errors.New("no balance in this snapshot")
+
+// ErrProofInvalid is returned when a balance proof does not lead to its
+// snapshot's published root
+var ErrProofInvalid = errors.New("balance proof does not verify")
+
+// ReservesService publishes Merkle sum trees over all wallet balances so
+// members can check their balance is counted in the published supply.
+//
+// Every node carries a hash and two sums: holdings (positive balances) and
+// owed (negative balances on credit lines). Keeping both non-negative means
+// no balance can be left out or shrunk without changing the root's totals.
+// With hex hashes and sums in minor units:
+//
+//	leaf = SHA-256("leaf:" + snapshot_id + ":" + user_id + ":" + salt + ":" + holdings + ":" + owed)
+//	node = SHA-256("node:" + left.hash + ":" + left.holdings + ":" + left.owed + ":" +
+//	                         right.hash + ":" + right.holdings + ":" + right.owed)
+//
+// There is one leaf per member, holding the sum of their wallets, ordered by
+// user ID. A node without a sibling moves up a level
+// unchanged.
+type ReservesService struct {
+	db       *gorm.DB
+	interval time.Duration
+}
+
+// NewReservesService creates a new reserves service
+func NewReservesService(db *gorm.DB) *ReservesService {
+	return &ReservesService{db: db, interval: DefaultSnapshotInterval}
+}
+
+// SetSnapshotInterval sets how often SnapshotIfDue takes a snapshot
+func (s *ReservesService) SetSnapshotInterval(interval time.Duration) error {
+	if interval < time.Hour {
+		return fmt.Errorf("snapshot interval must be at least an hour")
+	}
+	s.interval = interval
+	return nil
+}
+
+// merkleNode is a node of the sum tree
+type merkleNode struct {
+	Hash     string
+	Holdings models.Money
+	Owed     models.Money
+}
+
+func merkleLeaf(snapshotID, userID uuid.UUID, salt string, balance models.Money) merkleNode {
+	node := merkleNode{Holdings: models.MaxMoney(balance, 0), Owed: models.MaxMoney(balance.Neg(), 0)}
+	node.Hash = sha256Hex(fmt.Sprintf("leaf:%s:%s:%s:%d:%d", snapshotID, userID, salt, node.Holdings.Units(), node.Owed.Units()))
+	return node
+}
+
+func merkleParent(left, right merkleNode) merkleNode {
+	return merkleNode{
+		Hash: sha256Hex(fmt.Sprintf("node:%s:%d:%d:%s:%d:%d", left.Hash, left.Holdings.Units(), left.Owed.Units(),
+			right.Hash, right.Holdings.Units(), right.Owed.Units())),
+		Holdings: left.Holdings.Add(right.Holdings),
+		Owed:     left.Owed.Add(right.Owed),
+	}
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// merkleLevels builds the tree bottom-up; the last level holds the root
+func merkleLevels(leaves []merkleNode) [][]merkleNode {
+	levels := [][]merkleNode{leaves}
+	for level := leaves; len(level) > 1; {
+		next := make([]merkleNode, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+			} else {
+				next = append(next, merkleParent(level[i], level[i+1]))
+			}
+		}
+		levels = append(levels, next)
+		level = next
+	}
+	return levels
+}
+
+// CreateSnapshot builds and publishes a sum tree over every wallet balance.
+// It returns nil when there are no wallets.
+func (s *ReservesService) CreateSnapshot() (*models.BalanceSnapshot, error) {
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	// One leaf per member, so the leaves add up to the wallet totals
+	var balances []struct {
+		UserID  uuid.UUID
+		Balance models.Money
+	}
+	if err := tx.Model(&models.Wallet{}).Select("user_id, SUM(balance) as balance").Group("user_id").
+		Order("user_id ASC").Scan(&balances).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to get wallet balances: %w", err)
+	}
+	if len(balances) == 0 {
+		tx.Rollback()
+		return nil, nil
+	}
+	var walletSum struct {
+		Total models.Money
+	}
+	if err := tx.Model(&models.Wallet{}).Select("SUM(balance) as total").Scan(&walletSum).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to sum wallet balances: %w", err)
+	}
+
+	snapshot := &models.BalanceSnapshot{
+		ID:        uuid.New(),
+		Members:   len(balances),
+		CreatedAt: time.Now().UTC(),
+	}
+	leaves := make([]models.BalanceSnapshotLeaf, len(balances))
+	nodes := make([]merkleNode, len(balances))
+	for i, balance := range balances {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to generate salt: %w", err)
+		}
+		leaves[i] = models.BalanceSnapshotLeaf{
+			SnapshotID: snapshot.ID,
+			UserID:     balance.UserID,
+			Position:   i,
+			Balance:    balance.Balance,
+			Salt:       hex.EncodeToString(salt),
+		}
+		nodes[i] = merkleLeaf(snapshot.ID, balance.UserID, leaves[i].Salt, balance.Balance)
+	}
+
+	levels := merkleLevels(nodes)
+	root := levels[len(levels)-1][0]
+	snapshot.Root = root.Hash
+	snapshot.TotalHoldings = root.Holdings
+	snapshot.TotalOwed = root.Owed
+	snapshot.Total = root.Holdings.Sub(root.Owed)
+	if snapshot.Total != walletSum.Total {
+		tx.Rollback()
+		return nil, fmt.Errorf("snapshot total %s FC does not match wallet balances of %s FC", snapshot.Total, walletSum.Total)
+	}
+
+	if err := tx.Create(snapshot).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	for i := range leaves {
+		if err := tx.Create(&leaves[i]).Error; err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to save snapshot leaf: %w", err)
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit snapshot: %w", err)
+	}
+	return snapshot, nil
+}
+
+// SnapshotIfDue takes a snapshot when the last one is older than the
+// snapshot interval. It returns nil when none was due.
+func (s *ReservesService) SnapshotIfDue() (*models.BalanceSnapshot, error) {
+	var last models.BalanceSnapshot
+	if err := s.db.Order("created_at DESC").First(&last).Error; err == nil {
+		if time.Since(last.CreatedAt) < s.interval {
+			return nil, nil
+		}
+	} else if !gorm.IsRecordNotFoundError(err) {
+		return nil, fmt.Errorf("failed to get last snapshot: %w", err)
+	}
+	return s.CreateSnapshot()
+}
+
+// GetSnapshots returns the most recent snapshots, newest first
+func (s *ReservesService) GetSnapshots(limit int) ([]models.BalanceSnapshot, error) {
+	var snapshots []models.BalanceSnapshot
+	if err := s.db.Order("created_at DESC").Limit(limit).Find(&snapshots).Error; err != nil {
+		return nil, fmt.Errorf("failed to get snapshots: %w", err)
+	}
+	return snapshots, nil
+}
+
+// getSnapshot returns the snapshot with the given ID, or the latest one
+func (s *ReservesService) getSnapshot(snapshotID *uuid.UUID) (*models.BalanceSnapshot, error) {
+	var snapshot models.BalanceSnapshot
+	query := s.db
+	if snapshotID != nil {
+		query = query.Where("id = ?", *snapshotID)
+	}
+	if err := query.Order("created_at DESC").First(&snapshot).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrSnapshotNotFound
+		}
+		return nil, fmt.Errorf("failed to get snapshot: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// ProofStep is a sibling on the way from a leaf to the root. Side is where
+// the sibling sits: "left" or "right".
+type ProofStep struct {
+	Side     string       `json:"side"`
+	Hash     string       `json:"hash"`
+	Holdings models.Money `json:"holdings"`
+	Owed     models.Money `json:"owed"`
+}
+
+// BalanceProof shows that a member's balance is a leaf of a snapshot. The
+// sibling sums reveal the combined balances of other parts of the tree but
+// not any single member, whose leaf is salted.
+type BalanceProof struct {
+	SnapshotID    uuid.UUID    `json:"snapshot_id"`
+	Root          string       `json:"root"`
+	TotalHoldings models.Money `json:"total_holdings"`
+	TotalOwed     models.Money `json:"total_owed"`
+	CreatedAt     time.Time    `json:"created_at"`
+	UserID        uuid.UUID    `json:"user_id"`
+	Balance       models.Money `json:"balance"`
+	Salt          string       `json:"salt"`
+	Leaf          string       `json:"leaf"`
+	Path          []ProofStep  `json:"path"` // From the leaf up
+}
+
+// GetBalanceProof returns the inclusion proof for the user's balance in the
+// given snapshot, or the latest one when snapshotID is nil
+func (s *ReservesService) GetBalanceProof(userID uuid.UUID, snapshotID *uuid.UUID) (*BalanceProof, error) {
+	snapshot, err := s.getSnapshot(snapshotID)
+	if err != nil {
+		return nil, err
+	}
+
+	var leaves []models.BalanceSnapshotLeaf
+	if err := s.db.Where("snapshot_id = ?", snapshot.ID).Order("position ASC").Find(&leaves).Error; err != nil {
+		return nil, fmt.Errorf("failed to get snapshot leaves: %w", err)
+	}
+	position := -1
+	nodes := make([]merkleNode, len(leaves))
+	for i, leaf := range leaves {
+		nodes[i] = merkleLeaf(snapshot.ID, leaf.UserID, leaf.Salt, leaf.Balance)
+		if leaf.UserID == userID {
+			position = i
+		}
+	}
+	if position < 0 {
+		return nil, ErrNotInSnapshot
+	}
+
+	levels := merkleLevels(nodes)
+	if levels[len(levels)-1][0].Hash != snapshot.Root {
+		return nil, fmt.Errorf("snapshot leaves no longer match the published root")
+	}
+
+	proof := &BalanceProof{
+		SnapshotID:    snapshot.ID,
+		Root:          snapshot.Root,
+		TotalHoldings: snapshot.TotalHoldings,
+		TotalOwed:     snapshot.TotalOwed,
+		CreatedAt:     snapshot.CreatedAt,
+		UserID:        userID,
+		Balance:       leaves[position].Balance,
+		Salt:          leaves[position].Salt,
+		Leaf:          nodes[position].Hash,
+		Path:          []ProofStep{},
+	}
+	index := position
+	for _, level := range levels[:len(levels)-1] {
+		sibling, side := index+1, "right"
+		if index%2 == 1 {
+			sibling, side = index-1, "left"
+		}
+		if sibling < len(level) {
+			node := level[sibling]
+			proof.Path = append(proof.Path, ProofStep{Side: side, Hash: node.Hash, Holdings: node.Holdings, Owed: node.Owed})
+		}
+		index /= 2
+	}
+	return proof, nil
+}
+
+// Verify recomputes the root from the proof's balance and path and checks
+// it against the root and totals the proof states
+func (p *BalanceProof) Verify() error {
+	node := merkleLeaf(p.SnapshotID, p.UserID, p.Salt, p.Balance)
+	if node.Hash != p.Leaf {
+		return fmt.Errorf("%w: leaf hash does not match the balance", ErrProofInvalid)
+	}
+	for _, step := range p.Path {
+		// A sum tree only proves reserves if no node subtracts: a negative
+		// sibling would let the operator hide members' balances
+		if step.Holdings.IsNegative() || step.Owed.IsNegative() {
+			return fmt.Errorf("%w: negative sums in the path", ErrProofInvalid)
+		}
+		sibling := merkleNode{Hash: step.Hash, Holdings: step.Holdings, Owed: step.Owed}
+		switch step.Side {
+		case "left":
+			node = merkleParent(sibling, node)
+		case "right":
+			node = merkleParent(node, sibling)
+		default:
+			return fmt.Errorf("%w: unknown side %q", ErrProofInvalid, step.Side)
+		}
+	}
+	if node.Hash != p.Root || node.Holdings != p.TotalHoldings || node.Owed != p.TotalOwed {
+		return fmt.Errorf("%w: path does not lead to the root", ErrProofInvalid)
+	}
+	return nil
+}
+
+// VerifyBalanceProof checks a proof and that its root and totals are the
+// ones published for its snapshot
+func (s *ReservesService) VerifyBalanceProof(proof *BalanceProof) error {
+	if err := proof.Verify(); err != nil {
+		return err
+	}
+	snapshot, err := s.getSnapshot(&proof.SnapshotID)
+	if err != nil {
+		return err
+	}
+	if snapshot.Root != proof.Root || snapshot.TotalHoldings != proof.TotalHoldings || snapshot.TotalOwed != proof.TotalOwed {
+		return fmt.Errorf("%w: root differs from the one published", ErrProofInvalid)
+	}
+	return nil
+}
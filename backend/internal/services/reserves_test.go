@@ -0,0 +1,171 @@
+package services
+
+import (
+	"errors"
+	"faircoin/internal/models"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestBalanceProofVerify(t *testing.T) {
+	db := newTestDB(t)
+
+	// An odd number of members, so one node is carried up a level unpaired,
+	// and one member owing on a credit line
+	balances := []models.Money{models.FC(10), models.FC(25), models.FC(-3), models.Money(1), models.FC(7)}
+	userIDs := make([]uuid.UUID, len(balances))
+	for i, balance := range balances {
+		userIDs[i] = uuid.New()
+		if err := db.Create(&models.Wallet{ID: uuid.New(), UserID: userIDs[i], Balance: balance}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reserves := NewReservesService(db)
+	snapshot, err := reserves.CreateSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.TotalHoldings != models.FC(42).Add(1) || snapshot.TotalOwed != models.FC(3) {
+		t.Fatalf("snapshot holds %s and owes %s", snapshot.TotalHoldings, snapshot.TotalOwed)
+	}
+
+	for i, userID := range userIDs {
+		proof, err := reserves.GetBalanceProof(userID, nil)
+		if err != nil {
+			t.Fatalf("proof for member %d: %v", i, err)
+		}
+		if proof.Balance != balances[i] {
+			t.Errorf("proof for member %d shows %s, want %s", i, proof.Balance, balances[i])
+		}
+		if err := proof.Verify(); err != nil {
+			t.Errorf("proof for member %d: %v", i, err)
+		}
+		if err := reserves.VerifyBalanceProof(proof); err != nil {
+			t.Errorf("proof for member %d against the snapshot: %v", i, err)
+		}
+	}
+
+	if _, err := reserves.GetBalanceProof(uuid.New(), nil); !errors.Is(err, ErrNotInSnapshot) {
+		t.Errorf("proof for a non-member: %v, want ErrNotInSnapshot", err)
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(*BalanceProof)
+	}{
+		{"balance", func(p *BalanceProof) { p.Balance = p.Balance.Add(1) }},
+		{"balance sign", func(p *BalanceProof) { p.Balance = p.Balance.Neg() }},
+		{"salt", func(p *BalanceProof) { p.Salt += "00" }},
+		{"user", func(p *BalanceProof) { p.UserID = uuid.New() }},
+		{"snapshot", func(p *BalanceProof) { p.SnapshotID = uuid.New() }},
+		{"leaf", func(p *BalanceProof) { p.Leaf = p.Path[0].Hash }},
+		{"sibling hash", func(p *BalanceProof) { p.Path[0].Hash = p.Leaf }},
+		{"sibling holdings", func(p *BalanceProof) { p.Path[0].Holdings = p.Path[0].Holdings.Sub(models.FC(1)) }},
+		{"sibling owed", func(p *BalanceProof) { p.Path[0].Owed = p.Path[0].Owed.Add(1) }},
+		{"side", func(p *BalanceProof) {
+			if p.Path[0].Side == "left" {
+				p.Path[0].Side = "right"
+			} else {
+				p.Path[0].Side = "left"
+			}
+		}},
+		{"unknown side", func(p *BalanceProof) { p.Path[0].Side = "up" }},
+		{"dropped step", func(p *BalanceProof) { p.Path = p.Path[1:] }},
+		{"root", func(p *BalanceProof) { p.Root = p.Leaf }},
+		{"total holdings", func(p *BalanceProof) { p.TotalHoldings = p.TotalHoldings.Add(1) }},
+		{"total owed", func(p *BalanceProof) { p.TotalOwed = 0 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Member 2 owes, so the owed total is not zero
+			proof, err := reserves.GetBalanceProof(userIDs[2], &snapshot.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.tamper(proof)
+			if err := proof.Verify(); !errors.Is(err, ErrProofInvalid) {
+				t.Errorf("Verify() = %v, want ErrProofInvalid", err)
+			}
+		})
+	}
+}
+
+func TestBalanceProofVerifyRejectsNegativeSums(t *testing.T) {
+	db := newTestDB(t)
+	userID := uuid.New()
+	for _, wallet := range []models.Wallet{{UserID: userID, Balance: models.FC(10)}, {UserID: uuid.New(), Balance: models.FC(-4)}} {
+		wallet.ID = uuid.New()
+		if err := db.Create(&wallet).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	reserves := NewReservesService(db)
+	if _, err := reserves.CreateSnapshot(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		forge func(step *ProofStep)
+	}{
+		{"negative holdings", func(step *ProofStep) { step.Holdings = models.FC(-10) }},
+		{"negative owed", func(step *ProofStep) { step.Owed = models.FC(-4) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proof, err := reserves.GetBalanceProof(userID, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Forge a consistent tree around the negative sibling, so only
+			// the sign gives it away
+			tt.forge(&proof.Path[0])
+			node := merkleLeaf(proof.SnapshotID, proof.UserID, proof.Salt, proof.Balance)
+			for _, step := range proof.Path {
+				sibling := merkleNode{Hash: step.Hash, Holdings: step.Holdings, Owed: step.Owed}
+				if step.Side == "left" {
+					node = merkleParent(sibling, node)
+				} else {
+					node = merkleParent(node, sibling)
+				}
+			}
+			proof.Root, proof.TotalHoldings, proof.TotalOwed = node.Hash, node.Holdings, node.Owed
+
+			if err := proof.Verify(); !errors.Is(err, ErrProofInvalid) {
+				t.Errorf("Verify() = %v, want ErrProofInvalid", err)
+			}
+		})
+	}
+}
+
+func TestVerifyBalanceProofChecksPublishedRoot(t *testing.T) {
+	db := newTestDB(t)
+	userID := uuid.New()
+	if err := db.Create(&models.Wallet{ID: uuid.New(), UserID: userID, Balance: models.FC(5)}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	reserves := NewReservesService(db)
+	if _, err := reserves.CreateSnapshot(); err != nil {
+		t.Fatal(err)
+	}
+	proof, err := reserves.GetBalanceProof(userID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A self-consistent proof for a balance that was never published
+	proof.Balance = models.FC(500)
+	proof.Leaf = merkleLeaf(proof.SnapshotID, proof.UserID, proof.Salt, proof.Balance).Hash
+	proof.Root = proof.Leaf
+	proof.TotalHoldings = proof.Balance
+	if err := proof.Verify(); err != nil {
+		t.Fatalf("forged proof should be self-consistent: %v", err)
+	}
+	if err := reserves.VerifyBalanceProof(proof); !errors.Is(err, ErrProofInvalid) {
+		t.Errorf("VerifyBalanceProof() = %v, want ErrProofInvalid", err)
+	}
+}
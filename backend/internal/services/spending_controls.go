@@ -0,0 +1,312 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"faircoin/internal/models"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+)
+
+// ErrWalletFrozen is returned when a frozen wallet would send or receive
+var ErrWalletFrozen = errors.New("wallet is frozen")
+
+// ErrSpendingLimitExceeded is returned when a payment would take the sender
+// over their daily or monthly send limit
+var ErrSpendingLimitExceeded = errors.New("spending limit exceeded")
+
+// SpendingLimits are the default send limits per wallet. A zero limit means
+// no limit. Unverified users get their own, normally lower, limits.
+type SpendingLimits struct {
+	Daily             models.Money
+	Monthly           models.Money
+	UnverifiedDaily   models.Money
+	UnverifiedMonthly models.Money
+}
+
+// DefaultSpendingLimits returns the limits used when none are configured
+func DefaultSpendingLimits() SpendingLimits {
+	return SpendingLimits{
+		Daily:             models.FC(5000),
+		Monthly:           models.FC(50000),
+		UnverifiedDaily:   models.FC(250),
+		UnverifiedMonthly: models.FC(2500),
+	}
+}
+
+// NewSpendingLimits builds default send limits from configuration values
+func NewSpendingLimits(daily, monthly, unverifiedDaily, unverifiedMonthly float64) (SpendingLimits, error) {
+	if daily < 0 || monthly < 0 || unverifiedDaily < 0 || unverifiedMonthly < 0 {
+		return SpendingLimits{}, fmt.Errorf("spending limits cannot be negative")
+	}
+	return SpendingLimits{
+		Daily:             models.MoneyFromFloat(daily),
+		Monthly:           models.MoneyFromFloat(monthly),
+		UnverifiedDaily:   models.MoneyFromFloat(unverifiedDaily),
+		UnverifiedMonthly: models.MoneyFromFloat(unverifiedMonthly),
+	}, nil
+}
+
+// SetSpendingLimits replaces the default send limits
+func (s *WalletService) SetSpendingLimits(limits SpendingLimits) {
+	s.limits = limits
+}
+
+// SpendingStatus reports a wallet's limits and how much of them is used
+type SpendingStatus struct {
+	Frozen             bool          `json:"frozen"`
+	FrozenAt           *time.Time    `json:"frozen_at,omitempty"`
+	FrozenReason       string        `json:"frozen_reason,omitempty"`
+	Verified           bool          `json:"verified"`
+	CustomLimits       bool          `json:"custom_limits"` // Set by an admin rather than the defaults
+	DailyLimit         models.Money  `json:"daily_limit"`   // 0 means no limit
+	MonthlyLimit       models.Money  `json:"monthly_limit"` // 0 means no limit
+	SentToday          models.Money  `json:"sent_today"`    // Since midnight UTC
+	SentThisMonth      models.Money  `json:"sent_this_month"`
+	RemainingToday     *models.Money `json:"remaining_today"` // Null when there is no limit
+	RemainingThisMonth *models.Money `json:"remaining_this_month"`
+}
+
+// effectiveLimits returns the wallet's own limits where an admin set them
+// and the defaults for the user's verification status otherwise
+func (s *WalletService) effectiveLimits(user *models.User, wallet *models.Wallet) (daily, monthly models.Money) {
+	daily, monthly = s.limits.Daily, s.limits.Monthly
+	if !user.IsVerified {
+		daily, monthly = s.limits.UnverifiedDaily, s.limits.UnverifiedMonthly
+	}
+	if wallet.DailyLimit != nil {
+		daily = *wallet.DailyLimit
+	}
+	if wallet.MonthlyLimit != nil {
+		monthly = *wallet.MonthlyLimit
+	}
+	return daily, monthly
+}
+
+// sentSince sums what the user has paid out in transfers, escrows and
+// vouchers since t. Failed payments and cancelled ones, whose funds came
+// back, do not count.
+func (s *WalletService) sentSince(tx *gorm.DB, userID uuid.UUID, t time.Time) (models.Money, error) {
+	var sent struct {
+		Total models.Money
+	}
+	if err := tx.Model(&models.Transaction{}).
+		Where("user_id = ? AND type IN (?) AND created_at >= ?", userID,
+			[]models.TransactionType{models.TransactionTypeTransfer, models.TransactionTypeEscrow, models.TransactionTypeVoucher}, t).
+		Where("status NOT IN (?)", models.VoidTransactionStatuses).
+		Select("SUM(amount) as total").Scan(&sent).Error; err != nil {
+		return 0, fmt.Errorf("failed to sum sent payments: %w", err)
+	}
+	return sent.Total, nil
+}
+
+// spendingStatus computes the status of wallet inside tx
+func (s *WalletService) spendingStatus(tx *gorm.DB, wallet *models.Wallet) (*SpendingStatus, error) {
+	var user models.User
+	if err := tx.First(&user, "id = ?", wallet.UserID).Error; err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	now := time.Now().UTC()
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	sentToday, err := s.sentSince(tx, wallet.UserID, startOfDay)
+	if err != nil {
+		return nil, err
+	}
+	sentThisMonth, err := s.sentSince(tx, wallet.UserID, startOfMonth)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &SpendingStatus{
+		Frozen:        wallet.FrozenAt != nil,
+		FrozenAt:      wallet.FrozenAt,
+		FrozenReason:  wallet.FrozenReason,
+		Verified:      user.IsVerified,
+		CustomLimits:  wallet.DailyLimit != nil || wallet.MonthlyLimit != nil,
+		SentToday:     sentToday,
+		SentThisMonth: sentThisMonth,
+	}
+	status.DailyLimit, status.MonthlyLimit = s.effectiveLimits(&user, wallet)
+	if status.DailyLimit.IsPositive() {
+		remaining := models.MaxMoney(status.DailyLimit.Sub(sentToday), 0)
+		status.RemainingToday = &remaining
+	}
+	if status.MonthlyLimit.IsPositive() {
+		remaining := models.MaxMoney(status.MonthlyLimit.Sub(sentThisMonth), 0)
+		status.RemainingThisMonth = &remaining
+	}
+	return status, nil
+}
+
+// checkSend refuses a payment of amount from a frozen wallet or one that
+// would exceed the sender's daily or monthly limit
+func (s *WalletService) checkSend(tx *gorm.DB, wallet *models.Wallet, amount models.Money) error {
+	if wallet.FrozenAt != nil {
+		return fmt.Errorf("%w: %s", ErrWalletFrozen, wallet.FrozenReason)
+	}
+
+	status, err := s.spendingStatus(tx, wallet)
+	if err != nil {
+		return err
+	}
+	if status.RemainingToday != nil && amount > *status.RemainingToday {
+		return fmt.Errorf("%w: %s FC left of the %s FC daily limit", ErrSpendingLimitExceeded,
+			*status.RemainingToday, status.DailyLimit)
+	}
+	if status.RemainingThisMonth != nil && amount > *status.RemainingThisMonth {
+		return fmt.Errorf("%w: %s FC left of the %s FC monthly limit", ErrSpendingLimitExceeded,
+			*status.RemainingThisMonth, status.MonthlyLimit)
+	}
+	return nil
+}
+
+// checkReceive refuses payments into a frozen wallet
+func checkReceive(wallet *models.Wallet) error {
+	if wallet.FrozenAt != nil {
+		return fmt.Errorf("%w: the recipient cannot receive payments", ErrWalletFrozen)
+	}
+	return nil
+}
+
+// GetSpendingStatus returns the user's send limits and how much is left
+func (s *WalletService) GetSpendingStatus(userID uuid.UUID) (*SpendingStatus, error) {
+	var wallet models.Wallet
+	if err := s.db.Where("user_id = ?", userID).First(&wallet).Error; err != nil {
+		return nil, fmt.Errorf("wallet not found: %w", err)
+	}
+	return s.spendingStatus(s.db, &wallet)
+}
+
+// FreezeWallet stops a wallet from sending, receiving and being issued
+// FairCoins until it is unfrozen
+func (s *WalletService) FreezeWallet(userID, adminID uuid.UUID, reason string) (*models.Wallet, error) {
+	if reason == "" {
+		return nil, fmt.Errorf("a reason is required to freeze a wallet")
+	}
+	now := time.Now().UTC()
+	return s.updateControls(userID, adminID, models.WalletAuditFreeze, reason,
+		func(wallet *models.Wallet) (map[string]interface{}, error) {
+			if wallet.FrozenAt != nil {
+				return nil, fmt.Errorf("wallet is already frozen")
+			}
+			return map[string]interface{}{"frozen_at": now, "frozen_reason": reason}, nil
+		})
+}
+
+// UnfreezeWallet lifts a freeze
+func (s *WalletService) UnfreezeWallet(userID, adminID uuid.UUID, reason string) (*models.Wallet, error) {
+	if reason == "" {
+		return nil, fmt.Errorf("a reason is required to unfreeze a wallet")
+	}
+	return s.updateControls(userID, adminID, models.WalletAuditUnfreeze, reason,
+		func(wallet *models.Wallet) (map[string]interface{}, error) {
+			if wallet.FrozenAt == nil {
+				return nil, fmt.Errorf("wallet is not frozen")
+			}
+			return map[string]interface{}{"frozen_at": nil, "frozen_reason": ""}, nil
+		})
+}
+
+// SetWalletLimits gives a wallet its own daily and monthly send limits. A
+// nil limit returns that limit to the default; zero removes it.
+func (s *WalletService) SetWalletLimits(userID, adminID uuid.UUID, daily, monthly *models.Money, reason string) (*models.Wallet, error) {
+	if (daily != nil && daily.IsNegative()) || (monthly != nil && monthly.IsNegative()) {
+		return nil, fmt.Errorf("limits cannot be negative")
+	}
+	return s.updateControls(userID, adminID, models.WalletAuditSetLimits, reason,
+		func(wallet *models.Wallet) (map[string]interface{}, error) {
+			return map[string]interface{}{"daily_limit": daily, "monthly_limit": monthly}, nil
+		})
+}
+
+// updateControls applies the updates built by change to the user's wallet
+// and records them in the audit trail in the same transaction
+func (s *WalletService) updateControls(userID, adminID uuid.UUID, action models.WalletAuditAction, reason string,
+	change func(wallet *models.Wallet) (map[string]interface{}, error)) (*models.Wallet, error) {
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	var wallet models.Wallet
+	if err := tx.Where("user_id = ?", userID).First(&wallet).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("wallet not found: %w", err)
+	}
+
+	updates, err := change(&wallet)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	before := map[string]interface{}{
+		"daily_limit":   wallet.DailyLimit,
+		"monthly_limit": wallet.MonthlyLimit,
+		"frozen_at":     wallet.FrozenAt,
+		"frozen_reason": wallet.FrozenReason,
+	}
+	if err := tx.Model(&wallet).Updates(updates).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to update wallet: %w", err)
+	}
+
+	details := make(map[string]interface{})
+	for key, value := range updates {
+		details[key] = map[string]interface{}{"before": before[key], "after": value}
+	}
+	if err := s.audit(tx, userID, adminID, action, reason, details); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit wallet change: %w", err)
+	}
+
+	if err := s.db.Where("user_id = ?", userID).First(&wallet).Error; err != nil {
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+// RecordVerificationChange adds a change of the user's verification status,
+// which decides their default limits, to the audit trail
+func (s *WalletService) RecordVerificationChange(userID, adminID uuid.UUID, verified bool) error {
+	action := models.WalletAuditUnverify
+	if verified {
+		action = models.WalletAuditVerify
+	}
+	return s.audit(s.db, userID, adminID, action, "",
+		map[string]interface{}{"is_verified": map[string]interface{}{"before": !verified, "after": verified}})
+}
+
+func (s *WalletService) audit(tx *gorm.DB, userID, actorID uuid.UUID, action models.WalletAuditAction, reason string,
+	details map[string]interface{}) error {
+	encoded, _ := json.Marshal(details)
+	if err := tx.Create(&models.WalletAuditEntry{
+		UserID:    userID,
+		ActorID:   actorID,
+		Action:    action,
+		Reason:    reason,
+		Details:   string(encoded),
+		CreatedAt: time.Now(),
+	}).Error; err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	return nil
+}
+
+// GetWalletAudit returns the audit trail of the user's wallet, newest first
+func (s *WalletService) GetWalletAudit(userID uuid.UUID, limit int) ([]models.WalletAuditEntry, error) {
+	var entries []models.WalletAuditEntry
+	err := s.db.Preload("Actor").Where("user_id = ?", userID).
+		Order("created_at DESC").Limit(limit).Find(&entries).Error
+	return entries, err
+}
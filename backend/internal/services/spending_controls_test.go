@@ -0,0 +1,128 @@
+package services
+
+import (
+	"errors"
+	"faircoin/internal/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSendLimits(t *testing.T) {
+	db := newTestDB(t)
+	alice := newTestUser(t, db, "alice")
+	bob := newTestUser(t, db, "bob")
+
+	wallets := NewWalletService(db)
+	limits, err := NewSpendingLimits(1000, 1000, 50, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wallets.SetSpendingLimits(limits)
+
+	// Alice is unverified, so her daily limit is 50 FC, fees included
+	if _, err := wallets.Transfer(alice.ID, bob.ID, models.FC(30), "test"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wallets.Transfer(alice.ID, bob.ID, models.FC(30), "test"); !errors.Is(err, ErrSpendingLimitExceeded) {
+		t.Fatalf("transfer over the daily limit: %v, want ErrSpendingLimitExceeded", err)
+	}
+
+	// An admin can raise her limit
+	daily, monthly := models.FC(500), models.FC(500)
+	if _, err := wallets.SetWalletLimits(alice.ID, uuid.New(), &daily, &monthly, "regular supplier"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wallets.Transfer(alice.ID, bob.ID, models.FC(30), "test"); err != nil {
+		t.Errorf("transfer within the raised limit: %v", err)
+	}
+
+	var failed int
+	db.Model(&models.Transaction{}).Where("status = ?", models.TransactionStatusFailed).Count(&failed)
+	if failed != 1 {
+		t.Errorf("%d failed transactions recorded, want 1", failed)
+	}
+}
+
+func TestFrozenWalletCannotSendOrReceive(t *testing.T) {
+	db := newTestDB(t)
+	alice := newTestUser(t, db, "alice")
+	bob := newTestUser(t, db, "bob")
+	admin := uuid.New()
+
+	wallets := NewWalletService(db)
+	if _, err := wallets.FreezeWallet(alice.ID, admin, ""); err == nil {
+		t.Error("froze a wallet without a reason")
+	}
+	if _, err := wallets.FreezeWallet(alice.ID, admin, "reported stolen"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := wallets.Transfer(alice.ID, bob.ID, models.FC(1), "test"); !errors.Is(err, ErrWalletFrozen) {
+		t.Errorf("send from a frozen wallet: %v, want ErrWalletFrozen", err)
+	}
+	if _, err := wallets.Transfer(bob.ID, alice.ID, models.FC(1), "test"); !errors.Is(err, ErrWalletFrozen) {
+		t.Errorf("send to a frozen wallet: %v, want ErrWalletFrozen", err)
+	}
+
+	if _, err := wallets.UnfreezeWallet(alice.ID, admin, "recovered"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wallets.Transfer(alice.ID, bob.ID, models.FC(1), "test"); err != nil {
+		t.Errorf("send after unfreezing: %v", err)
+	}
+
+	audit, err := wallets.GetWalletAudit(alice.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(audit) != 2 {
+		t.Errorf("%d audit entries, want the freeze and the unfreeze", len(audit))
+	}
+}
+
+func TestEscrowReleaseToFrozenMerchant(t *testing.T) {
+	db := newTestDB(t)
+	alice := newTestUser(t, db, "alice")
+	bob := newTestUser(t, db, "bob")
+	db.Model(&models.User{}).Where("id = ?", bob.ID).Update("is_merchant", true)
+
+	wallets := NewWalletService(db)
+	escrow, err := wallets.OpenEscrow(alice.ID, bob.ID, models.FC(20), "bike", 0, models.EscrowTimeoutRelease)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wallets.FreezeWallet(bob.ID, uuid.New(), "under investigation"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The buyer cannot release to a frozen merchant; the escrow stays open
+	if _, err := wallets.ReleaseEscrow(escrow.ID, &alice.ID, false); !errors.Is(err, ErrWalletFrozen) {
+		t.Fatalf("release to a frozen merchant: %v, want ErrWalletFrozen", err)
+	}
+	if escrow, err = wallets.GetEscrow(escrow.ID); err != nil {
+		t.Fatal(err)
+	}
+	if escrow.Status != models.EscrowStatusOpen {
+		t.Fatalf("escrow is %s, want open", escrow.Status)
+	}
+
+	// On timeout it goes back to the buyer instead
+	db.Model(&models.Escrow{}).Where("id = ?", escrow.ID).Update("expires_at", time.Now().Add(-time.Minute))
+	if err := wallets.ResolveExpiredEscrows(); err != nil {
+		t.Fatal(err)
+	}
+	if escrow, err = wallets.GetEscrow(escrow.ID); err != nil {
+		t.Fatal(err)
+	}
+	if escrow.Status != models.EscrowStatusRefunded {
+		t.Errorf("escrow is %s, want refunded", escrow.Status)
+	}
+	if balance := walletBalance(t, db, alice.ID); balance != models.FC(100) {
+		t.Errorf("buyer holds %s, want 100", balance)
+	}
+	if balance := walletBalance(t, db, bob.ID); balance != models.FC(100) {
+		t.Errorf("merchant holds %s, want 100", balance)
+	}
+}
@@ -0,0 +1,439 @@
+package services
+
+import (
+	"errors"
+	"faircoin/internal/models"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// StandingOrderRetryInterval is how long the scheduler waits before
+	// retrying an occurrence that failed for lack of funds or a spending limit
+	StandingOrderRetryInterval = time.Hour
+	// DefaultStandingOrderRetries is how often a failed occurrence is retried
+	// when the order does not say otherwise
+	DefaultStandingOrderRetries = 3
+	// MaxStandingOrderRetries caps the retries a user can ask for
+	MaxStandingOrderRetries = 24
+)
+
+// ErrStandingOrderState is returned when a standing order does not exist or
+// is not in a status that allows the requested change
+var ErrStandingOrderState = errors.New("standing order not found or in the wrong status")
+
+// StandingOrderService manages future-dated and recurring transfers. Due
+// orders are paid like WalletService.Transfer, so they pay the normal
+// fee and respect locks and the holding cap like any manual transfer.
+type StandingOrderService struct {
+	db     *gorm.DB
+	wallet *WalletService
+}
+
+// NewStandingOrderService creates a new standing order service that pays
+// through the given wallet service
+func NewStandingOrderService(db *gorm.DB, wallet *WalletService) *StandingOrderService {
+	return &StandingOrderService{db: db, wallet: wallet}
+}
+
+// GetDB returns the database connection
+func (s *StandingOrderService) GetDB() *gorm.DB {
+	return s.db
+}
+
+// StandingOrderRequest describes a new standing order
+type StandingOrderRequest struct {
+	UserID      uuid.UUID
+	ToUserID    uuid.UUID
+	Amount      models.Money
+	Description string
+	Frequency   models.StandingOrderFrequency
+	CronExpr    string
+	StartAt     time.Time // Zero means now; for cron orders the first match after it
+	EndAt       *time.Time
+	MaxRetries  *int // Nil means DefaultStandingOrderRetries
+}
+
+// CreateStandingOrder validates and stores a new standing order
+func (s *StandingOrderService) CreateStandingOrder(req StandingOrderRequest) (*models.StandingOrder, error) {
+	if !req.Amount.IsPositive() {
+		return nil, fmt.Errorf("amount must be positive")
+	}
+	if req.UserID == req.ToUserID {
+		return nil, fmt.Errorf("cannot create a standing order to yourself")
+	}
+
+	// Schedules are kept in UTC so stored times compare and match exactly
+	now := time.Now().UTC()
+	if req.StartAt.IsZero() {
+		req.StartAt = now
+	}
+	req.StartAt = req.StartAt.UTC()
+	if req.EndAt != nil {
+		endAt := req.EndAt.UTC()
+		req.EndAt = &endAt
+		if !endAt.After(req.StartAt) {
+			return nil, fmt.Errorf("end_at must be after start_at")
+		}
+	}
+
+	maxRetries := DefaultStandingOrderRetries
+	if req.MaxRetries != nil {
+		maxRetries = *req.MaxRetries
+	}
+	if maxRetries < 0 || maxRetries > MaxStandingOrderRetries {
+		return nil, fmt.Errorf("max_retries must be between 0 and %d", MaxStandingOrderRetries)
+	}
+
+	order := &models.StandingOrder{
+		UserID:      req.UserID,
+		ToUserID:    req.ToUserID,
+		Amount:      req.Amount,
+		Description: req.Description,
+		Frequency:   req.Frequency,
+		StartAt:     req.StartAt,
+		EndAt:       req.EndAt,
+		NextRunAt:   req.StartAt,
+		MaxRetries:  maxRetries,
+		Status:      models.StandingOrderStatusActive,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	switch req.Frequency {
+	case models.StandingOrderOnce, models.StandingOrderDaily, models.StandingOrderWeekly, models.StandingOrderMonthly:
+		if req.CronExpr != "" {
+			return nil, fmt.Errorf("cron expression is only allowed with frequency cron")
+		}
+	case models.StandingOrderCron:
+		schedule, err := parseCron(req.CronExpr)
+		if err != nil {
+			return nil, err
+		}
+		order.CronExpr = req.CronExpr
+		order.NextRunAt = schedule.Next(req.StartAt.Add(-time.Minute))
+		if order.NextRunAt.IsZero() {
+			return nil, fmt.Errorf("cron expression never matches")
+		}
+	default:
+		return nil, fmt.Errorf("unknown frequency: %s", req.Frequency)
+	}
+	if req.EndAt != nil && order.NextRunAt.After(*req.EndAt) {
+		return nil, fmt.Errorf("standing order has no occurrence before end_at")
+	}
+
+	var recipient models.Wallet
+	if err := s.db.Where("user_id = ?", req.ToUserID).First(&recipient).Error; err != nil {
+		return nil, fmt.Errorf("recipient wallet not found: %w", err)
+	}
+
+	if err := s.db.Create(order).Error; err != nil {
+		return nil, fmt.Errorf("failed to create standing order: %w", err)
+	}
+	return order, nil
+}
+
+// WithIdempotency returns a copy of the service whose writes record what
+// they create on the given idempotency claim (see models.IdempotencyClaimKey)
+func (s *StandingOrderService) WithIdempotency(claim *models.IdempotencyRecord) *StandingOrderService {
+	scoped := *s
+	scoped.db = s.db.Set(models.IdempotencyClaimKey, claim.ID)
+	return &scoped
+}
+
+// nextOccurrence returns the occurrence after the one at t, or the zero time
+// when the order has no further occurrences
+func nextOccurrence(order *models.StandingOrder, t time.Time) time.Time {
+	var next time.Time
+	switch order.Frequency {
+	case models.StandingOrderDaily:
+		next = t.AddDate(0, 0, 1)
+	case models.StandingOrderWeekly:
+		next = t.AddDate(0, 0, 7)
+	case models.StandingOrderMonthly:
+		next = addMonthClamped(order.StartAt, t)
+	case models.StandingOrderCron:
+		schedule, err := parseCron(order.CronExpr)
+		if err != nil {
+			return time.Time{}
+		}
+		next = schedule.Next(t)
+	default:
+		return time.Time{}
+	}
+
+	if order.EndAt != nil && next.After(*order.EndAt) {
+		return time.Time{}
+	}
+	return next
+}
+
+// addMonthClamped moves t one month forward on the day of month of anchor,
+// using the last day of the month when it is shorter (rent due on the 31st
+// is paid on the 30th in April and on the 28th or 29th in February)
+func addMonthClamped(anchor, t time.Time) time.Time {
+	year, month, _ := t.Date()
+	firstOfNext := time.Date(year, month+1, 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	lastDay := firstOfNext.AddDate(0, 1, -1).Day()
+
+	day := anchor.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return firstOfNext.AddDate(0, 0, day-1)
+}
+
+// ProcessDue attempts every active standing order whose occurrence or retry
+// is due. Each order gets at most one attempt per call, so an order that
+// fell behind catches up over successive runs.
+func (s *StandingOrderService) ProcessDue() error {
+	now := time.Now().UTC()
+
+	var orders []models.StandingOrder
+	if err := s.db.Where("status = ? AND COALESCE(retry_at, next_run_at) <= ?", models.StandingOrderStatusActive, now).
+		Order("next_run_at ASC").Find(&orders).Error; err != nil {
+		return err
+	}
+
+	var failed int
+	for i := range orders {
+		if err := s.run(&orders[i], now); err != nil {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d due standing orders could not be processed", failed, len(orders))
+	}
+	return nil
+}
+
+// run makes one attempt at the order's current occurrence and records it.
+// Only errors in the bookkeeping itself are returned; a failed transfer is
+// recorded on the order and in its run history.
+func (s *StandingOrderService) run(order *models.StandingOrder, now time.Time) error {
+	attempt := order.Attempts + 1
+	scheduledFor := order.NextRunAt
+	next := nextOccurrence(order, scheduledFor)
+	run := &models.StandingOrderRun{
+		OrderID:      order.ID,
+		ScheduledFor: scheduledFor,
+		Attempt:      attempt,
+		CreatedAt:    now,
+	}
+	updates := map[string]interface{}{"updated_at": now}
+
+	description := order.Description
+	if description == "" {
+		description = "Standing order"
+	}
+
+	// Claim the attempt, pay and record it in one transaction, so
+	// overlapping schedulers cannot pay it twice and a payment is never left
+	// without its run
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if claimed, err := claimRun(tx, order, attempt, now); err != nil || !claimed {
+		tx.Rollback()
+		return err
+	}
+
+	transaction, transferErr := s.wallet.transfer(tx, order.UserID, order.ToUserID, order.Amount, description, nil)
+	if transferErr == nil {
+		run.Status = models.StandingOrderRunSucceeded
+		run.TransactionID = &transaction.ID
+		updates["run_count"] = order.RunCount + 1
+		updates["last_error"] = ""
+		advance(updates, next, models.StandingOrderStatusCompleted)
+		if err := finishRun(tx, order.ID, run, updates); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit().Error; err != nil {
+			return fmt.Errorf("failed to commit standing order run: %w", err)
+		}
+		return nil
+	}
+	tx.Rollback()
+
+	run.Error = transferErr.Error()
+	updates["last_error"] = transferErr.Error()
+	switch {
+	case (errors.Is(transferErr, ErrInsufficientBalance) || errors.Is(transferErr, ErrSpendingLimitExceeded)) &&
+		attempt <= order.MaxRetries &&
+		(next.IsZero() || now.Add(StandingOrderRetryInterval).Before(next)):
+		// Try again later, as long as that is before the next occurrence
+		run.Status = models.StandingOrderRunRetrying
+		updates["retry_at"] = now.Add(StandingOrderRetryInterval)
+
+	default:
+		// Give up on this occurrence
+		run.Status = models.StandingOrderRunFailed
+		// A recurring order that misses its last payment still completed its
+		// schedule; only a one-off order fails outright
+		finalStatus := models.StandingOrderStatusCompleted
+		if order.Frequency == models.StandingOrderOnce {
+			finalStatus = models.StandingOrderStatusFailed
+		}
+		advance(updates, next, finalStatus)
+	}
+
+	// The claim was rolled back with the transfer, so claim the attempt
+	// again to record the failure
+	tx = s.db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if claimed, err := claimRun(tx, order, attempt, now); err != nil || !claimed {
+		tx.Rollback()
+		return err
+	}
+	if declinedTransfer(transferErr) {
+		if err := s.wallet.recordFailedTransfer(tx, order.UserID, order.ToUserID, order.Amount, description, transferErr); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := finishRun(tx, order.ID, run, updates); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("failed to commit standing order run: %w", err)
+	}
+	return nil
+}
+
+// claimRun marks the attempt as taken, reporting false when another
+// scheduler took it first or the order is no longer active
+func claimRun(tx *gorm.DB, order *models.StandingOrder, attempt int, now time.Time) (bool, error) {
+	result := tx.Model(&models.StandingOrder{}).
+		Where("id = ? AND status = ? AND next_run_at = ? AND attempts = ?",
+			order.ID, models.StandingOrderStatusActive, order.NextRunAt, order.Attempts).
+		Updates(map[string]interface{}{"attempts": attempt, "last_run_at": now, "updated_at": now})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to claim standing order: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// finishRun records the run and moves the order on. The update only applies
+// to an active order, so a cancellation is never overwritten.
+func finishRun(tx *gorm.DB, orderID uuid.UUID, run *models.StandingOrderRun, updates map[string]interface{}) error {
+	if err := tx.Create(run).Error; err != nil {
+		return fmt.Errorf("failed to record standing order run: %w", err)
+	}
+	result := tx.Model(&models.StandingOrder{}).
+		Where("id = ? AND status = ?", orderID, models.StandingOrderStatusActive).
+		Updates(updates)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update standing order: %w", result.Error)
+	}
+	if result.RowsAffected != 1 {
+		return fmt.Errorf("%w: it changed while running", ErrStandingOrderState)
+	}
+	return nil
+}
+
+// advance moves the order on to its next occurrence, or finishes it with
+// the given status when there is none
+func advance(updates map[string]interface{}, next time.Time, finalStatus models.StandingOrderStatus) {
+	updates["attempts"] = 0
+	updates["retry_at"] = nil
+	if next.IsZero() {
+		updates["status"] = finalStatus
+		return
+	}
+	updates["next_run_at"] = next
+}
+
+// GetUserStandingOrders returns the standing orders a user pays, optionally
+// filtered by status
+func (s *StandingOrderService) GetUserStandingOrders(userID uuid.UUID, status string) ([]models.StandingOrder, error) {
+	query := s.db.Preload("ToUser").Where("user_id = ?", userID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var orders []models.StandingOrder
+	err := query.Order("created_at DESC").Find(&orders).Error
+	return orders, err
+}
+
+// GetStandingOrder returns one of the user's standing orders with its run
+// history, newest first
+func (s *StandingOrderService) GetStandingOrder(orderID, userID uuid.UUID) (*models.StandingOrder, error) {
+	var order models.StandingOrder
+	if err := s.db.Preload("ToUser").
+		Preload("Runs", func(db *gorm.DB) *gorm.DB { return db.Order("created_at DESC") }).
+		First(&order, "id = ? AND user_id = ?", orderID, userID).Error; err != nil {
+		return nil, fmt.Errorf("standing order not found: %w", err)
+	}
+	return &order, nil
+}
+
+// PauseStandingOrder stops an active order from running until it is resumed
+func (s *StandingOrderService) PauseStandingOrder(orderID, userID uuid.UUID) (*models.StandingOrder, error) {
+	return s.setStatus(orderID, userID, []models.StandingOrderStatus{models.StandingOrderStatusActive},
+		models.StandingOrderStatusPaused, nil)
+}
+
+// ResumeStandingOrder reactivates a paused order. Occurrences that fell due
+// while it was paused are skipped rather than paid in a burst.
+func (s *StandingOrderService) ResumeStandingOrder(orderID, userID uuid.UUID) (*models.StandingOrder, error) {
+	order, err := s.GetStandingOrder(orderID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	next := order.NextRunAt
+	for !next.IsZero() && next.Before(now) {
+		next = nextOccurrence(order, next)
+	}
+	if next.IsZero() && order.Frequency != models.StandingOrderOnce {
+		return nil, fmt.Errorf("standing order has no occurrences left")
+	}
+
+	updates := map[string]interface{}{"attempts": 0, "retry_at": nil}
+	if !next.IsZero() {
+		updates["next_run_at"] = next
+	}
+	return s.setStatus(orderID, userID, []models.StandingOrderStatus{models.StandingOrderStatusPaused},
+		models.StandingOrderStatusActive, updates)
+}
+
+// CancelStandingOrder permanently stops an active or paused order
+func (s *StandingOrderService) CancelStandingOrder(orderID, userID uuid.UUID) (*models.StandingOrder, error) {
+	return s.setStatus(orderID, userID,
+		[]models.StandingOrderStatus{models.StandingOrderStatusActive, models.StandingOrderStatusPaused},
+		models.StandingOrderStatusCancelled, nil)
+}
+
+// setStatus moves an order to a new status, failing if it is not in one of
+// the expected statuses
+func (s *StandingOrderService) setStatus(orderID, userID uuid.UUID, from []models.StandingOrderStatus, to models.StandingOrderStatus,
+	updates map[string]interface{}) (*models.StandingOrder, error) {
+	if updates == nil {
+		updates = make(map[string]interface{})
+	}
+	updates["status"] = to
+	updates["updated_at"] = time.Now().UTC()
+
+	result := s.db.Model(&models.StandingOrder{}).
+		Where("id = ? AND user_id = ? AND status IN (?)", orderID, userID, from).
+		Updates(updates)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to update standing order: %w", result.Error)
+	}
+	if result.RowsAffected != 1 {
+		return nil, fmt.Errorf("%w: cannot change a standing order to %s", ErrStandingOrderState, to)
+	}
+
+	return s.GetStandingOrder(orderID, userID)
+}
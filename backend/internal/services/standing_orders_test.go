@@ -0,0 +1,104 @@
+package services
+
+import (
+	"errors"
+	"faircoin/internal/models"
+	"testing"
+)
+
+func TestStandingOrderRun(t *testing.T) {
+	tests := []struct {
+		name        string
+		amount      models.Money
+		wantRun     models.StandingOrderRunStatus
+		wantStatus  models.StandingOrderStatus
+		wantBalance models.Money
+		wantFailed  int
+	}{
+		{"paid", models.FC(30), models.StandingOrderRunSucceeded, models.StandingOrderStatusCompleted, models.FC(130), 0},
+		{"insufficient balance", models.FC(500), models.StandingOrderRunRetrying, models.StandingOrderStatusActive, models.FC(100), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			alice := newTestUser(t, db, "alice")
+			bob := newTestUser(t, db, "bob")
+			orders := NewStandingOrderService(db, NewWalletService(db))
+
+			order, err := orders.CreateStandingOrder(StandingOrderRequest{UserID: alice.ID, ToUserID: bob.ID,
+				Amount: tt.amount, Frequency: models.StandingOrderOnce})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := orders.ProcessDue(); err != nil {
+				t.Fatal(err)
+			}
+
+			order, err = orders.GetStandingOrder(order.ID, alice.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(order.Runs) != 1 || order.Runs[0].Status != tt.wantRun {
+				t.Fatalf("runs = %+v, want one %s", order.Runs, tt.wantRun)
+			}
+			if order.Status != tt.wantStatus {
+				t.Errorf("order is %s, want %s", order.Status, tt.wantStatus)
+			}
+			if (order.Runs[0].TransactionID != nil) != (tt.wantRun == models.StandingOrderRunSucceeded) {
+				t.Errorf("run transaction = %v", order.Runs[0].TransactionID)
+			}
+			if balance := walletBalance(t, db, bob.ID); balance != tt.wantBalance {
+				t.Errorf("recipient holds %s, want %s", balance, tt.wantBalance)
+			}
+			var failed int
+			db.Model(&models.Transaction{}).Where("status = ?", models.TransactionStatusFailed).Count(&failed)
+			if failed != tt.wantFailed {
+				t.Errorf("%d failed transactions recorded, want %d", failed, tt.wantFailed)
+			}
+
+			// A second pass finds nothing due
+			if err := orders.ProcessDue(); err != nil {
+				t.Fatal(err)
+			}
+			var runs int
+			db.Model(&models.StandingOrderRun{}).Count(&runs)
+			if runs != 1 {
+				t.Errorf("%d runs after a second pass, want 1", runs)
+			}
+		})
+	}
+}
+
+func TestStandingOrderRunKeepsCancellation(t *testing.T) {
+	db := newTestDB(t)
+	alice := newTestUser(t, db, "alice")
+	bob := newTestUser(t, db, "bob")
+	orders := NewStandingOrderService(db, NewWalletService(db))
+
+	order, err := orders.CreateStandingOrder(StandingOrderRequest{UserID: alice.ID, ToUserID: bob.ID,
+		Amount: models.FC(30), Frequency: models.StandingOrderOnce})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The owner cancels while a run is under way: finishing the run must
+	// not complete the order over the cancellation
+	if _, err := orders.CancelStandingOrder(order.ID, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	updates := map[string]interface{}{}
+	advance(updates, nextOccurrence(order, order.NextRunAt), models.StandingOrderStatusCompleted)
+	tx := db.Begin()
+	err = finishRun(tx, order.ID, &models.StandingOrderRun{OrderID: order.ID, Status: models.StandingOrderRunSucceeded}, updates)
+	if !errors.Is(err, ErrStandingOrderState) {
+		t.Fatalf("finishRun() = %v, want ErrStandingOrderState", err)
+	}
+	tx.Rollback()
+
+	if order, err = orders.GetStandingOrder(order.ID, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != models.StandingOrderStatusCancelled {
+		t.Errorf("order is %s, want cancelled", order.Status)
+	}
+}
@@ -0,0 +1,392 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"faircoin/internal/models"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StatementCurrency is the currency code statements are issued in. FairCoin
+// has no ISO 4217 code; XFC is in the range the standard leaves to users.
+const StatementCurrency = "XFC"
+
+// Statement export formats
+const (
+	StatementFormatJSON    = "json"
+	StatementFormatCSV     = "csv"
+	StatementFormatOFX     = "ofx"
+	StatementFormatCamt053 = "camt053"
+)
+
+// truncate shortens s to at most n characters for fixed-width fields
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
+
+// statementRef shortens a line reference to the 35 characters ISO 20022
+// allows for identifiers
+func statementRef(reference string) string {
+	return truncate(strings.ReplaceAll(reference, "-", ""), 35)
+}
+
+// csvText keeps a free-text cell from being read as a formula by
+// spreadsheets, which evaluate cells starting with =, +, -, @, tab or
+// carriage return
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// WriteCSV writes the statement as CSV, one line per movement between an
+// opening and a closing balance row. Descriptions and counterparties are
+// escaped with csvText; amounts and balances are left as numbers.
+func (st *Statement) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	rows := [][]string{
+		{"date", "reference", "type", "transaction_id", "description", "counterparty", "amount", "balance"},
+		{st.From.UTC().Format(time.RFC3339), "", "opening_balance", "", "Opening balance", "", "", st.OpeningBalance.String()},
+	}
+	for _, line := range st.Lines {
+		transactionID := ""
+		if line.TransactionID != nil {
+			transactionID = line.TransactionID.String()
+		}
+		rows = append(rows, []string{
+			line.Date.UTC().Format(time.RFC3339), line.Reference, line.Kind, transactionID,
+			csvText(line.Description), csvText(line.Counterparty), line.Amount.String(), line.Balance.String(),
+		})
+	}
+	rows = append(rows, []string{st.To.UTC().Format(time.RFC3339), "", "closing_balance", "", "Closing balance", "", "", st.ClosingBalance.String()})
+
+	if err := writer.WriteAll(rows); err != nil {
+		return fmt.Errorf("failed to write CSV statement: %w", err)
+	}
+	return nil
+}
+
+// OFX 2.2 (XML) bank statement response
+
+type ofxDocument struct {
+	XMLName xml.Name `xml:"OFX"`
+	SignOn  struct {
+		Response struct {
+			Status   ofxStatus `xml:"STATUS"`
+			DTServer string    `xml:"DTSERVER"`
+			Language string    `xml:"LANGUAGE"`
+		} `xml:"SONRS"`
+	} `xml:"SIGNONMSGSRSV1"`
+	Bank struct {
+		Transaction struct {
+			TrnUID    string    `xml:"TRNUID"`
+			Status    ofxStatus `xml:"STATUS"`
+			Statement struct {
+				CurDef  string `xml:"CURDEF"`
+				Account struct {
+					BankID   string `xml:"BANKID"`
+					AcctID   string `xml:"ACCTID"`
+					AcctType string `xml:"ACCTTYPE"`
+				} `xml:"BANKACCTFROM"`
+				TranList struct {
+					DTStart      string           `xml:"DTSTART"`
+					DTEnd        string           `xml:"DTEND"`
+					Transactions []ofxTransaction `xml:"STMTTRN"`
+				} `xml:"BANKTRANLIST"`
+				LedgerBal struct {
+					BalAmt string `xml:"BALAMT"`
+					DTAsOf string `xml:"DTASOF"`
+				} `xml:"LEDGERBAL"`
+			} `xml:"STMTRS"`
+		} `xml:"STMTTRNRS"`
+	} `xml:"BANKMSGSRSV1"`
+}
+
+type ofxStatus struct {
+	Code     int    `xml:"CODE"`
+	Severity string `xml:"SEVERITY"`
+}
+
+type ofxTransaction struct {
+	TrnType  string `xml:"TRNTYPE"`
+	DTPosted string `xml:"DTPOSTED"`
+	TrnAmt   string `xml:"TRNAMT"`
+	FITID    string `xml:"FITID"`
+	Name     string `xml:"NAME,omitempty"`
+	Memo     string `xml:"MEMO,omitempty"`
+}
+
+func ofxTime(t time.Time) string {
+	return t.UTC().Format("20060102150405.000") + "[0:GMT]"
+}
+
+// WriteOFX writes the statement as an OFX 2.2 bank statement. OFX has no
+// opening balance; the closing balance is the ledger balance.
+func (st *Statement) WriteOFX(w io.Writer) error {
+	var doc ofxDocument
+	doc.SignOn.Response.Status = ofxStatus{Code: 0, Severity: "INFO"}
+	doc.SignOn.Response.DTServer = ofxTime(st.GeneratedAt)
+	doc.SignOn.Response.Language = "ENG"
+
+	trn := &doc.Bank.Transaction
+	trn.TrnUID = strconv.FormatInt(st.GeneratedAt.Unix(), 10)
+	trn.Status = ofxStatus{Code: 0, Severity: "INFO"}
+	trn.Statement.CurDef = StatementCurrency
+	trn.Statement.Account.BankID = "FAIRCOIN"
+	trn.Statement.Account.AcctID = st.UserID.String()
+	trn.Statement.Account.AcctType = "CHECKING"
+	trn.Statement.TranList.DTStart = ofxTime(st.From)
+	trn.Statement.TranList.DTEnd = ofxTime(st.To)
+	trn.Statement.TranList.Transactions = make([]ofxTransaction, 0, len(st.Lines))
+	for _, line := range st.Lines {
+		trnType := "CREDIT"
+		switch {
+		case line.Kind == StatementLineFee && line.Amount.IsNegative():
+			trnType = "FEE"
+		case line.Amount.IsNegative():
+			trnType = "DEBIT"
+		}
+		trn.Statement.TranList.Transactions = append(trn.Statement.TranList.Transactions, ofxTransaction{
+			TrnType:  trnType,
+			DTPosted: ofxTime(line.Date),
+			TrnAmt:   line.Amount.String(),
+			FITID:    line.Reference,
+			Name:     truncate(line.Counterparty, 32),
+			Memo:     truncate(line.Description, 255),
+		})
+	}
+	trn.Statement.LedgerBal.BalAmt = st.ClosingBalance.String()
+	trn.Statement.LedgerBal.DTAsOf = ofxTime(st.To)
+
+	if _, err := io.WriteString(w, xml.Header+
+		`<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>`+"\n"); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to write OFX statement: %w", err)
+	}
+	return nil
+}
+
+// ISO 20022 camt.053.001.02 bank-to-customer statement
+
+type camtDocument struct {
+	XMLName   xml.Name `xml:"urn:iso:std:iso:20022:tech:xsd:camt.053.001.02 Document"`
+	Statement struct {
+		GroupHeader struct {
+			MsgID    string `xml:"MsgId"`
+			CreDtTm  string `xml:"CreDtTm"`
+			MsgPgntn struct {
+				PgNb      int  `xml:"PgNb"`
+				LastPgInd bool `xml:"LastPgInd"`
+			} `xml:"MsgPgntn"`
+		} `xml:"GrpHdr"`
+		Stmt struct {
+			ID      string `xml:"Id"`
+			CreDtTm string `xml:"CreDtTm"`
+			FrToDt  struct {
+				FrDtTm string `xml:"FrDtTm"`
+				ToDtTm string `xml:"ToDtTm"`
+			} `xml:"FrToDt"`
+			Acct struct {
+				ID struct {
+					Othr struct {
+						ID string `xml:"Id"`
+					} `xml:"Othr"`
+				} `xml:"Id"`
+				Ccy  string `xml:"Ccy"`
+				Ownr struct {
+					Nm string `xml:"Nm"`
+				} `xml:"Ownr"`
+			} `xml:"Acct"`
+			Balances []camtBalance `xml:"Bal"`
+			Summary  struct {
+				Total   camtEntryCount `xml:"TtlNtries"`
+				Credits camtEntryCount `xml:"TtlCdtNtries"`
+				Debits  camtEntryCount `xml:"TtlDbtNtries"`
+			} `xml:"TxsSummry"`
+			Entries []camtEntry `xml:"Ntry"`
+		} `xml:"Stmt"`
+	} `xml:"BkToCstmrStmt"`
+}
+
+type camtAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type camtBalance struct {
+	Type struct {
+		CdOrPrtry struct {
+			Cd string `xml:"Cd"`
+		} `xml:"CdOrPrtry"`
+	} `xml:"Tp"`
+	Amt       camtAmount `xml:"Amt"`
+	CdtDbtInd string     `xml:"CdtDbtInd"`
+	Dt        struct {
+		DtTm string `xml:"DtTm"`
+	} `xml:"Dt"`
+}
+
+type camtEntryCount struct {
+	NbOfNtries int    `xml:"NbOfNtries"`
+	Sum        string `xml:"Sum"`
+}
+
+type camtParty struct {
+	Nm string `xml:"Nm"`
+}
+
+type camtEntry struct {
+	NtryRef   string     `xml:"NtryRef"`
+	Amt       camtAmount `xml:"Amt"`
+	CdtDbtInd string     `xml:"CdtDbtInd"`
+	Sts       string     `xml:"Sts"`
+	BookgDt   struct {
+		DtTm string `xml:"DtTm"`
+	} `xml:"BookgDt"`
+	ValDt struct {
+		DtTm string `xml:"DtTm"`
+	} `xml:"ValDt"`
+	AcctSvcrRef string      `xml:"AcctSvcrRef"`
+	BkTxCd      camtTxCode  `xml:"BkTxCd"`
+	Details     camtDetails `xml:"NtryDtls"`
+}
+
+type camtTxCode struct {
+	Prtry struct {
+		Cd   string `xml:"Cd"`
+		Issr string `xml:"Issr"`
+	} `xml:"Prtry"`
+}
+
+type camtDetails struct {
+	TxDtls struct {
+		Refs struct {
+			AcctSvcrRef string `xml:"AcctSvcrRef"`
+			EndToEndID  string `xml:"EndToEndId,omitempty"`
+		} `xml:"Refs"`
+		RltdPties *camtParties    `xml:"RltdPties,omitempty"`
+		RmtInf    *camtRemittance `xml:"RmtInf,omitempty"`
+	} `xml:"TxDtls"`
+}
+
+type camtParties struct {
+	Dbtr *camtParty `xml:"Dbtr,omitempty"`
+	Cdtr *camtParty `xml:"Cdtr,omitempty"`
+}
+
+type camtRemittance struct {
+	Ustrd string `xml:"Ustrd"`
+}
+
+// camtSigned splits a signed amount into its absolute value and the
+// credit/debit indicator
+func camtSigned(amount models.Money) (camtAmount, string) {
+	if amount.IsNegative() {
+		return camtAmount{Currency: StatementCurrency, Value: amount.Neg().String()}, "DBIT"
+	}
+	return camtAmount{Currency: StatementCurrency, Value: amount.String()}, "CRDT"
+}
+
+func camtTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+// WriteCamt053 writes the statement as an ISO 20022 camt.053.001.02
+// message. Amounts keep all eight FairCoin decimals.
+func (st *Statement) WriteCamt053(w io.Writer) error {
+	var doc camtDocument
+	msgID := statementRef("FC" + st.UserID.String()[:8] + st.GeneratedAt.UTC().Format("20060102150405"))
+	header := &doc.Statement.GroupHeader
+	header.MsgID = msgID
+	header.CreDtTm = camtTime(st.GeneratedAt)
+	header.MsgPgntn.PgNb = 1
+	header.MsgPgntn.LastPgInd = true
+
+	stmt := &doc.Statement.Stmt
+	stmt.ID = msgID
+	stmt.CreDtTm = camtTime(st.GeneratedAt)
+	stmt.FrToDt.FrDtTm = camtTime(st.From)
+	stmt.FrToDt.ToDtTm = camtTime(st.To)
+	stmt.Acct.ID.Othr.ID = st.UserID.String()
+	stmt.Acct.Ccy = StatementCurrency
+	stmt.Acct.Ownr.Nm = truncate(st.Username, 140)
+
+	for _, b := range []struct {
+		code   string
+		amount models.Money
+		at     time.Time
+	}{{"OPBD", st.OpeningBalance, st.From}, {"CLBD", st.ClosingBalance, st.To}} {
+		var balance camtBalance
+		balance.Type.CdOrPrtry.Cd = b.code
+		balance.Amt, balance.CdtDbtInd = camtSigned(b.amount)
+		balance.Dt.DtTm = camtTime(b.at)
+		stmt.Balances = append(stmt.Balances, balance)
+	}
+
+	var credits, debits int
+	stmt.Entries = make([]camtEntry, 0, len(st.Lines))
+	for _, line := range st.Lines {
+		var entry camtEntry
+		ref := statementRef(line.Reference)
+		entry.NtryRef = ref
+		entry.Amt, entry.CdtDbtInd = camtSigned(line.Amount)
+		entry.Sts = "BOOK"
+		entry.BookgDt.DtTm = camtTime(line.Date)
+		entry.ValDt.DtTm = camtTime(line.Date)
+		entry.AcctSvcrRef = ref
+		entry.BkTxCd.Prtry.Cd = line.Kind
+		entry.BkTxCd.Prtry.Issr = "FairCoin"
+
+		details := &entry.Details.TxDtls
+		details.Refs.AcctSvcrRef = ref
+		if line.TransactionID != nil {
+			details.Refs.EndToEndID = statementRef(line.TransactionID.String())
+		}
+		if line.Counterparty != "" {
+			details.RltdPties = &camtParties{}
+			party := &camtParty{Nm: truncate(line.Counterparty, 140)}
+			if line.Amount.IsNegative() {
+				details.RltdPties.Cdtr = party
+			} else {
+				details.RltdPties.Dbtr = party
+			}
+		}
+		if line.Description != "" {
+			details.RmtInf = &camtRemittance{Ustrd: truncate(line.Description, 140)}
+		}
+
+		if line.Amount.IsNegative() {
+			debits++
+		} else {
+			credits++
+		}
+		stmt.Entries = append(stmt.Entries, entry)
+	}
+
+	stmt.Summary.Total = camtEntryCount{NbOfNtries: len(st.Lines), Sum: st.TotalCredits.Sub(st.TotalDebits).String()}
+	stmt.Summary.Credits = camtEntryCount{NbOfNtries: credits, Sum: st.TotalCredits.String()}
+	stmt.Summary.Debits = camtEntryCount{NbOfNtries: debits, Sum: st.TotalDebits.Neg().String()}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to write camt.053 statement: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"faircoin/internal/models"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+)
+
+// MaxStatementPeriod caps the date range of one statement
+const MaxStatementPeriod = 366 * 24 * time.Hour
+
+// Statement line kinds besides the transaction types
+const (
+	StatementLineFee    = "fee"    // Fee paid, or returned by a refund
+	StatementLineLedger = "ledger" // Ledger entry without a transaction, e.g. an opening balance
+)
+
+// StatementLine is one movement on a member's account. Amounts are signed:
+// credits are positive, debits negative.
+type StatementLine struct {
+	Reference     string       `json:"reference"` // Unique per line
+	Date          time.Time    `json:"date"`
+	Kind          string       `json:"kind"` // Transaction type, "fee" or "ledger"
+	TransactionID *uuid.UUID   `json:"transaction_id,omitempty"`
+	Description   string       `json:"description"`
+	Counterparty  string       `json:"counterparty,omitempty"` // Username
+	Amount        models.Money `json:"amount"`
+	Balance       models.Money `json:"balance"` // After this line
+}
+
+// Statement lists the movements on a member's account between From
+// (inclusive) and To (exclusive), with the balances either side
+type Statement struct {
+	UserID         uuid.UUID       `json:"user_id"`
+	Username       string          `json:"username"`
+	From           time.Time       `json:"from"`
+	To             time.Time       `json:"to"`
+	OpeningBalance models.Money    `json:"opening_balance"`
+	ClosingBalance models.Money    `json:"closing_balance"`
+	TotalCredits   models.Money    `json:"total_credits"`
+	TotalDebits    models.Money    `json:"total_debits"` // Negative
+	Lines          []StatementLine `json:"lines"`
+	GeneratedAt    time.Time       `json:"generated_at"`
+}
+
+// GetStatement builds the user's statement for [from, to) from the ledger
+// postings on their account, so the balances agree with the wallet. A fee
+// paid with a payment, or returned with a refund, is a line of its own.
+func (s *TransactionService) GetStatement(userID uuid.UUID, from, to time.Time) (*Statement, error) {
+	if !to.After(from) {
+		return nil, fmt.Errorf("statement end must be after its start")
+	}
+	if to.Sub(from) > MaxStatementPeriod {
+		return nil, fmt.Errorf("a statement can cover at most %d days", int(MaxStatementPeriod.Hours()/24))
+	}
+
+	var user models.User
+	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+	statement := &Statement{
+		UserID:      userID,
+		Username:    user.Username,
+		From:        from,
+		To:          to,
+		Lines:       []StatementLine{},
+		GeneratedAt: time.Now(),
+	}
+
+	// Read only: a member without a ledger account has had no movements
+	var account models.LedgerAccount
+	if err := s.db.Where("code = ?", userAccountCode(userID)).First(&account).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return statement, nil
+		}
+		return nil, fmt.Errorf("failed to get ledger account: %w", err)
+	}
+
+	var opening struct {
+		Total models.Money
+	}
+	if err := s.db.Model(&models.Posting{}).Select("SUM(amount) as total").
+		Where("account_id = ? AND created_at < ?", account.ID, from).Scan(&opening).Error; err != nil {
+		return nil, fmt.Errorf("failed to compute opening balance: %w", err)
+	}
+
+	var postings []struct {
+		ID            uuid.UUID
+		EntryID       uuid.UUID
+		Amount        models.Money
+		CreatedAt     time.Time
+		TransactionID *uuid.UUID
+		Description   string
+	}
+	if err := s.db.Table("postings").
+		Select("postings.id, postings.entry_id, postings.amount, postings.created_at, journal_entries.transaction_id, journal_entries.description").
+		Joins("JOIN journal_entries ON journal_entries.id = postings.entry_id").
+		Where("postings.account_id = ? AND postings.created_at >= ? AND postings.created_at < ?", account.ID, from, to).
+		Order("postings.created_at ASC, postings.id ASC").
+		Scan(&postings).Error; err != nil {
+		return nil, fmt.Errorf("failed to get postings: %w", err)
+	}
+
+	var transactionIDs []uuid.UUID
+	for _, posting := range postings {
+		if posting.TransactionID != nil {
+			transactionIDs = append(transactionIDs, *posting.TransactionID)
+		}
+	}
+	transactions := make(map[uuid.UUID]*models.Transaction, len(transactionIDs))
+	if len(transactionIDs) > 0 {
+		var loaded []models.Transaction
+		if err := s.db.Preload("User").Preload("ToUser").Where("id IN (?)", transactionIDs).
+			Find(&loaded).Error; err != nil {
+			return nil, fmt.Errorf("failed to get transactions: %w", err)
+		}
+		for i := range loaded {
+			transactions[loaded[i].ID] = &loaded[i]
+		}
+	}
+
+	balance := opening.Total
+	statement.OpeningBalance = balance
+	addLine := func(line StatementLine) {
+		balance = balance.Add(line.Amount)
+		line.Balance = balance
+		if line.Amount.IsPositive() {
+			statement.TotalCredits = statement.TotalCredits.Add(line.Amount)
+		} else {
+			statement.TotalDebits = statement.TotalDebits.Add(line.Amount)
+		}
+		statement.Lines = append(statement.Lines, line)
+	}
+
+	for _, posting := range postings {
+		line := StatementLine{
+			Reference:   posting.ID.String(),
+			Date:        posting.CreatedAt,
+			Kind:        StatementLineLedger,
+			Description: posting.Description,
+			Amount:      posting.Amount,
+		}
+
+		var fee models.Money
+		if posting.TransactionID != nil {
+			if transaction, ok := transactions[*posting.TransactionID]; ok {
+				line.TransactionID = &transaction.ID
+				line.Kind = string(transaction.Type)
+				if transaction.Description != "" {
+					line.Description = transaction.Description
+				}
+				line.Counterparty = statementCounterparty(transaction, userID)
+
+				// The payer's posting carries amount plus fee, in either
+				// direction for refunds
+				total := transaction.Amount.Add(transaction.Fee)
+				if transaction.Fee.IsPositive() && (posting.Amount == total || posting.Amount == total.Neg()) {
+					fee = transaction.Fee
+					if posting.Amount.IsNegative() {
+						fee = fee.Neg()
+					}
+					line.Amount = posting.Amount.Sub(fee)
+				}
+			}
+		}
+
+		addLine(line)
+		if !fee.IsZero() {
+			description := "Fee: " + line.Description
+			if fee.IsPositive() {
+				description = "Fee returned: " + line.Description
+			}
+			addLine(StatementLine{
+				Reference:     posting.ID.String() + "-fee",
+				Date:          line.Date,
+				Kind:          StatementLineFee,
+				TransactionID: line.TransactionID,
+				Description:   description,
+				Amount:        fee,
+			})
+		}
+	}
+	statement.ClosingBalance = balance
+
+	return statement, nil
+}
+
+// statementCounterparty returns the username on the other side of a
+// transaction from the member
+func statementCounterparty(transaction *models.Transaction, userID uuid.UUID) string {
+	other := transaction.User
+	if transaction.UserID == userID {
+		other = transaction.ToUser
+	}
+	if other == nil || other.ID == userID {
+		return ""
+	}
+	return other.Username
+}
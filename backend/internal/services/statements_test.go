@@ -0,0 +1,89 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"faircoin/internal/models"
+	"testing"
+	"time"
+)
+
+func TestGetStatementMatchesWallet(t *testing.T) {
+	db := newTestDB(t)
+	alice := newTestUser(t, db, "alice")
+	bob := newTestUser(t, db, "bob")
+
+	wallets := NewWalletService(db)
+	for _, amount := range []models.Money{models.FC(10), models.FC(25)} {
+		if _, err := wallets.Transfer(alice.ID, bob.ID, amount, "groceries"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := wallets.Transfer(bob.ID, alice.ID, models.FC(4), "change"); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	statement, err := NewTransactionService(db).GetStatement(alice.ID, now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statement.ClosingBalance != walletBalance(t, db, alice.ID) {
+		t.Errorf("closing balance %s, wallet holds %s", statement.ClosingBalance, walletBalance(t, db, alice.ID))
+	}
+	if got := statement.OpeningBalance.Add(statement.TotalCredits).Add(statement.TotalDebits); got != statement.ClosingBalance {
+		t.Errorf("opening %s + credits %s + debits %s = %s, want the closing balance %s", statement.OpeningBalance,
+			statement.TotalCredits, statement.TotalDebits, got, statement.ClosingBalance)
+	}
+
+	var payments, fees int
+	for _, line := range statement.Lines {
+		switch {
+		case line.Kind == StatementLineFee:
+			fees++
+		case line.Counterparty == "bob":
+			payments++
+		}
+	}
+	if payments != 3 {
+		t.Errorf("%d lines with bob, want 3", payments)
+	}
+	if fees != 2 {
+		t.Errorf("%d fee lines, want one for each payment alice made", fees)
+	}
+}
+
+func TestWriteCSVEscapesFormulas(t *testing.T) {
+	statement := &Statement{
+		OpeningBalance: models.FC(10),
+		ClosingBalance: models.FC(5),
+		Lines: []StatementLine{
+			{Description: "=HYPERLINK(\"http://example.com\")", Counterparty: "@mallory", Amount: models.FC(-3), Balance: models.FC(7)},
+			{Description: "+1", Counterparty: "-bob", Amount: models.FC(-2), Balance: models.FC(5)},
+			{Description: "\tindented", Counterparty: "\rcarol", Amount: models.FC(1), Balance: models.FC(6)},
+			{Description: "Rent 3-4", Counterparty: "dave", Amount: models.FC(-1), Balance: models.FC(5)},
+		},
+	}
+	var buf bytes.Buffer
+	if err := statement.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Header and opening balance come first
+	want := [][]string{
+		{"'=HYPERLINK(\"http://example.com\")", "'@mallory", "-3", "7"},
+		{"'+1", "'-bob", "-2", "5"},
+		{"'\tindented", "'\rcarol", "1", "6"},
+		{"Rent 3-4", "dave", "-1", "5"},
+	}
+	for i, w := range want {
+		row := rows[i+2]
+		if got := row[4:8]; got[0] != w[0] || got[1] != w[1] || got[2] != w[2] || got[3] != w[3] {
+			t.Errorf("line %d = %q, want %q", i, got, w)
+		}
+	}
+}
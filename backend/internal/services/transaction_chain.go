@@ -0,0 +1,496 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"faircoin/internal/models"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// chainVerifyBatch is how many transactions VerifyChain reads at a time
+	chainVerifyBatch = 1000
+	// maxChainBreaks caps the breaks listed in a chain report
+	maxChainBreaks = 100
+)
+
+// Kinds of chain break
+const (
+	ChainBreakContent    = "content_changed"     // The stored hash does not match the content
+	ChainBreakLink       = "link_broken"         // PrevHash is not the previous transaction's hash
+	ChainBreakGap        = "missing"             // Sequence numbers are missing, i.e. rows were deleted
+	ChainBreakUnsealed   = "unsealed"            // A row was inserted without joining the chain
+	ChainBreakCheckpoint = "checkpoint_mismatch" // The chain no longer matches a signed checkpoint
+	ChainBreakStatus     = "status_mismatch"     // A transaction's status is not the one its history records
+)
+
+// The chains a break can be in
+const (
+	ChainTransactions  = "transactions"
+	ChainStatusHistory = "status_history"
+)
+
+// TransactionChainService verifies the tamper-evident transaction chain and
+// keeps signed checkpoints of its head. Transactions join the chain when
+// they are created (see models.Transaction).
+type TransactionChainService struct {
+	db          *gorm.DB
+	signingKey  ed25519.PrivateKey
+	trustedKeys map[string]bool // Earlier signing keys whose checkpoints still count
+}
+
+// NewTransactionChainService creates a new transaction chain service
+func NewTransactionChainService(db *gorm.DB) *TransactionChainService {
+	return &TransactionChainService{db: db}
+}
+
+// SetSigningSecret derives the checkpoint signing key. The key is
+// Ed25519 so auditors can check exported checkpoints with the public key
+// alone.
+func (s *TransactionChainService) SetSigningSecret(secret string) {
+	s.signingKey = ed25519.NewKeyFromSeed(deriveKey(secret, "faircoin chain checkpoint signing key"))
+}
+
+// SetTrustedKeys sets the comma-separated hex public keys, besides the
+// current one, whose checkpoints are accepted, e.g. keys from before
+// JWT_SECRET was rotated
+func (s *TransactionChainService) SetTrustedKeys(keys string) error {
+	trusted := map[string]bool{}
+	for _, key := range strings.Split(keys, ",") {
+		key = strings.ToLower(strings.TrimSpace(key))
+		if key == "" {
+			continue
+		}
+		if decoded, err := hex.DecodeString(key); err != nil || len(decoded) != ed25519.PublicKeySize {
+			return fmt.Errorf("invalid checkpoint public key %q", key)
+		}
+		trusted[key] = true
+	}
+	s.trustedKeys = trusted
+	return nil
+}
+
+// PublicKey returns the hex checkpoint verification key
+func (s *TransactionChainService) PublicKey() string {
+	if s.signingKey == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.signingKey.Public().(ed25519.PublicKey))
+}
+
+// SealLegacyTransactions brings transactions that predate the chain into
+// it, oldest first. It only runs while the chain is empty: once it has
+// started, a row without a place in it was inserted behind the
+// application's back and is reported by VerifyChain instead.
+func (s *TransactionChainService) SealLegacyTransactions() (int, error) {
+	var sealed int
+	if err := s.db.Model(&models.Transaction{}).Where("chain_seq IS NOT NULL").Count(&sealed).Error; err != nil {
+		return 0, fmt.Errorf("failed to check transaction chain: %w", err)
+	}
+	if sealed > 0 {
+		return 0, nil
+	}
+
+	var transactions []models.Transaction
+	if err := s.db.Order("created_at ASC, id ASC").Find(&transactions).Error; err != nil {
+		return 0, fmt.Errorf("failed to get transactions: %w", err)
+	}
+	if len(transactions) == 0 {
+		return 0, nil
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return 0, tx.Error
+	}
+	prevHash := ""
+	for i := range transactions {
+		transaction := &transactions[i]
+		seq := int64(i + 1)
+		transaction.ChainSeq = &seq
+		transaction.PrevHash = prevHash
+		transaction.Hash = transaction.ChainHash()
+		if err := tx.Model(&models.Transaction{}).Where("id = ?", transaction.ID).
+			Updates(map[string]interface{}{"chain_seq": seq, "prev_hash": prevHash, "hash": transaction.Hash}).Error; err != nil {
+			tx.Rollback()
+			return 0, fmt.Errorf("failed to seal transaction %s: %w", transaction.ID, err)
+		}
+		prevHash = transaction.Hash
+	}
+	if err := tx.Commit().Error; err != nil {
+		return 0, fmt.Errorf("failed to commit transaction chain: %w", err)
+	}
+	return len(transactions), nil
+}
+
+// SealStatusHistory brings status changes that predate the status chain into
+// it, oldest first, and records the current status of transactions that
+// have no status history yet. Like SealLegacyTransactions, the sealing only
+// runs while the status chain is empty.
+func (s *TransactionChainService) SealStatusHistory() (int, error) {
+	var sealed int
+	if err := s.db.Model(&models.TransactionStatusChange{}).Where("chain_seq IS NOT NULL").Count(&sealed).Error; err != nil {
+		return 0, fmt.Errorf("failed to check status chain: %w", err)
+	}
+
+	var count int
+	if sealed == 0 {
+		var changes []models.TransactionStatusChange
+		if err := s.db.Order("created_at ASC, id ASC").Find(&changes).Error; err != nil {
+			return 0, fmt.Errorf("failed to get status changes: %w", err)
+		}
+		tx := s.db.Begin()
+		if tx.Error != nil {
+			return 0, tx.Error
+		}
+		prevHash := ""
+		for i := range changes {
+			change := &changes[i]
+			seq := int64(i + 1)
+			change.ChainSeq = &seq
+			change.PrevHash = prevHash
+			change.Hash = change.ChainHash()
+			if err := tx.Model(&models.TransactionStatusChange{}).Where("id = ?", change.ID).
+				Updates(map[string]interface{}{"chain_seq": seq, "prev_hash": prevHash, "hash": change.Hash}).Error; err != nil {
+				tx.Rollback()
+				return 0, fmt.Errorf("failed to seal status change %s: %w", change.ID, err)
+			}
+			prevHash = change.Hash
+		}
+		if err := tx.Commit().Error; err != nil {
+			return 0, fmt.Errorf("failed to commit status chain: %w", err)
+		}
+		count = len(changes)
+	}
+
+	// Transactions from before the status history start it with their
+	// current status; creating the change appends it to the chain
+	recorded := s.db.Table("transaction_status_changes").Select("transaction_id").QueryExpr()
+	var transactions []models.Transaction
+	if err := s.db.Where("id NOT IN (?)", recorded).Order("created_at ASC, id ASC").
+		Find(&transactions).Error; err != nil {
+		return count, fmt.Errorf("failed to find transactions without status history: %w", err)
+	}
+	for _, transaction := range transactions {
+		changedAt := transaction.StatusChangedAt
+		if changedAt.IsZero() {
+			changedAt = transaction.CreatedAt
+		}
+		if err := s.db.Create(&models.TransactionStatusChange{
+			TransactionID: transaction.ID,
+			To:            transaction.Status,
+			Reason:        transaction.FailureReason,
+			CreatedAt:     changedAt,
+		}).Error; err != nil {
+			return count, fmt.Errorf("failed to record status of transaction %s: %w", transaction.ID, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// ChainBreak is one place where the transaction or status chain does not hold
+type ChainBreak struct {
+	Chain         string `json:"chain"`
+	Kind          string `json:"kind"`
+	ChainSeq      int64  `json:"chain_seq,omitempty"`
+	TransactionID string `json:"transaction_id,omitempty"`
+	StatusChange  string `json:"status_change_id,omitempty"`
+	Detail        string `json:"detail"`
+}
+
+// ChainReport is the result of walking the transaction chain
+type ChainReport struct {
+	Valid               bool         `json:"valid"`
+	Transactions        int64        `json:"transactions"` // Rows in the chain
+	Unsealed            int          `json:"unsealed"`     // Rows outside it
+	HeadSeq             int64        `json:"head_seq"`
+	HeadHash            string       `json:"head_hash"`
+	StatusChanges       int64        `json:"status_changes"` // Rows in the status chain
+	StatusHeadSeq       int64        `json:"status_head_seq"`
+	StatusHeadHash      string       `json:"status_head_hash"`
+	CheckpointsVerified int          `json:"checkpoints_verified"`
+	Breaks              []ChainBreak `json:"breaks"` // The first maxChainBreaks
+	BreakCount          int          `json:"break_count"`
+	CheckedAt           time.Time    `json:"checked_at"`
+}
+
+func (r *ChainReport) addBreak(b ChainBreak) {
+	r.BreakCount++
+	if len(r.Breaks) < maxChainBreaks {
+		r.Breaks = append(r.Breaks, b)
+	}
+}
+
+// VerifyChain walks the transaction and status chains in order, recomputing
+// every hash and link, reports rows that are not in them, checks each
+// transaction's status against its last recorded change, and checks the
+// chains still match each signed checkpoint
+func (s *TransactionChainService) VerifyChain() (*ChainReport, error) {
+	report := &ChainReport{Breaks: []ChainBreak{}, CheckedAt: time.Now()}
+
+	// Hashes at checkpointed positions, to compare against the checkpoints
+	var checkpoints []models.ChainCheckpoint
+	if err := s.db.Order("chain_seq ASC").Find(&checkpoints).Error; err != nil {
+		return nil, fmt.Errorf("failed to get checkpoints: %w", err)
+	}
+	checkpointed := make(map[int64]string, len(checkpoints))
+	statusCheckpointed := make(map[int64]string, len(checkpoints))
+	for _, checkpoint := range checkpoints {
+		checkpointed[checkpoint.ChainSeq] = ""
+		if checkpoint.StatusChainSeq > 0 {
+			statusCheckpointed[checkpoint.StatusChainSeq] = ""
+		}
+	}
+
+	// Status each transaction's history last moved it to
+	lastStatus, err := s.verifyStatusChain(report, statusCheckpointed)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastSeq int64
+	prevHash := ""
+	for {
+		var batch []models.Transaction
+		if err := s.db.Where("chain_seq > ?", lastSeq).Order("chain_seq ASC").Limit(chainVerifyBatch).
+			Find(&batch).Error; err != nil {
+			return nil, fmt.Errorf("failed to read transaction chain: %w", err)
+		}
+		for i := range batch {
+			transaction := &batch[i]
+			seq := *transaction.ChainSeq
+			if seq != lastSeq+1 {
+				report.addBreak(ChainBreak{Chain: ChainTransactions, Kind: ChainBreakGap, ChainSeq: lastSeq + 1,
+					Detail: fmt.Sprintf("transactions %d to %d are missing", lastSeq+1, seq-1)})
+			}
+			if transaction.PrevHash != prevHash {
+				report.addBreak(ChainBreak{Chain: ChainTransactions, Kind: ChainBreakLink, ChainSeq: seq,
+					TransactionID: transaction.ID.String(), Detail: "previous hash does not match the transaction before it"})
+			}
+			if transaction.ChainHash() != transaction.Hash {
+				report.addBreak(ChainBreak{Chain: ChainTransactions, Kind: ChainBreakContent, ChainSeq: seq,
+					TransactionID: transaction.ID.String(), Detail: "content does not match its hash"})
+			}
+			if recorded, ok := lastStatus[transaction.ID.String()]; !ok {
+				report.addBreak(ChainBreak{Chain: ChainStatusHistory, Kind: ChainBreakStatus, ChainSeq: seq,
+					TransactionID: transaction.ID.String(), Detail: "transaction has no status history"})
+			} else if recorded != transaction.Status {
+				detail := fmt.Sprintf("status is %s but its history ends at %s", transaction.Status, recorded)
+				report.addBreak(ChainBreak{Chain: ChainStatusHistory, Kind: ChainBreakStatus, ChainSeq: seq,
+					TransactionID: transaction.ID.String(), Detail: detail})
+			}
+			if _, ok := checkpointed[seq]; ok {
+				checkpointed[seq] = transaction.Hash
+			}
+
+			report.Transactions++
+			lastSeq = seq
+			prevHash = transaction.Hash
+		}
+		if len(batch) < chainVerifyBatch {
+			break
+		}
+	}
+	report.HeadSeq, report.HeadHash = lastSeq, prevHash
+
+	// Read IDs as text: a hand-inserted row need not have a valid one
+	var unsealed []string
+	if err := s.db.Model(&models.Transaction{}).Where("chain_seq IS NULL").Pluck("id", &unsealed).Error; err != nil {
+		return nil, fmt.Errorf("failed to find unsealed transactions: %w", err)
+	}
+	report.Unsealed = len(unsealed)
+	for _, id := range unsealed {
+		report.addBreak(ChainBreak{Chain: ChainTransactions, Kind: ChainBreakUnsealed, TransactionID: id,
+			Detail: "transaction is not in the chain"})
+	}
+
+	for _, checkpoint := range checkpoints {
+		detail := ""
+		switch {
+		case !s.checkpointKeyTrusted(&checkpoint):
+			detail = "signed with an untrusted key"
+		case !s.checkpointSignatureValid(&checkpoint):
+			detail = "signature does not verify"
+		case checkpointed[checkpoint.ChainSeq] != checkpoint.Hash:
+			detail = fmt.Sprintf("chain no longer matches the checkpoint of %s", checkpoint.CreatedAt.UTC().Format(time.RFC3339))
+		case checkpoint.StatusChainSeq > 0 && statusCheckpointed[checkpoint.StatusChainSeq] != checkpoint.StatusHash:
+			detail = fmt.Sprintf("status chain no longer matches the checkpoint of %s", checkpoint.CreatedAt.UTC().Format(time.RFC3339))
+		}
+		if detail != "" {
+			report.addBreak(ChainBreak{Chain: ChainTransactions, Kind: ChainBreakCheckpoint, ChainSeq: checkpoint.ChainSeq,
+				Detail: detail})
+			continue
+		}
+		report.CheckpointsVerified++
+	}
+
+	report.Valid = report.BreakCount == 0
+	return report, nil
+}
+
+// verifyStatusChain walks the status chain like VerifyChain walks the
+// transaction chain, filling in the hashes at checkpointed positions. It
+// returns the status each transaction's last change moved it to.
+func (s *TransactionChainService) verifyStatusChain(report *ChainReport, checkpointed map[int64]string) (map[string]models.TransactionStatus, error) {
+	lastStatus := make(map[string]models.TransactionStatus)
+	var lastSeq int64
+	prevHash := ""
+	for {
+		var batch []models.TransactionStatusChange
+		if err := s.db.Where("chain_seq > ?", lastSeq).Order("chain_seq ASC").Limit(chainVerifyBatch).
+			Find(&batch).Error; err != nil {
+			return nil, fmt.Errorf("failed to read status chain: %w", err)
+		}
+		for i := range batch {
+			change := &batch[i]
+			seq := *change.ChainSeq
+			if seq != lastSeq+1 {
+				report.addBreak(ChainBreak{Chain: ChainStatusHistory, Kind: ChainBreakGap, ChainSeq: lastSeq + 1,
+					Detail: fmt.Sprintf("status changes %d to %d are missing", lastSeq+1, seq-1)})
+			}
+			if change.PrevHash != prevHash {
+				report.addBreak(ChainBreak{Chain: ChainStatusHistory, Kind: ChainBreakLink, ChainSeq: seq,
+					TransactionID: change.TransactionID.String(), StatusChange: change.ID.String(),
+					Detail: "previous hash does not match the status change before it"})
+			}
+			if change.ChainHash() != change.Hash {
+				report.addBreak(ChainBreak{Chain: ChainStatusHistory, Kind: ChainBreakContent, ChainSeq: seq,
+					TransactionID: change.TransactionID.String(), StatusChange: change.ID.String(),
+					Detail: "content does not match its hash"})
+			}
+			if _, ok := checkpointed[seq]; ok {
+				checkpointed[seq] = change.Hash
+			}
+			lastStatus[change.TransactionID.String()] = change.To
+
+			report.StatusChanges++
+			lastSeq = seq
+			prevHash = change.Hash
+		}
+		if len(batch) < chainVerifyBatch {
+			break
+		}
+	}
+	report.StatusHeadSeq, report.StatusHeadHash = lastSeq, prevHash
+
+	var unsealed []string
+	if err := s.db.Model(&models.TransactionStatusChange{}).Where("chain_seq IS NULL").Pluck("id", &unsealed).Error; err != nil {
+		return nil, fmt.Errorf("failed to find unsealed status changes: %w", err)
+	}
+	for _, id := range unsealed {
+		report.addBreak(ChainBreak{Chain: ChainStatusHistory, Kind: ChainBreakUnsealed, StatusChange: id,
+			Detail: "status change is not in the chain"})
+	}
+	return lastStatus, nil
+}
+
+// CreateCheckpoint signs the current heads of the transaction and status
+// chains. It returns nil when the transaction chain is empty or neither
+// chain has grown since the last checkpoint.
+func (s *TransactionChainService) CreateCheckpoint() (*models.ChainCheckpoint, error) {
+	if s.signingKey == nil {
+		return nil, fmt.Errorf("checkpoint signing is not configured")
+	}
+
+	var head models.Transaction
+	if err := s.db.Where("chain_seq IS NOT NULL").Order("chain_seq DESC").First(&head).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read transaction chain head: %w", err)
+	}
+
+	var statusHead models.TransactionStatusChange
+	if err := s.db.Where("chain_seq IS NOT NULL").Order("chain_seq DESC").First(&statusHead).Error; err != nil &&
+		!gorm.IsRecordNotFoundError(err) {
+		return nil, fmt.Errorf("failed to read status chain head: %w", err)
+	}
+	var statusSeq int64
+	if statusHead.ChainSeq != nil {
+		statusSeq = *statusHead.ChainSeq
+	}
+
+	var last models.ChainCheckpoint
+	if err := s.db.Order("created_at DESC").First(&last).Error; err == nil &&
+		last.ChainSeq >= *head.ChainSeq && last.StatusChainSeq >= statusSeq {
+		return nil, nil
+	} else if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return nil, fmt.Errorf("failed to get last checkpoint: %w", err)
+	}
+
+	var count int64
+	if err := s.db.Model(&models.Transaction{}).Where("chain_seq IS NOT NULL").Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to count transactions: %w", err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	checkpoint := &models.ChainCheckpoint{
+		ChainSeq:         *head.ChainSeq,
+		Hash:             head.Hash,
+		TransactionCount: count,
+		StatusChainSeq:   statusSeq,
+		StatusHash:       statusHead.Hash,
+		PublicKey:        s.PublicKey(),
+		Signature: hex.EncodeToString(ed25519.Sign(s.signingKey,
+			models.CheckpointMessage(*head.ChainSeq, head.Hash, statusSeq, statusHead.Hash, now))),
+		CreatedAt: now,
+	}
+	if err := s.db.Create(checkpoint).Error; err != nil {
+		return nil, fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return checkpoint, nil
+}
+
+// checkpointKeyTrusted reports whether a checkpoint names the current
+// signing key or a trusted earlier one. The stored key alone proves nothing:
+// whoever rewrites the chain can re-sign its checkpoints with a key of their
+// own.
+func (s *TransactionChainService) checkpointKeyTrusted(checkpoint *models.ChainCheckpoint) bool {
+	key := strings.ToLower(checkpoint.PublicKey)
+	return (s.signingKey != nil && key == s.PublicKey()) || s.trustedKeys[key]
+}
+
+func (s *TransactionChainService) checkpointSignatureValid(checkpoint *models.ChainCheckpoint) bool {
+	publicKey, err := hex.DecodeString(checkpoint.PublicKey)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return false
+	}
+	signature, err := hex.DecodeString(checkpoint.Signature)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(publicKey, models.CheckpointMessage(checkpoint.ChainSeq, checkpoint.Hash,
+		checkpoint.StatusChainSeq, checkpoint.StatusHash, checkpoint.CreatedAt), signature)
+}
+
+// CheckpointExport is the published form of the checkpoints. Each signature
+// covers models.CheckpointMessage: the chain position, head hash and
+// creation time, then the status chain position and head hash if any, one
+// per line.
+type CheckpointExport struct {
+	Algorithm   string                   `json:"algorithm"`
+	PublicKey   string                   `json:"public_key"` // Current signing key
+	Message     string                   `json:"message"`    // How the signed message is built
+	Checkpoints []models.ChainCheckpoint `json:"checkpoints"`
+	ExportedAt  time.Time                `json:"exported_at"`
+}
+
+// ExportCheckpoints returns every checkpoint, oldest first
+func (s *TransactionChainService) ExportCheckpoints() (*CheckpointExport, error) {
+	export := &CheckpointExport{
+		Algorithm:   "ed25519",
+		PublicKey:   s.PublicKey(),
+		Message:     "\"faircoin transaction chain checkpoint\\n\" + chain_seq + \"\\n\" + hash + \"\\n\" + created_at (RFC 3339 in UTC, at most microseconds), followed by \"\\n\" + status_chain_seq + \"\\n\" + status_hash when status_chain_seq is not 0",
+		Checkpoints: []models.ChainCheckpoint{},
+		ExportedAt:  time.Now(),
+	}
+	if err := s.db.Order("chain_seq ASC").Find(&export.Checkpoints).Error; err != nil {
+		return nil, fmt.Errorf("failed to get checkpoints: %w", err)
+	}
+	return export, nil
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"faircoin/internal/models"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+)
+
+// newTestChain records a few transfers and signs a checkpoint over them
+func newTestChain(t *testing.T) (*gorm.DB, *TransactionChainService, []uuid.UUID) {
+	t.Helper()
+	db := newTestDB(t)
+	alice := newTestUser(t, db, "alice")
+	bob := newTestUser(t, db, "bob")
+
+	wallets := NewWalletService(db)
+	var ids []uuid.UUID
+	for _, amount := range []models.Money{models.FC(1), models.FC(2), models.FC(3)} {
+		transaction, err := wallets.Transfer(alice.ID, bob.ID, amount, "test")
+		if err != nil {
+			t.Fatalf("transfer %s: %v", amount, err)
+		}
+		ids = append(ids, transaction.ID)
+	}
+
+	chain := NewTransactionChainService(db)
+	chain.SetSigningSecret("test secret")
+	if _, err := chain.CreateCheckpoint(); err != nil {
+		t.Fatalf("checkpoint: %v", err)
+	}
+	return db, chain, ids
+}
+
+func TestVerifyChainValid(t *testing.T) {
+	db, chain, ids := newTestChain(t)
+
+	// A refund moves the status on and extends the status chain
+	recipientID := *mustTransaction(t, db, ids[0]).ToUserID
+	if _, err := NewWalletService(db).Refund(ids[0], recipientID, 0, ""); err != nil {
+		t.Fatalf("refund: %v", err)
+	}
+
+	report, err := chain.VerifyChain()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Valid {
+		t.Fatalf("chain is invalid: %+v", report.Breaks)
+	}
+	if report.Transactions != 4 || report.StatusChanges != 5 {
+		t.Errorf("chain has %d transactions and %d status changes, want 4 and 5",
+			report.Transactions, report.StatusChanges)
+	}
+	if report.CheckpointsVerified != 1 {
+		t.Errorf("%d checkpoints verified, want 1", report.CheckpointsVerified)
+	}
+}
+
+func TestVerifyChainDetectsTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(db *gorm.DB, ids []uuid.UUID)
+		want   string
+	}{
+		{"amount edited", func(db *gorm.DB, ids []uuid.UUID) {
+			db.Exec("UPDATE transactions SET amount = amount + 1 WHERE id = ?", ids[1])
+		}, ChainBreakContent},
+		{"status edited", func(db *gorm.DB, ids []uuid.UUID) {
+			db.Exec("UPDATE transactions SET status = ? WHERE id = ?", models.TransactionStatusReversed, ids[1])
+		}, ChainBreakStatus},
+		{"transaction deleted", func(db *gorm.DB, ids []uuid.UUID) {
+			db.Exec("DELETE FROM transactions WHERE id = ?", ids[1])
+		}, ChainBreakGap},
+		{"transaction inserted", func(db *gorm.DB, ids []uuid.UUID) {
+			db.Exec("INSERT INTO transactions (id, user_id, type, amount, status) VALUES (?, ?, ?, ?, ?)",
+				uuid.New(), uuid.New(), models.TransactionTypeTransfer, models.FC(100), models.TransactionStatusCompleted)
+		}, ChainBreakUnsealed},
+		{"hash rewritten", func(db *gorm.DB, ids []uuid.UUID) {
+			db.Exec("UPDATE transactions SET hash = prev_hash WHERE id = ?", ids[2])
+		}, ChainBreakCheckpoint},
+		{"link rewritten", func(db *gorm.DB, ids []uuid.UUID) {
+			db.Exec("UPDATE transactions SET prev_hash = hash WHERE id = ?", ids[1])
+		}, ChainBreakLink},
+		{"status history deleted", func(db *gorm.DB, ids []uuid.UUID) {
+			db.Exec("DELETE FROM transaction_status_changes WHERE transaction_id = ?", ids[0])
+		}, ChainBreakStatus},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, chain, ids := newTestChain(t)
+			tt.tamper(db, ids)
+
+			report, err := chain.VerifyChain()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Valid {
+				t.Fatal("tampered chain verified")
+			}
+			for _, b := range report.Breaks {
+				if b.Kind == tt.want {
+					return
+				}
+			}
+			t.Errorf("no %s break in %+v", tt.want, report.Breaks)
+		})
+	}
+}
+
+func TestVerifyChainRejectsForeignCheckpointKey(t *testing.T) {
+	db, chain, _ := newTestChain(t)
+
+	// Re-sign the checkpoint with a key of the forger's own, as whoever
+	// rewrites the chain could
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var checkpoint models.ChainCheckpoint
+	if err := db.First(&checkpoint).Error; err != nil {
+		t.Fatal(err)
+	}
+	signature := ed25519.Sign(privateKey, models.CheckpointMessage(checkpoint.ChainSeq, checkpoint.Hash,
+		checkpoint.StatusChainSeq, checkpoint.StatusHash, checkpoint.CreatedAt))
+	db.Model(&checkpoint).Updates(map[string]interface{}{
+		"public_key": hex.EncodeToString(publicKey),
+		"signature":  hex.EncodeToString(signature),
+	})
+
+	report, err := chain.VerifyChain()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Valid || report.CheckpointsVerified != 0 {
+		t.Fatalf("checkpoint signed with a foreign key verified: %+v", report)
+	}
+	if len(report.Breaks) != 1 || report.Breaks[0].Kind != ChainBreakCheckpoint {
+		t.Errorf("breaks = %+v, want one %s", report.Breaks, ChainBreakCheckpoint)
+	}
+
+	// Once the key is trusted, e.g. after a rotation, the checkpoint counts
+	if err := chain.SetTrustedKeys(hex.EncodeToString(publicKey)); err != nil {
+		t.Fatal(err)
+	}
+	if report, err = chain.VerifyChain(); err != nil {
+		t.Fatal(err)
+	}
+	if !report.Valid || report.CheckpointsVerified != 1 {
+		t.Errorf("checkpoint signed with a trusted key did not verify: %+v", report)
+	}
+}
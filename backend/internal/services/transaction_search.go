@@ -0,0 +1,136 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"faircoin/internal/models"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidCursor is returned for a pagination cursor that was not issued
+// by SearchTransactions
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// TransactionFilter narrows a transaction search. Zero values match anything.
+type TransactionFilter struct {
+	UserID       *uuid.UUID // Only transactions the user sent or received; nil for everyone's
+	Type         models.TransactionType
+	Status       string
+	Counterparty string // Username of the other side, or of either side without UserID
+	MinAmount    *models.Money
+	MaxAmount    *models.Money
+	From         *time.Time // Inclusive
+	To           *time.Time // Exclusive
+	Query        string     // Matches the description
+	Cursor       string     // NextCursor of the previous page
+	Offset       int        // Only used without a cursor
+	Limit        int
+}
+
+// TransactionPage is one page of a transaction search, newest first
+type TransactionPage struct {
+	Transactions []models.Transaction `json:"transactions"`
+	NextCursor   string               `json:"next_cursor,omitempty"` // Empty on the last page
+}
+
+// Pages are ordered by (created_at, id), so backdated transactions sort by
+// when they happened and the ID breaks ties. A cursor names the last
+// transaction of a page, and the next page starts after that row's stored
+// created_at and ID, however many transactions are added meanwhile. The
+// timestamp is read back from the row rather than carried in the cursor, as
+// not every database stores times in a form that round-trips exactly.
+
+func encodeTransactionCursor(t *models.Transaction) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(t.ID.String()))
+}
+
+func decodeTransactionCursor(cursor string) (uuid.UUID, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return uuid.Nil, ErrInvalidCursor
+	}
+	id, err := uuid.Parse(string(decoded))
+	if err != nil {
+		return uuid.Nil, ErrInvalidCursor
+	}
+	return id, nil
+}
+
+// SearchTransactions returns a page of transactions matching the filter,
+// newest first, with the users on both sides
+func (s *TransactionService) SearchTransactions(filter TransactionFilter) (*TransactionPage, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = 20
+	}
+
+	query := s.db.Model(&models.Transaction{})
+	if filter.UserID != nil {
+		query = query.Where("user_id = ? OR to_user_id = ?", *filter.UserID, *filter.UserID)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Counterparty != "" {
+		counterparty := s.db.Table("users").Select("id").Where("username = ?", filter.Counterparty).QueryExpr()
+		if filter.UserID != nil {
+			query = query.Where("(user_id = ? AND to_user_id IN (?)) OR (to_user_id = ? AND user_id IN (?))",
+				*filter.UserID, counterparty, *filter.UserID, counterparty)
+		} else {
+			query = query.Where("user_id IN (?) OR to_user_id IN (?)", counterparty, counterparty)
+		}
+	}
+	if filter.MinAmount != nil {
+		query = query.Where("amount >= ?", *filter.MinAmount)
+	}
+	if filter.MaxAmount != nil {
+		query = query.Where("amount <= ?", *filter.MaxAmount)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	if filter.Query != "" {
+		query = query.Where("description LIKE ?", "%"+filter.Query+"%")
+	}
+
+	if filter.Cursor != "" {
+		id, err := decodeTransactionCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		var last int
+		if err := s.db.Model(&models.Transaction{}).Where("id = ?", id).Count(&last).Error; err != nil {
+			return nil, fmt.Errorf("failed to read cursor: %w", err)
+		}
+		if last == 0 {
+			return nil, ErrInvalidCursor
+		}
+		lastCreatedAt := s.db.Model(&models.Transaction{}).Select("created_at").Where("id = ?", id).QueryExpr()
+		query = query.Where("created_at < (?) OR (created_at = (?) AND id < ?)", lastCreatedAt, lastCreatedAt, id)
+	} else if filter.Offset > 0 {
+		query = query.Offset(filter.Offset)
+	}
+
+	// One extra row tells whether there is another page
+	var transactions []models.Transaction
+	if err := query.Preload("User").Preload("ToUser").
+		Order("created_at DESC").Order("id DESC").Limit(filter.Limit + 1).
+		Find(&transactions).Error; err != nil {
+		return nil, fmt.Errorf("failed to search transactions: %w", err)
+	}
+
+	page := &TransactionPage{Transactions: transactions}
+	if len(transactions) > filter.Limit {
+		page.Transactions = transactions[:filter.Limit]
+		page.NextCursor = encodeTransactionCursor(&page.Transactions[filter.Limit-1])
+	}
+	return page, nil
+}
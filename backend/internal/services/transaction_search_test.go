@@ -0,0 +1,84 @@
+package services
+
+import (
+	"errors"
+	"faircoin/internal/models"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSearchTransactionsCursorPaging(t *testing.T) {
+	db := newTestDB(t)
+	alice := newTestUser(t, db, "alice")
+	bob := newTestUser(t, db, "bob")
+	carol := newTestUser(t, db, "carol")
+	wallets := NewWalletService(db)
+
+	// Two transfers share a timestamp and one is backdated, so chain
+	// position, time and ID order all differ
+	base := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	createdAt := []time.Time{base, base.Add(time.Minute), base.Add(time.Minute), base.Add(-24 * time.Hour), base.Add(2 * time.Minute)}
+	var transfers []models.Transaction
+	for _, at := range createdAt {
+		transfer, err := wallets.Transfer(alice.ID, bob.ID, models.FC(1), "rent")
+		if err != nil {
+			t.Fatal(err)
+		}
+		db.Model(&models.Transaction{}).Where("id = ?", transfer.ID).Update("created_at", at)
+		transfer.CreatedAt = at
+		transfers = append(transfers, *transfer)
+	}
+	sort.Slice(transfers, func(i, j int) bool {
+		if !transfers[i].CreatedAt.Equal(transfers[j].CreatedAt) {
+			return transfers[i].CreatedAt.After(transfers[j].CreatedAt)
+		}
+		return transfers[i].ID.String() > transfers[j].ID.String()
+	})
+
+	search := NewTransactionService(db)
+	filter := TransactionFilter{UserID: &alice.ID, Counterparty: "bob", Limit: 2}
+	var seen []uuid.UUID
+	for pages := 0; ; pages++ {
+		if pages > len(transfers) {
+			t.Fatal("paging did not end")
+		}
+		page, err := search.SearchTransactions(filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, transaction := range page.Transactions {
+			seen = append(seen, transaction.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		filter.Cursor = page.NextCursor
+
+		// New activity between pages must not shift the next one
+		if _, err := wallets.Transfer(alice.ID, bob.ID, models.FC(1), "rent"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := wallets.Transfer(alice.ID, carol.ID, models.FC(1), "rent"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(seen) != len(transfers) {
+		t.Fatalf("paged through %d transactions, want %d", len(seen), len(transfers))
+	}
+	for i, transfer := range transfers {
+		if seen[i] != transfer.ID {
+			t.Errorf("position %d is %s, want %s", i, seen[i], transfer.ID)
+		}
+	}
+
+	for _, cursor := range []string{"not a cursor", encodeTransactionCursor(&models.Transaction{ID: uuid.New()})} {
+		filter.Cursor = cursor
+		if _, err := search.SearchTransactions(filter); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("cursor %q: err = %v", cursor, err)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"errors"
+	"faircoin/internal/models"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	// ErrInvalidTransition is returned for a status change the transaction
+	// state machine does not allow (see models.TransactionStatus)
+	ErrInvalidTransition = errors.New("invalid transaction status change")
+	// ErrTransactionChanged is returned when another request changed a
+	// transaction's status first
+	ErrTransactionChanged = errors.New("transaction was changed concurrently, please retry")
+)
+
+// setTransactionStatus moves a transaction to next inside tx and records the
+// change in its status history. The caller posts the balance effects of the
+// transition in the same tx. The update is guarded on the status read into
+// transaction, so of two concurrent changes only the first succeeds. Reason
+// becomes the failure reason of a failed or cancelled transaction; a nil
+// actorID means the system made the change.
+func setTransactionStatus(tx *gorm.DB, transaction *models.Transaction, next models.TransactionStatus,
+	reason string, actorID *uuid.UUID) error {
+	if !transaction.Status.CanTransitionTo(next) {
+		return fmt.Errorf("%w: a %s transaction cannot become %s", ErrInvalidTransition, transaction.Status, next)
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{"status": next, "status_changed_at": now}
+	if next == models.TransactionStatusFailed || next == models.TransactionStatusCancelled {
+		updates["failure_reason"] = reason
+	}
+	result := tx.Model(&models.Transaction{}).
+		Where("id = ? AND status = ?", transaction.ID, transaction.Status).
+		Updates(updates)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update transaction: %w", result.Error)
+	}
+	if result.RowsAffected != 1 {
+		return ErrTransactionChanged
+	}
+
+	change := &models.TransactionStatusChange{
+		TransactionID: transaction.ID,
+		From:          transaction.Status,
+		To:            next,
+		Reason:        reason,
+		ActorID:       actorID,
+		CreatedAt:     now,
+	}
+	if err := tx.Create(change).Error; err != nil {
+		return fmt.Errorf("failed to record status change: %w", err)
+	}
+
+	transaction.Status = next
+	transaction.StatusChangedAt = now
+	if reason, ok := updates["failure_reason"]; ok {
+		transaction.FailureReason = reason.(string)
+	}
+	return nil
+}
+
+// declinedTransfer reports whether a transfer was refused for a reason
+// worth recording as a failed transaction, rather than rejected as invalid
+func declinedTransfer(err error) bool {
+	return errors.Is(err, ErrInsufficientBalance) || errors.Is(err, ErrSpendingLimitExceeded) ||
+		errors.Is(err, ErrWalletFrozen) || errors.Is(err, ErrHoldingCapExceeded)
+}
+
+// recordFailedTransfer records a declined transfer in tx. No funds move and
+// no fee is charged; the transaction only documents the attempt and its
+// reason.
+func (s *WalletService) recordFailedTransfer(tx *gorm.DB, fromUserID, toUserID uuid.UUID, amount models.Money,
+	description string, reason error) error {
+	transaction := &models.Transaction{
+		UserID:        fromUserID,
+		ToUserID:      &toUserID,
+		Type:          models.TransactionTypeTransfer,
+		Amount:        amount,
+		Description:   description,
+		Status:        models.TransactionStatusFailed,
+		FailureReason: reason.Error(),
+		CreatedAt:     time.Now(),
+	}
+	if err := tx.Create(transaction).Error; err != nil {
+		return fmt.Errorf("failed to record failed transfer: %w", err)
+	}
+	return nil
+}
+
+// GetTransactionStatusHistory returns a transaction's status changes, oldest first
+func (s *TransactionService) GetTransactionStatusHistory(transactionID uuid.UUID) ([]models.TransactionStatusChange, error) {
+	var changes []models.TransactionStatusChange
+	err := s.db.Where("transaction_id = ?", transactionID).Order("created_at ASC").Find(&changes).Error
+	return changes, err
+}
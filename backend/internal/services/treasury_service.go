@@ -0,0 +1,245 @@
+package services
+
+import (
+	"faircoin/internal/models"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+)
+
+// TreasuryService manages the community treasury. The treasury receives
+// transfer fees and the maintenance share of monthly issuance, and can only
+// pay out what a passed treasury spend proposal has authorized.
+type TreasuryService struct {
+	db         *gorm.DB
+	ledger     *LedgerService
+	holdingCap HoldingCap
+}
+
+// NewTreasuryService creates a new treasury service
+func NewTreasuryService(db *gorm.DB) *TreasuryService {
+	return &TreasuryService{db: db, ledger: NewLedgerService(db), holdingCap: DefaultHoldingCap()}
+}
+
+// SetHoldingCap replaces the holding cap enforced on treasury payouts
+func (s *TreasuryService) SetHoldingCap(holdingCap HoldingCap) {
+	s.holdingCap = holdingCap
+}
+
+// GetDB returns the database connection
+func (s *TreasuryService) GetDB() *gorm.DB {
+	return s.db
+}
+
+// GetBalance returns the current treasury balance
+func (s *TreasuryService) GetBalance() (models.Money, error) {
+	treasury, err := s.ledger.SystemAccount(s.db, models.AccountTypeTreasury)
+	if err != nil {
+		return 0, err
+	}
+	return treasury.Balance, nil
+}
+
+// GetTreasuryInfo returns the treasury balance, its recent journal entries
+// and the spend proposals that have passed or been paid out
+func (s *TreasuryService) GetTreasuryInfo(limit int) (map[string]interface{}, error) {
+	treasury, err := s.ledger.SystemAccount(s.db, models.AccountTypeTreasury)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.ledger.GetAccountEntries(treasury.ID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get treasury entries: %w", err)
+	}
+
+	var pending []models.Proposal
+	if err := s.db.Where("type = ? AND status = ? AND executed_at IS NULL",
+		models.ProposalTypeTreasurySpend, models.ProposalStatusPassed).
+		Order("end_time ASC").Find(&pending).Error; err != nil {
+		return nil, fmt.Errorf("failed to get pending spends: %w", err)
+	}
+
+	var executed []models.Proposal
+	if err := s.db.Where("type = ? AND executed_at IS NOT NULL", models.ProposalTypeTreasurySpend).
+		Order("executed_at DESC").Limit(limit).Find(&executed).Error; err != nil {
+		return nil, fmt.Errorf("failed to get executed spends: %w", err)
+	}
+
+	return map[string]interface{}{
+		"account_id":      treasury.ID,
+		"balance":         treasury.Balance,
+		"recent_entries":  entries,
+		"pending_spends":  pending,
+		"executed_spends": executed,
+	}, nil
+}
+
+// ExecuteProposal pays out a passed treasury spend proposal. Each proposal
+// is paid at most once; the payment fails if the treasury cannot cover it,
+// the recipient's wallet is frozen or, unless overflow goes to the treasury,
+// the payout would take the recipient over the holding cap. With treasury
+// overflow only the part up to the cap is paid and the rest stays put.
+func (s *TreasuryService) ExecuteProposal(proposalID uuid.UUID) (*models.Transaction, error) {
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	var proposal models.Proposal
+	if err := tx.First(&proposal, "id = ?", proposalID).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("proposal not found: %w", err)
+	}
+
+	if proposal.Type != models.ProposalTypeTreasurySpend {
+		tx.Rollback()
+		return nil, fmt.Errorf("proposal is not a treasury spend")
+	}
+	if proposal.Status != models.ProposalStatusPassed {
+		tx.Rollback()
+		return nil, fmt.Errorf("proposal has not passed")
+	}
+	if proposal.ExecutedAt != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("proposal has already been executed")
+	}
+	if proposal.TreasuryRecipientID == nil || !proposal.TreasuryAmount.IsPositive() {
+		tx.Rollback()
+		return nil, fmt.Errorf("proposal has no recipient or amount")
+	}
+
+	// Claim the proposal first so a concurrent run cannot pay it twice
+	now := time.Now()
+	result := tx.Model(&models.Proposal{}).
+		Where("id = ? AND executed_at IS NULL", proposal.ID).
+		Update("executed_at", now)
+	if result.Error != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to mark proposal executed: %w", result.Error)
+	}
+	if result.RowsAffected != 1 {
+		tx.Rollback()
+		return nil, fmt.Errorf("proposal has already been executed")
+	}
+
+	treasury, err := s.ledger.SystemAccount(tx, models.AccountTypeTreasury)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if treasury.Balance < proposal.TreasuryAmount {
+		tx.Rollback()
+		return nil, fmt.Errorf("insufficient treasury balance: have %s, need %s", treasury.Balance, proposal.TreasuryAmount)
+	}
+
+	// A payout is received like any other payment
+	var wallet models.Wallet
+	if err := tx.Where("user_id = ?", *proposal.TreasuryRecipientID).First(&wallet).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("recipient wallet not found: %w", err)
+	}
+	if err := checkReceive(&wallet); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	accepted, overflow, limit, err := s.holdingCap.Split(tx, s.ledger, *proposal.TreasuryRecipientID, proposal.TreasuryAmount)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to check holding cap: %w", err)
+	}
+	if overflow.IsPositive() && (s.holdingCap.Overflow == HoldingCapOverflowReject || accepted.IsZero()) {
+		tx.Rollback()
+		return nil, fmt.Errorf("%w: the recipient can receive at most %s FC more (limit %s FC, %.1f%% of circulating supply)",
+			ErrHoldingCapExceeded, accepted, limit, s.holdingCap.Percentage*100)
+	}
+
+	recipient, err := s.ledger.UserAccount(tx, *proposal.TreasuryRecipientID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	description := fmt.Sprintf("Treasury spend: %s", proposal.Title)
+	transaction := &models.Transaction{
+		UserID:      *proposal.TreasuryRecipientID,
+		Type:        models.TransactionTypeTreasurySpend,
+		Amount:      accepted,
+		Description: description,
+		Status:      models.TransactionStatusCompleted,
+		CreatedAt:   now,
+	}
+	if err := tx.Create(transaction).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	if _, err := s.ledger.Move(tx, &transaction.ID, description, treasury, recipient, accepted); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to post treasury spend: %w", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit treasury spend: %w", err)
+	}
+
+	return transaction, nil
+}
+
+// ExecutePassedProposals pays out every passed treasury spend proposal that
+// has not been executed yet. Proposals the treasury cannot cover stay pending
+// and are retried on the next run.
+func (s *TreasuryService) ExecutePassedProposals() error {
+	var proposals []models.Proposal
+	if err := s.db.Where("type = ? AND status = ? AND executed_at IS NULL",
+		models.ProposalTypeTreasurySpend, models.ProposalStatusPassed).
+		Order("end_time ASC").Find(&proposals).Error; err != nil {
+		return err
+	}
+
+	var failed int
+	for _, proposal := range proposals {
+		if _, err := s.ExecuteProposal(proposal.ID); err != nil {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d treasury spends could not be executed", failed, len(proposals))
+	}
+	return nil
+}
+
+// SweepFeeIncome moves any balance left on the legacy fee income account
+// into the treasury. Safe to call on every startup.
+func (s *TreasuryService) SweepFeeIncome() error {
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	feeIncome, err := s.ledger.SystemAccount(tx, models.AccountTypeFeeIncome)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if !feeIncome.Balance.IsPositive() {
+		tx.Rollback()
+		return nil
+	}
+
+	treasury, err := s.ledger.SystemAccount(tx, models.AccountTypeTreasury)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err := s.ledger.Move(tx, nil, "Fee income swept into treasury", feeIncome, treasury, feeIncome.Balance); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
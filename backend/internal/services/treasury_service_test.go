@@ -0,0 +1,86 @@
+package services
+
+import (
+	"errors"
+	"faircoin/internal/models"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+func TestExecuteProposalTreatsPayoutAsIncomingPayment(t *testing.T) {
+	tests := []struct {
+		name          string
+		overflow      HoldingCapOverflow
+		freeze        bool
+		wantErr       error
+		wantRecipient models.Money
+		wantTreasury  models.Money
+	}{
+		{"paid", "", false, nil, models.FC(120), models.FC(30)},
+		{"frozen recipient", "", true, ErrWalletFrozen, models.FC(100), models.FC(50)},
+		{"over the cap", HoldingCapOverflowReject, false, ErrHoldingCapExceeded, models.FC(100), models.FC(50)},
+		{"over the cap with treasury overflow", HoldingCapOverflowTreasury, false, nil, models.FC(105), models.FC(45)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			alice := newTestUser(t, db, "alice")
+			treasury := NewTreasuryService(db)
+			if tt.overflow != "" {
+				// Alice starts at 100 FC and may hold 105
+				holdingCap, err := NewHoldingCap(0.01, 105, string(tt.overflow), "")
+				if err != nil {
+					t.Fatal(err)
+				}
+				treasury.SetHoldingCap(holdingCap)
+			}
+			if tt.freeze {
+				db.Model(&models.Wallet{}).Where("user_id = ?", alice.ID).Update("frozen_at", time.Now())
+			}
+			fundTreasury(t, db, models.FC(50))
+
+			proposal := &models.Proposal{ProposerID: alice.ID, Title: "Garden", Description: "Seeds",
+				Type: models.ProposalTypeTreasurySpend, Status: models.ProposalStatusPassed,
+				TreasuryAmount: models.FC(20), TreasuryRecipientID: &alice.ID}
+			if err := db.Create(proposal).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := treasury.ExecuteProposal(proposal.ID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ExecuteProposal() = %v, want %v", err, tt.wantErr)
+			}
+			if balance := walletBalance(t, db, alice.ID); balance != tt.wantRecipient {
+				t.Errorf("recipient holds %s, want %s", balance, tt.wantRecipient)
+			}
+			if balance, _ := treasury.GetBalance(); balance != tt.wantTreasury {
+				t.Errorf("treasury holds %s, want %s", balance, tt.wantTreasury)
+			}
+
+			// A declined payout stays pending for the next run
+			db.First(proposal, "id = ?", proposal.ID)
+			if executed := proposal.ExecutedAt != nil; executed != (tt.wantErr == nil) {
+				t.Errorf("proposal executed = %v", executed)
+			}
+		})
+	}
+}
+
+// fundTreasury mints amount straight into the treasury
+func fundTreasury(t *testing.T, db *gorm.DB, amount models.Money) {
+	t.Helper()
+	ledger := NewLedgerService(db)
+	issuance, err := ledger.SystemAccount(db, models.AccountTypeIssuance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	treasury, err := ledger.SystemAccount(db, models.AccountTypeTreasury)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ledger.Move(db, nil, "Test funding", issuance, treasury, amount); err != nil {
+		t.Fatal(err)
+	}
+}
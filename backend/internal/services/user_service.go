@@ -1,6 +1,8 @@
 package services
 
 import (
+	"encoding/json"
+	"errors"
 	"faircoin/internal/models"
 	"fmt"
 	"math"
@@ -12,12 +14,13 @@ import (
 
 // UserService handles user-related operations
 type UserService struct {
-	db *gorm.DB
+	db     *gorm.DB
+	ledger *LedgerService
 }
 
 // NewUserService creates a new user service
 func NewUserService(db *gorm.DB) *UserService {
-	return &UserService{db: db}
+	return &UserService{db: db, ledger: NewLedgerService(db)}
 }
 
 // GetDB returns the database connection
@@ -51,10 +54,9 @@ func (s *UserService) CreateUser(username, email, password, firstName, lastName
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
-	// Create wallet for user
+	// Create wallet for user; the balance is funded through the ledger below
 	wallet := &models.Wallet{
-		UserID:  user.ID,
-		Balance: 100.0, // Starting balance for new users
+		UserID: user.ID,
 	}
 
 	if err := tx.Create(wallet).Error; err != nil {
@@ -62,6 +64,22 @@ func (s *UserService) CreateUser(username, email, password, firstName, lastName
 		return nil, fmt.Errorf("failed to create wallet: %w", err)
 	}
 
+	// Mint the starting balance for new users from the issuance account
+	account, err := s.ledger.UserAccount(tx, user.ID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	issuance, err := s.ledger.SystemAccount(tx, models.AccountTypeIssuance)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if _, err := s.ledger.Move(tx, nil, "Starting balance", issuance, account, models.FC(100)); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to fund wallet: %w", err)
+	}
+
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
@@ -122,12 +140,20 @@ func (s *UserService) RegisterMerchant(userID uuid.UUID) error {
 
 // WalletService handles wallet operations
 type WalletService struct {
-	db *gorm.DB
+	db            *gorm.DB
+	ledger        *LedgerService
+	holdingCap    HoldingCap
+	fees          *FeeService
+	limits        SpendingLimits
+	credit        CreditPolicy
+	voucherKey    []byte
+	paymentURIKey []byte
 }
 
 // NewWalletService creates a new wallet service
 func NewWalletService(db *gorm.DB) *WalletService {
-	return &WalletService{db: db}
+	return &WalletService{db: db, ledger: NewLedgerService(db), holdingCap: DefaultHoldingCap(), fees: NewFeeService(db),
+		limits: DefaultSpendingLimits(), credit: DefaultCreditPolicy()}
 }
 
 // GetDB returns the database connection
@@ -135,85 +161,249 @@ func (s *WalletService) GetDB() *gorm.DB {
 	return s.db
 }
 
+// WithIdempotency returns a copy of the service whose writes record what
+// they create on the given idempotency claim (see models.IdempotencyClaimKey)
+func (s *WalletService) WithIdempotency(claim *models.IdempotencyRecord) *WalletService {
+	scoped := *s
+	scoped.db = s.db.Set(models.IdempotencyClaimKey, claim.ID)
+	return &scoped
+}
+
+// SetHoldingCap replaces the holding cap enforced on incoming transfers
+func (s *WalletService) SetHoldingCap(holdingCap HoldingCap) {
+	s.holdingCap = holdingCap
+}
+
 // GetBalance returns the wallet balance for a user
 func (s *WalletService) GetBalance(userID uuid.UUID) (*models.Wallet, error) {
 	var wallet models.Wallet
-	err := s.db.Where("user_id = ?", userID).First(&wallet).Error
+	if err := s.db.Where("user_id = ?", userID).First(&wallet).Error; err != nil {
+		return &wallet, err
+	}
+	pots, err := s.pots(s.db, userID)
+	wallet.Pots = pots
 	return &wallet, err
 }
 
-// Transfer transfers FairCoins between users
-func (s *WalletService) Transfer(fromUserID, toUserID uuid.UUID, amount float64, description string) (*models.Transaction, error) {
-	// Calculate fee (0.1% flat fee)
-	fee := amount * 0.001
-	if fee < 0.01 {
-		fee = 0.01 // Minimum fee
+// QuoteFee previews the fee on a transfer under the fee schedule in force
+func (s *WalletService) QuoteFee(fromUserID, toUserID uuid.UUID, amount models.Money) (*FeeQuote, error) {
+	if !amount.IsPositive() {
+		return nil, fmt.Errorf("amount must be positive")
+	}
+	if fromUserID == toUserID {
+		return nil, fmt.Errorf("cannot send FairCoins to yourself")
 	}
+	return s.fees.Quote(s.db, fromUserID, toUserID, amount)
+}
+
+// ErrInsufficientBalance is returned when a sender cannot cover a transfer
+// and its fee from their spendable balance
+var ErrInsufficientBalance = errors.New("insufficient balance")
 
+// Transfer transfers FairCoins between users. A transfer declined for lack
+// of funds, a send limit, a frozen wallet or the holding cap is recorded as
+// a failed transaction before the error is returned.
+func (s *WalletService) Transfer(fromUserID, toUserID uuid.UUID, amount models.Money, description string) (*models.Transaction, error) {
 	// Start transaction
 	tx := s.db.Begin()
 	if tx.Error != nil {
 		return nil, tx.Error
 	}
 
+	transaction, err := s.transfer(tx, fromUserID, toUserID, amount, description, nil)
+	if err != nil {
+		tx.Rollback()
+		// Keep a record of declined payments, without moving any funds
+		if declinedTransfer(err) {
+			if recordErr := s.recordFailedTransfer(s.db, fromUserID, toUserID, amount, description, err); recordErr != nil {
+				return nil, fmt.Errorf("%w (%v)", err, recordErr)
+			}
+		}
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return transaction, nil
+}
+
+// transfer moves amount plus the transfer fee between users inside tx.
+// Metadata, if any, is stored on the transaction. The caller rolls back on
+// error.
+func (s *WalletService) transfer(tx *gorm.DB, fromUserID, toUserID uuid.UUID, amount models.Money, description string,
+	metadata map[string]interface{}) (*models.Transaction, error) {
+	if !amount.IsPositive() {
+		return nil, fmt.Errorf("amount must be positive")
+	}
+
+	quote, err := s.fees.Quote(tx, fromUserID, toUserID, amount)
+	if err != nil {
+		return nil, err
+	}
+	fee := quote.Fee
+
 	// Check sender balance
 	var fromWallet models.Wallet
 	if err := tx.Where("user_id = ?", fromUserID).First(&fromWallet).Error; err != nil {
-		tx.Rollback()
 		return nil, fmt.Errorf("sender wallet not found: %w", err)
 	}
 
-	if fromWallet.Balance < amount+fee {
-		tx.Rollback()
-		return nil, fmt.Errorf("insufficient balance")
+	// Locked (vesting or time-locked) funds and savings pots cannot be
+	// spent; trusted members can spend into their credit line
+	if fromWallet.Spendable() < amount+fee {
+		available, creditLimit, err := s.availableToSpend(tx, &fromWallet)
+		if err != nil {
+			return nil, err
+		}
+		if available < amount+fee {
+			if creditLimit.IsPositive() {
+				return nil, fmt.Errorf("%w: %s FC available including a credit line of %s FC",
+					ErrInsufficientBalance, available, creditLimit)
+			}
+			if fromWallet.Balance >= amount+fee {
+				return nil, fmt.Errorf("%w: %s FC is locked and %s FC is in savings pots",
+					ErrInsufficientBalance, fromWallet.LockedFC, fromWallet.PottedFC)
+			}
+			return nil, ErrInsufficientBalance
+		}
 	}
 
 	// Get receiver wallet
 	var toWallet models.Wallet
 	if err := tx.Where("user_id = ?", toUserID).First(&toWallet).Error; err != nil {
-		tx.Rollback()
 		return nil, fmt.Errorf("receiver wallet not found: %w", err)
 	}
 
-	// Update balances
-	fromWallet.Balance -= (amount + fee)
-	toWallet.Balance += amount
-
-	if err := tx.Save(&fromWallet).Error; err != nil {
-		tx.Rollback()
-		return nil, fmt.Errorf("failed to update sender balance: %w", err)
+	// Frozen wallets can neither send nor receive, and senders stay within
+	// their daily and monthly limits
+	if err := s.checkSend(tx, &fromWallet, amount); err != nil {
+		return nil, err
+	}
+	if err := checkReceive(&toWallet); err != nil {
+		return nil, err
 	}
 
-	if err := tx.Save(&toWallet).Error; err != nil {
-		tx.Rollback()
-		return nil, fmt.Errorf("failed to update receiver balance: %w", err)
+	// Enforce the holding cap on the receiver
+	accepted, overflow, limit, err := s.holdingCap.Split(tx, s.ledger, toUserID, amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check holding cap: %w", err)
+	}
+	if overflow.IsPositive() && s.holdingCap.Overflow == HoldingCapOverflowReject {
+		return nil, fmt.Errorf("%w: the recipient can receive at most %s FC more (limit %s FC, %.1f%% of circulating supply)",
+			ErrHoldingCapExceeded, accepted, limit, s.holdingCap.Percentage*100)
 	}
 
 	// Create transaction record
 	transaction := &models.Transaction{
-		UserID:      fromUserID,
-		ToUserID:    &toUserID,
-		Type:        models.TransactionTypeTransfer,
-		Amount:      amount,
-		Fee:         fee,
-		Description: description,
-		Status:      "completed",
-		CreatedAt:   time.Now(),
+		UserID:             fromUserID,
+		ToUserID:           &toUserID,
+		Type:               models.TransactionTypeTransfer,
+		Amount:             amount,
+		Fee:                fee,
+		FeeScheduleVersion: quote.ScheduleVersion,
+		Description:        description,
+		Status:             models.TransactionStatusCompleted,
+		CreatedAt:          time.Now(),
+	}
+	if overflow.IsPositive() {
+		if metadata == nil {
+			metadata = make(map[string]interface{})
+		}
+		metadata["holding_cap_overflow"] = overflow
+		metadata["holding_cap_limit"] = limit
+	}
+	if len(metadata) > 0 {
+		encoded, _ := json.Marshal(metadata)
+		transaction.Metadata = string(encoded)
 	}
 
 	if err := tx.Create(transaction).Error; err != nil {
-		tx.Rollback()
 		return nil, fmt.Errorf("failed to create transaction: %w", err)
 	}
 
-	// Commit transaction
-	if err := tx.Commit().Error; err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	// Post the transfer to the ledger: sender pays amount + fee, receiver
+	// gets amount and the fee, plus any overflow above the holding cap, goes
+	// to the community treasury
+	fromAccount, err := s.ledger.UserAccount(tx, fromUserID)
+	if err != nil {
+		return nil, err
+	}
+	toAccount, err := s.ledger.UserAccount(tx, toUserID)
+	if err != nil {
+		return nil, err
+	}
+	treasury, err := s.ledger.SystemAccount(tx, models.AccountTypeTreasury)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.ledger.Post(tx, &transaction.ID, description,
+		Leg{Account: fromAccount, Amount: amount.Add(fee).Neg()},
+		Leg{Account: toAccount, Amount: accepted},
+		Leg{Account: treasury, Amount: fee.Add(overflow)},
+	); err != nil {
+		return nil, fmt.Errorf("failed to post transfer: %w", err)
 	}
 
 	return transaction, nil
 }
 
+// GetHoldingCapReport lists wallets holding at least threshold (0-1) of the
+// current holding cap, largest first
+func (s *WalletService) GetHoldingCapReport(threshold float64) (map[string]interface{}, error) {
+	supply, err := s.ledger.CirculatingSupply(s.db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get circulating supply: %w", err)
+	}
+	limit := s.holdingCap.Limit(supply)
+
+	var rows []struct {
+		UserID   uuid.UUID
+		Username string
+		Balance  models.Money
+	}
+	if err := s.db.Table("ledger_accounts").
+		Select("ledger_accounts.user_id as user_id, users.username as username, ledger_accounts.balance as balance").
+		Joins("JOIN users ON users.id = ledger_accounts.user_id").
+		Where("ledger_accounts.type = ? AND ledger_accounts.balance >= ?", models.AccountTypeUser, limit.MulRate(threshold)).
+		Order("ledger_accounts.balance DESC").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to get wallets near cap: %w", err)
+	}
+
+	wallets := make([]map[string]interface{}, 0, len(rows))
+	for _, row := range rows {
+		var share float64
+		if supply.IsPositive() {
+			share = row.Balance.Float64() / supply.Float64()
+		}
+		wallets = append(wallets, map[string]interface{}{
+			"user_id":       row.UserID,
+			"username":      row.Username,
+			"balance":       row.Balance,
+			"supply_share":  share,
+			"cap_usage":     row.Balance.Float64() / limit.Float64(),
+			"over_cap":      row.Balance > limit,
+			"exempt":        s.holdingCap.IsExempt(row.UserID),
+			"remaining_cap": models.MaxMoney(limit.Sub(row.Balance), 0),
+		})
+	}
+
+	return map[string]interface{}{
+		"enabled":            s.holdingCap.Enabled(),
+		"cap_percentage":     s.holdingCap.Percentage,
+		"cap_minimum":        s.holdingCap.Minimum,
+		"overflow_policy":    s.holdingCap.Overflow,
+		"circulating_supply": supply,
+		"holding_limit":      limit,
+		"threshold":          threshold,
+		"wallets":            wallets,
+	}, nil
+}
+
 // TransactionService handles transaction operations
 type TransactionService struct {
 	db *gorm.DB
@@ -229,21 +419,12 @@ func (s *TransactionService) GetDB() *gorm.DB {
 	return s.db
 }
 
-// GetUserTransactions returns transaction history for a user
-func (s *TransactionService) GetUserTransactions(userID uuid.UUID, limit, offset int) ([]models.Transaction, error) {
-	var transactions []models.Transaction
-	err := s.db.Preload("User").Preload("ToUser").
-		Where("user_id = ? OR to_user_id = ?", userID, userID).
-		Order("created_at DESC").
-		Limit(limit).Offset(offset).
-		Find(&transactions).Error
-	return transactions, err
-}
-
 // GetTransactionByID returns a specific transaction
 func (s *TransactionService) GetTransactionByID(id uuid.UUID) (*models.Transaction, error) {
 	var transaction models.Transaction
-	err := s.db.Preload("User").Preload("ToUser").First(&transaction, "id = ?", id).Error
+	err := s.db.Preload("User").Preload("ToUser").
+		Preload("StatusHistory", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		First(&transaction, "id = ?", id).Error
 	return &transaction, err
 }
 
@@ -268,7 +449,7 @@ func (s *TransactionService) GetCommunityStats() (map[string]interface{}, error)
 
 	// Total circulating supply
 	var totalSupply struct {
-		Total float64
+		Total models.Money
 	}
 	s.db.Model(&models.Wallet{}).Select("SUM(balance) as total").Scan(&totalSupply)
 	stats["circulating_supply"] = totalSupply.Total
@@ -282,13 +463,21 @@ func (s *TransactionService) GetCommunityStats() (map[string]interface{}, error)
 
 	// Transaction volume (last 30 days)
 	var volume struct {
-		Total float64
+		Total models.Money
 	}
 	thirtyDaysAgo := time.Now().AddDate(0, 0, -30)
 	s.db.Model(&models.Transaction{}).
 		Where("created_at > ? AND type = ?", thirtyDaysAgo, models.TransactionTypeTransfer).
+		Where("status NOT IN (?)", models.VoidTransactionStatuses).
 		Select("SUM(amount) as total").Scan(&volume)
 	stats["transaction_volume_30d"] = volume.Total
 
+	// Latest published balance root (proof of reserves)
+	var snapshot models.BalanceSnapshot
+	if err := s.db.Order("created_at DESC").First(&snapshot).Error; err == nil {
+		stats["balance_root"] = snapshot.Root
+		stats["balance_root_at"] = snapshot.CreatedAt
+	}
+
 	return stats, nil
 }
@@ -0,0 +1,243 @@
+package services
+
+import (
+	"faircoin/internal/models"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+)
+
+// VestingService manages vesting schedules and time-locks on wallet balances.
+// Locked funds stay in Wallet.Balance and are tracked in Wallet.LockedFC, so
+// only Balance - LockedFC, less any savings pots, can be spent.
+type VestingService struct {
+	db *gorm.DB
+}
+
+// NewVestingService creates a new vesting service
+func NewVestingService(db *gorm.DB) *VestingService {
+	return &VestingService{db: db}
+}
+
+// GetDB returns the database connection
+func (s *VestingService) GetDB() *gorm.DB {
+	return s.db
+}
+
+// LockRequest describes a new lock schedule
+type LockRequest struct {
+	UserID    uuid.UUID
+	Kind      models.LockKind
+	Amount    models.Money
+	StartTime time.Time
+	CliffTime time.Time // Ignored for time-locks
+	EndTime   time.Time
+	Source    string
+	Reason    string
+	CreatedBy *uuid.UUID
+}
+
+// CreateLock locks part of a user's spendable balance in its own transaction
+func (s *VestingService) CreateLock(req LockRequest) (*models.LockSchedule, error) {
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	schedule, err := s.Lock(tx, req)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit lock: %w", err)
+	}
+	return schedule, nil
+}
+
+// Lock creates a lock schedule inside tx. The amount must already be part of
+// the wallet's spendable balance, e.g. minted earlier in the same tx.
+func (s *VestingService) Lock(tx *gorm.DB, req LockRequest) (*models.LockSchedule, error) {
+	if !req.Amount.IsPositive() {
+		return nil, fmt.Errorf("lock amount must be positive")
+	}
+	if req.StartTime.IsZero() {
+		req.StartTime = time.Now()
+	}
+
+	switch req.Kind {
+	case models.LockKindTimeLock:
+		req.CliffTime = req.EndTime
+	case models.LockKindVesting:
+		if req.CliffTime.IsZero() {
+			req.CliffTime = req.StartTime
+		}
+		if req.CliffTime.Before(req.StartTime) || req.CliffTime.After(req.EndTime) {
+			return nil, fmt.Errorf("cliff must be between start and end")
+		}
+	default:
+		return nil, fmt.Errorf("unknown lock kind: %s", req.Kind)
+	}
+	if !req.EndTime.After(req.StartTime) {
+		return nil, fmt.Errorf("end time must be after start time")
+	}
+	if !req.EndTime.After(time.Now()) {
+		return nil, fmt.Errorf("end time must be in the future")
+	}
+
+	var wallet models.Wallet
+	if err := tx.Where("user_id = ?", req.UserID).First(&wallet).Error; err != nil {
+		return nil, fmt.Errorf("wallet not found: %w", err)
+	}
+	if wallet.Spendable() < req.Amount {
+		return nil, fmt.Errorf("insufficient spendable balance to lock %s FC (spendable: %s FC)", req.Amount, wallet.Spendable())
+	}
+
+	schedule := &models.LockSchedule{
+		UserID:    req.UserID,
+		Kind:      req.Kind,
+		Amount:    req.Amount,
+		StartTime: req.StartTime,
+		CliffTime: req.CliffTime,
+		EndTime:   req.EndTime,
+		Status:    models.LockStatusActive,
+		Source:    req.Source,
+		Reason:    req.Reason,
+		CreatedBy: req.CreatedBy,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := tx.Create(schedule).Error; err != nil {
+		return nil, fmt.Errorf("failed to create lock schedule: %w", err)
+	}
+
+	// Guard on the spendable balance so a concurrent spend or lock cannot
+	// lock funds that are already gone
+	result := tx.Model(&models.Wallet{}).
+		Where("user_id = ? AND balance - locked_fc - potted_fc >= ?", req.UserID, req.Amount).
+		Update("locked_fc", gorm.Expr("locked_fc + ?", req.Amount))
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to update locked balance: %w", result.Error)
+	}
+	if result.RowsAffected != 1 {
+		return nil, fmt.Errorf("insufficient spendable balance to lock %s FC", req.Amount)
+	}
+
+	return schedule, nil
+}
+
+// ReleaseMatured releases everything that has vested on active schedules
+// and completes schedules that are fully released
+func (s *VestingService) ReleaseMatured() error {
+	now := time.Now()
+
+	var schedules []models.LockSchedule
+	if err := s.db.Where("status = ? AND cliff_time <= ?", models.LockStatusActive, now).
+		Find(&schedules).Error; err != nil {
+		return err
+	}
+
+	for i := range schedules {
+		if err := s.release(&schedules[i], now); err != nil {
+			return fmt.Errorf("failed to release lock %s: %w", schedules[i].ID, err)
+		}
+	}
+
+	return nil
+}
+
+// release unlocks the vested but unreleased part of one schedule
+func (s *VestingService) release(schedule *models.LockSchedule, now time.Time) error {
+	delta := schedule.VestedAt(now).Sub(schedule.Released)
+	if !delta.IsPositive() {
+		return nil
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	updates := map[string]interface{}{
+		"released":   schedule.Released.Add(delta),
+		"updated_at": now,
+	}
+	if schedule.Released.Add(delta) == schedule.Amount {
+		updates["status"] = models.LockStatusCompleted
+	}
+
+	// Guard on the previous released amount so overlapping runs cannot
+	// release the same funds twice
+	result := tx.Model(&models.LockSchedule{}).
+		Where("id = ? AND released = ?", schedule.ID, schedule.Released).
+		Updates(updates)
+	if result.Error != nil {
+		tx.Rollback()
+		return result.Error
+	}
+	if result.RowsAffected != 1 {
+		tx.Rollback()
+		return nil
+	}
+
+	if err := tx.Model(&models.Wallet{}).Where("user_id = ?", schedule.UserID).
+		Update("locked_fc", gorm.Expr("locked_fc - ?", delta)).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// GetLockSummary returns a user's locked and spendable balance together
+// with their active schedules and upcoming unlocks
+func (s *VestingService) GetLockSummary(userID uuid.UUID) (map[string]interface{}, error) {
+	var wallet models.Wallet
+	if err := s.db.Where("user_id = ?", userID).First(&wallet).Error; err != nil {
+		return nil, fmt.Errorf("wallet not found: %w", err)
+	}
+
+	var schedules []models.LockSchedule
+	if err := s.db.Where("user_id = ? AND status = ?", userID, models.LockStatusActive).
+		Order("end_time ASC").Find(&schedules).Error; err != nil {
+		return nil, fmt.Errorf("failed to get lock schedules: %w", err)
+	}
+
+	now := time.Now()
+	upcoming := make([]map[string]interface{}, 0)
+	for _, schedule := range schedules {
+		if schedule.CliffTime.After(now) {
+			upcoming = append(upcoming, map[string]interface{}{
+				"schedule_id": schedule.ID,
+				"kind":        schedule.Kind,
+				"unlock_at":   schedule.CliffTime,
+				"amount":      schedule.VestedAt(schedule.CliffTime).Sub(schedule.Released),
+			})
+		}
+		if schedule.EndTime.After(schedule.CliffTime) && schedule.EndTime.After(now) {
+			// Between the cliff and the end funds unlock linearly
+			from := schedule.CliffTime
+			if now.After(from) {
+				from = now
+			}
+			upcoming = append(upcoming, map[string]interface{}{
+				"schedule_id": schedule.ID,
+				"kind":        schedule.Kind,
+				"linear_from": from,
+				"unlock_at":   schedule.EndTime,
+				"amount":      schedule.Amount.Sub(schedule.VestedAt(from)),
+			})
+		}
+	}
+
+	return map[string]interface{}{
+		"balance":   wallet.Balance,
+		"locked":    wallet.LockedFC,
+		"spendable": wallet.Spendable(),
+		"schedules": schedules,
+		"upcoming":  upcoming,
+	}, nil
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"faircoin/internal/models"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// lockedFC returns how much of a user's wallet is locked
+func lockedFC(t *testing.T, db *gorm.DB, user *models.User) models.Money {
+	t.Helper()
+	var wallet models.Wallet
+	if err := db.Where("user_id = ?", user.ID).First(&wallet).Error; err != nil {
+		t.Fatal(err)
+	}
+	return wallet.LockedFC
+}
+
+func TestLockRespectsSpendableBalance(t *testing.T) {
+	db := newTestDB(t)
+	alice := newTestUser(t, db, "alice")
+	vesting := NewVestingService(db)
+
+	lock := func(amount models.Money) error {
+		_, err := vesting.CreateLock(LockRequest{UserID: alice.ID, Kind: models.LockKindTimeLock, Amount: amount,
+			EndTime: time.Now().Add(24 * time.Hour)})
+		return err
+	}
+	if err := lock(models.FC(60)); err != nil {
+		t.Fatal(err)
+	}
+	if err := lock(models.FC(50)); err == nil {
+		t.Error("locked more than the spendable balance")
+	}
+
+	// Funds moved into a pot are not spendable either
+	if err := db.Model(&models.Wallet{}).Where("user_id = ?", alice.ID).Update("potted_fc", models.FC(30)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := lock(models.FC(20)); err == nil {
+		t.Error("locked funds held in a pot")
+	}
+	if err := lock(models.FC(10)); err != nil {
+		t.Errorf("lock of the remaining spendable balance: %v", err)
+	}
+	if locked := lockedFC(t, db, alice); locked != models.FC(70) {
+		t.Errorf("locked %s, want 70", locked)
+	}
+}
+
+func TestReleaseMaturedVestsLinearly(t *testing.T) {
+	db := newTestDB(t)
+	alice := newTestUser(t, db, "alice")
+	vesting := NewVestingService(db)
+
+	// Halfway through a schedule past its cliff, and one not yet at its cliff
+	now := time.Now()
+	halfway, err := vesting.CreateLock(LockRequest{UserID: alice.ID, Kind: models.LockKindVesting, Amount: models.FC(40),
+		StartTime: now.Add(-2 * time.Hour), CliffTime: now.Add(-time.Hour), EndTime: now.Add(2 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vesting.CreateLock(LockRequest{UserID: alice.ID, Kind: models.LockKindVesting, Amount: models.FC(20),
+		CliffTime: now.Add(time.Hour), EndTime: now.Add(2 * time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := vesting.ReleaseMatured(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var schedule models.LockSchedule
+	if err := db.First(&schedule, "id = ?", halfway.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if schedule.Released < models.FC(20) || schedule.Released > models.FC(21) {
+		t.Errorf("released %s of 40 halfway through, want about 20", schedule.Released)
+	}
+	if locked := lockedFC(t, db, alice); locked != models.FC(60).Sub(schedule.Released) {
+		t.Errorf("locked %s, want %s", locked, models.FC(60).Sub(schedule.Released))
+	}
+}
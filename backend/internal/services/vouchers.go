@@ -0,0 +1,472 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"faircoin/internal/models"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// DefaultVoucherExpiry is how long a voucher stays redeemable when no expiry is given
+	DefaultVoucherExpiry = 30 * 24 * time.Hour
+	// MaxVoucherExpiry is the longest a voucher may stay redeemable
+	MaxVoucherExpiry = 365 * 24 * time.Hour
+
+	// voucherCodePrefix marks and versions voucher codes
+	voucherCodePrefix = "FCV1"
+)
+
+// ErrVoucherInvalid is returned when a voucher code is malformed, its
+// signature does not match or it names a voucher that does not exist
+var ErrVoucherInvalid = errors.New("invalid voucher")
+
+// ErrVoucherNotOutstanding is returned when a voucher has been redeemed,
+// cancelled or has expired
+var ErrVoucherNotOutstanding = errors.New("voucher is not outstanding")
+
+// voucherPayload is what a voucher code carries and signs
+type voucherPayload struct {
+	ID        uuid.UUID `json:"id"`
+	Amount    string    `json:"amount"`
+	ExpiresAt int64     `json:"expires_at"` // Unix seconds
+	Nonce     string    `json:"nonce"`
+}
+
+// SetSigningSecret derives the keys that sign voucher codes and payment
+// URIs from secret (the JWT secret). Each key is bound to its purpose, so a
+// leaked key cannot sign tokens or the other kind of payload.
+func (s *WalletService) SetSigningSecret(secret string) {
+	s.voucherKey = deriveKey(secret, "faircoin voucher signing key")
+	s.paymentURIKey = deriveKey(secret, "faircoin payment uri signing key")
+}
+
+func deriveKey(secret, purpose string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// IssueVoucher reserves amount plus the transfer fee from the issuer's
+// wallet and returns the voucher with its signed code. The fee is computed
+// as for a payment to a user who is not a merchant.
+func (s *WalletService) IssueVoucher(issuerID uuid.UUID, amount models.Money, memo string, expiresAt time.Time) (*models.Voucher, error) {
+	if len(s.voucherKey) == 0 {
+		return nil, fmt.Errorf("voucher signing is not configured")
+	}
+	if !amount.IsPositive() {
+		return nil, fmt.Errorf("amount must be positive")
+	}
+	now := time.Now()
+	if expiresAt.IsZero() {
+		expiresAt = now.Add(DefaultVoucherExpiry)
+	}
+	expiresAt = expiresAt.UTC().Truncate(time.Second)
+	if !expiresAt.After(now) || expiresAt.Sub(now) > MaxVoucherExpiry {
+		return nil, fmt.Errorf("expiry must be in the future and at most %d days away", int(MaxVoucherExpiry.Hours()/24))
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	quote, err := s.fees.Quote(tx, issuerID, uuid.Nil, amount)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	var wallet models.Wallet
+	if err := tx.Where("user_id = ?", issuerID).First(&wallet).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("issuer wallet not found: %w", err)
+	}
+	if wallet.Spendable() < quote.Total {
+		tx.Rollback()
+		return nil, fmt.Errorf("%w: %s FC needed including fees, %s FC spendable",
+			ErrInsufficientBalance, quote.Total, wallet.Spendable())
+	}
+	if err := s.checkSend(tx, &wallet, amount); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	transaction := &models.Transaction{
+		UserID:             issuerID,
+		Type:               models.TransactionTypeVoucher,
+		Amount:             amount,
+		Fee:                quote.Fee,
+		FeeScheduleVersion: quote.ScheduleVersion,
+		Description:        memo,
+		Status:             models.TransactionStatusPending,
+		CreatedAt:          now,
+	}
+	if err := tx.Create(transaction).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	voucher := &models.Voucher{
+		TransactionID: transaction.ID,
+		IssuerID:      issuerID,
+		Amount:        amount,
+		Fee:           quote.Fee,
+		Nonce:         hex.EncodeToString(nonce),
+		Memo:          memo,
+		Status:        models.VoucherStatusOutstanding,
+		ExpiresAt:     expiresAt,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := tx.Create(voucher).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to create voucher: %w", err)
+	}
+
+	issuerAccount, err := s.ledger.UserAccount(tx, issuerID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	voucherAccount, err := s.ledger.SystemAccount(tx, models.AccountTypeVoucher)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if _, err := s.ledger.Move(tx, &transaction.ID, "Voucher issued", issuerAccount, voucherAccount, quote.Total); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to post voucher: %w", err)
+	}
+	if err := setTransactionStatus(tx, transaction, models.TransactionStatusAuthorized,
+		"Funds held for voucher", &issuerID); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit voucher: %w", err)
+	}
+
+	voucher.Transaction = transaction
+	voucher.Code = s.voucherCode(voucher)
+	return voucher, nil
+}
+
+// CheckVoucher verifies a code and returns the voucher it names without
+// redeeming it
+func (s *WalletService) CheckVoucher(code string) (*models.Voucher, error) {
+	return s.verifyVoucher(s.db, code)
+}
+
+// RedeemVoucher pays an outstanding voucher to the redeemer and the fee to
+// the treasury. The voucher is claimed with a guarded update, so a code can
+// only ever be redeemed once.
+func (s *WalletService) RedeemVoucher(code string, redeemerID uuid.UUID) (*models.Voucher, error) {
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	voucher, err := s.verifyVoucher(tx, code)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if voucher.IssuerID == redeemerID {
+		tx.Rollback()
+		return nil, fmt.Errorf("cannot redeem your own voucher, cancel it instead")
+	}
+	now := time.Now()
+	if voucher.Status == models.VoucherStatusOutstanding && !voucher.ExpiresAt.After(now) {
+		tx.Rollback()
+		return nil, fmt.Errorf("%w: it expired at %s", ErrVoucherNotOutstanding, voucher.ExpiresAt.Format(time.RFC3339))
+	}
+
+	var redeemerWallet models.Wallet
+	if err := tx.Where("user_id = ?", redeemerID).First(&redeemerWallet).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("redeemer wallet not found: %w", err)
+	}
+	if err := checkReceive(&redeemerWallet); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	result := tx.Model(&models.Voucher{}).
+		Where("id = ? AND status = ? AND expires_at > ?", voucher.ID, models.VoucherStatusOutstanding, now.UTC()).
+		Updates(map[string]interface{}{
+			"status":      models.VoucherStatusRedeemed,
+			"redeemed_by": redeemerID,
+			"redeemed_at": now,
+			"updated_at":  now,
+		})
+	if result.Error != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to update voucher: %w", result.Error)
+	}
+	if result.RowsAffected != 1 {
+		tx.Rollback()
+		return nil, fmt.Errorf("%w: it is %s", ErrVoucherNotOutstanding, voucher.Status)
+	}
+
+	accepted, overflow, limit, err := s.holdingCap.Split(tx, s.ledger, redeemerID, voucher.Amount)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to check holding cap: %w", err)
+	}
+	if overflow.IsPositive() && s.holdingCap.Overflow == HoldingCapOverflowReject {
+		tx.Rollback()
+		return nil, fmt.Errorf("%w: you can receive at most %s FC more (limit %s FC)",
+			ErrHoldingCapExceeded, accepted, limit)
+	}
+
+	voucherAccount, err := s.ledger.SystemAccount(tx, models.AccountTypeVoucher)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	redeemerAccount, err := s.ledger.UserAccount(tx, redeemerID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	treasury, err := s.ledger.SystemAccount(tx, models.AccountTypeTreasury)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if _, err := s.ledger.Post(tx, &voucher.TransactionID, "Voucher redeemed",
+		Leg{Account: voucherAccount, Amount: voucher.Amount.Add(voucher.Fee).Neg()},
+		Leg{Account: redeemerAccount, Amount: accepted},
+		Leg{Account: treasury, Amount: voucher.Fee.Add(overflow)},
+	); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to post voucher redemption: %w", err)
+	}
+
+	if err := tx.Model(&models.Transaction{}).Where("id = ?", voucher.TransactionID).
+		Update("to_user_id", redeemerID).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to update transaction: %w", err)
+	}
+	if err := setTransactionStatus(tx, voucher.Transaction, models.TransactionStatusCompleted,
+		"Voucher redeemed", &redeemerID); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit voucher redemption: %w", err)
+	}
+
+	return s.getVoucher(s.db, voucher.ID)
+}
+
+// CancelVoucher returns an outstanding voucher, fee included, to the issuer.
+// Only the issuer can cancel it.
+func (s *WalletService) CancelVoucher(voucherID, issuerID uuid.UUID) (*models.Voucher, error) {
+	voucher, err := s.getVoucher(s.db, voucherID)
+	if err != nil {
+		return nil, err
+	}
+	if voucher.IssuerID != issuerID {
+		return nil, ErrVoucherInvalid
+	}
+	if err := s.refundVoucher(voucher.ID, models.VoucherStatusCancelled, &issuerID); err != nil {
+		return nil, err
+	}
+	return s.GetVoucher(voucherID, issuerID)
+}
+
+// ExpireVouchers refunds every outstanding voucher past its expiry to its issuer
+func (s *WalletService) ExpireVouchers() error {
+	var vouchers []models.Voucher
+	if err := s.db.Where("status = ? AND expires_at <= ?", models.VoucherStatusOutstanding, time.Now().UTC()).
+		Find(&vouchers).Error; err != nil {
+		return err
+	}
+
+	var failed int
+	for _, voucher := range vouchers {
+		if err := s.refundVoucher(voucher.ID, models.VoucherStatusExpired, nil); err != nil &&
+			!errors.Is(err, ErrVoucherNotOutstanding) {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d expired vouchers could not be refunded", failed, len(vouchers))
+	}
+	return nil
+}
+
+// refundVoucher claims an outstanding voucher as cancelled or expired and
+// moves the reserved amount and fee back to the issuer. A nil actorID means
+// the voucher expired.
+func (s *WalletService) refundVoucher(voucherID uuid.UUID, outcome models.VoucherStatus, actorID *uuid.UUID) error {
+	tx := s.db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	voucher, err := s.getVoucher(tx, voucherID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	now := time.Now()
+	result := tx.Model(&models.Voucher{}).
+		Where("id = ? AND status = ?", voucher.ID, models.VoucherStatusOutstanding).
+		Updates(map[string]interface{}{"status": outcome, "refunded_at": now, "updated_at": now})
+	if result.Error != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to update voucher: %w", result.Error)
+	}
+	if result.RowsAffected != 1 {
+		tx.Rollback()
+		return fmt.Errorf("%w: it is %s", ErrVoucherNotOutstanding, voucher.Status)
+	}
+
+	voucherAccount, err := s.ledger.SystemAccount(tx, models.AccountTypeVoucher)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	issuerAccount, err := s.ledger.UserAccount(tx, voucher.IssuerID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := s.ledger.Move(tx, &voucher.TransactionID, "Voucher "+string(outcome),
+		voucherAccount, issuerAccount, voucher.Amount.Add(voucher.Fee)); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to post voucher refund: %w", err)
+	}
+
+	if err := setTransactionStatus(tx, voucher.Transaction, models.TransactionStatusCancelled,
+		"Voucher "+string(outcome), actorID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("failed to commit voucher refund: %w", err)
+	}
+	return nil
+}
+
+// GetVoucher returns a voucher the user issued, with its code while it is outstanding
+func (s *WalletService) GetVoucher(voucherID, issuerID uuid.UUID) (*models.Voucher, error) {
+	voucher, err := s.getVoucher(s.db, voucherID)
+	if err != nil {
+		return nil, err
+	}
+	if voucher.IssuerID != issuerID {
+		return nil, ErrVoucherInvalid
+	}
+	if voucher.Status == models.VoucherStatusOutstanding {
+		voucher.Code = s.voucherCode(voucher)
+	}
+	return voucher, nil
+}
+
+// GetIssuedVouchers returns the vouchers the user issued, newest first,
+// optionally filtered by status
+func (s *WalletService) GetIssuedVouchers(issuerID uuid.UUID, status string) ([]models.Voucher, error) {
+	query := s.db.Preload("Transaction").Where("issuer_id = ?", issuerID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var vouchers []models.Voucher
+	if err := query.Order("created_at DESC").Find(&vouchers).Error; err != nil {
+		return nil, err
+	}
+	for i := range vouchers {
+		if vouchers[i].Status == models.VoucherStatusOutstanding {
+			vouchers[i].Code = s.voucherCode(&vouchers[i])
+		}
+	}
+	return vouchers, nil
+}
+
+func (s *WalletService) getVoucher(db *gorm.DB, voucherID uuid.UUID) (*models.Voucher, error) {
+	var voucher models.Voucher
+	if err := db.Preload("Transaction").First(&voucher, "id = ?", voucherID).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrVoucherInvalid
+		}
+		return nil, fmt.Errorf("failed to get voucher: %w", err)
+	}
+	return &voucher, nil
+}
+
+// voucherCode encodes and signs a voucher as
+// FCV1.<base64url payload>.<base64url HMAC-SHA256 of the payload>
+func (s *WalletService) voucherCode(voucher *models.Voucher) string {
+	payload, _ := json.Marshal(voucherPayload{
+		ID:        voucher.ID,
+		Amount:    voucher.Amount.String(),
+		ExpiresAt: voucher.ExpiresAt.Unix(),
+		Nonce:     voucher.Nonce,
+	})
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return voucherCodePrefix + "." + encoded + "." + base64.RawURLEncoding.EncodeToString(s.signVoucher(encoded))
+}
+
+func (s *WalletService) signVoucher(encoded string) []byte {
+	mac := hmac.New(sha256.New, s.voucherKey)
+	mac.Write([]byte(voucherCodePrefix + "." + encoded))
+	return mac.Sum(nil)
+}
+
+// verifyVoucher checks a code's signature and that it matches the stored voucher
+func (s *WalletService) verifyVoucher(db *gorm.DB, code string) (*models.Voucher, error) {
+	if len(s.voucherKey) == 0 {
+		return nil, fmt.Errorf("voucher signing is not configured")
+	}
+
+	parts := strings.Split(strings.TrimSpace(code), ".")
+	if len(parts) != 3 || parts[0] != voucherCodePrefix {
+		return nil, fmt.Errorf("%w: malformed code", ErrVoucherInvalid)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, s.signVoucher(parts[1])) {
+		return nil, fmt.Errorf("%w: bad signature", ErrVoucherInvalid)
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed code", ErrVoucherInvalid)
+	}
+	var payload voucherPayload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil, fmt.Errorf("%w: malformed code", ErrVoucherInvalid)
+	}
+
+	voucher, err := s.getVoucher(db, payload.ID)
+	if err != nil {
+		return nil, err
+	}
+	if voucher.Nonce != payload.Nonce || voucher.Amount.String() != payload.Amount ||
+		voucher.ExpiresAt.Unix() != payload.ExpiresAt {
+		return nil, fmt.Errorf("%w: code does not match the voucher", ErrVoucherInvalid)
+	}
+	return voucher, nil
+}
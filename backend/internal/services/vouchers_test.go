@@ -0,0 +1,139 @@
+package services
+
+import (
+	"errors"
+	"faircoin/internal/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// expireVoucher moves a voucher's expiry into the past and re-signs its code
+func expireVoucher(wallets *WalletService, db *gorm.DB, voucher *models.Voucher) {
+	voucher.ExpiresAt = time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+	db.Model(&models.Voucher{}).Where("id = ?", voucher.ID).Update("expires_at", voucher.ExpiresAt)
+	voucher.Code = wallets.voucherCode(voucher)
+}
+
+func TestVoucherSettlesOnce(t *testing.T) {
+	fee := models.MoneyFromFloat(0.01)
+	tests := []struct {
+		name         string
+		settle       func(*WalletService, *gorm.DB, *models.Voucher, *models.User) error
+		wantStatus   models.VoucherStatus
+		wantIssuer   models.Money
+		wantBob      models.Money
+		wantTreasury models.Money
+	}{
+		{"redeemed", func(wallets *WalletService, _ *gorm.DB, voucher *models.Voucher, bob *models.User) error {
+			_, err := wallets.RedeemVoucher(voucher.Code, bob.ID)
+			return err
+		}, models.VoucherStatusRedeemed, models.FC(90).Sub(fee), models.FC(110), fee},
+		{"cancelled", func(wallets *WalletService, _ *gorm.DB, voucher *models.Voucher, _ *models.User) error {
+			_, err := wallets.CancelVoucher(voucher.ID, voucher.IssuerID)
+			return err
+		}, models.VoucherStatusCancelled, models.FC(100), models.FC(100), 0},
+		{"expired", func(wallets *WalletService, db *gorm.DB, voucher *models.Voucher, _ *models.User) error {
+			expireVoucher(wallets, db, voucher)
+			return wallets.ExpireVouchers()
+		}, models.VoucherStatusExpired, models.FC(100), models.FC(100), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			alice := newTestUser(t, db, "alice")
+			bob := newTestUser(t, db, "bob")
+			carol := newTestUser(t, db, "carol")
+			wallets := NewWalletService(db)
+			wallets.SetSigningSecret("test secret")
+
+			voucher, err := wallets.IssueVoucher(alice.ID, models.FC(10), "gift", time.Time{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if balance := walletBalance(t, db, alice.ID); balance != models.FC(90).Sub(fee) {
+				t.Fatalf("issuer holds %s after issuing, want the amount and fee reserved", balance)
+			}
+			if _, err := wallets.RedeemVoucher(voucher.Code, alice.ID); err == nil {
+				t.Error("the issuer redeemed their own voucher")
+			}
+
+			if err := tt.settle(wallets, db, voucher, bob); err != nil {
+				t.Fatal(err)
+			}
+
+			// Whatever settled the voucher first, nothing else can settle it again
+			if _, err := wallets.RedeemVoucher(voucher.Code, carol.ID); !errors.Is(err, ErrVoucherNotOutstanding) {
+				t.Errorf("redeem after %s: err = %v", tt.name, err)
+			}
+			if _, err := wallets.CancelVoucher(voucher.ID, alice.ID); !errors.Is(err, ErrVoucherNotOutstanding) {
+				t.Errorf("cancel after %s: err = %v", tt.name, err)
+			}
+			expireVoucher(wallets, db, voucher)
+			if err := wallets.ExpireVouchers(); err != nil {
+				t.Errorf("expire after %s: %v", tt.name, err)
+			}
+
+			stored, err := wallets.getVoucher(db, voucher.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Status != tt.wantStatus {
+				t.Errorf("voucher is %s, want %s", stored.Status, tt.wantStatus)
+			}
+			if balance := walletBalance(t, db, alice.ID); balance != tt.wantIssuer {
+				t.Errorf("issuer holds %s, want %s", balance, tt.wantIssuer)
+			}
+			if balance := walletBalance(t, db, bob.ID); balance != tt.wantBob {
+				t.Errorf("bob holds %s, want %s", balance, tt.wantBob)
+			}
+			if balance := walletBalance(t, db, carol.ID); balance != models.FC(100) {
+				t.Errorf("carol holds %s, want 100", balance)
+			}
+			ledger := NewLedgerService(db)
+			for accountType, want := range map[models.AccountType]models.Money{
+				models.AccountTypeVoucher:  0,
+				models.AccountTypeTreasury: tt.wantTreasury,
+			} {
+				account, err := ledger.SystemAccount(db, accountType)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if account.Balance != want {
+					t.Errorf("%s account holds %s, want %s", accountType, account.Balance, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRedeemVoucherRejectsTamperedCodes(t *testing.T) {
+	db := newTestDB(t)
+	alice := newTestUser(t, db, "alice")
+	bob := newTestUser(t, db, "bob")
+	wallets := NewWalletService(db)
+	wallets.SetSigningSecret("test secret")
+
+	voucher, err := wallets.IssueVoucher(alice.ID, models.FC(10), "", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := NewWalletService(db)
+	forged.SetSigningSecret("another secret")
+	parts := strings.Split(voucher.Code, ".")
+
+	for name, code := range map[string]string{
+		"malformed":       "FCV1." + parts[1],
+		"bad signature":   forged.voucherCode(voucher),
+		"swapped payload": parts[0] + "." + parts[1] + "x." + parts[2],
+	} {
+		if _, err := wallets.RedeemVoucher(code, bob.ID); !errors.Is(err, ErrVoucherInvalid) {
+			t.Errorf("%s code: err = %v", name, err)
+		}
+	}
+	if balance := walletBalance(t, db, bob.ID); balance != models.FC(100) {
+		t.Errorf("bob holds %s after rejected codes, want 100", balance)
+	}
+}